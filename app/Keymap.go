@@ -1,6 +1,11 @@
 package app
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/gdamore/tcell/v2"
 	. "github.com/jorgerojas26/lazysql/commands"
 	. "github.com/jorgerojas26/lazysql/keymap"
@@ -12,6 +17,10 @@ import (
 type KeymapSystem struct {
 	Global Map
 	Groups map[string]Map
+	// Leader is the key <leader> stands for in the sequences of the config
+	Leader Key
+	// Timeout is how long a sequence waits for its next key
+	Timeout time.Duration
 }
 
 func (c KeymapSystem) Group(name string) Map {
@@ -35,8 +44,22 @@ func (c KeymapSystem) Resolve(event *tcell.EventKey) Command {
 	return c.Global.Resolve(event)
 }
 
+// Maps returns the maps read while a group has the focus: the group, along
+// with the global one for the groups of the home view
+func (c KeymapSystem) Maps(group string) []Map {
+	for _, home := range homeGroups {
+		if home == group {
+			return []Map{c.Group(group), c.Global}
+		}
+	}
+
+	return []Map{c.Group(group)}
+}
+
 // Define a global KeymapSystem object with default keybinds
 var Keymaps KeymapSystem = KeymapSystem{
+	Leader:  Key{Char: '\\'},
+	Timeout: time.Second,
 	Global: Map{
 		Bind{Key: Key{Char: 'L'}, Cmd: MoveRight},
 		Bind{Key: Key{Char: 'H'}, Cmd: MoveLeft},
@@ -44,6 +67,7 @@ var Keymaps KeymapSystem = KeymapSystem{
 		Bind{Key: Key{Code: tcell.KeyCtrlS}, Cmd: Save},
 		Bind{Key: Key{Char: 'q'}, Cmd: Quit},
 		Bind{Key: Key{Code: tcell.KeyBackspace2}, Cmd: SwitchToConnectionsView},
+		Bind{Key: Key{Char: '?'}, Cmd: Help},
 	},
 	Groups: map[string]Map{
 		"tree": {
@@ -54,8 +78,17 @@ var Keymaps KeymapSystem = KeymapSystem{
 			Bind{Key: Key{Code: tcell.KeyDown}, Cmd: MoveDown},
 			Bind{Key: Key{Char: 'k'}, Cmd: MoveUp},
 			Bind{Key: Key{Code: tcell.KeyUp}, Cmd: MoveUp},
+			Bind{Key: Key{Char: 'I'}, Cmd: Import},
 		},
 		"table": {
+			Bind{Key: Key{Char: 'j'}, Cmd: MoveDown},
+			Bind{Key: Key{Code: tcell.KeyDown}, Cmd: MoveDown},
+			Bind{Key: Key{Char: 'k'}, Cmd: MoveUp},
+			Bind{Key: Key{Code: tcell.KeyUp}, Cmd: MoveUp},
+			Bind{Key: Key{Char: 'h'}, Cmd: MoveLeft},
+			Bind{Key: Key{Code: tcell.KeyLeft}, Cmd: MoveLeft},
+			Bind{Key: Key{Char: 'l'}, Cmd: MoveRight},
+			Bind{Key: Key{Code: tcell.KeyRight}, Cmd: MoveRight},
 			Bind{Key: Key{Char: '/'}, Cmd: Search},
 			Bind{Key: Key{Char: 'c'}, Cmd: Edit},
 			Bind{Key: Key{Char: 'd'}, Cmd: Delete},
@@ -64,7 +97,18 @@ var Keymaps KeymapSystem = KeymapSystem{
 			Bind{Key: Key{Char: '$'}, Cmd: GotoEnd},
 			Bind{Key: Key{Char: '0'}, Cmd: GotoStart},
 			Bind{Key: Key{Char: 'y'}, Cmd: Copy},
+			Bind{Key: Key{Char: 'v'}, Cmd: Visual},
+			Bind{Key: Key{Char: 'V'}, Cmd: VisualRows},
+			Bind{Key: Key{Code: tcell.KeyCtrlV}, Cmd: VisualColumns},
 			Bind{Key: Key{Char: 'o'}, Cmd: AppendNewRow},
+			Bind{Key: Key{Char: 'E'}, Cmd: Export},
+			Bind{Key: Key{Char: 'K'}, Cmd: SortAscending},
+			Bind{Key: Key{Char: 'J'}, Cmd: SortDescending},
+			Bind{Key: Key{Code: tcell.KeyCtrlD}, Cmd: HalfPageDown},
+			Bind{Key: Key{Code: tcell.KeyCtrlU}, Cmd: HalfPageUp},
+			// While editing a cell
+			Bind{Key: Key{Code: tcell.KeyCtrlN}, Cmd: SetValueNull},
+			Bind{Key: Key{Code: tcell.KeyCtrlG}, Cmd: SetValueDefault},
 			// Tabs
 			Bind{Key: Key{Char: '['}, Cmd: TabPrev},
 			Bind{Key: Key{Char: ']'}, Cmd: TabNext},
@@ -74,11 +118,200 @@ var Keymaps KeymapSystem = KeymapSystem{
 			// Pages
 			Bind{Key: Key{Char: '>'}, Cmd: PageNext},
 			Bind{Key: Key{Char: '<'}, Cmd: PagePrev},
+			// Menu options, or the results of the editor
+			Bind{Key: Key{Char: '1'}, Cmd: Menu1},
+			Bind{Key: Key{Char: '2'}, Cmd: Menu2},
+			Bind{Key: Key{Char: '3'}, Cmd: Menu3},
+			Bind{Key: Key{Char: '4'}, Cmd: Menu4},
+			Bind{Key: Key{Char: '5'}, Cmd: Menu5},
+			Bind{Key: Key{Char: '6'}, Cmd: Menu6},
+			Bind{Key: Key{Char: '7'}, Cmd: Menu7},
+			Bind{Key: Key{Char: '8'}, Cmd: Menu8},
+			Bind{Key: Key{Char: '9'}, Cmd: Menu9},
 		},
 		"editor": {
 			Bind{Key: Key{Code: tcell.KeyCtrlR}, Cmd: Execute},
+			Bind{Key: Key{Code: tcell.KeyCtrlG}, Cmd: ExecuteStatement},
 			Bind{Key: Key{Code: tcell.KeyEscape}, Cmd: Quit},
 			Bind{Key: Key{Code: tcell.KeyCtrlSpace}, Cmd: OpenInExternalEditor},
+			Bind{Key: Key{Code: tcell.KeyCtrlP}, Cmd: ShowHistory},
+			Bind{Key: Key{Code: tcell.KeyF1}, Cmd: Help},
+		},
+		"connections": {
+			Bind{Key: Key{Char: 'c'}, Cmd: Connect},
+			Bind{Key: Key{Code: tcell.KeyEnter}, Cmd: Connect},
+			Bind{Key: Key{Char: 'n'}, Cmd: NewConnection},
+			Bind{Key: Key{Char: 'e'}, Cmd: Edit},
+			Bind{Key: Key{Char: 'd'}, Cmd: Delete},
+			Bind{Key: Key{Char: 'q'}, Cmd: Quit},
+			Bind{Key: Key{Char: '?'}, Cmd: Help},
 		},
 	},
 }
+
+// homeGroups are the groups whose keys are read along with the global ones
+var homeGroups = []string{"tree", "table", "editor"}
+
+// Configure replaces the keys of the commands set in the [keymap] section of
+// the config, e.g.
+//
+//	[keymap]
+//	leader = "<Space>"
+//	timeout = 1000
+//
+//	[keymap.table]
+//	SortAscending = "<A-k>"
+//	Edit = ["c", "<CR>"]
+//	Delete = "dd"
+//	Export = "<leader>e"
+//
+// An empty list unbinds a command. Every unknown group, command or key is
+// reported, and so is a key bound to two commands of a group, or to a command
+// of a group and a global one.
+func (c *KeymapSystem) Configure(config map[string]interface{}) error {
+	errs := []error{}
+
+	if notation, ok := config["leader"]; ok {
+		leader, err := parseLeader(notation)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("keymap.leader: %w", err))
+		} else {
+			c.Leader = leader
+		}
+	}
+
+	if timeout, ok := config["timeout"]; ok {
+		if milliseconds, ok := timeout.(int64); ok && milliseconds > 0 {
+			c.Timeout = time.Duration(milliseconds) * time.Millisecond
+		} else {
+			errs = append(errs, fmt.Errorf("keymap.timeout: expected a number of milliseconds"))
+		}
+	}
+
+	for _, group := range sortedKeys(config) {
+		if group == "leader" || group == "timeout" {
+			continue
+		}
+
+		entries, ok := config[group].(map[string]interface{})
+
+		if !ok || (group != "global" && c.Groups[group] == nil) {
+			errs = append(errs, fmt.Errorf("keymap: unknown group %q", group))
+			continue
+		}
+
+		binds := c.Group(group)
+
+		for _, name := range sortedKeys(entries) {
+			command, ok := Parse(name)
+			if !ok || command == Noop {
+				errs = append(errs, fmt.Errorf("keymap.%s: unknown command %q", group, name))
+				continue
+			}
+
+			var notations []string
+
+			switch value := entries[name].(type) {
+			case string:
+				notations = []string{value}
+			case []interface{}:
+				for _, item := range value {
+					notation, ok := item.(string)
+					if !ok {
+						errs = append(errs, fmt.Errorf("keymap.%s.%s: %v isn't a key", group, name, item))
+						continue
+					}
+
+					notations = append(notations, notation)
+				}
+			default:
+				errs = append(errs, fmt.Errorf("keymap.%s.%s: expected a key or a list of keys", group, name))
+				continue
+			}
+
+			kept := Map{}
+
+			for _, bind := range binds {
+				if bind.Cmd != command {
+					kept = append(kept, bind)
+				}
+			}
+
+			binds = kept
+
+			for _, notation := range notations {
+				keys, err := ParseSequence(notation, c.Leader)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("keymap.%s.%s: %w", group, name, err))
+					continue
+				}
+
+				binds = append(binds, Bind{Prefix: keys[:len(keys)-1], Key: keys[len(keys)-1], Cmd: command})
+			}
+		}
+
+		if group == "global" {
+			c.Global = binds
+		} else {
+			c.Groups[group] = binds
+		}
+	}
+
+	errs = append(errs, conflicts("global", c.Global, nil)...)
+
+	for _, group := range sortedKeys(c.Groups) {
+		var global Map
+
+		if maps := c.Maps(group); len(maps) > 1 {
+			global = maps[1]
+		}
+
+		errs = append(errs, conflicts(group, c.Groups[group], global)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// conflicts reports the keys bound to two commands of a map, or to a command
+// of the map and another of global
+func conflicts(group string, binds Map, global Map) []error {
+	errs := []error{}
+
+	for i, bind := range binds {
+		for _, other := range binds[:i] {
+			if SequenceString(other.Keys()) == SequenceString(bind.Keys()) && other.Cmd != bind.Cmd {
+				errs = append(errs, fmt.Errorf("keymap.%s: %s is bound to both %s and %s", group, SequenceString(bind.Keys()), other.Cmd, bind.Cmd))
+			}
+		}
+
+		for _, other := range global {
+			if SequenceString(other.Keys()) == SequenceString(bind.Keys()) && other.Cmd != bind.Cmd {
+				errs = append(errs, fmt.Errorf("keymap.%s: %s is bound to %s, and to %s in keymap.global", group, SequenceString(bind.Keys()), bind.Cmd, other.Cmd))
+			}
+		}
+	}
+
+	return errs
+}
+
+// parseLeader reads the leader key, a single key
+func parseLeader(notation interface{}) (Key, error) {
+	text, ok := notation.(string)
+	if !ok {
+		return Key{}, fmt.Errorf("expected a key")
+	}
+
+	return ParseKey(text)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/jorgerojas26/lazysql/commands"
+	"github.com/jorgerojas26/lazysql/keymap"
+)
+
+// configureKeymaps configures a copy of the default keymaps with the [keymap]
+// section of a config file
+func configureKeymaps(t *testing.T, config string) (KeymapSystem, error) {
+	t.Helper()
+
+	var file struct {
+		Keymap map[string]interface{} `toml:"keymap"`
+	}
+
+	err := toml.Unmarshal([]byte(config), &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keymaps := Keymaps
+	keymaps.Groups = map[string]keymap.Map{}
+
+	for group, binds := range Keymaps.Groups {
+		keymaps.Groups[group] = binds
+	}
+
+	err = keymaps.Configure(file.Keymap)
+
+	return keymaps, err
+}
+
+func TestConfigureDefaults(t *testing.T) {
+	if _, err := configureKeymaps(t, ""); err != nil {
+		t.Errorf("the default keymaps have conflicts: %v", err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	keymaps, err := configureKeymaps(t, `
+[keymap]
+leader = "<Space>"
+timeout = 500
+
+[keymap.table]
+SortAscending = "<A-k>"
+Edit = ["c", "<CR>"]
+Delete = "dd"
+Export = "<leader>e"
+Copy = []
+
+[keymap.connections]
+NewConnection = "H"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keymaps.Leader != (keymap.Key{Char: ' '}) {
+		t.Errorf("leader = %v, want <Space>", keymaps.Leader)
+	}
+
+	if keymaps.Timeout != 500*time.Millisecond {
+		t.Errorf("timeout = %s, want 500ms", keymaps.Timeout)
+	}
+
+	tests := []struct {
+		group    string
+		notation string
+		command  commands.Command
+		prefix   bool
+	}{
+		{"table", "<A-k>", commands.SortAscending, false},
+		{"table", "K", commands.Noop, false},
+		{"table", "c", commands.Edit, false},
+		{"table", "<CR>", commands.Edit, false},
+		{"table", "dd", commands.Delete, false},
+		{"table", "d", commands.Noop, true},
+		{"table", "<Space>e", commands.Export, false},
+		{"table", "E", commands.Noop, false},
+		{"table", "y", commands.Noop, false},
+		// The commands that aren't set keep their keys
+		{"table", "j", commands.MoveDown, false},
+		{"connections", "H", commands.NewConnection, false},
+		{"connections", "n", commands.Noop, false},
+		{"global", "H", commands.MoveLeft, false},
+	}
+
+	for _, test := range tests {
+		keys, err := keymap.ParseSequence(test.notation, keymaps.Leader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		command, prefix := keymaps.Group(test.group).Match(keys)
+		if command != test.command || prefix != test.prefix {
+			t.Errorf("%s in %s is %s, prefix: %t, want %s, prefix: %t", test.notation, test.group, command, prefix, test.command, test.prefix)
+		}
+	}
+}
+
+func TestConfigureErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "unknown group",
+			config: "[keymap.nope]\nQuit = \"q\"",
+			err:    `keymap: unknown group "nope"`,
+		},
+		{
+			name:   "unknown command",
+			config: "[keymap.table]\nFly = \"f\"",
+			err:    `keymap.table: unknown command "Fly"`,
+		},
+		{
+			name:   "unknown key",
+			config: "[keymap.table]\nEdit = \"<Nope>\"",
+			err:    "keymap.table.Edit: ",
+		},
+		{
+			name:   "not a key",
+			config: "[keymap.table]\nEdit = 1",
+			err:    "keymap.table.Edit: expected a key or a list of keys",
+		},
+		{
+			name:   "not a key in a list",
+			config: "[keymap.table]\nEdit = [\"c\", 2]",
+			err:    "keymap.table.Edit: 2 isn't a key",
+		},
+		{
+			name:   "invalid leader",
+			config: "[keymap]\nleader = \"ab\"",
+			err:    "keymap.leader: ",
+		},
+		{
+			name:   "invalid timeout",
+			config: "[keymap]\ntimeout = \"1s\"",
+			err:    "keymap.timeout: expected a number of milliseconds",
+		},
+		{
+			name:   "timeout of zero",
+			config: "[keymap]\ntimeout = 0",
+			err:    "keymap.timeout: expected a number of milliseconds",
+		},
+		{
+			name:   "key of two commands of a group",
+			config: "[keymap.table]\nEdit = \"d\"",
+			err:    "keymap.table: d is bound to both Delete and Edit",
+		},
+		{
+			name:   "key of a command of a group and a global one",
+			config: "[keymap.table]\nEdit = \"q\"",
+			err:    "keymap.table: q is bound to Edit, and to Quit in keymap.global",
+		},
+		{
+			name:   "key of two global commands",
+			config: "[keymap.global]\nHelp = \"q\"",
+			err:    "keymap.global: q is bound to both Quit and Help",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := configureKeymaps(t, test.config)
+
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Configure() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestConfigureReportsEveryError(t *testing.T) {
+	keymaps, err := configureKeymaps(t, "[keymap.table]\nFly = \"f\"\nEdit = \"<Nope>\"\nCopy = \"Y\"")
+
+	if err == nil || strings.Count(err.Error(), "\n") != 1 {
+		t.Fatalf("Configure() error = %v, want the two errors", err)
+	}
+
+	if command, _ := keymaps.Group("table").Match([]keymap.Key{{Char: 'Y'}}); command != commands.Copy {
+		t.Errorf("Y is %s, want the valid keys set along with the errors", command)
+	}
+}
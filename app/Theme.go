@@ -0,0 +1,423 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// SyntaxTheme holds the colors the statements of the SQL editor are
+// highlighted with.
+type SyntaxTheme struct {
+	KeywordColor    tcell.Color
+	IdentifierColor tcell.Color
+	StringColor     tcell.Color
+	NumberColor     tcell.Color
+	CommentColor    tcell.Color
+	// PlaceholderColor is the color of bind parameters, e.g. ? or $1
+	PlaceholderColor tcell.Color
+	// ErrorColor marks strings, quoted identifiers and comments left open
+	ErrorColor tcell.Color
+}
+
+// Theme holds the colors of the interface. The ones tview has a place for
+// are set in tview.Styles, the components read the others from Styles.
+type Theme struct {
+	// Background is the background of the views, the default one keeps the
+	// background of the terminal
+	Background tcell.Color
+	// ModalBackground is the background of the modals and menus drawn over
+	// the views
+	ModalBackground tcell.Color
+	Border          tcell.Color
+	Text            tcell.Color
+	// SecondaryText is the color of the headers, labels and shortcuts
+	SecondaryText tcell.Color
+	TertiaryText  tcell.Color
+	// DimText is the color of the views that don't have the focus
+	DimText tcell.Color
+	// Contrast, MoreContrast and ContrastText are the colors tview draws
+	// its modals, buttons and drop-downs with when they aren't set
+	Contrast     tcell.Color
+	MoreContrast tcell.Color
+	ContrastText tcell.Color
+	// Focus is the border of the view that has the focus
+	Focus tcell.Color
+	// Selection and SelectionText are the colors of the selected row or cell.
+	// SelectionText is also the text written over the other colors, e.g. on
+	// the error modal.
+	Selection     tcell.Color
+	SelectionText tcell.Color
+	// Visual is the background of the cells selected in visual mode
+	Visual     tcell.Color
+	Field      tcell.Color
+	FieldText  tcell.Color
+	Button     tcell.Color
+	ButtonText tcell.Color
+	// ButtonKey is the color of the key written on a button
+	ButtonKey tcell.Color
+	// Accent is the color of the keywords written in the interface, e.g. the
+	// WHERE of the filter
+	Accent tcell.Color
+	// PendingUpdate, PendingInsert and PendingDelete mark the changes that
+	// aren't saved yet, on the cells and on the tables of the tree
+	PendingUpdate tcell.Color
+	PendingInsert tcell.Color
+	PendingDelete tcell.Color
+	Error         tcell.Color
+	Warning       tcell.Color
+	Success       tcell.Color
+	Syntax        SyntaxTheme
+	// NoColor draws the interface without colors, the cells drawn over a
+	// background color are reversed instead
+	NoColor bool
+}
+
+// Themes are the built-in themes, by name
+var Themes = map[string]Theme{
+	"dark": {
+		Background:      tcell.ColorDefault,
+		ModalBackground: tcell.ColorBlack,
+		Border:          tcell.ColorWhite,
+		Text:            tcell.ColorWhite.TrueColor(),
+		SecondaryText:   tcell.ColorCadetBlue,
+		TertiaryText:    tcell.ColorGreen,
+		DimText:         tcell.ColorDarkGray,
+		Contrast:        tcell.ColorBlue,
+		MoreContrast:    tcell.ColorGreen,
+		ContrastText:    tcell.ColorNavy,
+		Focus:           tcell.ColorWhite.TrueColor(),
+		Selection:       tcell.ColorCadetBlue,
+		SelectionText:   tcell.ColorBlack.TrueColor(),
+		Visual:          tcell.ColorDarkCyan.TrueColor(),
+		Field:           tcell.ColorWhite,
+		FieldText:       tcell.ColorBlack,
+		Button:          tcell.ColorGhostWhite,
+		ButtonText:      tcell.ColorBlack,
+		ButtonKey:       tcell.ColorDarkRed,
+		Accent:          tcell.ColorOrange,
+		PendingUpdate:   tcell.ColorDarkOrange.TrueColor(),
+		PendingInsert:   tcell.ColorDarkGreen.TrueColor(),
+		PendingDelete:   tcell.ColorRed,
+		Error:           tcell.ColorRed,
+		Warning:         tcell.ColorOrange,
+		Success:         tcell.ColorGreen,
+		Syntax: SyntaxTheme{
+			KeywordColor:     tcell.ColorCadetBlue,
+			IdentifierColor:  tcell.ColorWhite.TrueColor(),
+			StringColor:      tcell.ColorGreen,
+			NumberColor:      tcell.ColorOrange,
+			CommentColor:     tcell.ColorDarkGray,
+			PlaceholderColor: tcell.ColorYellow,
+			ErrorColor:       tcell.ColorRed,
+		},
+	},
+	"light": {
+		Background:      tcell.ColorDefault,
+		ModalBackground: tcell.NewHexColor(0xeeeeee),
+		Border:          tcell.ColorBlack,
+		Text:            tcell.ColorBlack.TrueColor(),
+		SecondaryText:   tcell.NewHexColor(0x005f87),
+		TertiaryText:    tcell.NewHexColor(0x008700),
+		DimText:         tcell.ColorGray,
+		Contrast:        tcell.NewHexColor(0x0087d7),
+		MoreContrast:    tcell.NewHexColor(0xd7d7ff),
+		ContrastText:    tcell.NewHexColor(0x5f5f5f),
+		Focus:           tcell.ColorBlack.TrueColor(),
+		Selection:       tcell.NewHexColor(0x005f87),
+		SelectionText:   tcell.ColorWhite.TrueColor(),
+		Visual:          tcell.NewHexColor(0xafd7ff),
+		Field:           tcell.NewHexColor(0xd0d0d0),
+		FieldText:       tcell.ColorBlack,
+		Button:          tcell.NewHexColor(0xd0d0d0),
+		ButtonText:      tcell.ColorBlack,
+		ButtonKey:       tcell.ColorDarkRed,
+		Accent:          tcell.NewHexColor(0xaf5f00),
+		PendingUpdate:   tcell.NewHexColor(0xd78700),
+		PendingInsert:   tcell.NewHexColor(0x5faf5f),
+		PendingDelete:   tcell.NewHexColor(0xd75f5f),
+		Error:           tcell.NewHexColor(0xd70000),
+		Warning:         tcell.NewHexColor(0xaf5f00),
+		Success:         tcell.NewHexColor(0x008700),
+		Syntax: SyntaxTheme{
+			KeywordColor:     tcell.NewHexColor(0x005f87),
+			IdentifierColor:  tcell.ColorBlack.TrueColor(),
+			StringColor:      tcell.NewHexColor(0x008700),
+			NumberColor:      tcell.NewHexColor(0xaf5f00),
+			CommentColor:     tcell.ColorGray,
+			PlaceholderColor: tcell.NewHexColor(0x8700af),
+			ErrorColor:       tcell.NewHexColor(0xd70000),
+		},
+	},
+	"high-contrast": {
+		Background:      tcell.ColorBlack,
+		ModalBackground: tcell.ColorBlack,
+		Border:          tcell.ColorWhite,
+		Text:            tcell.ColorWhite.TrueColor(),
+		SecondaryText:   tcell.ColorAqua,
+		TertiaryText:    tcell.ColorLime,
+		DimText:         tcell.ColorSilver,
+		Contrast:        tcell.ColorBlue,
+		MoreContrast:    tcell.ColorBlack,
+		ContrastText:    tcell.ColorSilver,
+		Focus:           tcell.ColorYellow,
+		Selection:       tcell.ColorYellow,
+		SelectionText:   tcell.ColorBlack.TrueColor(),
+		Visual:          tcell.NewHexColor(0x0000af),
+		Field:           tcell.ColorWhite,
+		FieldText:       tcell.ColorBlack,
+		Button:          tcell.ColorWhite,
+		ButtonText:      tcell.ColorBlack,
+		ButtonKey:       tcell.ColorRed,
+		Accent:          tcell.ColorYellow,
+		PendingUpdate:   tcell.NewHexColor(0xaf5f00),
+		PendingInsert:   tcell.NewHexColor(0x008700),
+		PendingDelete:   tcell.NewHexColor(0xd70000),
+		Error:           tcell.NewHexColor(0xff5f5f),
+		Warning:         tcell.ColorYellow,
+		Success:         tcell.ColorLime,
+		Syntax: SyntaxTheme{
+			KeywordColor:     tcell.ColorAqua,
+			IdentifierColor:  tcell.ColorWhite.TrueColor(),
+			StringColor:      tcell.ColorLime,
+			NumberColor:      tcell.ColorYellow,
+			CommentColor:     tcell.ColorSilver,
+			PlaceholderColor: tcell.ColorFuchsia,
+			ErrorColor:       tcell.NewHexColor(0xff5f5f),
+		},
+	},
+	// The colors of no-color are only told apart from the default one: the
+	// cells drawn over a background are reversed, the others are plain
+	"no-color": {
+		Background:      tcell.ColorDefault,
+		ModalBackground: tcell.ColorDefault,
+		Border:          tcell.ColorWhite,
+		Text:            tcell.ColorWhite,
+		SecondaryText:   tcell.ColorWhite,
+		TertiaryText:    tcell.ColorWhite,
+		DimText:         tcell.ColorWhite,
+		Contrast:        tcell.ColorWhite,
+		MoreContrast:    tcell.ColorWhite,
+		ContrastText:    tcell.ColorWhite,
+		Focus:           tcell.ColorWhite,
+		Selection:       tcell.ColorWhite,
+		SelectionText:   tcell.ColorDefault,
+		Visual:          tcell.ColorWhite,
+		Field:           tcell.ColorWhite,
+		FieldText:       tcell.ColorDefault,
+		Button:          tcell.ColorWhite,
+		ButtonText:      tcell.ColorDefault,
+		ButtonKey:       tcell.ColorDefault,
+		Accent:          tcell.ColorWhite,
+		PendingUpdate:   tcell.ColorWhite,
+		PendingInsert:   tcell.ColorWhite,
+		PendingDelete:   tcell.ColorWhite,
+		Error:           tcell.ColorWhite,
+		Warning:         tcell.ColorWhite,
+		Success:         tcell.ColorWhite,
+		Syntax: SyntaxTheme{
+			KeywordColor:     tcell.ColorWhite,
+			IdentifierColor:  tcell.ColorWhite,
+			StringColor:      tcell.ColorWhite,
+			NumberColor:      tcell.ColorWhite,
+			CommentColor:     tcell.ColorWhite,
+			PlaceholderColor: tcell.ColorWhite,
+			ErrorColor:       tcell.ColorWhite,
+		},
+		NoColor: true,
+	},
+}
+
+// DefaultTheme is the theme used when the config doesn't set one
+const DefaultTheme = "dark"
+
+// Styles is the theme the interface is drawn with
+var Styles Theme
+
+func init() {
+	Styles = Themes[DefaultTheme]
+	Styles.apply()
+}
+
+// colors returns the colors of the theme by the name they are set with in
+// the config
+func (theme *Theme) colors() map[string]*tcell.Color {
+	return map[string]*tcell.Color{
+		"background":       &theme.Background,
+		"modal_background": &theme.ModalBackground,
+		"border":           &theme.Border,
+		"text":             &theme.Text,
+		"secondary_text":   &theme.SecondaryText,
+		"tertiary_text":    &theme.TertiaryText,
+		"dim_text":         &theme.DimText,
+		"contrast":         &theme.Contrast,
+		"more_contrast":    &theme.MoreContrast,
+		"contrast_text":    &theme.ContrastText,
+		"focus":            &theme.Focus,
+		"selection":        &theme.Selection,
+		"selection_text":   &theme.SelectionText,
+		"visual":           &theme.Visual,
+		"field":            &theme.Field,
+		"field_text":       &theme.FieldText,
+		"button":           &theme.Button,
+		"button_text":      &theme.ButtonText,
+		"button_key":       &theme.ButtonKey,
+		"accent":           &theme.Accent,
+		"pending_update":   &theme.PendingUpdate,
+		"pending_insert":   &theme.PendingInsert,
+		"pending_delete":   &theme.PendingDelete,
+		"error":            &theme.Error,
+		"warning":          &theme.Warning,
+		"success":          &theme.Success,
+		"keyword":          &theme.Syntax.KeywordColor,
+		"identifier":       &theme.Syntax.IdentifierColor,
+		"string":           &theme.Syntax.StringColor,
+		"number":           &theme.Syntax.NumberColor,
+		"comment":          &theme.Syntax.CommentColor,
+		"placeholder":      &theme.Syntax.PlaceholderColor,
+		"syntax_error":     &theme.Syntax.ErrorColor,
+	}
+}
+
+// apply sets the colors tview draws with by default
+func (theme *Theme) apply() {
+	tview.Styles = tview.Theme{
+		PrimitiveBackgroundColor:    theme.Background,
+		ContrastBackgroundColor:     theme.Contrast,
+		MoreContrastBackgroundColor: theme.MoreContrast,
+		BorderColor:                 theme.Border,
+		TitleColor:                  theme.Border,
+		GraphicsColor:               theme.Border,
+		PrimaryTextColor:            theme.Text,
+		SecondaryTextColor:          theme.SecondaryText,
+		TertiaryTextColor:           theme.TertiaryText,
+		InverseTextColor:            theme.DimText,
+		ContrastSecondaryTextColor:  theme.ContrastText,
+	}
+}
+
+// SetTheme draws the interface with one of the built-in themes, with the
+// colors of the [theme] section of the config on top of it, e.g.
+//
+//	[theme]
+//	name = "light"
+//
+//	[theme.colors]
+//	pending_update = "#ffaf00"
+//	focus = "blue"
+//
+// When the config doesn't name a theme and the NO_COLOR environment variable
+// is set, the interface is drawn without colors. Every unknown theme or color
+// is reported.
+func SetTheme(name string, colors map[string]string) error {
+	errs := []error{}
+
+	if name == "" && os.Getenv("NO_COLOR") != "" {
+		name = "no-color"
+	} else if name == "" {
+		name = DefaultTheme
+	}
+
+	theme, ok := Themes[name]
+	if !ok {
+		errs = append(errs, fmt.Errorf("theme: unknown theme %q, expected one of %s", name, strings.Join(themeNames(), ", ")))
+		theme = Themes[DefaultTheme]
+	}
+
+	fields := theme.colors()
+	names := make([]string, 0, len(colors))
+
+	for name := range colors {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		field, ok := fields[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("theme.colors: no color is named %q", name))
+			continue
+		}
+
+		color, err := parseColor(colors[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("theme.colors.%s: %w", name, err))
+			continue
+		}
+
+		*field = color
+	}
+
+	Styles = theme
+	Styles.apply()
+
+	return errors.Join(errs...)
+}
+
+// parseColor reads a color by its W3C name, e.g. "orange", or as a hex
+// string, e.g. "#ff8700". "default" is the color of the terminal.
+func parseColor(value string) (tcell.Color, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	if value == "default" {
+		return tcell.ColorDefault, nil
+	}
+
+	color := tcell.GetColor(value)
+	if color == tcell.ColorDefault {
+		return color, fmt.Errorf("unknown color %q, expected a name like \"orange\" or a hex color like \"#ff8700\"", value)
+	}
+
+	return color, nil
+}
+
+func themeNames() []string {
+	names := make([]string, 0, len(Themes))
+
+	for name := range Themes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Tag returns the style tag that writes the text after it in a color, e.g.
+// "[#ff8700]"
+func Tag(color tcell.Color) string {
+	if color == tcell.ColorDefault {
+		return "[-]"
+	}
+
+	return fmt.Sprintf("[#%06x]", color.Hex())
+}
+
+// noColorScreen draws the styles without their colors. The cells drawn over
+// a background color are reversed, so that the selections and the pending
+// changes still stand out.
+type noColorScreen struct {
+	tcell.Screen
+}
+
+// NoColorScreen returns screen drawing without colors, for the themes that
+// set NoColor
+func NoColorScreen(screen tcell.Screen) tcell.Screen {
+	return noColorScreen{Screen: screen}
+}
+
+func (screen noColorScreen) SetContent(x, y int, primary rune, combining []rune, style tcell.Style) {
+	_, background, attributes := style.Decompose()
+
+	if background != tcell.ColorDefault {
+		attributes |= tcell.AttrReverse
+	}
+
+	screen.Screen.SetContent(x, y, primary, combining, tcell.StyleDefault.Attributes(attributes))
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// keepStyles restores the theme once the test ends
+func keepStyles(t *testing.T) {
+	styles, tviewStyles := Styles, tview.Styles
+
+	t.Cleanup(func() {
+		Styles, tview.Styles = styles, tviewStyles
+	})
+}
+
+func TestThemes(t *testing.T) {
+	keepStyles(t)
+	t.Setenv("NO_COLOR", "")
+
+	for _, name := range themeNames() {
+		t.Run(name, func(t *testing.T) {
+			theme := Themes[name]
+
+			err := SetTheme(name, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if Styles != theme {
+				t.Errorf("Styles = %+v, want the %s theme", Styles, name)
+			}
+
+			if tview.Styles.PrimaryTextColor != theme.Text || tview.Styles.BorderColor != theme.Border {
+				t.Error("tview doesn't draw with the colors of the theme")
+			}
+
+			if theme.NoColor != (name == "no-color") {
+				t.Errorf("NoColor = %t", theme.NoColor)
+			}
+
+			// The background keeps the one of the terminal, the other colors
+			// are set unless the theme has none
+			for color, value := range theme.colors() {
+				if color != "background" && !theme.NoColor && *value == tcell.ColorDefault {
+					t.Errorf("%s isn't set", color)
+				}
+			}
+		})
+	}
+
+	if err := SetTheme("", nil); err != nil || Styles != Themes[DefaultTheme] {
+		t.Errorf("SetTheme() = %v, want the %s theme", err, DefaultTheme)
+	}
+}
+
+func TestSetThemeColors(t *testing.T) {
+	keepStyles(t)
+
+	err := SetTheme("light", map[string]string{
+		"focus":          "Blue",
+		"pending_update": "#ffaf00",
+		"keyword":        "default",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if Styles.Focus != tcell.ColorBlue || Styles.PendingUpdate != tcell.NewHexColor(0xffaf00) || Styles.Syntax.KeywordColor != tcell.ColorDefault {
+		t.Errorf("the colors set are %v, %v and %v", Styles.Focus, Styles.PendingUpdate, Styles.Syntax.KeywordColor)
+	}
+
+	if Styles.Text != Themes["light"].Text {
+		t.Errorf("text = %v, want the one of the light theme", Styles.Text)
+	}
+
+	if Themes["light"].Focus == tcell.ColorBlue {
+		t.Error("the colors set change the built-in theme")
+	}
+}
+
+func TestSetThemeErrors(t *testing.T) {
+	keepStyles(t)
+
+	err := SetTheme("solarized", map[string]string{
+		"focus":   "not a color",
+		"borders": "red",
+		"accent":  "red",
+	})
+	if err == nil {
+		t.Fatal("SetTheme() didn't fail")
+	}
+
+	for _, expected := range []string{
+		`theme: unknown theme "solarized", expected one of dark, high-contrast, light, no-color`,
+		`theme.colors: no color is named "borders"`,
+		`theme.colors.focus: unknown color "not a color"`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("SetTheme() error = %v, want %q", err, expected)
+		}
+	}
+
+	// The default theme is used instead, along with the valid colors
+	if Styles.Text != Themes[DefaultTheme].Text || Styles.Accent != tcell.ColorRed {
+		t.Errorf("the theme isn't the %s one with a red accent", DefaultTheme)
+	}
+}
+
+func TestSetThemeNoColor(t *testing.T) {
+	keepStyles(t)
+
+	tests := []struct {
+		noColor  string
+		name     string
+		expected string
+	}{
+		{noColor: "1", expected: "no-color"},
+		{noColor: "1", name: "light", expected: "light"},
+		{noColor: "", expected: DefaultTheme},
+		{noColor: "", name: "no-color", expected: "no-color"},
+	}
+
+	for _, test := range tests {
+		t.Setenv("NO_COLOR", test.noColor)
+
+		err := SetTheme(test.name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if Styles != Themes[test.expected] {
+			t.Errorf("NO_COLOR=%q with the theme %q draws another theme than %s", test.noColor, test.name, test.expected)
+		}
+	}
+}
+
+func TestNoColorScreen(t *testing.T) {
+	simulation := tcell.NewSimulationScreen("UTF-8")
+	if err := simulation.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer simulation.Fini()
+
+	screen := NoColorScreen(simulation)
+
+	tests := []struct {
+		style    tcell.Style
+		expected tcell.Style
+	}{
+		{
+			style:    tcell.StyleDefault.Foreground(tcell.ColorRed),
+			expected: tcell.StyleDefault,
+		},
+		{
+			style:    tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlue),
+			expected: tcell.StyleDefault.Reverse(true),
+		},
+		{
+			style:    tcell.StyleDefault.Background(tcell.ColorYellow).Bold(true),
+			expected: tcell.StyleDefault.Reverse(true).Bold(true),
+		},
+	}
+
+	for i, test := range tests {
+		screen.SetContent(i, 0, 'x', nil, test.style)
+
+		if _, _, style, _ := simulation.GetContent(i, 0); style != test.expected {
+			t.Errorf("%v is drawn as %v, want %v", test.style, style, test.expected)
+		}
+	}
+}
+
+func TestTag(t *testing.T) {
+	if tag := Tag(tcell.ColorDefault); tag != "[-]" {
+		t.Errorf("Tag(default) = %s, want [-]", tag)
+	}
+
+	if tag := Tag(tcell.NewHexColor(0xff8700)); tag != "[#ff8700]" {
+		t.Errorf("Tag(#ff8700) = %s, want [#ff8700]", tag)
+	}
+
+	if tag := Tag(tcell.ColorOrange); tag != "[#ffa500]" {
+		t.Errorf("Tag(orange) = %s, want [#ffa500]", tag)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jorgerojas26/lazysql/helpers"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected options
+		err      string
+	}{
+		{
+			name:     "no arguments",
+			expected: options{},
+		},
+		{
+			name:     "URL",
+			args:     []string{"postgres://localhost/app"},
+			expected: options{target: "postgres://localhost/app"},
+		},
+		{
+			name:     "flags after the URL",
+			args:     []string{"postgres://localhost/app", "--read-only", "--database", "logs"},
+			expected: options{target: "postgres://localhost/app", readOnly: true, database: "logs"},
+		},
+		{
+			name:     "flags around the file",
+			args:     []string{"--config", "lazysql.toml", "app.db", "--read-only"},
+			expected: options{config: "lazysql.toml", target: "app.db", readOnly: true},
+		},
+		{
+			name:     "saved connection",
+			args:     []string{"--connection", "prod", "--database", "logs"},
+			expected: options{connection: "prod", database: "logs"},
+		},
+		{
+			name: "two targets",
+			args: []string{"app.db", "logs.db"},
+			err:  "expected a single URL or file, got 2 arguments",
+		},
+		{
+			name: "connection along with a URL",
+			args: []string{"--connection", "prod", "postgres://localhost/app"},
+			err:  "--connection can't be used along with a URL or a file",
+		},
+		{
+			name: "database without a connection",
+			args: []string{"--database", "logs"},
+			err:  "--database needs a URL, a file or --connection",
+		},
+		{
+			name: "unknown flag",
+			args: []string{"--verbose"},
+			err:  "flag provided but not defined: -verbose",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := parseOptions(test.args, &bytes.Buffer{})
+
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("parseOptions(%q) error = %v, want %q", test.args, err, test.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if opts != test.expected {
+				t.Errorf("parseOptions(%q) = %+v, want %+v", test.args, opts, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseOptionsHelp(t *testing.T) {
+	output := &bytes.Buffer{}
+
+	_, err := parseOptions([]string{"--help"}, output)
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("parseOptions(--help) error = %v, want flag.ErrHelp", err)
+	}
+
+	if !strings.HasPrefix(output.String(), usage) || !strings.Contains(output.String(), "-read-only") {
+		t.Errorf("--help wrote %q, want the usage and the flags", output)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	valid := "[application]\nmax_query_rows = 50\n"
+	invalid := "[application\n"
+
+	tests := []struct {
+		name string
+		// file is the content of the config file, it's missing when empty
+		file string
+		// given is true when the file is given with --config
+		given        bool
+		expectedRows int
+		expectedErr  bool
+	}{
+		{name: "missing default file", expectedRows: 0},
+		{name: "missing given file", given: true, expectedErr: true},
+		{name: "default file", file: valid, expectedRows: 50},
+		{name: "given file", file: valid, given: true, expectedRows: 50},
+		{name: "invalid default file", file: invalid, expectedErr: true},
+		{name: "invalid given file", file: invalid, given: true, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Cleanup(func() { helpers.SetConfigFilePath("") })
+
+			path := filepath.Join(home, ".config", "lazysql", "config.toml")
+			opts := options{}
+
+			if test.given {
+				path = filepath.Join(home, "lazysql.toml")
+				opts.config = path
+			}
+
+			if test.file != "" {
+				err := os.MkdirAll(filepath.Dir(path), 0o755)
+				if err == nil {
+					err = os.WriteFile(path, []byte(test.file), 0o600)
+				}
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			config, err := opts.loadConfig()
+
+			if (err != nil) != test.expectedErr {
+				t.Fatalf("loadConfig() error = %v, want an error: %t", err, test.expectedErr)
+			}
+
+			if config.Application.MaxQueryRows != test.expectedRows {
+				t.Errorf("MaxQueryRows = %d, want %d", config.Application.MaxQueryRows, test.expectedRows)
+			}
+		})
+	}
+}
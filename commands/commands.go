@@ -1,5 +1,7 @@
 package commands
 
+import "fmt"
+
 type Command uint8
 
 const (
@@ -21,6 +23,8 @@ const (
 	GotoEnd
 	GotoTop
 	GotoBottom
+	HalfPageDown
+	HalfPageUp
 
 	// Movement: Page
 	PageNext
@@ -33,16 +37,43 @@ const (
 	TabLast
 	TabClose
 
+	// Menu: the options of the menu of a table, or the results of the editor
+	Menu1
+	Menu2
+	Menu3
+	Menu4
+	Menu5
+	Menu6
+	Menu7
+	Menu8
+	Menu9
+
+	// Connections
+	Connect
+	NewConnection
+
 	// Operations
 	Copy
+	Visual
+	VisualRows
+	VisualColumns
 	Edit
 	Save
 	Delete
 	Search
 	Quit
 	Execute
+	ExecuteStatement
 	OpenInExternalEditor
+	ShowHistory
+	Help
+	Export
+	Import
 	AppendNewRow
+	SetValueNull
+	SetValueDefault
+	SortAscending
+	SortDescending
 )
 
 func (c Command) String() string {
@@ -61,7 +92,7 @@ func (c Command) String() string {
 	case MoveDown:
 		return "MoveDown"
 	case MoveLeft:
-		return "MoveRight"
+		return "MoveLeft"
 	case MoveRight:
 		return "MoveRight"
 	// Movement: Jumps
@@ -77,6 +108,10 @@ func (c Command) String() string {
 		return "GotoTop"
 	case GotoBottom:
 		return "GotoBottom"
+	case HalfPageDown:
+		return "HalfPageDown"
+	case HalfPageUp:
+		return "HalfPageUp"
 
 	// Movement: Page
 	case PageNext:
@@ -96,9 +131,41 @@ func (c Command) String() string {
 	case TabClose:
 		return "TabClose"
 
+	// Menu
+	case Menu1:
+		return "Menu1"
+	case Menu2:
+		return "Menu2"
+	case Menu3:
+		return "Menu3"
+	case Menu4:
+		return "Menu4"
+	case Menu5:
+		return "Menu5"
+	case Menu6:
+		return "Menu6"
+	case Menu7:
+		return "Menu7"
+	case Menu8:
+		return "Menu8"
+	case Menu9:
+		return "Menu9"
+
+	// Connections
+	case Connect:
+		return "Connect"
+	case NewConnection:
+		return "NewConnection"
+
 	// Operations
 	case Copy:
 		return "Copy"
+	case Visual:
+		return "Visual"
+	case VisualRows:
+		return "VisualRows"
+	case VisualColumns:
+		return "VisualColumns"
 	case Edit:
 		return "Edit"
 	case Save:
@@ -111,10 +178,170 @@ func (c Command) String() string {
 		return "Quit"
 	case Execute:
 		return "Execute"
+	case ExecuteStatement:
+		return "ExecuteStatement"
 	case OpenInExternalEditor:
 		return "OpenInExternalEditor"
+	case ShowHistory:
+		return "ShowHistory"
+	case Help:
+		return "Help"
+	case Export:
+		return "Export"
+	case Import:
+		return "Import"
 	case AppendNewRow:
 		return "AppendNewRow"
+	case SetValueNull:
+		return "SetValueNull"
+	case SetValueDefault:
+		return "SetValueDefault"
+	case SortAscending:
+		return "SortAscending"
+	case SortDescending:
+		return "SortDescending"
 	}
 	return "Unknown"
 }
+
+// Description tells what the command does
+func (c Command) Description() string {
+	switch c {
+	// Views
+	case SwitchToEditorView:
+		return "Open the SQL editor"
+	case SwitchToConnectionsView:
+		return "Return to the connections"
+
+	// Movement: Basic
+	case MoveUp:
+		return "Move up"
+	case MoveDown:
+		return "Move down"
+	case MoveLeft:
+		return "Move left"
+	case MoveRight:
+		return "Move right"
+	// Movement: Jumps
+	case GotoNext:
+		return "Go to the next column"
+	case GotoPrev:
+		return "Go to the previous column"
+	case GotoStart:
+		return "Go to the first column"
+	case GotoEnd:
+		return "Go to the last column"
+	case GotoTop:
+		return "Go to the top"
+	case GotoBottom:
+		return "Go to the bottom"
+	case HalfPageDown:
+		return "Move half a page down"
+	case HalfPageUp:
+		return "Move half a page up"
+
+	// Movement: Page
+	case PageNext:
+		return "Show the next page, or the next result of the editor"
+	case PagePrev:
+		return "Show the previous page, or the previous result of the editor"
+
+	// Tabs
+	case TabNext:
+		return "Focus the next tab"
+	case TabPrev:
+		return "Focus the previous tab"
+	case TabFirst:
+		return "Focus the first tab"
+	case TabLast:
+		return "Focus the last tab"
+	case TabClose:
+		return "Close the tab"
+
+	// Menu
+	case Menu1:
+		return "Show the records, or the 1st result of the editor"
+	case Menu2:
+		return "Show the columns, or the 2nd result of the editor"
+	case Menu3:
+		return "Show the constraints, or the 3rd result of the editor"
+	case Menu4:
+		return "Show the foreign keys, or the 4th result of the editor"
+	case Menu5:
+		return "Show the indexes, or the 5th result of the editor"
+	case Menu6, Menu7, Menu8, Menu9:
+		return fmt.Sprintf("Show the %dth result of the editor", c-Menu1+1)
+
+	// Connections
+	case Connect:
+		return "Connect to the selected database"
+	case NewConnection:
+		return "Add a connection"
+
+	// Operations
+	case Copy:
+		return "Copy the cell, or the selection"
+	case Visual:
+		return "Select cells"
+	case VisualRows:
+		return "Select rows"
+	case VisualColumns:
+		return "Select columns"
+	case Edit:
+		return "Edit the cell"
+	case Save:
+		return "Commit the pending changes"
+	case Delete:
+		return "Delete the row"
+	case Search:
+		return "Focus the filter or the SQL editor"
+	case Quit:
+		return "Quit"
+	case Execute:
+		return "Open the table, or run the statements"
+	case ExecuteStatement:
+		return "Run the selection, or the statement under the cursor"
+	case OpenInExternalEditor:
+		return "Open the statements in an external editor"
+	case ShowHistory:
+		return "Search the query history of the connection"
+	case Help:
+		return "Show the keys"
+	case Export:
+		return "Export the results to a file"
+	case Import:
+		return "Import a file into the table"
+	case AppendNewRow:
+		return "Add a row"
+	case SetValueNull:
+		return "Set the cell being edited to NULL"
+	case SetValueDefault:
+		return "Set the cell being edited to its DEFAULT"
+	case SortAscending:
+		return "Sort by the column, ascending"
+	case SortDescending:
+		return "Sort by the column, descending"
+	}
+	return ""
+}
+
+// Parse returns the command named name, as String writes it
+func Parse(name string) (Command, bool) {
+	for c := Noop; c.String() != "Unknown"; c++ {
+		if c.String() == name {
+			return c, true
+		}
+	}
+
+	return Noop, false
+}
+
+// MenuIndex returns the option of the menu a Menu command selects, from 0, or
+// -1 for the other commands
+func MenuIndex(c Command) int {
+	if c >= Menu1 && c <= Menu9 {
+		return int(c - Menu1)
+	}
+
+	return -1
+}
@@ -1,8 +1,9 @@
 package components
 
 import (
-	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/app"
 )
 
 type ConfirmationModal struct {
@@ -17,7 +18,7 @@ func NewConfirmationModal(confirmationText string) *ConfirmationModal {
 		modal.SetText("Are you sure?")
 	}
 	modal.AddButtons([]string{"Yes", "No"})
-	modal.SetBackgroundColor(tcell.ColorBlack)
+	modal.SetBackgroundColor(app.Styles.ModalBackground)
 	modal.SetTextColor(tview.Styles.PrimaryTextColor)
 
 	return &ConfirmationModal{
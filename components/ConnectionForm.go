@@ -1,8 +1,11 @@
 package components
 
 import (
-	"strings"
+	"context"
+	"errors"
+	"fmt"
 
+	"github.com/jorgerojas26/lazysql/app"
 	"github.com/jorgerojas26/lazysql/drivers"
 	"github.com/jorgerojas26/lazysql/helpers"
 	"github.com/jorgerojas26/lazysql/models"
@@ -23,29 +26,37 @@ func NewConnectionForm(connectionPages *models.ConnectionPages) *ConnectionForm
 
 	wrapper.SetDirection(tview.FlexColumnCSS)
 
-	addForm := tview.NewForm().SetFieldBackgroundColor(tcell.ColorWhite).SetButtonBackgroundColor(tcell.ColorWhite).SetLabelColor(tcell.ColorWhite.TrueColor()).SetFieldTextColor(tcell.ColorBlack)
+	addForm := tview.NewForm().SetFieldBackgroundColor(app.Styles.Field).SetButtonBackgroundColor(app.Styles.Button).SetLabelColor(tview.Styles.PrimaryTextColor).SetFieldTextColor(app.Styles.FieldText)
 	addForm.AddInputField("Name", "", 0, nil, nil)
 	addForm.AddInputField("URL", "", 0, nil, nil)
+	addForm.AddInputField("Password command", "", 0, nil, nil)
+	addForm.AddCheckbox("Password in keyring", false, nil)
+	addForm.AddCheckbox("Read-only", false, nil)
+	addForm.AddInputField("SSH host", "", 0, nil, nil)
+	addForm.AddInputField("SSH user", "", 0, nil, nil)
+	addForm.AddInputField("SSH key file", "", 0, nil, nil)
+	addForm.AddCheckbox("SSH agent", false, nil)
+	addForm.AddInputField("SSH known_hosts", "", 0, nil, nil)
 
 	buttonsWrapper := tview.NewFlex().SetDirection(tview.FlexColumn)
 
-	saveButton := tview.NewButton("[darkred]F1 [black]Save")
-	saveButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorGhostWhite))
+	saveButton := tview.NewButton(buttonLabel("F1 ", "Save"))
+	saveButton.SetStyle(tcell.StyleDefault.Background(app.Styles.Button))
 	buttonsWrapper.AddItem(saveButton, 0, 1, false)
 	buttonsWrapper.AddItem(nil, 1, 0, false)
 
-	testButton := tview.NewButton("[darkred]F2 [black]Test")
-	testButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorGhostWhite))
+	testButton := tview.NewButton(buttonLabel("F2 ", "Test"))
+	testButton.SetStyle(tcell.StyleDefault.Background(app.Styles.Button))
 	buttonsWrapper.AddItem(testButton, 0, 1, false)
 	buttonsWrapper.AddItem(nil, 1, 0, false)
 
-	connectButton := tview.NewButton("[darkred]F3 [black]Connect")
-	connectButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorGhostWhite))
+	connectButton := tview.NewButton(buttonLabel("F3 ", "Connect"))
+	connectButton.SetStyle(tcell.StyleDefault.Background(app.Styles.Button))
 	buttonsWrapper.AddItem(connectButton, 0, 1, false)
 	buttonsWrapper.AddItem(nil, 1, 0, false)
 
-	cancelButton := tview.NewButton("[darkred]Esc [black]Cancel")
-	cancelButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorGhostWhite))
+	cancelButton := tview.NewButton(buttonLabel("Esc ", "Cancel"))
+	cancelButton.SetStyle(tcell.StyleDefault.Background(app.Styles.Button))
 	buttonsWrapper.AddItem(cancelButton, 0, 1, false)
 
 	statusText := tview.NewTextView()
@@ -63,6 +74,13 @@ func NewConnectionForm(connectionPages *models.ConnectionPages) *ConnectionForm
 
 	wrapper.SetInputCapture(form.inputCapture(connectionPages))
 
+	addForm.GetFormItemByLabel("URL").(*tview.InputField).SetChangedFunc(func(_ string) {
+		form.warnReadOnly()
+	})
+	addForm.GetFormItemByLabel("Read-only").(*tview.Checkbox).SetChangedFunc(func(_ bool) {
+		form.warnReadOnly()
+	})
+
 	return form
 }
 
@@ -74,42 +92,30 @@ func (form *ConnectionForm) inputCapture(connectionPages *models.ConnectionPages
 			connectionName := form.GetFormItem(0).(*tview.InputField).GetText()
 
 			if connectionName == "" {
-				form.StatusText.SetText("Connection name is required").SetTextStyle(tcell.StyleDefault.Foreground(tcell.ColorRed))
+				form.StatusText.SetText("Connection name is required").SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Error))
 				return event
 			}
 
-			connectionString := form.GetFormItem(1).(*tview.InputField).GetText()
-
-			parsed, err := helpers.ParseConnectionString(connectionString)
+			parsedDatabaseData, err := form.connection(connectionName)
+			if err == nil && parsedDatabaseData.Keyring {
+				parsedDatabaseData, err = form.keepPassword(parsedDatabaseData)
+			}
 
 			if err != nil {
-				form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(tcell.ColorRed))
+				form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Error))
 				return event
 			} else {
 
 				databases, _ := helpers.LoadConnections()
 				newDatabases := make([]models.Connection, len(databases))
 
-				DBName := strings.Split(parsed.Normalize(",", "NULL", 0), ",")[3]
-
-				if DBName == "NULL" {
-					DBName = ""
-				}
-
-				parsedDatabaseData := models.Connection{
-					Name:     connectionName,
-					Provider: parsed.Driver,
-					DBName:   DBName,
-					URL:      connectionString,
-				}
-
 				switch form.Action {
 				case "create":
 
 					newDatabases = append(databases, parsedDatabaseData)
 					err := helpers.SaveConnectionConfig(newDatabases)
 					if err != nil {
-						form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(tcell.ColorRed))
+						form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Error))
 						return event
 					}
 
@@ -121,6 +127,14 @@ func (form *ConnectionForm) inputCapture(connectionPages *models.ConnectionPages
 						if i == row {
 							newDatabases[i] = parsedDatabaseData
 
+							if database.Keyring && database.Name != connectionName {
+								err := moveKeyringPassword(database.Name, connectionName)
+								if err != nil {
+									form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Error))
+									return event
+								}
+							}
+
 							// newDatabases[i].Name = connectionName
 							// newDatabases[i].Provider = database.Provider
 							// newDatabases[i].User = parsed.User.Username()
@@ -137,7 +151,7 @@ func (form *ConnectionForm) inputCapture(connectionPages *models.ConnectionPages
 
 					err := helpers.SaveConnectionConfig(newDatabases)
 					if err != nil {
-						form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(tcell.ColorRed))
+						form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Error))
 						return event
 
 					}
@@ -147,43 +161,170 @@ func (form *ConnectionForm) inputCapture(connectionPages *models.ConnectionPages
 				connectionPages.SwitchToPage("Connections")
 			}
 		} else if event.Key() == tcell.KeyF2 {
-			connectionString := form.GetFormItem(1).(*tview.InputField).GetText()
-			go form.testConnection(connectionString)
+			connectionName := form.GetFormItem(0).(*tview.InputField).GetText()
+			go form.testConnection(connectionName)
 		}
 		return event
 	}
 }
 
-func (form *ConnectionForm) testConnection(connectionString string) {
-	parsed, err := helpers.ParseConnectionString(connectionString)
+func (form *ConnectionForm) testConnection(connectionName string) {
+	connection, err := form.connection(connectionName)
 	if err != nil {
-		form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(tcell.ColorRed))
+		form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Error))
 		return
 	}
 
-	form.StatusText.SetText("Connecting...").SetTextColor(tcell.ColorGreen)
+	form.StatusText.SetText("Connecting...").SetTextColor(app.Styles.Success)
 
-	var db drivers.Driver
-
-	switch parsed.Driver {
-	case "mysql":
-		db = &drivers.MySQL{}
-	case "postgres":
-		db = &drivers.Postgres{}
-	case "sqlite3":
-		db = &drivers.SQLite{}
+	db, err := drivers.NewForConnection(connection)
+	if err != nil {
+		form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Error))
+		App.ForceDraw()
+		return
 	}
 
-	err = db.TestConnection(connectionString)
+	err = helpers.Authenticate(connection, func(urlstr string) error {
+		return db.TestConnection(context.Background(), urlstr)
+	}, nil)
+	db.Close()
 
 	if err != nil {
-		form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(tcell.ColorRed))
+		form.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Error))
 	} else {
-		form.StatusText.SetText("Connection success").SetTextColor(tcell.ColorGreen)
+		form.StatusText.SetText("Connection success").SetTextColor(app.Styles.Success)
 	}
 	App.ForceDraw()
 }
 
+// connection returns the connection filled in the form
+func (form *ConnectionForm) connection(name string) (models.Connection, error) {
+	connectionString := form.GetFormItemByLabel("URL").(*tview.InputField).GetText()
+
+	// The environment variables are only expanded to know the provider and
+	// the database, the URL is saved with its references
+	expanded, err := helpers.ExpandEnv(connectionString)
+	if err != nil {
+		return models.Connection{}, err
+	}
+
+	connection, err := drivers.NewConnection(name, expanded)
+	if err != nil {
+		return connection, err
+	}
+
+	connection.URL = connectionString
+	connection.PasswordCmd = form.GetFormItemByLabel("Password command").(*tview.InputField).GetText()
+	connection.Keyring = form.GetFormItemByLabel("Password in keyring").(*tview.Checkbox).IsChecked()
+	connection.ReadOnly = form.GetFormItemByLabel("Read-only").(*tview.Checkbox).IsChecked()
+
+	if host := form.GetFormItemByLabel("SSH host").(*tview.InputField).GetText(); host != "" {
+		connection.SSH = &models.SSHTunnel{
+			Host:       host,
+			User:       form.GetFormItemByLabel("SSH user").(*tview.InputField).GetText(),
+			KeyFile:    form.GetFormItemByLabel("SSH key file").(*tview.InputField).GetText(),
+			Agent:      form.GetFormItemByLabel("SSH agent").(*tview.Checkbox).IsChecked(),
+			KnownHosts: form.GetFormItemByLabel("SSH known_hosts").(*tview.InputField).GetText(),
+		}
+	}
+
+	return connection, nil
+}
+
+// keepPassword moves the password of the URL of a connection to the keyring,
+// so that it isn't saved in the config
+func (form *ConnectionForm) keepPassword(connection models.Connection) (models.Connection, error) {
+	// A URL that can't be parsed before its environment variables are
+	// expanded, e.g. postgres://${USER}@host/db, has no password to move
+	stripped, password, err := helpers.StripPassword(connection.URL)
+	if err != nil || password == "" {
+		return connection, nil
+	}
+
+	keyring, err := helpers.OpenKeyring()
+	if err == nil {
+		err = keyring.Set(connection.Name, password)
+	}
+
+	if err != nil {
+		return connection, fmt.Errorf("can't keep the password in the keyring: %w", err)
+	}
+
+	connection.URL = stripped
+	form.GetFormItemByLabel("URL").(*tview.InputField).SetText(stripped)
+
+	return connection, nil
+}
+
+// SetConnection fills the form with a connection
+func (form *ConnectionForm) SetConnection(connection models.Connection) {
+	form.GetFormItemByLabel("Name").(*tview.InputField).SetText(connection.Name)
+	form.GetFormItemByLabel("URL").(*tview.InputField).SetText(connection.URL)
+	form.GetFormItemByLabel("Password command").(*tview.InputField).SetText(connection.PasswordCmd)
+	form.GetFormItemByLabel("Password in keyring").(*tview.Checkbox).SetChecked(connection.Keyring)
+	form.GetFormItemByLabel("Read-only").(*tview.Checkbox).SetChecked(connection.ReadOnly)
+
+	tunnel := models.SSHTunnel{}
+	if connection.SSH != nil {
+		tunnel = *connection.SSH
+	}
+
+	form.GetFormItemByLabel("SSH host").(*tview.InputField).SetText(tunnel.Host)
+	form.GetFormItemByLabel("SSH user").(*tview.InputField).SetText(tunnel.User)
+	form.GetFormItemByLabel("SSH key file").(*tview.InputField).SetText(tunnel.KeyFile)
+	form.GetFormItemByLabel("SSH agent").(*tview.Checkbox).SetChecked(tunnel.Agent)
+	form.GetFormItemByLabel("SSH known_hosts").(*tview.InputField).SetText(tunnel.KnownHosts)
+	form.StatusText.SetText("")
+	form.warnReadOnly()
+}
+
+// readOnlyWarning is shown for a read-only connection to a database that
+// can't open read-only sessions
+const readOnlyWarning = "This database has no read-only sessions: only lazysql refuses the statements that write, the database itself doesn't."
+
+// warnReadOnly shows readOnlyWarning while it applies to the connection
+// filled in the form
+func (form *ConnectionForm) warnReadOnly() {
+	readOnly := form.GetFormItemByLabel("Read-only").(*tview.Checkbox).IsChecked()
+	connectionString := form.GetFormItemByLabel("URL").(*tview.InputField).GetText()
+
+	provider := ""
+	if expanded, err := helpers.ExpandEnv(connectionString); err == nil {
+		if connection, err := drivers.NewConnection("", expanded); err == nil {
+			provider = connection.Provider
+		}
+	}
+
+	if readOnly && provider != "" && !drivers.OpensReadOnlySessions(provider) {
+		form.StatusText.SetText(readOnlyWarning).SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Warning))
+	} else if form.StatusText.GetText(false) == readOnlyWarning {
+		form.StatusText.SetText("")
+	}
+}
+
+// moveKeyringPassword keeps the password of a renamed connection under its
+// new name
+func moveKeyringPassword(from, to string) error {
+	keyring, err := helpers.OpenKeyring()
+	if err != nil {
+		return err
+	}
+
+	password, err := keyring.Get(from)
+	if errors.Is(err, helpers.ErrNoPassword) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	err = keyring.Set(to, password)
+	if err != nil {
+		return err
+	}
+
+	return keyring.Delete(from)
+}
+
 func (form *ConnectionForm) SetAction(action string) {
 	form.Action = action
 }
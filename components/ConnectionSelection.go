@@ -1,10 +1,12 @@
 package components
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/jorgerojas26/lazysql/app"
+	"github.com/jorgerojas26/lazysql/commands"
 	"github.com/jorgerojas26/lazysql/drivers"
 	"github.com/jorgerojas26/lazysql/helpers"
 	"github.com/jorgerojas26/lazysql/models"
@@ -13,12 +15,21 @@ import (
 	"github.com/rivo/tview"
 )
 
+// buttonLabel writes the key that presses a button before the rest of its text
+func buttonLabel(key, text string) string {
+	return app.Tag(app.Styles.ButtonKey) + key + app.Tag(app.Styles.ButtonText) + text
+}
+
 type ConnectionSelection struct {
 	*tview.Flex
 	StatusText *tview.TextView
 }
 
-var ConnectionListTable = NewConnectionsTable()
+var ConnectionListTable *ConnectionsTable
+
+// ReadOnly opens every connection of the list read-only, it's set by
+// --read-only
+var ReadOnly bool
 
 func NewConnectionSelection(connectionForm *ConnectionForm, connectionPages *models.ConnectionPages) *ConnectionSelection {
 	wrapper := tview.NewFlex()
@@ -27,28 +38,28 @@ func NewConnectionSelection(connectionForm *ConnectionForm, connectionPages *mod
 
 	buttonsWrapper := tview.NewFlex().SetDirection(tview.FlexRowCSS)
 
-	newButton := tview.NewButton("[darkred]N[black]ew")
-	newButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorGhostWhite))
+	newButton := tview.NewButton(buttonLabel("N", "ew"))
+	newButton.SetStyle(tcell.StyleDefault.Background(app.Styles.Button))
 	buttonsWrapper.AddItem(newButton, 0, 1, false)
 	buttonsWrapper.AddItem(nil, 1, 0, false)
 
-	connectButton := tview.NewButton("[darkred]C[black]onnect")
-	connectButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorGhostWhite))
+	connectButton := tview.NewButton(buttonLabel("C", "onnect"))
+	connectButton.SetStyle(tcell.StyleDefault.Background(app.Styles.Button))
 	buttonsWrapper.AddItem(connectButton, 0, 1, false)
 	buttonsWrapper.AddItem(nil, 1, 0, false)
 
-	editButton := tview.NewButton("[darkred]E[black]dit")
-	editButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorGhostWhite))
+	editButton := tview.NewButton(buttonLabel("E", "dit"))
+	editButton.SetStyle(tcell.StyleDefault.Background(app.Styles.Button))
 	buttonsWrapper.AddItem(editButton, 0, 1, false)
 	buttonsWrapper.AddItem(nil, 1, 0, false)
 
-	deleteButton := tview.NewButton("[darkred]D[black]elete")
-	deleteButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorGhostWhite))
+	deleteButton := tview.NewButton(buttonLabel("D", "elete"))
+	deleteButton.SetStyle(tcell.StyleDefault.Background(app.Styles.Button))
 	buttonsWrapper.AddItem(deleteButton, 0, 1, false)
 	buttonsWrapper.AddItem(nil, 1, 0, false)
 
-	quitButton := tview.NewButton("[darkred]Q[black]uit")
-	quitButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorGhostWhite))
+	quitButton := tview.NewButton(buttonLabel("Q", "uit"))
+	quitButton.SetStyle(tcell.StyleDefault.Background(app.Styles.Button))
 	buttonsWrapper.AddItem(quitButton, 0, 1, false)
 
 	statusText := tview.NewTextView()
@@ -65,23 +76,22 @@ func NewConnectionSelection(connectionForm *ConnectionForm, connectionPages *mod
 
 	wrapper.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		connections := ConnectionListTable.GetConnections()
+		command := app.Keymaps.Group("connections").Resolve(event)
 
 		if len(connections) != 0 {
 			row, _ := ConnectionListTable.GetSelection()
 			selectedConnection := connections[row]
 
-			if event.Rune() == 'c' || event.Key() == tcell.KeyEnter {
+			if command == commands.Connect {
 				go cs.Connect(selectedConnection)
-			} else if event.Rune() == 'e' {
+			} else if command == commands.Edit {
 				connectionPages.SwitchToPage("ConnectionForm")
-				connectionForm.GetFormItemByLabel("Name").(*tview.InputField).SetText(selectedConnection.Name)
-				connectionForm.GetFormItemByLabel("URL").(*tview.InputField).SetText(selectedConnection.URL)
-				connectionForm.StatusText.SetText("")
+				connectionForm.SetConnection(selectedConnection)
 
 				connectionForm.SetAction("edit")
 				return nil
 
-			} else if event.Rune() == 'd' {
+			} else if command == commands.Delete {
 				confirmationModal := NewConfirmationModal("")
 
 				confirmationModal.SetDoneFunc(func(_ int, buttonLabel string) {
@@ -96,6 +106,13 @@ func NewConnectionSelection(connectionForm *ConnectionForm, connectionPages *mod
 							ConnectionListTable.SetError(err.Error())
 						} else {
 							ConnectionListTable.SetConnections(newConnections)
+
+							// The password isn't left behind in the keyring
+							if selectedConnection.Keyring {
+								if keyring, err := helpers.OpenKeyring(); err == nil {
+									_ = keyring.Delete(selectedConnection.Name)
+								}
+							}
 						}
 
 					}
@@ -107,13 +124,14 @@ func NewConnectionSelection(connectionForm *ConnectionForm, connectionPages *mod
 			}
 		}
 
-		if event.Rune() == 'n' {
+		if command == commands.NewConnection {
 			connectionForm.SetAction("create")
-			connectionForm.GetFormItemByLabel("Name").(*tview.InputField).SetText("")
-			connectionForm.GetFormItemByLabel("URL").(*tview.InputField).SetText("")
-			connectionForm.StatusText.SetText("")
+			connectionForm.SetConnection(models.Connection{})
 			connectionPages.SwitchToPage("ConnectionForm")
-		} else if event.Rune() == 'q' {
+		} else if command == commands.Help {
+			showHelp("connections")
+			return nil
+		} else if command == commands.Quit {
 			if wrapper.HasFocus() {
 				app.App.Stop()
 			}
@@ -130,45 +148,134 @@ func (cs *ConnectionSelection) Connect(connection models.Connection) {
 		MainPages.SwitchToPage(connection.URL)
 		App.Draw()
 	} else {
-		cs.StatusText.SetText("Connecting...").SetTextColor(tcell.ColorGreen)
+		cs.StatusText.SetText("Connecting...").SetTextColor(app.Styles.Success)
 		App.Draw()
 
-		var newDbDriver drivers.Driver
+		connection.ReadOnly = connection.ReadOnly || ReadOnly
 
-		switch connection.Provider {
-		case "mysql":
-			newDbDriver = &drivers.MySQL{}
-		case "postgres":
-			newDbDriver = &drivers.Postgres{}
-		case "sqlite3":
-			newDbDriver = &drivers.SQLite{}
+		if err := Open(connection, askPassword); err != nil {
+			cs.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Error))
+			App.Draw()
+			return
 		}
 
-		err := newDbDriver.Connect(connection.URL)
+		cs.StatusText.SetText("")
 
-		if err != nil {
-			cs.StatusText.SetText(err.Error()).SetTextStyle(tcell.StyleDefault.Foreground(tcell.ColorRed))
-			App.Draw()
-		} else {
-			newHome := NewHomePage(connection, newDbDriver)
+		selectedRow, selectedCol := ConnectionListTable.GetSelection()
+		cell := ConnectionListTable.GetCell(selectedRow, selectedCol)
+		cell.SetText(fmt.Sprintf("%s* %s", app.Tag(app.Styles.Success), cell.Text))
 
-			MainPages.AddAndSwitchToPage(connection.URL, newHome, true)
+		ConnectionListTable.SetCell(selectedRow, selectedCol, cell)
+		App.Draw()
+	}
+}
 
-			cs.StatusText.SetText("")
-			App.Draw()
+// opened are the drivers of the connections opened, they are closed when
+// lazysql quits
+var opened []drivers.Driver
 
-			selectedRow, selectedCol := ConnectionListTable.GetSelection()
-			cell := ConnectionListTable.GetCell(selectedRow, selectedCol)
-			cell.SetText(fmt.Sprintf("[green]* %s", cell.Text))
+// Open connects to a connection and shows its home page. ask asks the
+// password when connecting fails without one.
+func Open(connection models.Connection, ask helpers.PasswordPrompt) error {
+	driver, err := drivers.NewForConnection(connection)
+	if err != nil {
+		return err
+	}
 
-			ConnectionListTable.SetCell(selectedRow, selectedCol, cell)
+	err = helpers.Authenticate(connection, func(urlstr string) error {
+		return driver.Connect(context.Background(), urlstr)
+	}, ask)
+	if err != nil {
+		// Closes the SSH tunnel the connection may have opened
+		driver.Close()
+		return err
+	}
 
-			MainPages.SwitchToPage(connection.URL)
-			newHome.Tree.SetCurrentNode(newHome.Tree.GetRoot())
-			newHome.Tree.SetTitle(fmt.Sprintf("%s (%s)", connection.Name, strings.ToUpper(connection.Provider)))
-			App.SetFocus(newHome.Tree)
-			App.Draw()
-		}
+	opened = append(opened, driver)
+
+	home := NewHomePage(connection, driver)
 
+	MainPages.SwitchToPage(connection.URL)
+	home.Tree.SetCurrentNode(home.Tree.GetRoot())
+	home.Tree.SetTitle(fmt.Sprintf("%s (%s)", connection.Name, strings.ToUpper(connection.Provider)))
+	App.SetFocus(home.Tree)
+
+	return nil
+}
+
+// CloseConnections closes the opened connections, along with their SSH
+// tunnels
+func CloseConnections() {
+	for _, driver := range opened {
+		driver.Close()
 	}
+
+	opened = nil
+}
+
+// askPassword asks the password of a connection in a modal. It's called from
+// the goroutine connecting, which waits for the answer.
+func askPassword(connection string, err error) (string, bool) {
+	answer := make(chan string, 1)
+
+	App.QueueUpdateDraw(func() {
+		focus := App.GetFocus()
+
+		form := tview.NewForm().SetFieldBackgroundColor(app.Styles.Field).SetButtonBackgroundColor(app.Styles.Button).SetLabelColor(tview.Styles.PrimaryTextColor).SetFieldTextColor(app.Styles.FieldText)
+		form.SetButtonTextColor(app.Styles.ButtonText)
+
+		status := tview.NewTextView()
+		status.SetText(err.Error())
+		status.SetTextColor(app.Styles.Error)
+		status.SetBorderPadding(0, 0, 1, 1)
+
+		content := tview.NewFlex().SetDirection(tview.FlexRow)
+		content.SetBorder(true)
+		content.SetTitle(fmt.Sprintf(" Password of %s (Esc to cancel) ", connection))
+		content.SetBackgroundColor(app.Styles.ModalBackground)
+		content.AddItem(form, 0, 1, true)
+		content.AddItem(status, 3, 0, false)
+
+		done := func(password string) {
+			MainPages.RemovePage("Password")
+			App.SetFocus(focus)
+			answer <- password
+		}
+
+		form.AddPasswordField("Password", "", 0, '*', nil)
+		field := form.GetFormItem(0).(*tview.InputField)
+
+		// Enter connects right away instead of moving to the button
+		form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyEnter && field.HasFocus() {
+				done(field.GetText())
+				return nil
+			}
+
+			return event
+		})
+		form.AddButton("Connect", func() {
+			done(field.GetText())
+		})
+		form.SetCancelFunc(func() {
+			done("")
+		})
+
+		// Centers the content over the connections
+		modal := tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(content, 10, 0, true).
+				AddItem(nil, 0, 1, false), 70, 0, true).
+			AddItem(nil, 0, 1, false)
+
+		MainPages.AddPage("Password", modal, true, true)
+		App.SetFocus(form)
+	})
+
+	// An empty password is giving up, since connecting without one failed
+	password := <-answer
+
+	return password, password != ""
 }
@@ -1,6 +1,7 @@
 package components
 
 import (
+	"github.com/jorgerojas26/lazysql/app"
 	"github.com/jorgerojas26/lazysql/helpers"
 	"github.com/jorgerojas26/lazysql/models"
 
@@ -20,7 +21,7 @@ func NewConnectionsTable() *ConnectionsTable {
 	wrapper := tview.NewFlex()
 
 	errorTextView := tview.NewTextView()
-	errorTextView.SetTextStyle(tcell.StyleDefault.Foreground(tcell.ColorRed))
+	errorTextView.SetTextStyle(tcell.StyleDefault.Foreground(app.Styles.Error))
 
 	table := &ConnectionsTable{
 		Table:         tview.NewTable().SetSelectable(true, false),
@@ -28,7 +29,7 @@ func NewConnectionsTable() *ConnectionsTable {
 		errorTextView: errorTextView,
 	}
 
-	table.SetSelectedStyle(tcell.StyleDefault.Foreground(tview.Styles.PrimaryTextColor).Background(tview.Styles.SecondaryTextColor))
+	table.SetSelectedStyle(tcell.StyleDefault.Foreground(app.Styles.SelectionText).Background(app.Styles.Selection))
 
 	wrapper.AddItem(table, 0, 1, true)
 
@@ -0,0 +1,192 @@
+package components
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/app"
+	"github.com/jorgerojas26/lazysql/drivers"
+)
+
+// ExportScope is which rows of the results are exported.
+type ExportScope int
+
+const (
+	// PageScope is the page of the table shown
+	PageScope ExportScope = iota
+	// TableScope is every row of the table that matches the filter
+	TableScope
+	// QueryResultScope is the editor result shown
+	QueryResultScope
+)
+
+func (scope ExportScope) String() string {
+	switch scope {
+	case TableScope:
+		return "Whole table (filtered)"
+	case QueryResultScope:
+		return "Query result"
+	}
+
+	return "Current page"
+}
+
+// ExportOptions are the choices made in the export form.
+type ExportOptions struct {
+	Scope  ExportScope
+	Format drivers.ExportFormat
+	Path   string
+	// Table is the table the SQL INSERT statements insert into
+	Table string
+}
+
+// ExportModal asks which rows of the results are exported, in which format
+// and to which file.
+type ExportModal struct {
+	*tview.Flex
+	Form     *tview.Form
+	Status   *tview.TextView
+	scopes   []ExportScope
+	format   drivers.ExportFormat
+	onExport func(options ExportOptions)
+	onClose  func()
+	// overwrite is the existing file the next export is allowed to replace
+	overwrite string
+}
+
+func NewExportModal() *ExportModal {
+	form := tview.NewForm().SetFieldBackgroundColor(app.Styles.Field).SetButtonBackgroundColor(app.Styles.Button).SetLabelColor(tview.Styles.PrimaryTextColor).SetFieldTextColor(app.Styles.FieldText)
+	form.SetButtonTextColor(app.Styles.ButtonText)
+
+	status := tview.NewTextView()
+	status.SetDynamicColors(true)
+
+	content := tview.NewFlex().SetDirection(tview.FlexRow)
+	content.SetBorder(true)
+	content.SetTitle(" Export (Esc to close) ")
+	content.SetBackgroundColor(app.Styles.ModalBackground)
+	content.AddItem(form, 0, 1, true)
+	content.AddItem(status, 2, 0, false)
+
+	// Centers the content over the results
+	modal := &ExportModal{
+		Flex: tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(content, 15, 0, true).
+				AddItem(nil, 0, 1, false), 70, 0, true).
+			AddItem(nil, 0, 1, false),
+		Form:   form,
+		Status: status,
+	}
+
+	form.SetCancelFunc(func() {
+		if modal.onClose != nil {
+			modal.onClose()
+		}
+	})
+
+	return modal
+}
+
+// Show fills the form for the given scopes. name is the file name offered,
+// without its extension, and table the quoted table offered for SQL INSERT.
+func (modal *ExportModal) Show(scopes []ExportScope, name, table string, onExport func(options ExportOptions), onClose func()) {
+	modal.scopes = scopes
+	modal.onExport = onExport
+	modal.onClose = onClose
+	modal.overwrite = ""
+	modal.format = drivers.ExportFormats[0]
+
+	scopeLabels := make([]string, len(scopes))
+	for i, scope := range scopes {
+		scopeLabels[i] = scope.String()
+	}
+
+	formatLabels := make([]string, len(drivers.ExportFormats))
+	for i, format := range drivers.ExportFormats {
+		formatLabels[i] = string(format)
+	}
+
+	modal.Form.Clear(true)
+	modal.Form.AddDropDown("Rows", scopeLabels, 0, nil)
+	modal.Form.AddDropDown("Format", formatLabels, 0, nil)
+	modal.Form.AddInputField("File", name+"."+modal.format.Extension(), 0, nil, nil)
+	modal.Form.AddInputField("Table", table, 0, nil, nil)
+	modal.Form.AddButton("Export", modal.submit)
+	modal.Form.AddButton("Cancel", onClose)
+	modal.Form.SetFocus(0)
+
+	// Set after the file field exists, since setting it selects an option
+	modal.Form.GetFormItemByLabel("Format").(*tview.DropDown).SetSelectedFunc(func(_ string, index int) {
+		modal.setFormat(drivers.ExportFormats[index])
+	})
+
+	modal.SetStatus("", tcell.ColorDefault)
+}
+
+// SetStatus shows a message under the form
+func (modal *ExportModal) SetStatus(message string, color tcell.Color) {
+	modal.Status.SetText(tview.Escape(message))
+	modal.Status.SetTextColor(color)
+}
+
+// setFormat changes the extension of the file along with the format
+func (modal *ExportModal) setFormat(format drivers.ExportFormat) {
+	file := modal.Form.GetFormItemByLabel("File").(*tview.InputField)
+	path := file.GetText()
+
+	if strings.HasSuffix(path, "."+modal.format.Extension()) {
+		file.SetText(strings.TrimSuffix(path, modal.format.Extension()) + format.Extension())
+	}
+
+	modal.format = format
+}
+
+func (modal *ExportModal) submit() {
+	scope, _ := modal.Form.GetFormItemByLabel("Rows").(*tview.DropDown).GetCurrentOption()
+	path := expandHome(strings.TrimSpace(modal.Form.GetFormItemByLabel("File").(*tview.InputField).GetText()))
+	table := strings.TrimSpace(modal.Form.GetFormItemByLabel("Table").(*tview.InputField).GetText())
+
+	if path == "" {
+		modal.SetStatus("The file is required", app.Styles.Error)
+		return
+	}
+
+	if modal.format == drivers.SQLExport && table == "" {
+		modal.SetStatus("The table is required to export SQL INSERT statements", app.Styles.Error)
+		return
+	}
+
+	if _, err := os.Stat(path); err == nil && modal.overwrite != path {
+		modal.overwrite = path
+		modal.SetStatus(path+" already exists, export again to replace it", app.Styles.Warning)
+		return
+	}
+
+	modal.onExport(ExportOptions{
+		Scope:  modal.scopes[scope],
+		Format: modal.format,
+		Path:   path,
+		Table:  table,
+	})
+}
+
+// expandHome replaces a leading ~ with the home directory of the user
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
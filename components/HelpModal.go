@@ -0,0 +1,234 @@
+package components
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/app"
+	"github.com/jorgerojas26/lazysql/commands"
+	"github.com/jorgerojas26/lazysql/keymap"
+)
+
+// helpDescriptions tell what the commands that do something of their own in a
+// group do there
+var helpDescriptions = map[string]map[commands.Command]string{
+	"global": {
+		commands.MoveLeft:  "Focus the tree",
+		commands.MoveRight: "Focus the results",
+	},
+	"tree": {
+		commands.Execute: "Open the table, or expand the node",
+	},
+	"editor": {
+		commands.Execute: "Run the SQL statements",
+		commands.Quit:    "Leave the editor",
+	},
+	"connections": {
+		commands.Edit:   "Edit the connection",
+		commands.Delete: "Delete the connection",
+	},
+}
+
+// helpTitles are the titles of the sections of the groups
+var helpTitles = map[string]string{
+	"global":      "Global",
+	"tree":        "Tree",
+	"table":       "Table",
+	"editor":      "SQL Editor",
+	"connections": "Connections",
+}
+
+// helpRow is a command of the help, along with every key bound to it
+type helpRow struct {
+	keys        []string
+	command     commands.Command
+	description string
+	// text is what the search is matched against
+	text string
+}
+
+type helpSection struct {
+	title string
+	rows  []helpRow
+}
+
+// HelpModal lists the keys of the focused group and of the global one, as
+// they are bound in the keymap.
+type HelpModal struct {
+	*tview.Flex
+	Input    *tview.InputField
+	Table    *tview.Table
+	sections []helpSection
+	onClose  func()
+}
+
+func NewHelpModal() *HelpModal {
+	input := tview.NewInputField()
+	input.SetLabel("Search: ")
+	input.SetFieldBackgroundColor(tcell.ColorDefault)
+	input.SetFieldTextColor(tview.Styles.PrimaryTextColor)
+	input.SetLabelColor(tview.Styles.SecondaryTextColor)
+
+	table := tview.NewTable()
+	table.SetBackgroundColor(app.Styles.ModalBackground)
+
+	content := tview.NewFlex().SetDirection(tview.FlexRow)
+	content.SetBorder(true)
+	content.SetTitle(" Keys (Esc to close) ")
+	content.SetBackgroundColor(app.Styles.ModalBackground)
+	content.AddItem(input, 1, 0, true)
+	content.AddItem(table, 0, 1, false)
+
+	// Centers the content over the view
+	modal := &HelpModal{
+		Flex: tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(content, 0, 8, true).
+				AddItem(nil, 0, 1, false), 0, 8, true).
+			AddItem(nil, 0, 1, false),
+		Input: input,
+		Table: table,
+	}
+
+	input.SetChangedFunc(modal.filter)
+	input.SetInputCapture(modal.inputCapture)
+
+	return modal
+}
+
+// Show fills the modal with the keys read while a group has the focus
+func (modal *HelpModal) Show(group string, onClose func()) {
+	modal.onClose = onClose
+	modal.sections = []helpSection{}
+
+	names := []string{group}
+
+	if maps := app.Keymaps.Maps(group); len(maps) > 1 {
+		names = append(names, "global")
+	}
+
+	for _, name := range names {
+		section := helpSection{title: helpTitles[name]}
+		rows := map[commands.Command]int{}
+
+		for _, bind := range app.Keymaps.Group(name) {
+			index, ok := rows[bind.Cmd]
+
+			if !ok {
+				description, ok := helpDescriptions[name][bind.Cmd]
+				if !ok {
+					description = bind.Cmd.Description()
+				}
+
+				index = len(section.rows)
+				rows[bind.Cmd] = index
+				section.rows = append(section.rows, helpRow{command: bind.Cmd, description: description, text: description})
+			}
+
+			row := &section.rows[index]
+			row.keys = append(row.keys, keymap.SequenceString(bind.Keys()))
+			row.text += "\n" + bind.String()
+		}
+
+		modal.sections = append(modal.sections, section)
+	}
+
+	modal.Input.SetText("")
+	modal.filter("")
+}
+
+func (modal *HelpModal) inputCapture(event *tcell.EventKey) *tcell.EventKey {
+	row, _ := modal.Table.GetOffset()
+
+	switch event.Key() {
+	case tcell.KeyDown, tcell.KeyCtrlN:
+		modal.scrollTo(row + 1)
+	case tcell.KeyUp, tcell.KeyCtrlP:
+		modal.scrollTo(row - 1)
+	case tcell.KeyPgDn:
+		modal.scrollTo(row + 10)
+	case tcell.KeyPgUp:
+		modal.scrollTo(row - 10)
+	case tcell.KeyEscape:
+		if modal.onClose != nil {
+			modal.onClose()
+		}
+	default:
+		return event
+	}
+
+	return nil
+}
+
+func (modal *HelpModal) scrollTo(row int) {
+	if row >= modal.Table.GetRowCount() {
+		row = modal.Table.GetRowCount() - 1
+	}
+
+	if row < 0 {
+		row = 0
+	}
+
+	modal.Table.SetOffset(row, 0)
+}
+
+// filter lists the commands whose keys, name or description hold the search
+func (modal *HelpModal) filter(search string) {
+	search = strings.ToLower(strings.TrimSpace(search))
+
+	modal.Table.Clear()
+	modal.Table.SetOffset(0, 0)
+
+	for _, section := range modal.sections {
+		matched := []helpRow{}
+
+		for _, row := range section.rows {
+			if strings.Contains(strings.ToLower(row.text), search) {
+				matched = append(matched, row)
+			}
+		}
+
+		if len(matched) == 0 {
+			continue
+		}
+
+		index := modal.Table.GetRowCount()
+
+		if index > 0 {
+			index++
+		}
+
+		modal.Table.SetCell(index, 0, tview.NewTableCell(section.title).SetTextColor(tview.Styles.SecondaryTextColor).SetAttributes(tcell.AttrBold))
+
+		for _, row := range matched {
+			index++
+
+			modal.Table.SetCell(index, 0, tview.NewTableCell(tview.Escape(strings.Join(row.keys, ", "))).SetTextColor(tview.Styles.TertiaryTextColor))
+			modal.Table.SetCell(index, 1, tview.NewTableCell(row.command.String()).SetTextColor(tview.Styles.PrimaryTextColor))
+			modal.Table.SetCell(index, 2, tview.NewTableCell(row.description).SetTextColor(tview.Styles.PrimaryTextColor).SetExpansion(1))
+		}
+	}
+
+	if modal.Table.GetRowCount() == 0 {
+		modal.Table.SetCell(0, 0, tview.NewTableCell("No key matches the search").SetTextColor(tview.Styles.InverseTextColor))
+	}
+}
+
+// showHelp opens the keys read while a group has the focus, the focus goes
+// back where it was once it's closed
+func showHelp(group string) {
+	focus := App.GetFocus()
+	modal := NewHelpModal()
+
+	modal.Show(group, func() {
+		MainPages.RemovePage("Help")
+		App.SetFocus(focus)
+	})
+
+	MainPages.AddPage("Help", modal, true, true)
+	App.SetFocus(modal)
+}
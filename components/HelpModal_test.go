@@ -0,0 +1,114 @@
+package components
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+
+	"github.com/jorgerojas26/lazysql/app"
+	"github.com/jorgerojas26/lazysql/keymap"
+)
+
+// helpListing returns the rows of the help, their cells separated by |
+func helpListing(modal *HelpModal) []string {
+	listing := []string{}
+
+	for row := 0; row < modal.Table.GetRowCount(); row++ {
+		cells := []string{}
+
+		for column := 0; column < modal.Table.GetColumnCount(); column++ {
+			if cell := modal.Table.GetCell(row, column); cell.Text != "" {
+				cells = append(cells, cell.Text)
+			}
+		}
+
+		listing = append(listing, strings.Join(cells, " | "))
+	}
+
+	return listing
+}
+
+func TestHelpModal(t *testing.T) {
+	defaults := app.Keymaps
+	t.Cleanup(func() { app.Keymaps = defaults })
+
+	app.Keymaps.Groups = map[string]keymap.Map{}
+	for group, binds := range defaults.Groups {
+		app.Keymaps.Groups[group] = binds
+	}
+
+	err := app.Keymaps.Configure(map[string]interface{}{
+		"table": map[string]interface{}{
+			"Export": []interface{}{"x", "gx"},
+			"Copy":   []interface{}{},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modal := NewHelpModal()
+	modal.Show("table", nil)
+
+	listing := helpListing(modal)
+
+	if listing[0] != "Table" || !contains(listing, "Global") {
+		t.Errorf("the sections of the table are %q, want Table and Global", listing)
+	}
+
+	if !contains(listing, "x, gx | Export | Export the results to a file") {
+		t.Errorf("the help doesn't list the keys of Export as bound in the keymap: %q", listing)
+	}
+
+	for _, row := range listing {
+		if strings.Contains(row, "| Copy |") {
+			t.Errorf("the unbound Copy is listed: %q", row)
+		}
+	}
+
+	// The search field replaces its text once it's drawn
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	modal.SetRect(0, 0, 100, 40)
+	modal.Draw(screen)
+
+	tests := []struct {
+		search   string
+		expected []string
+	}{
+		{"EXPORT", []string{"Table", "x, gx | Export | Export the results to a file"}},
+		{"gx", []string{"Table", "x, gx | Export | Export the results to a file"}},
+		{"focus the tree", []string{"Global", "H | MoveLeft | Focus the tree"}},
+		{"nothing like this", []string{"No key matches the search"}},
+	}
+
+	for _, test := range tests {
+		modal.Input.SetText(test.search)
+
+		if listing := helpListing(modal); !reflect.DeepEqual(listing, test.expected) {
+			t.Errorf("the search of %q lists %q, want %q", test.search, listing, test.expected)
+		}
+	}
+
+	modal.Show("connections", nil)
+
+	if listing := helpListing(modal); listing[0] != "Connections" || contains(listing, "Global") {
+		t.Errorf("the sections of the connections are %q, want Connections only", listing)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,163 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/app"
+	"github.com/jorgerojas26/lazysql/helpers"
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// HistoryModal lists the statements run on a connection, newest first, and
+// narrows them down with a fuzzy search.
+type HistoryModal struct {
+	*tview.Flex
+	Input    *tview.InputField
+	List     *tview.List
+	entries  []models.HistoryEntry
+	filtered []models.HistoryEntry
+	onSelect func(entry models.HistoryEntry)
+	onClose  func()
+}
+
+func NewHistoryModal() *HistoryModal {
+	input := tview.NewInputField()
+	input.SetLabel("Search: ")
+	input.SetFieldBackgroundColor(tcell.ColorDefault)
+	input.SetFieldTextColor(tview.Styles.PrimaryTextColor)
+	input.SetLabelColor(tview.Styles.SecondaryTextColor)
+
+	list := tview.NewList()
+	list.SetMainTextColor(tview.Styles.PrimaryTextColor)
+	list.SetSecondaryTextColor(tview.Styles.InverseTextColor)
+	list.SetSelectedTextColor(app.Styles.SelectionText)
+	list.SetSelectedBackgroundColor(app.Styles.Selection)
+
+	content := tview.NewFlex().SetDirection(tview.FlexRow)
+	content.SetBorder(true)
+	content.SetTitle(" History (Enter to load, Esc to close) ")
+	content.SetBackgroundColor(app.Styles.ModalBackground)
+	content.AddItem(input, 1, 0, true)
+	content.AddItem(list, 0, 1, false)
+
+	// Centers the content over the results
+	modal := &HistoryModal{
+		Flex: tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(content, 0, 8, true).
+				AddItem(nil, 0, 1, false), 0, 8, true).
+			AddItem(nil, 0, 1, false),
+		Input: input,
+		List:  list,
+	}
+
+	input.SetChangedFunc(modal.filter)
+	input.SetInputCapture(modal.inputCapture)
+
+	return modal
+}
+
+// Show fills the modal with the entries of a history
+func (modal *HistoryModal) Show(entries []models.HistoryEntry, onSelect func(entry models.HistoryEntry), onClose func()) {
+	modal.entries = entries
+	modal.onSelect = onSelect
+	modal.onClose = onClose
+
+	modal.Input.SetText("")
+	modal.filter("")
+}
+
+func (modal *HistoryModal) inputCapture(event *tcell.EventKey) *tcell.EventKey {
+	current := modal.List.GetCurrentItem()
+
+	switch event.Key() {
+	case tcell.KeyDown, tcell.KeyCtrlN:
+		modal.moveTo(current + 1)
+	case tcell.KeyUp, tcell.KeyCtrlP:
+		modal.moveTo(current - 1)
+	case tcell.KeyPgDn:
+		modal.moveTo(current + 10)
+	case tcell.KeyPgUp:
+		modal.moveTo(current - 10)
+	case tcell.KeyEnter:
+		if current < len(modal.filtered) && modal.onSelect != nil {
+			modal.onSelect(modal.filtered[current])
+		}
+	case tcell.KeyEscape:
+		if modal.onClose != nil {
+			modal.onClose()
+		}
+	default:
+		return event
+	}
+
+	return nil
+}
+
+func (modal *HistoryModal) moveTo(index int) {
+	if index >= modal.List.GetItemCount() {
+		index = modal.List.GetItemCount() - 1
+	}
+
+	// A negative index would count from the end of the list
+	if index < 0 {
+		index = 0
+	}
+
+	modal.List.SetCurrentItem(index)
+}
+
+// filter lists the entries that match the search, the best matches first
+func (modal *HistoryModal) filter(search string) {
+	type match struct {
+		entry models.HistoryEntry
+		score int
+	}
+
+	matches := []match{}
+
+	for _, entry := range modal.entries {
+		if score, ok := helpers.FuzzyMatch(search, entry.Query); ok {
+			matches = append(matches, match{entry: entry, score: score})
+		}
+	}
+
+	// Stable, so that the newest entries come first among equal scores
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	modal.filtered = make([]models.HistoryEntry, 0, len(matches))
+	modal.List.Clear()
+
+	for _, match := range matches {
+		modal.filtered = append(modal.filtered, match.entry)
+		modal.List.AddItem(tview.Escape(strings.Join(strings.Fields(match.entry.Query), " ")), historyEntryDetails(match.entry), 0, nil)
+	}
+}
+
+func historyEntryDetails(entry models.HistoryEntry) string {
+	details := []string{
+		entry.Time.Local().Format("2006-01-02 15:04:05"),
+		entry.Duration.Round(time.Millisecond).String(),
+		fmt.Sprintf("%d rows", entry.Rows),
+	}
+
+	if entry.Database != "" {
+		details = append(details, entry.Database)
+	}
+
+	if entry.Error != "" {
+		details = append(details, "error: "+entry.Error)
+	}
+
+	return tview.Escape(strings.Join(details, " | "))
+}
@@ -1,7 +1,12 @@
 package components
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/jorgerojas26/lazysql/commands"
+	"github.com/jorgerojas26/lazysql/keymap"
 	"github.com/jorgerojas26/lazysql/models"
 
 	"github.com/jorgerojas26/lazysql/app"
@@ -17,12 +22,18 @@ type Home struct {
 	TabbedPane      *TabbedPane
 	LeftWrapper     *tview.Flex
 	RightWrapper    *tview.Flex
+	Connection      models.Connection
 	DBDriver        drivers.Driver
+	Metadata        *drivers.MetadataCache
 	FocusedWrapper  string
 	ListOfDbChanges []models.DbDmlChange
 	ListOfDbInserts []models.DbInsert
 }
 
+// metadataRefreshInterval is how often the tables and columns completed in the
+// editor are read again
+const metadataRefreshInterval = 5 * time.Minute
+
 func NewHomePage(connection models.Connection, dbdriver drivers.Driver) *Home {
 	tree := NewTree(connection.DBName, dbdriver)
 	tabbedPane := NewTabbedPane()
@@ -37,7 +48,9 @@ func NewHomePage(connection models.Connection, dbdriver drivers.Driver) *Home {
 		RightWrapper:    rightWrapper,
 		ListOfDbChanges: []models.DbDmlChange{},
 		ListOfDbInserts: []models.DbInsert{},
+		Connection:      connection,
 		DBDriver:        dbdriver,
+		Metadata:        drivers.NewMetadataCache(dbdriver, metadataRefreshInterval),
 	}
 
 	go home.subscribeToTreeChanges()
@@ -75,38 +88,211 @@ func (home *Home) subscribeToTreeChanges() {
 	for stateChange := range ch {
 		switch stateChange.Key {
 		case "SelectedTable":
-			tableName := stateChange.Value.(string)
+			reference := stateChange.Value.(TableReference)
 
-			tab := home.TabbedPane.GetTabByName(tableName)
+			tab := home.TabbedPane.GetTabByName(home.tabName(reference))
 			var table *ResultsTable = nil
 
 			if tab != nil {
 				table = tab.Content
 				home.TabbedPane.SwitchToTabByName(tab.Name)
 			} else {
-				table = NewResultsTable(&home.ListOfDbChanges, &home.ListOfDbInserts, home.Tree, home.DBDriver).WithFilter()
-				table.SetDBReference(tableName)
+				driver, err := drivers.ForDatabase(home.DBDriver, reference.Database)
+				if err != nil {
+					showError(err.Error())
+					continue
+				}
 
-				home.TabbedPane.AppendTab(tableName, table)
+				table = NewResultsTable(&home.ListOfDbChanges, &home.ListOfDbInserts, home.Tree, driver).SetReadOnly(home.Connection.ReadOnly).SetTable(reference).WithFilter()
+
+				home.TabbedPane.AppendTab(home.tabName(reference), table)
 			}
 
 			table.FetchRecords(func() {
 				home.focusLeftWrapper()
 			})
 
-			if table.state.error == "" {
+			if tab == nil && table.Loading.Context().Err() != nil {
+				// Opening the table was cancelled
+				home.TabbedPane.RemoveCurrentTab()
+				home.focusLeftWrapper()
+			} else if table.state.error == "" {
 				home.focusRightWrapper()
 			}
 
 			app.App.ForceDraw()
+		case "ImportTable":
+			home.showImport(stateChange.Value.(TableReference))
+		}
+	}
+}
+
+// showImport opens the import of a file into a table. Staged rows are added
+// to the pending inserts, and the tab of the table shows the rows inserted.
+func (home *Home) showImport(table TableReference) {
+	if home.Connection.ReadOnly {
+		showError(readOnlyError)
+		return
+	}
+
+	driver, err := drivers.ForDatabase(home.DBDriver, table.Database)
+	if err != nil {
+		showError(err.Error())
+		return
+	}
+
+	modal := NewImportModal(driver, table, func(staged []models.DbInsert, inserted int) {
+		MainPages.RemovePage("Import")
+		home.focusLeftWrapper()
+
+		if len(staged) > 0 {
+			home.ListOfDbInserts = append(home.ListOfDbInserts, staged...)
+
+			if color := home.Tree.GetCurrentNode().GetColor(); color == app.Styles.PendingDelete || color == app.Styles.PendingUpdate {
+				home.Tree.GetCurrentNode().SetColor(app.Styles.PendingUpdate)
+			} else {
+				home.Tree.GetCurrentNode().SetColor(app.Styles.PendingInsert)
+			}
+		}
+
+		if tab := home.TabbedPane.GetTabByName(home.tabName(table)); tab != nil && (len(staged) > 0 || inserted > 0) {
+			go tab.Content.FetchRecords(nil)
+		}
+	})
+
+	MainPages.AddPage("Import", modal, true, true)
+	App.SetFocus(modal)
+	app.App.ForceDraw()
+}
+
+// saveChanges saves the pending changes behind the loading modal of a table,
+// so that the save can be cancelled. The changes that weren't saved then stay
+// pending.
+func (home *Home) saveChanges(table *ResultsTable) {
+	table.SetLoading(true)
+	table.Loading.SetMessage("Saving...")
+	ctx := table.Loading.Context()
+
+	err := home.savePendingChanges(ctx)
+
+	table.SetLoading(false)
+
+	switch {
+	case ctx.Err() != nil:
+		// Cancelled, what wasn't saved stays pending
+	case err != nil:
+		table.SetError(err.Error(), nil)
+	default:
+		go table.FetchRecords(nil)
+		home.Tree.ForceRemoveHighlight()
+	}
+}
+
+// savePendingChanges saves the pending changes and inserts in a transaction,
+// or in a transaction per database when each database has a connection of its
+// own. The changes saved are removed from the pending ones.
+func (home *Home) savePendingChanges(ctx context.Context) error {
+	if !drivers.CapabilitiesOf(home.DBDriver.GetProvider()).ConnectionPerDatabase {
+		err := home.DBDriver.ExecutePendingChanges(ctx, home.ListOfDbChanges, home.ListOfDbInserts)
+		if err != nil {
+			return err
+		}
+
+		home.ListOfDbChanges = []models.DbDmlChange{}
+		home.ListOfDbInserts = []models.DbInsert{}
+
+		return nil
+	}
+
+	databases := []string{}
+	pending := make(map[string]bool)
+
+	for _, change := range home.ListOfDbChanges {
+		if !pending[change.Database] {
+			pending[change.Database] = true
+			databases = append(databases, change.Database)
+		}
+	}
+
+	for _, insert := range home.ListOfDbInserts {
+		if !pending[insert.Database] {
+			pending[insert.Database] = true
+			databases = append(databases, insert.Database)
 		}
 	}
+
+	for _, database := range databases {
+		changes, otherChanges := []models.DbDmlChange{}, []models.DbDmlChange{}
+		inserts, otherInserts := []models.DbInsert{}, []models.DbInsert{}
+
+		for _, change := range home.ListOfDbChanges {
+			if change.Database == database {
+				changes = append(changes, change)
+			} else {
+				otherChanges = append(otherChanges, change)
+			}
+		}
+
+		for _, insert := range home.ListOfDbInserts {
+			if insert.Database == database {
+				inserts = append(inserts, insert)
+			} else {
+				otherInserts = append(otherInserts, insert)
+			}
+		}
+
+		driver, err := drivers.ForDatabase(home.DBDriver, database)
+		if err == nil {
+			err = driver.ExecutePendingChanges(ctx, changes, inserts)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", database, err)
+		}
+
+		home.ListOfDbChanges = otherChanges
+		home.ListOfDbInserts = otherInserts
+	}
+
+	return nil
+}
+
+// tabName returns the name of the tab of a table. Tables are prefixed with
+// their database when their names don't have it, so that the same table of
+// two databases opens in two tabs.
+func (home *Home) tabName(table TableReference) string {
+	if drivers.CapabilitiesOf(home.DBDriver.GetProvider()).SchemaTableNames {
+		return table.Database + "." + table.Name()
+	}
+
+	return table.Name()
+}
+
+// showError shows a message over the page, the focus goes back where it was
+// once it's closed
+func showError(message string) {
+	focus := App.GetFocus()
+
+	modal := tview.NewModal()
+	modal.AddButtons([]string{"Ok"})
+	modal.SetText(message)
+	modal.SetBackgroundColor(app.Styles.Error)
+	modal.SetTextColor(app.Styles.SelectionText)
+	modal.SetButtonStyle(tcell.StyleDefault.Foreground(app.Styles.SelectionText))
+	modal.SetDoneFunc(func(_ int, _ string) {
+		MainPages.RemovePage("Error")
+		App.SetFocus(focus)
+	})
+
+	MainPages.AddPage("Error", modal, true, true)
+	App.SetFocus(modal)
+	app.App.ForceDraw()
 }
 
 func (home *Home) focusRightWrapper() {
 	home.Tree.RemoveHighlight()
 
-	home.RightWrapper.SetBorderColor(tview.Styles.PrimaryTextColor)
+	home.RightWrapper.SetBorderColor(app.Styles.Focus)
 	home.LeftWrapper.SetBorderColor(tview.Styles.InverseTextColor)
 	home.TabbedPane.Highlight()
 	tab := home.TabbedPane.GetCurrentTab()
@@ -148,7 +334,7 @@ func (home *Home) focusLeftWrapper() {
 	home.Tree.Highlight()
 
 	home.RightWrapper.SetBorderColor(tview.Styles.InverseTextColor)
-	home.LeftWrapper.SetBorderColor(tview.Styles.PrimaryTextColor)
+	home.LeftWrapper.SetBorderColor(app.Styles.Focus)
 
 	tab := home.TabbedPane.GetCurrentTab()
 
@@ -190,6 +376,7 @@ func (home *Home) rightWrapperInputCapture(event *tcell.EventKey) *tcell.EventKe
 			table := tab.Content
 
 			if !table.GetIsFiltering() && !table.GetIsEditing() && !table.GetIsLoading() {
+				table.CloseQueryCursor()
 				home.TabbedPane.RemoveCurrentTab()
 
 				if home.TabbedPane.GetLenght() == 0 {
@@ -205,8 +392,14 @@ func (home *Home) rightWrapperInputCapture(event *tcell.EventKey) *tcell.EventKe
 			table := tab.Content
 
 			if ((table.Menu != nil && table.Menu.GetSelectedOption() == 1) || table.Menu == nil) && !table.Pagination.GetIsFirstPage() && !table.GetIsLoading() {
-				table.Pagination.SetOffset(table.Pagination.GetOffset() - table.Pagination.GetLimit())
-				table.FetchRecords(nil)
+				offset := table.Pagination.GetOffset() - table.Pagination.GetLimit()*keymap.Count(event)
+
+				if offset < 0 {
+					offset = 0
+				}
+
+				table.Pagination.SetOffset(offset)
+				go table.FetchRecords(nil)
 
 			}
 
@@ -219,8 +412,16 @@ func (home *Home) rightWrapperInputCapture(event *tcell.EventKey) *tcell.EventKe
 			table := tab.Content
 
 			if ((table.Menu != nil && table.Menu.GetSelectedOption() == 1) || table.Menu == nil) && !table.Pagination.GetIsLastPage() && !table.GetIsLoading() {
-				table.Pagination.SetOffset(table.Pagination.GetOffset() + table.Pagination.GetLimit())
-				table.FetchRecords(nil)
+				limit := table.Pagination.GetLimit()
+				offset := table.Pagination.GetOffset() + limit*keymap.Count(event)
+
+				// Past the last page, the last page is shown
+				if last := (table.Pagination.GetTotalRecords() - 1) / limit * limit; offset > last {
+					offset = last
+				}
+
+				table.Pagination.SetOffset(offset)
+				go table.FetchRecords(nil)
 			}
 		}
 	}
@@ -253,7 +454,7 @@ func (home *Home) homeInputCapture(event *tcell.EventKey) *tcell.EventKey {
 		if tab != nil {
 			home.TabbedPane.SwitchToTabByName("Editor")
 		} else {
-			tableWithEditor := NewResultsTable(&home.ListOfDbChanges, &home.ListOfDbInserts, home.Tree, home.DBDriver).WithEditor()
+			tableWithEditor := NewResultsTable(&home.ListOfDbChanges, &home.ListOfDbInserts, home.Tree, home.DBDriver).SetReadOnly(home.Connection.ReadOnly).WithEditor(home.Connection, home.Metadata)
 			home.TabbedPane.AppendTab("Editor", tableWithEditor)
 			tableWithEditor.SetIsFiltering(true)
 		}
@@ -273,7 +474,18 @@ func (home *Home) homeInputCapture(event *tcell.EventKey) *tcell.EventKey {
 		} else {
 			App.Stop()
 		}
+	} else if command == commands.Help {
+		// The editor has keys of its own, ? is typed there
+		if group, ok := focusedKeymapGroup(); ok && group != "editor" {
+			showHelp(group)
+			return nil
+		}
 	} else if command == commands.Save {
+		if home.Connection.ReadOnly {
+			showError(readOnlyError)
+			return nil
+		}
+
 		if (home.ListOfDbChanges != nil && len(home.ListOfDbChanges) > 0) || (home.ListOfDbInserts != nil && len(home.ListOfDbInserts) > 0) && !table.GetIsEditing() {
 			confirmationModal := NewConfirmationModal("")
 
@@ -282,20 +494,7 @@ func (home *Home) homeInputCapture(event *tcell.EventKey) *tcell.EventKey {
 				confirmationModal = nil
 
 				if buttonLabel == "Yes" {
-
-					err := home.DBDriver.ExecutePendingChanges(home.ListOfDbChanges, home.ListOfDbInserts)
-
-					if err != nil {
-						table.SetError(err.Error(), nil)
-					} else {
-						home.ListOfDbChanges = []models.DbDmlChange{}
-						home.ListOfDbInserts = []models.DbInsert{}
-
-						table.FetchRecords(nil)
-						home.Tree.ForceRemoveHighlight()
-
-					}
-
+					go home.saveChanges(table)
 				}
 			})
 
@@ -0,0 +1,83 @@
+package components
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+func pendingDelete(database, id string) models.DbDmlChange {
+	return models.DbDmlChange{Type: "DELETE", Database: database, Schema: "public", Table: "t", PrimaryKeyInfo: []models.PrimaryKeyInfo{{Name: "id", Value: id}}}
+}
+
+func TestSavePendingChanges(t *testing.T) {
+	driver := newFakeDriver()
+	driver.saves.errors["logs"] = errors.New("permission denied")
+
+	home := &Home{
+		DBDriver:        driver,
+		ListOfDbChanges: []models.DbDmlChange{pendingDelete("app", "1"), pendingDelete("logs", "2"), pendingDelete("app", "3")},
+		ListOfDbInserts: []models.DbInsert{},
+	}
+
+	err := home.savePendingChanges(context.Background())
+	if err == nil || err.Error() != "logs: permission denied" {
+		t.Fatalf("savePendingChanges() = %v, want the error of logs", err)
+	}
+
+	// Each database is saved through a connection of its own
+	if saved := driver.saves.changes["app"]; !reflect.DeepEqual(saved, []models.DbDmlChange{pendingDelete("app", "1"), pendingDelete("app", "3")}) {
+		t.Errorf("app saved %+v", saved)
+	}
+
+	// The changes of the database that failed stay pending
+	if !reflect.DeepEqual(home.ListOfDbChanges, []models.DbDmlChange{pendingDelete("logs", "2")}) {
+		t.Errorf("pending changes are %+v, want the ones of logs", home.ListOfDbChanges)
+	}
+}
+
+func TestSaveChangesCancelled(t *testing.T) {
+	driver := newFakeDriver()
+	driver.saves.started = make(chan string, 1)
+	driver.saves.block = true
+
+	home := &Home{
+		DBDriver:        driver,
+		ListOfDbChanges: []models.DbDmlChange{pendingDelete("app", "1")},
+		ListOfDbInserts: []models.DbInsert{},
+	}
+
+	table := NewResultsTable(&home.ListOfDbChanges, &home.ListOfDbInserts, nil, driver)
+
+	done := make(chan struct{})
+
+	go func() {
+		home.saveChanges(table)
+		close(done)
+	}()
+
+	<-driver.saves.started
+
+	if !table.GetIsLoading() {
+		t.Error("the loading modal isn't shown while saving")
+	}
+
+	// Esc in the loading modal
+	table.Loading.Cancel()
+	<-done
+
+	if table.GetIsLoading() {
+		t.Error("the loading modal is still shown after the save was cancelled")
+	}
+
+	if table.state.error != "" {
+		t.Errorf("cancelling shows the error %q", table.state.error)
+	}
+
+	if len(home.ListOfDbChanges) != 1 {
+		t.Errorf("pending changes are %+v after cancelling, want them kept", home.ListOfDbChanges)
+	}
+}
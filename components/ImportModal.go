@@ -0,0 +1,568 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/google/uuid"
+	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/app"
+	"github.com/jorgerojas26/lazysql/drivers"
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+const (
+	// importBatchSize is how many rows are inserted in each transaction
+	importBatchSize = 500
+	// importPreviewRows is how many rows of the file are previewed
+	importPreviewRows = 10
+)
+
+var importModes = []string{"Insert into the table", "Stage as pending inserts"}
+
+// importColumn is a column of the table rows are imported into
+type importColumn struct {
+	name       string
+	columnType string
+}
+
+// ImportModal imports the rows of a CSV, TSV or JSON file into a table. The
+// file is picked first, then its columns are mapped to the columns of the
+// table while a preview shows the values that don't fit their column.
+type ImportModal struct {
+	*tview.Flex
+	Pages    *tview.Pages
+	FileForm *tview.Form
+	Mapping  *tview.Table
+	Preview  *tview.Table
+	Options  *tview.Form
+	Sources  *tview.List
+	Result   *tview.TextView
+	Status   *tview.TextView
+	driver   drivers.Driver
+	table    TableReference
+	columns  []importColumn
+	// fileColumns and rows are read from the file
+	fileColumns []string
+	rows        [][]models.CellValue
+	// sources holds, for every column of the table, the index of the file
+	// column it is read from, or -1 to leave it to its default
+	sources []int
+	// cancel cancels the import running, it is nil when none is
+	cancel   context.CancelFunc
+	inserted int
+	onDone   func(staged []models.DbInsert, inserted int)
+}
+
+// NewImportModal returns the import of a file into a table. onDone is called
+// when the modal is closed, with the rows staged as pending inserts or the
+// number of rows inserted.
+func NewImportModal(driver drivers.Driver, table TableReference, onDone func(staged []models.DbInsert, inserted int)) *ImportModal {
+	modal := &ImportModal{
+		Pages:    tview.NewPages(),
+		FileForm: newImportForm(),
+		Mapping:  tview.NewTable(),
+		Preview:  tview.NewTable(),
+		Options:  newImportForm(),
+		Sources:  tview.NewList(),
+		Result:   tview.NewTextView(),
+		Status:   tview.NewTextView(),
+		driver:   driver,
+		table:    table,
+		onDone:   onDone,
+	}
+
+	modal.FileForm.AddInputField("File", "", 0, nil, func(path string) {
+		format := drivers.ImportFormatOf(path)
+
+		for i, importFormat := range drivers.ImportFormats {
+			if importFormat == format {
+				modal.FileForm.GetFormItemByLabel("Format").(*tview.DropDown).SetCurrentOption(i)
+			}
+		}
+	})
+
+	formatLabels := make([]string, len(drivers.ImportFormats))
+	for i, format := range drivers.ImportFormats {
+		formatLabels[i] = string(format)
+	}
+
+	modal.FileForm.AddDropDown("Format", formatLabels, 0, nil)
+	modal.FileForm.AddCheckbox("Header row", true, nil)
+	modal.FileForm.AddCheckbox("Empty fields are NULL", true, nil)
+	modal.FileForm.AddButton("Next", modal.readFile)
+	modal.FileForm.AddButton("Cancel", func() { modal.close(nil, 0) })
+	modal.FileForm.SetCancelFunc(func() { modal.close(nil, 0) })
+
+	modal.Mapping.SetSelectable(true, false)
+	modal.Mapping.SetFixed(1, 0)
+	modal.Mapping.SetSelectedFunc(func(row, _ int) {
+		if row > 0 {
+			modal.chooseSource(row - 1)
+		}
+	})
+	modal.Mapping.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			modal.close(nil, 0)
+		}
+	})
+	modal.Mapping.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab || event.Key() == tcell.KeyBacktab {
+			App.SetFocus(modal.Options)
+			return nil
+		}
+
+		return event
+	})
+
+	modal.Preview.SetFixed(1, 0)
+	modal.Preview.SetBorders(true)
+
+	modal.Options.SetHorizontal(true)
+	modal.Options.AddDropDown("Mode", importModes, 0, nil)
+	modal.Options.AddButton("Import", modal.submit)
+	modal.Options.AddButton("Back", func() {
+		modal.SetStatus("", tcell.ColorDefault)
+		modal.Pages.SwitchToPage("file")
+		App.SetFocus(modal.FileForm)
+	})
+	modal.Options.AddButton("Cancel", func() { modal.close(nil, 0) })
+	modal.Options.SetCancelFunc(func() { modal.close(nil, 0) })
+	modal.Options.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		item, button := modal.Options.GetFocusedItemIndex()
+
+		if (event.Key() == tcell.KeyTab && button == modal.Options.GetButtonCount()-1) || (event.Key() == tcell.KeyBacktab && item == 0) {
+			App.SetFocus(modal.Mapping)
+			return nil
+		}
+
+		return event
+	})
+
+	modal.Sources.ShowSecondaryText(false)
+	modal.Sources.SetBorder(true)
+	modal.Sources.SetTitle(" From file column ")
+	modal.Sources.SetDoneFunc(func() {
+		modal.Pages.HidePage("sources")
+		App.SetFocus(modal.Mapping)
+	})
+
+	modal.Result.SetDynamicColors(true)
+	modal.Result.SetScrollable(true)
+
+	resultForm := newImportForm()
+	resultForm.AddButton("Close", func() { modal.close(nil, modal.inserted) })
+	resultForm.SetCancelFunc(func() { modal.close(nil, modal.inserted) })
+
+	modal.Status.SetDynamicColors(true)
+
+	hint := tview.NewTextView().SetText("Enter: pick the file column, Tab: import options")
+	hint.SetTextColor(tview.Styles.InverseTextColor)
+
+	previewTitle := tview.NewTextView().SetText(fmt.Sprintf("First %d rows, invalid values in red", importPreviewRows))
+	previewTitle.SetTextColor(tview.Styles.InverseTextColor)
+
+	mapping := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(hint, 1, 0, false).
+		AddItem(modal.Mapping, 0, 1, true).
+		AddItem(previewTitle, 1, 0, false).
+		AddItem(modal.Preview, 0, 1, false).
+		AddItem(modal.Options, 3, 0, false)
+
+	result := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(modal.Result, 0, 1, false).
+		AddItem(resultForm, 3, 0, true)
+
+	sources := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(modal.Sources, 0, 2, true).
+			AddItem(nil, 0, 1, false), 40, 0, true).
+		AddItem(nil, 0, 1, false)
+
+	modal.Pages.AddPage("file", modal.FileForm, true, true)
+	modal.Pages.AddPage("mapping", mapping, true, false)
+	modal.Pages.AddPage("sources", sources, true, false)
+	modal.Pages.AddPage("result", result, true, false)
+
+	content := tview.NewFlex().SetDirection(tview.FlexRow)
+	content.SetBorder(true)
+	content.SetTitle(fmt.Sprintf(" Import into %s (Esc to close) ", tview.Escape(table.Name())))
+	content.SetBackgroundColor(app.Styles.ModalBackground)
+	content.AddItem(modal.Pages, 0, 1, true)
+	content.AddItem(modal.Status, 2, 0, false)
+
+	// Centers the content
+	modal.Flex = tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(content, 0, 8, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	// While rows are inserted, Esc cancels and nothing else can be done
+	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if modal.cancel == nil {
+			return event
+		}
+
+		if event.Key() == tcell.KeyEscape {
+			modal.cancel()
+		}
+
+		return nil
+	})
+
+	return modal
+}
+
+func newImportForm() *tview.Form {
+	form := tview.NewForm().SetFieldBackgroundColor(app.Styles.Field).SetButtonBackgroundColor(app.Styles.Button).SetLabelColor(tview.Styles.PrimaryTextColor).SetFieldTextColor(app.Styles.FieldText)
+	form.SetButtonTextColor(app.Styles.ButtonText)
+
+	return form
+}
+
+// SetStatus shows a message under the import
+func (modal *ImportModal) SetStatus(message string, color tcell.Color) {
+	modal.Status.SetText(tview.Escape(message))
+	modal.Status.SetTextColor(color)
+}
+
+func (modal *ImportModal) close(staged []models.DbInsert, inserted int) {
+	if modal.onDone != nil {
+		modal.onDone(staged, inserted)
+	}
+}
+
+// readFile reads the file along with the columns of the table, then shows the
+// mapping of the columns.
+func (modal *ImportModal) readFile() {
+	path := expandHome(strings.TrimSpace(modal.FileForm.GetFormItemByLabel("File").(*tview.InputField).GetText()))
+	format, _ := modal.FileForm.GetFormItemByLabel("Format").(*tview.DropDown).GetCurrentOption()
+	header := modal.FileForm.GetFormItemByLabel("Header row").(*tview.Checkbox).IsChecked()
+	emptyIsNull := modal.FileForm.GetFormItemByLabel("Empty fields are NULL").(*tview.Checkbox).IsChecked()
+
+	if path == "" {
+		modal.SetStatus("The file is required", app.Styles.Error)
+		return
+	}
+
+	modal.SetStatus("Reading "+path+"...", tview.Styles.PrimaryTextColor)
+
+	go func() {
+		columns, err := modal.readColumns()
+		if err != nil {
+			modal.showError(err)
+			return
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			modal.showError(err)
+			return
+		}
+
+		fileColumns, rows, err := drivers.ReadRows(drivers.ImportFormats[format], file, header, emptyIsNull)
+		file.Close()
+
+		if err != nil {
+			modal.showError(fmt.Errorf("reading %s: %w", path, err))
+			return
+		}
+
+		App.QueueUpdateDraw(func() {
+			modal.columns = columns
+			modal.fileColumns = fileColumns
+			modal.rows = rows
+			modal.sources = make([]int, len(columns))
+
+			// Columns are mapped by name to begin with
+			for i, column := range columns {
+				modal.sources[i] = -1
+
+				for j, fileColumn := range fileColumns {
+					if strings.EqualFold(strings.TrimSpace(fileColumn), column.name) {
+						modal.sources[i] = j
+						break
+					}
+				}
+			}
+
+			modal.updateMapping()
+			modal.Mapping.Select(1, 0)
+			modal.Pages.SwitchToPage("mapping")
+			App.SetFocus(modal.Mapping)
+		})
+	}()
+}
+
+func (modal *ImportModal) readColumns() ([]importColumn, error) {
+	rows, err := modal.driver.GetTableColumns(context.Background(), modal.table.Database, modal.table.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []importColumn{}
+
+	// The first row holds the headers
+	for i, row := range rows {
+		if i == 0 || len(row) == 0 {
+			continue
+		}
+
+		column := importColumn{name: row[0].Value}
+		if len(row) > 1 {
+			column.columnType = row[1].Value
+		}
+
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%s has no columns", modal.table.Name())
+	}
+
+	return columns, nil
+}
+
+func (modal *ImportModal) showError(err error) {
+	App.QueueUpdateDraw(func() {
+		modal.SetStatus(err.Error(), app.Styles.Error)
+	})
+}
+
+// checkValue returns why the value of a row doesn't fit a column of the
+// table, or nil when it does or the column isn't imported.
+func (modal *ImportModal) checkValue(row []models.CellValue, column int) error {
+	source := modal.sources[column]
+	if source < 0 {
+		return nil
+	}
+
+	dialect, err := drivers.DialectOf(modal.driver.GetProvider())
+	if err != nil {
+		return err
+	}
+
+	return dialect.CheckValue(modal.columns[column].columnType, row[source])
+}
+
+// updateMapping shows the mapping of the columns, the preview of the rows
+// and how many values are invalid.
+func (modal *ImportModal) updateMapping() {
+	invalidValues := make([]int, len(modal.columns))
+	invalidRows := 0
+
+	for _, row := range modal.rows {
+		valid := true
+
+		for i := range modal.columns {
+			if modal.checkValue(row, i) != nil {
+				invalidValues[i]++
+				valid = false
+			}
+		}
+
+		if !valid {
+			invalidRows++
+		}
+	}
+
+	selectedRow, _ := modal.Mapping.GetSelection()
+	modal.Mapping.Clear()
+
+	for i, header := range []string{"Column", "Type", "From file column", "Invalid values"} {
+		modal.Mapping.SetCell(0, i, tview.NewTableCell(header).SetTextColor(tview.Styles.PrimaryTextColor).SetSelectable(false).SetExpansion(1))
+	}
+
+	previewColumns := 0
+
+	for i, column := range modal.columns {
+		source := tview.NewTableCell("(default)").SetTextColor(tview.Styles.InverseTextColor).SetAttributes(DefaultAttributes)
+		if modal.sources[i] >= 0 {
+			source = tview.NewTableCell(tview.Escape(modal.fileColumns[modal.sources[i]])).SetTextColor(tview.Styles.PrimaryTextColor)
+		}
+
+		invalid := tview.NewTableCell(fmt.Sprint(invalidValues[i])).SetTextColor(tview.Styles.PrimaryTextColor)
+		if invalidValues[i] > 0 {
+			invalid.SetTextColor(app.Styles.Error)
+		}
+
+		modal.Mapping.SetCell(i+1, 0, tview.NewTableCell(tview.Escape(column.name)).SetTextColor(tview.Styles.PrimaryTextColor).SetExpansion(1))
+		modal.Mapping.SetCell(i+1, 1, tview.NewTableCell(tview.Escape(column.columnType)).SetTextColor(tview.Styles.PrimaryTextColor).SetExpansion(1))
+		modal.Mapping.SetCell(i+1, 2, source.SetExpansion(1))
+		modal.Mapping.SetCell(i+1, 3, invalid.SetExpansion(1))
+
+		if modal.sources[i] >= 0 {
+			modal.updatePreviewColumn(i, previewColumns)
+			previewColumns++
+		}
+	}
+
+	modal.Mapping.Select(selectedRow, 0)
+
+	// The columns left from a previous mapping
+	for column := modal.Preview.GetColumnCount() - 1; column >= previewColumns; column-- {
+		modal.Preview.RemoveColumn(column)
+	}
+
+	if invalidRows > 0 {
+		modal.SetStatus(fmt.Sprintf("%d rows in the file, %d with invalid values", len(modal.rows), invalidRows), app.Styles.Warning)
+	} else {
+		modal.SetStatus(fmt.Sprintf("%d rows in the file", len(modal.rows)), app.Styles.Success)
+	}
+}
+
+// updatePreviewColumn shows the first rows of a column of the table in a
+// column of the preview.
+func (modal *ImportModal) updatePreviewColumn(column, previewColumn int) {
+	modal.Preview.SetCell(0, previewColumn, tview.NewTableCell(tview.Escape(modal.columns[column].name)).SetTextColor(tview.Styles.PrimaryTextColor).SetExpansion(1))
+
+	for i, row := range modal.rows {
+		if i == importPreviewRows {
+			break
+		}
+
+		cell := tview.NewTableCell("").SetExpansion(1).SetMaxWidth(30)
+		setCellValue(cell, row[modal.sources[column]])
+
+		if modal.checkValue(row, column) != nil {
+			cell.SetTextColor(app.Styles.Error)
+		}
+
+		modal.Preview.SetCell(i+1, previewColumn, cell)
+	}
+}
+
+// chooseSource lists the columns of the file a column of the table can be
+// read from.
+func (modal *ImportModal) chooseSource(column int) {
+	modal.Sources.Clear()
+
+	choose := func(source int) func() {
+		return func() {
+			modal.sources[column] = source
+			modal.updateMapping()
+			modal.Pages.HidePage("sources")
+			App.SetFocus(modal.Mapping)
+		}
+	}
+
+	modal.Sources.AddItem("(default)", "", 0, choose(-1))
+
+	for i, fileColumn := range modal.fileColumns {
+		modal.Sources.AddItem(tview.Escape(fileColumn), "", 0, choose(i))
+	}
+
+	modal.Sources.SetCurrentItem(modal.sources[column] + 1)
+	modal.Pages.ShowPage("sources")
+	App.SetFocus(modal.Sources)
+}
+
+// inserts returns the rows of the file as inserts into every column of the
+// table, the columns that aren't imported take their default.
+func (modal *ImportModal) inserts() []models.DbInsert {
+	columns := make([]string, len(modal.columns))
+	for i, column := range modal.columns {
+		columns[i] = column.name
+	}
+
+	inserts := make([]models.DbInsert, len(modal.rows))
+
+	for i, row := range modal.rows {
+		values := make([]models.CellValue, len(modal.columns))
+
+		for j, source := range modal.sources {
+			if source < 0 {
+				values[j] = models.CellValue{Type: models.Default}
+			} else {
+				values[j] = row[source]
+			}
+		}
+
+		inserts[i] = models.DbInsert{
+			Database:        modal.table.Database,
+			Schema:          modal.table.Schema,
+			Table:           modal.table.Table,
+			Columns:         columns,
+			Values:          values,
+			PrimaryKeyValue: uuid.New(),
+			Option:          1,
+		}
+	}
+
+	return inserts
+}
+
+func (modal *ImportModal) submit() {
+	mode, _ := modal.Options.GetFormItemByLabel("Mode").(*tview.DropDown).GetCurrentOption()
+
+	imported := false
+	for _, source := range modal.sources {
+		imported = imported || source >= 0
+	}
+
+	if !imported {
+		modal.SetStatus("Choose the file column of at least one column of the table", app.Styles.Error)
+		return
+	}
+
+	inserts := modal.inserts()
+
+	if mode == 1 {
+		modal.close(inserts, 0)
+		return
+	}
+
+	var ctx context.Context
+	ctx, modal.cancel = context.WithCancel(context.Background())
+	modal.SetStatus(fmt.Sprintf("Inserting %d rows, press Esc to cancel...", len(inserts)), tview.Styles.PrimaryTextColor)
+
+	go func() {
+		inserted, failed, err := drivers.ImportRows(ctx, modal.driver, inserts, importBatchSize, func(done int) {
+			App.QueueUpdateDraw(func() {
+				modal.SetStatus(fmt.Sprintf("Inserted %d of %d rows, press Esc to cancel...", done, len(inserts)), tview.Styles.PrimaryTextColor)
+			})
+		})
+
+		App.QueueUpdateDraw(func() {
+			modal.cancel()
+			modal.cancel = nil
+			modal.inserted = inserted
+			modal.showResult(len(inserts), inserted, failed, err)
+		})
+	}()
+}
+
+// showResult reports how many rows were inserted and why the others weren't.
+// Rows are numbered from 1, not counting the header row.
+func (modal *ImportModal) showResult(total, inserted int, failed []drivers.ImportError, err error) {
+	var report strings.Builder
+
+	fmt.Fprintf(&report, "Inserted %d of %d rows into %s.\n", inserted, total, tview.Escape(modal.table.Name()))
+
+	if err != nil {
+		report.WriteString(app.Tag(app.Styles.Error) + "The import was cancelled, the rows inserted before stay.[-]\n")
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintf(&report, "\n%sRows not inserted: %d[-]\n", app.Tag(app.Styles.Error), len(failed))
+
+		for _, rowErr := range failed {
+			fmt.Fprintf(&report, "Row %d: %s\n", rowErr.Row+1, tview.Escape(rowErr.Err.Error()))
+		}
+	}
+
+	modal.Result.SetText(report.String())
+	modal.Result.ScrollToBeginning()
+	modal.SetStatus("", tcell.ColorDefault)
+	modal.Pages.SwitchToPage("result")
+	App.SetFocus(modal.Pages)
+}
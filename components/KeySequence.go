@@ -0,0 +1,252 @@
+package components
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/app"
+	"github.com/jorgerojas26/lazysql/commands"
+	"github.com/jorgerojas26/lazysql/keymap"
+)
+
+// countableCommands are the commands repeated by a count typed before their
+// keys, e.g. 5j or 10>
+var countableCommands = map[commands.Command]bool{
+	commands.MoveDown:     true,
+	commands.MoveUp:       true,
+	commands.MoveLeft:     true,
+	commands.MoveRight:    true,
+	commands.GotoNext:     true,
+	commands.GotoPrev:     true,
+	commands.HalfPageDown: true,
+	commands.HalfPageUp:   true,
+	commands.PageNext:     true,
+	commands.PagePrev:     true,
+}
+
+// keySequence holds the keys of a sequence being typed, and the count typed
+// before them
+type keySequence struct {
+	group  string
+	events []*tcell.EventKey
+	keys   []keymap.Key
+	count  int
+	// generation tells apart the sequences, a timeout is only for the
+	// sequence it was started for
+	generation int
+	// replayed are the keys sent again once a sequence is over, each with
+	// the sequence it ends
+	replayed []replayedKey
+}
+
+type replayedKey struct {
+	event *tcell.EventKey
+	keys  []keymap.Key
+	count int
+}
+
+var sequence keySequence
+
+// sequenceInputCapture reads the sequences of keys and the counts typed in the
+// tree, the results, the editor and the connections. The keys of a sequence
+// are held until it is complete, and the event of its last key is resolved by
+// the maps as the whole sequence. The keys that don't make a sequence are sent
+// again one by one.
+func sequenceInputCapture(event *tcell.EventKey) *tcell.EventKey {
+	for i, replayed := range sequence.replayed {
+		if replayed.event == event {
+			sequence.replayed = append(sequence.replayed[:i], sequence.replayed[i+1:]...)
+			keymap.SetSequence(event, replayed.keys, replayed.count)
+			return event
+		}
+	}
+
+	group, ok := focusedKeymapGroup()
+
+	if !ok || group != sequence.group {
+		sequence.reset()
+		sequence.group = group
+	}
+
+	if !ok {
+		return event
+	}
+
+	return captureSequence(group, event)
+}
+
+// captureSequence reads a key typed in a keymap group, see
+// sequenceInputCapture
+func captureSequence(group string, event *tcell.EventKey) *tcell.EventKey {
+	key := keymap.KeyOf(event)
+	digit := int(key.Char - '0')
+
+	// 0 is a key of its own unless it follows another digit. The digits the
+	// group binds, like the menus of the results, start a count too, and run
+	// their command when no movement follows them.
+	if key.Mod == 0 && len(sequence.keys) == 0 && digit >= 0 && digit <= 9 && (sequence.count > 0 || digit > 0) && takesCount(group) {
+		sequence.count = sequence.count*10 + digit
+		sequence.events = append(sequence.events, event)
+		sequence.wait()
+		return nil
+	}
+
+	keys := append(append([]keymap.Key{}, sequence.keys...), key)
+	command, prefix := matchSequence(group, keys)
+
+	switch {
+	case prefix:
+		sequence.keys = keys
+		sequence.events = append(sequence.events, event)
+		sequence.wait()
+		return nil
+	case command != commands.Noop && (sequence.count == 0 || countableCommands[command]):
+		count := sequence.count
+		sequence.reset()
+		keymap.SetSequence(event, keys, count)
+		return event
+	case len(sequence.events) == 0:
+		return event
+	case key.Code == tcell.KeyEscape:
+		sequence.reset()
+		return nil
+	default:
+		sequence.replay(append(sequence.events, event))
+		return nil
+	}
+}
+
+// focusedKeymapGroup returns the keymap group of the primitive with the focus
+func focusedKeymapGroup() (string, bool) {
+	if ConnectionListTable.HasFocus() {
+		return "connections", true
+	}
+
+	_, page := MainPages.GetFrontPage()
+	home, ok := page.(*Home)
+
+	if !ok {
+		return "", false
+	}
+
+	if home.Tree.HasFocus() {
+		return "tree", true
+	}
+
+	if tab := home.TabbedPane.GetCurrentTab(); tab != nil {
+		if tab.Content.HasFocus() {
+			return "table", true
+		}
+
+		if tab.Content.Editor != nil && tab.Content.Editor.HasFocus() {
+			return "editor", true
+		}
+	}
+
+	return "", false
+}
+
+// takesCount tells whether a group has commands a count repeats
+func takesCount(group string) bool {
+	for _, bind := range app.Keymaps.Group(group) {
+		if countableCommands[bind.Cmd] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchSequence returns the command a sequence is bound to in the maps of a
+// group, and whether it's the start of a longer sequence
+func matchSequence(group string, keys []keymap.Key) (commands.Command, bool) {
+	command, prefix := commands.Noop, false
+
+	for _, m := range app.Keymaps.Maps(group) {
+		cmd, isPrefix := m.Match(keys)
+
+		if command == commands.Noop {
+			command = cmd
+		}
+
+		prefix = prefix || isPrefix
+	}
+
+	return command, prefix
+}
+
+// wait starts the timeout of the sequence. A sequence that is complete but is
+// also the start of a longer one runs once it's over, the keys of the others
+// are sent again one by one.
+func (s *keySequence) wait() {
+	generation := s.generation
+
+	go func() {
+		<-time.After(app.Keymaps.Timeout)
+
+		App.QueueUpdateDraw(func() {
+			if s.generation == generation {
+				s.expire()
+			}
+		})
+	}()
+}
+
+// expire ends the sequence once its timeout is over. A count alone sends its
+// digits again, so that the digits bound to a command run it.
+func (s *keySequence) expire() {
+	command, _ := matchSequence(s.group, s.keys)
+
+	if len(s.keys) > 0 && command != commands.Noop && (s.count == 0 || countableCommands[command]) {
+		last := s.events[len(s.events)-1]
+		keys, count := s.keys, s.count
+
+		s.reset()
+		s.replayed = append(s.replayed, replayedKey{event: last, keys: keys, count: count})
+		go App.QueueEvent(last)
+	} else {
+		s.replay(s.events)
+	}
+}
+
+// replay ends the sequence and sends its keys again, each one on its own
+func (s *keySequence) replay(events []*tcell.EventKey) {
+	s.reset()
+
+	for _, event := range events {
+		s.replayed = append(s.replayed, replayedKey{event: event, keys: []keymap.Key{keymap.KeyOf(event)}})
+	}
+
+	go func() {
+		for _, event := range events {
+			App.QueueEvent(event)
+		}
+	}()
+}
+
+func (s *keySequence) reset() {
+	s.events = nil
+	s.keys = nil
+	s.count = 0
+	s.generation++
+}
+
+// drawKeySequence shows the keys of the sequence being typed in the bottom
+// right corner of the screen
+func drawKeySequence(screen tcell.Screen) {
+	if len(sequence.events) == 0 {
+		return
+	}
+
+	text := keymap.SequenceString(sequence.keys)
+
+	if sequence.count > 0 {
+		text = strconv.Itoa(sequence.count) + text
+	}
+
+	width, height := screen.Size()
+	tview.Print(screen, tview.Escape(" "+text+" "), 0, height-1, width-2, tview.AlignRight, tview.Styles.SecondaryTextColor)
+}
@@ -0,0 +1,101 @@
+package components
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+
+	"github.com/jorgerojas26/lazysql/app"
+	"github.com/jorgerojas26/lazysql/commands"
+	"github.com/jorgerojas26/lazysql/keymap"
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+func typeKeys(group string, chars string) (event *tcell.EventKey) {
+	for _, char := range chars {
+		event = captureSequence(group, tcell.NewEventKey(tcell.KeyRune, char, tcell.ModNone))
+	}
+
+	return event
+}
+
+func TestCaptureSequenceMenuDigits(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   string
+		expire bool
+	}{
+		{name: "timeout", keys: "2", expire: true},
+		{name: "followed by another command", keys: "2c"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sequence = keySequence{group: "table"}
+			defer func() { sequence = keySequence{} }()
+
+			if event := typeKeys("table", test.keys); event != nil {
+				t.Fatalf("%s isn't held", test.keys)
+			}
+
+			if test.expire {
+				sequence.expire()
+			}
+
+			if len(sequence.replayed) == 0 {
+				t.Fatal("2 isn't sent again")
+			}
+
+			// The key is read again as it's sent back
+			event := sequenceInputCapture(sequence.replayed[0].event)
+
+			if command := app.Keymaps.Group("table").Resolve(event); command != commands.Menu2 {
+				t.Fatalf("2 resolves to %v, want %v", command, commands.Menu2)
+			}
+
+			table := NewResultsTable(&[]models.DbDmlChange{}, &[]models.DbInsert{}, nil, nil).WithFilter()
+			table.tableInputCapture(event)
+
+			if option := table.Menu.GetSelectedOption(); option != 2 {
+				t.Fatalf("selected menu option is %d, want 2", option)
+			}
+		})
+	}
+}
+
+func TestCaptureSequenceCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		group   string
+		keys    string
+		command commands.Command
+		count   int
+	}{
+		{name: "count", group: "tree", keys: "5j", command: commands.MoveDown, count: 5},
+		{name: "count of two digits", group: "tree", keys: "12k", command: commands.MoveUp, count: 12},
+		{name: "no count", group: "tree", keys: "j", command: commands.MoveDown, count: 1},
+		{name: "count before a menu digit", group: "table", keys: "5j", command: commands.MoveDown, count: 5},
+		{name: "pages", group: "table", keys: "10>", command: commands.PageNext, count: 10},
+		{name: "count of the table", group: "table", keys: "23w", command: commands.GotoNext, count: 23},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sequence = keySequence{}
+			defer func() { sequence = keySequence{} }()
+
+			event := typeKeys(test.group, test.keys)
+			if event == nil {
+				t.Fatalf("%s is still held", test.keys)
+			}
+
+			if command := app.Keymaps.Group(test.group).Resolve(event); command != test.command {
+				t.Errorf("%s resolves to %v, want %v", test.keys, command, test.command)
+			}
+
+			if count := keymap.Count(event); count != test.count {
+				t.Errorf("count of %s is %d, want %d", test.keys, count, test.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package components
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// LoadingModal is shown while a query runs. Pressing Esc or Ctrl+C on it
+// cancels the context the query runs with.
+type LoadingModal struct {
+	*tview.Modal
+	mutex  sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var (
+	runningQueriesMutex sync.Mutex
+	runningQueries      = make(map[*LoadingModal]bool)
+)
+
+func NewLoadingModal() *LoadingModal {
+	modal := &LoadingModal{
+		Modal: tview.NewModal(),
+		ctx:   context.Background(),
+	}
+
+	modal.SetMessage("Loading...")
+	modal.SetBackgroundColor(tview.Styles.SecondaryTextColor)
+	modal.SetTextColor(tview.Styles.PrimaryTextColor)
+
+	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyCtrlC {
+			modal.Cancel()
+			return nil
+		}
+
+		return event
+	})
+
+	return modal
+}
+
+// Start creates the context of a new query
+func (modal *LoadingModal) Start() {
+	modal.mutex.Lock()
+	defer modal.mutex.Unlock()
+
+	modal.ctx, modal.cancel = context.WithCancel(context.Background())
+	modal.SetMessage("Loading...")
+
+	runningQueriesMutex.Lock()
+	runningQueries[modal] = true
+	runningQueriesMutex.Unlock()
+}
+
+// Stop marks the query as over, it can't be cancelled anymore
+func (modal *LoadingModal) Stop() {
+	modal.mutex.Lock()
+	defer modal.mutex.Unlock()
+
+	modal.cancel = nil
+
+	runningQueriesMutex.Lock()
+	delete(runningQueries, modal)
+	runningQueriesMutex.Unlock()
+}
+
+// SetMessage replaces the text shown, e.g. with the progress of the query
+func (modal *LoadingModal) SetMessage(message string) {
+	modal.SetText(message + "\n\nPress Esc to cancel")
+}
+
+// Cancel cancels the running query
+func (modal *LoadingModal) Cancel() {
+	modal.mutex.Lock()
+	defer modal.mutex.Unlock()
+
+	if modal.cancel != nil {
+		modal.cancel()
+	}
+}
+
+// Context returns the context of the last query. It is left as is by Stop, so
+// that ctx.Err() still tells whether the query was cancelled.
+func (modal *LoadingModal) Context() context.Context {
+	modal.mutex.Lock()
+	defer modal.mutex.Unlock()
+
+	return modal.ctx
+}
+
+// loadingInputCapture keeps Ctrl+C from quitting the application while a
+// loading modal has the focus, so that it cancels the query instead.
+func loadingInputCapture(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() != tcell.KeyCtrlC {
+		return event
+	}
+
+	runningQueriesMutex.Lock()
+	defer runningQueriesMutex.Unlock()
+
+	for modal := range runningQueries {
+		if modal.HasFocus() {
+			// tview only quits on the original Ctrl+C event
+			return tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+		}
+	}
+
+	return event
+}
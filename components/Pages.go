@@ -1,11 +1,27 @@
 package components
 
 import (
+	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
 var MainPages = tview.NewPages()
 
-func init() {
+// Init builds the pages of the application. The components read the theme
+// and the keymap as they are built, so it's called once they are set.
+func Init() {
+	ConnectionListTable = NewConnectionsTable()
+
 	MainPages.AddPage("Connections", NewConnectionPages().Flex, true, true)
+
+	App.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		event = loadingInputCapture(event)
+
+		if event == nil {
+			return nil
+		}
+
+		return sequenceInputCapture(event)
+	})
+	App.SetAfterDrawFunc(drawKeySequence)
 }
@@ -101,3 +101,17 @@ func (pagination *Pagination) SetOffset(offset int) {
 
 	pagination.textView.SetText(fmt.Sprintf("%d-%d of %d rows", offset+1, limit, total))
 }
+
+// SetStreamedRecords shows how many rows of a query were read so far. When more
+// is true the query has rows that weren't read yet.
+func (pagination *Pagination) SetStreamedRecords(count int, more bool) {
+	pagination.state.Offset = 0
+	pagination.state.Limit = count
+	pagination.state.TotalRecords = count
+
+	if more {
+		pagination.textView.SetText(fmt.Sprintf("showing first %d rows, more available", count))
+	} else {
+		pagination.textView.SetText(fmt.Sprintf("%d-%d of %d rows", 1, count, count))
+	}
+}
@@ -1,6 +1,9 @@
 package components
 
 import (
+	"strings"
+
+	"github.com/jorgerojas26/lazysql/app"
 	"github.com/jorgerojas26/lazysql/models"
 
 	"github.com/gdamore/tcell/v2"
@@ -27,14 +30,14 @@ func NewResultsFilter() *ResultsTableFilter {
 	recordsFilter.SetTitleAlign(tview.AlignCenter)
 	recordsFilter.SetBorderPadding(0, 0, 1, 1)
 
-	recordsFilter.Label.SetTextColor(tcell.ColorOrange)
+	recordsFilter.Label.SetTextColor(app.Styles.Accent)
 	recordsFilter.Label.SetText("WHERE")
 	recordsFilter.Label.SetBorderPadding(0, 0, 0, 1)
 
 	recordsFilter.Input.SetPlaceholder("Enter a WHERE clause to filter the results")
-	recordsFilter.Input.SetPlaceholderStyle(tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorDefault))
+	recordsFilter.Input.SetPlaceholderStyle(tcell.StyleDefault.Foreground(tview.Styles.PrimaryTextColor).Background(tcell.ColorDefault))
 	recordsFilter.Input.SetFieldBackgroundColor(tcell.ColorDefault)
-	recordsFilter.Input.SetFieldTextColor(tcell.ColorWhite.TrueColor())
+	recordsFilter.Input.SetFieldTextColor(tview.Styles.PrimaryTextColor)
 	recordsFilter.Input.SetDoneFunc(func(key tcell.Key) {
 		switch key {
 		case tcell.KeyEnter:
@@ -50,7 +53,7 @@ func NewResultsFilter() *ResultsTableFilter {
 
 		}
 	})
-	recordsFilter.Input.SetAutocompleteStyles(tcell.ColorBlack, tcell.StyleDefault.Foreground(tview.Styles.PrimaryTextColor).Background(tcell.ColorBlack), tcell.StyleDefault.Foreground(tview.Styles.PrimaryTextColor).Background(tcell.ColorBlack))
+	recordsFilter.Input.SetAutocompleteStyles(app.Styles.ModalBackground, tcell.StyleDefault.Foreground(tview.Styles.PrimaryTextColor).Background(app.Styles.ModalBackground), tcell.StyleDefault.Foreground(tview.Styles.PrimaryTextColor).Background(app.Styles.ModalBackground))
 
 	recordsFilter.AddItem(recordsFilter.Label, 6, 0, false)
 	recordsFilter.AddItem(recordsFilter.Input, 0, 1, false)
@@ -81,6 +84,12 @@ func (filter *ResultsTableFilter) GetCurrentFilter() string {
 	return filter.currentFilter
 }
 
+// SetCurrentFilter puts back a filter as if it was entered
+func (filter *ResultsTableFilter) SetCurrentFilter(currentFilter string) {
+	filter.currentFilter = currentFilter
+	filter.Input.SetText(strings.TrimPrefix(currentFilter, "WHERE "))
+}
+
 func (filter *ResultsTableFilter) SetIsFiltering(filtering bool) {
 	filter.filtering = filtering
 }
@@ -94,22 +103,22 @@ func (filter *ResultsTableFilter) RemoveHighlight() {
 }
 
 func (filter *ResultsTableFilter) RemoveLocalHighlight() {
-	filter.SetBorderColor(tcell.ColorWhite)
-	filter.Label.SetTextColor(tcell.ColorOrange)
+	filter.SetBorderColor(tview.Styles.BorderColor)
+	filter.Label.SetTextColor(app.Styles.Accent)
 	filter.Input.SetPlaceholderTextColor(tview.Styles.InverseTextColor)
 	filter.Input.SetFieldTextColor(tview.Styles.InverseTextColor)
 }
 
 func (filter *ResultsTableFilter) Highlight() {
-	filter.SetBorderColor(tcell.ColorWhite)
-	filter.Label.SetTextColor(tcell.ColorOrange)
-	filter.Input.SetPlaceholderTextColor(tcell.ColorWhite)
+	filter.SetBorderColor(tview.Styles.BorderColor)
+	filter.Label.SetTextColor(app.Styles.Accent)
+	filter.Input.SetPlaceholderTextColor(tview.Styles.PrimaryTextColor)
 	filter.Input.SetFieldTextColor(tview.Styles.PrimaryTextColor)
 }
 
 func (filter *ResultsTableFilter) HighlightLocal() {
-	filter.SetBorderColor(tview.Styles.PrimaryTextColor)
-	filter.Label.SetTextColor(tcell.ColorOrange)
-	filter.Input.SetPlaceholderTextColor(tcell.ColorWhite)
+	filter.SetBorderColor(app.Styles.Focus)
+	filter.Label.SetTextColor(app.Styles.Accent)
+	filter.Input.SetPlaceholderTextColor(tview.Styles.PrimaryTextColor)
 	filter.Input.SetFieldTextColor(tview.Styles.PrimaryTextColor)
 }
@@ -1,11 +1,17 @@
 package components
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jorgerojas26/lazysql/app"
 	"github.com/jorgerojas26/lazysql/commands"
+	"github.com/jorgerojas26/lazysql/helpers"
+	"github.com/jorgerojas26/lazysql/keymap"
 	"github.com/jorgerojas26/lazysql/models"
 
 	"github.com/jorgerojas26/lazysql/drivers"
@@ -17,19 +23,90 @@ import (
 )
 
 type ResultsTableState struct {
-	listOfDbChanges *[]models.DbDmlChange
-	listOfDbInserts *[]models.DbInsert
-	error           string
-	currentSort     string
-	dbReference     string
-	records         [][]string
-	columns         [][]string
-	constraints     [][]string
-	foreignKeys     [][]string
-	indexes         [][]string
-	isEditing       bool
-	isFiltering     bool
-	isLoading       bool
+	listOfDbChanges       *[]models.DbDmlChange
+	listOfDbInserts       *[]models.DbInsert
+	error                 string
+	currentSort           string
+	dbReference           string
+	records               [][]models.CellValue
+	columns               [][]models.CellValue
+	constraints           [][]models.CellValue
+	foreignKeys           [][]models.CellValue
+	indexes               [][]models.CellValue
+	primaryKeyColumnNames []string
+	rowIDs                []string
+	fetchedOffset         int
+	fetchedFilter         string
+	isEditing             bool
+	isFiltering           bool
+	isLoading             bool
+	// queryResults are the results of the statements of the last editor script
+	queryResults       []*queryResult
+	currentQueryResult int
+	maxQueryRows       int
+	isFetchingMore     bool
+	cursorMutex        sync.Mutex
+	// visual is the mode of the visual selection, which goes from the anchor
+	// cell to the selected cell
+	visual       visualMode
+	visualRow    int
+	visualColumn int
+}
+
+// visualMode tells which cells between the anchor and the selected cell are
+// in the visual selection
+type visualMode int
+
+const (
+	noVisual visualMode = iota
+	// visualCells selects the block of cells
+	visualCells
+	// visualRows selects the rows, every column of them
+	visualRows
+	// visualColumns selects the columns, every row of them
+	visualColumns
+)
+
+var visualModes = map[commands.Command]visualMode{
+	commands.Visual:        visualCells,
+	commands.VisualRows:    visualRows,
+	commands.VisualColumns: visualColumns,
+}
+
+// queryResult is the result of a statement of an editor script
+type queryResult struct {
+	// records holds the header and the rows read so far, it is nil when the
+	// statement doesn't return rows
+	records [][]models.CellValue
+	// rowsAffected is set for a statement that doesn't return rows
+	rowsAffected int64
+	// more is true when the statement has rows that weren't read
+	more bool
+	// cursor reads the rows that weren't read yet
+	cursor drivers.Cursor
+}
+
+func (result *queryResult) rowCount() int {
+	return len(result.records) - 1
+}
+
+func (result *queryResult) summary() string {
+	if result.records == nil {
+		return fmt.Sprintf("%d rows affected", result.rowsAffected)
+	}
+
+	if result.more {
+		return fmt.Sprintf("%d+ rows", result.rowCount())
+	}
+
+	return fmt.Sprintf("%d rows", result.rowCount())
+}
+
+func (result *queryResult) closeCursor() {
+	if result.cursor != nil {
+		result.cursor.Close()
+		result.cursor = nil
+	}
 }
 
 type ResultsTable struct {
@@ -40,29 +117,56 @@ type ResultsTable struct {
 	Menu        *ResultsTableMenu
 	Filter      *ResultsTableFilter
 	Error       *tview.Modal
-	Loading     *tview.Modal
+	Loading     *LoadingModal
 	Pagination  *Pagination
 	Editor      *SQLEditor
 	EditorPages *tview.Pages
 	ResultsInfo *tview.TextView
-	Tree        *Tree
-	DBDriver    drivers.Driver
+	// QueryResultsMenu lists the results of an editor script with several statements
+	QueryResultsMenu *tview.TextView
+	History          *HistoryModal
+	Export           *ExportModal
+	Yank             *YankMenu
+	Tree             *Tree
+	DBDriver         drivers.Driver
+	// database is the database of the table, the tree can have another one
+	// selected since the tab was opened
+	database string
+	// schema and tableName are the table of a table tab, empty in the editor
+	schema    string
+	tableName string
+	// connection is the connection the editor runs its statements on
+	connection models.Connection
+	// metadata completes the tables and columns of the connection in the editor
+	metadata *drivers.MetadataCache
+	// readOnly disables the changes to the rows, and the statements of the
+	// editor that write
+	readOnly bool
 }
 
+// fetchMoreRowsThreshold is how close to the last row read the editor results
+// are scrolled before the next batch of rows is read.
+const fetchMoreRowsThreshold = 50
+
+const readOnlyError = "The connection is read-only: the rows can't be changed and the SQL editor only runs statements that read."
+
+const noRowIdentityError = "The rows of this table can't be identified: it has no primary key, no unique index over NOT NULL columns and no row id. Editing is disabled to avoid changing the wrong rows."
+
 var (
-	ErrorModal  = tview.NewModal()
-	ChangeColor = tcell.ColorDarkOrange.TrueColor()
-	InsertColor = tcell.ColorDarkGreen.TrueColor()
-	DeleteColor = tcell.ColorRed
+	ErrorModal = tview.NewModal()
+	// NULL and DEFAULT are shown as keywords in their own style so they can't
+	// be mistaken for text
+	NullAttributes    = tcell.AttrItalic | tcell.AttrDim
+	DefaultAttributes = tcell.AttrItalic
 )
 
 func NewResultsTable(listOfDbChanges *[]models.DbDmlChange, listOfDbInserts *[]models.DbInsert, tree *Tree, dbdriver drivers.Driver) *ResultsTable {
 	state := &ResultsTableState{
-		records:         [][]string{},
-		columns:         [][]string{},
-		constraints:     [][]string{},
-		foreignKeys:     [][]string{},
-		indexes:         [][]string{},
+		records:         [][]models.CellValue{},
+		columns:         [][]models.CellValue{},
+		constraints:     [][]models.CellValue{},
+		foreignKeys:     [][]models.CellValue{},
+		indexes:         [][]models.CellValue{},
 		isEditing:       false,
 		isLoading:       false,
 		listOfDbChanges: listOfDbChanges,
@@ -75,18 +179,19 @@ func NewResultsTable(listOfDbChanges *[]models.DbDmlChange, listOfDbInserts *[]m
 	errorModal := tview.NewModal()
 	errorModal.AddButtons([]string{"Ok"})
 	errorModal.SetText("An error occurred")
-	errorModal.SetBackgroundColor(tcell.ColorRed)
-	errorModal.SetTextColor(tcell.ColorBlack)
-	errorModal.SetButtonStyle(tcell.StyleDefault.Foreground(tcell.ColorBlack))
+	errorModal.SetBackgroundColor(app.Styles.Error)
+	errorModal.SetTextColor(app.Styles.SelectionText)
+	errorModal.SetButtonStyle(tcell.StyleDefault.Foreground(app.Styles.SelectionText))
 	errorModal.SetFocus(0)
 
-	loadingModal := tview.NewModal()
-	loadingModal.SetText("Loading...")
-	loadingModal.SetBackgroundColor(tview.Styles.SecondaryTextColor)
-	loadingModal.SetTextColor(tview.Styles.PrimaryTextColor)
+	loadingModal := NewLoadingModal()
+	exportModal := NewExportModal()
+	yankMenu := NewYankMenu()
 
 	pages := tview.NewPages()
 	pages.AddPage("table", wrapper, true, true)
+	pages.AddPage("export", exportModal, true, false)
+	pages.AddPage("yank", yankMenu, true, false)
 	pages.AddPage("error", errorModal, true, false)
 	pages.AddPage("loading", loadingModal, false, false)
 
@@ -99,6 +204,8 @@ func NewResultsTable(listOfDbChanges *[]models.DbDmlChange, listOfDbInserts *[]m
 		Wrapper:    wrapper,
 		Error:      errorModal,
 		Loading:    loadingModal,
+		Export:     exportModal,
+		Yank:       yankMenu,
 		Pagination: pagination,
 		Editor:     nil,
 		Tree:       tree,
@@ -109,7 +216,7 @@ func NewResultsTable(listOfDbChanges *[]models.DbDmlChange, listOfDbInserts *[]m
 	table.SetBorders(true)
 	table.SetFixed(1, 0)
 	table.SetInputCapture(table.tableInputCapture)
-	table.SetSelectedStyle(tcell.StyleDefault.Background(tview.Styles.SecondaryTextColor).Foreground(tcell.ColorBlack.TrueColor()))
+	table.SetSelectedStyle(tcell.StyleDefault.Background(app.Styles.Selection).Foreground(app.Styles.SelectionText))
 
 	return table
 }
@@ -131,11 +238,50 @@ func (table *ResultsTable) WithFilter() *ResultsTable {
 	return table
 }
 
-func (table *ResultsTable) WithEditor() *ResultsTable {
+// SetReadOnly disables the changes to the rows, and the statements of the
+// editor that write
+func (table *ResultsTable) SetReadOnly(readOnly bool) *ResultsTable {
+	table.readOnly = readOnly
+	return table
+}
+
+// SetTable sets the table of a table tab, DBDriver has to query its database
+func (table *ResultsTable) SetTable(reference TableReference) *ResultsTable {
+	table.database = reference.Database
+	table.schema = reference.Schema
+	table.tableName = reference.Table
+	table.SetDBReference(reference.Name())
+
+	return table
+}
+
+// dialect returns the dialect of the driver of the table
+func (table *ResultsTable) dialect() (drivers.Dialect, error) {
+	return drivers.DialectOf(table.DBDriver.GetProvider())
+}
+
+func (table *ResultsTable) WithEditor(connection models.Connection, metadata *drivers.MetadataCache) *ResultsTable {
 	editor := NewSQLEditor()
+
+	// Without a dialect the editor still highlights the common SQL, the
+	// unknown provider is reported when a statement runs
+	if dialect, err := table.dialect(); err == nil {
+		editor.SetDialect(dialect)
+	}
+
+	editor.SetCompletionSource(func() drivers.CompletionSource {
+		database := table.currentDatabase()
+		if database == "" {
+			return nil
+		}
+
+		return metadata.Source(database)
+	})
 	editorPages := tview.NewPages()
 
 	table.Editor = editor
+	table.connection = connection
+	table.metadata = metadata
 
 	table.Wrapper.Clear()
 
@@ -156,41 +302,62 @@ func (table *ResultsTable) WithEditor() *ResultsTable {
 	editorPages.AddPage("Table", tableWrapper, true, false)
 	editorPages.AddPage("ResultsInfo", resultsInfoWrapper, true, true)
 
+	queryResultsMenu := tview.NewTextView()
+	queryResultsMenu.SetBorder(true)
+	queryResultsMenu.SetRegions(true)
+	queryResultsMenu.SetDynamicColors(true)
+	queryResultsMenu.SetTextColor(tview.Styles.PrimaryTextColor)
+
 	table.EditorPages = editorPages
 	table.ResultsInfo = resultsInfoText
+	table.QueryResultsMenu = queryResultsMenu
 
+	historyModal := NewHistoryModal()
+	table.History = historyModal
+	table.Page.AddPage("history", historyModal, true, false)
+
+	// The menu is only shown when there are several results
+	table.Wrapper.AddItem(queryResultsMenu, 0, 0, false)
 	table.Wrapper.AddItem(editorPages, 0, 1, true)
 
 	go table.subscribeToEditorChanges()
+	go table.subscribeToMetadataChanges()
 
 	return table
 }
 
-func (table *ResultsTable) AddRows(rows [][]string) {
+func (table *ResultsTable) AddRows(rows [][]models.CellValue) {
+	table.addRowsAt(0, rows)
+}
+
+// addRowsAt sets the rows starting at the given row index, row 0 being the
+// header.
+func (table *ResultsTable) addRowsAt(index int, rows [][]models.CellValue) {
 	for i, row := range rows {
 		for j, cell := range row {
-			tableCell := tview.NewTableCell(cell)
-			tableCell.SetSelectable(i > 0)
+			tableCell := tview.NewTableCell("")
+			setCellValue(tableCell, cell)
+			tableCell.SetSelectable(index+i > 0)
 			tableCell.SetExpansion(1)
 
-			if i == 0 {
+			if index+i == 0 {
 				tableCell.SetTextColor(tview.Styles.PrimaryTextColor)
 			} else {
 				tableCell.SetTextColor(tview.Styles.PrimaryTextColor)
 			}
 
-			table.SetCell(i, j, tableCell)
+			table.SetCell(index+i, j, tableCell)
 		}
 	}
 }
 
 func (table *ResultsTable) AddInsertedRows() {
 	inserts := *table.state.listOfDbInserts
-	rows := make([][]string, len(inserts))
+	rows := make([][]models.CellValue, len(inserts))
 
 	if len(inserts) > 0 {
 		for i, insert := range inserts {
-			if insert.Table == table.GetDBReference() && insert.Option == table.Menu.GetSelectedOption() {
+			if insert.Database == table.database && insert.Schema == table.schema && insert.Table == table.tableName && insert.Option == table.Menu.GetSelectedOption() {
 				rows[i] = insert.Values
 			}
 		}
@@ -201,25 +368,27 @@ func (table *ResultsTable) AddInsertedRows() {
 		rowIndex := rowCount + i
 
 		for j, cell := range row {
-			tableCell := tview.NewTableCell(cell)
+			tableCell := tview.NewTableCell("")
+			setCellValue(tableCell, cell)
 			tableCell.SetExpansion(1)
-			tableCell.SetReference(inserts[i].PrimaryKeyValue)
+			setInsertID(tableCell, inserts[i].PrimaryKeyValue)
 
 			tableCell.SetTextColor(tview.Styles.PrimaryTextColor)
-			tableCell.SetBackgroundColor(InsertColor)
+			tableCell.SetBackgroundColor(app.Styles.PendingInsert)
 
 			table.SetCell(rowIndex, j, tableCell)
 		}
 	}
 }
 
-func (table *ResultsTable) InsertRow(cols []string, index int, UUID uuid.UUID) {
+func (table *ResultsTable) InsertRow(cols []models.CellValue, index int, UUID uuid.UUID) {
 	for i, cell := range cols {
-		tableCell := tview.NewTableCell(cell)
+		tableCell := tview.NewTableCell("")
+		setCellValue(tableCell, cell)
 		tableCell.SetExpansion(1)
 
 		if i == 0 {
-			tableCell.SetReference(UUID)
+			setInsertID(tableCell, UUID)
 		}
 		tableCell.SetTextColor(tview.Styles.PrimaryTextColor)
 
@@ -232,26 +401,64 @@ func (table *ResultsTable) tableInputCapture(event *tcell.EventKey) *tcell.Event
 	colCount := table.GetColumnCount()
 	rowCount := table.GetRowCount()
 
-	eventKey := event.Rune()
+	if table.state.visual != noVisual && !table.visualInputCapture(event) {
+		return nil
+	}
+
+	command := app.Keymaps.Group("table").Resolve(event)
+	count := keymap.Count(event)
+	menuIndex := commands.MenuIndex(command)
+
+	if table.readOnly && (command == commands.Edit || command == commands.Delete || command == commands.AppendNewRow) {
+		table.SetError(readOnlyError, nil)
+		return nil
+	}
 
-	if eventKey == '1' || eventKey == '2' || eventKey == '3' || eventKey == '4' || eventKey == '5' {
+	if menuIndex >= 0 && menuIndex < 5 {
 		table.Select(1, 0)
 	}
 
+	if table.Editor != nil {
+		if menuIndex >= 0 {
+			table.ShowQueryResult(menuIndex)
+			return nil
+		}
+
+		last := len(table.state.queryResults) - 1
+
+		switch command {
+		case commands.PageNext:
+			if table.state.currentQueryResult+count > last {
+				table.ShowQueryResult(last)
+			} else {
+				table.ShowQueryResult(table.state.currentQueryResult + count)
+			}
+			return nil
+		case commands.PagePrev:
+			if table.state.currentQueryResult-count < 0 {
+				table.ShowQueryResult(0)
+			} else {
+				table.ShowQueryResult(table.state.currentQueryResult - count)
+			}
+			return nil
+		}
+	}
+
 	if table.Menu != nil {
-		if eventKey == '1' {
+		switch command {
+		case commands.Menu1:
 			table.Menu.SetSelectedOption(1)
 			table.UpdateRows(table.GetRecords())
-		} else if eventKey == '2' {
+		case commands.Menu2:
 			table.Menu.SetSelectedOption(2)
 			table.UpdateRows(table.GetColumns())
-		} else if eventKey == '3' {
+		case commands.Menu3:
 			table.Menu.SetSelectedOption(3)
 			table.UpdateRows(table.GetConstraints())
-		} else if eventKey == '4' {
+		case commands.Menu4:
 			table.Menu.SetSelectedOption(4)
 			table.UpdateRows(table.GetForeignKeys())
-		} else if eventKey == '5' {
+		case commands.Menu5:
 			table.Menu.SetSelectedOption(5)
 			table.UpdateRows(table.GetIndexes())
 		}
@@ -260,8 +467,6 @@ func (table *ResultsTable) tableInputCapture(event *tcell.EventKey) *tcell.Event
 		return nil
 	}
 
-	command := app.Keymaps.Group("table").Resolve(event)
-
 	if command == commands.Search {
 		if table.Editor != nil {
 			App.SetFocus(table.Editor)
@@ -289,7 +494,7 @@ func (table *ResultsTable) tableInputCapture(event *tcell.EventKey) *tcell.Event
 
 					for i, col := range columns {
 						if i > 0 {
-							columnNames = append(columnNames, col[0])
+							columnNames = append(columnNames, col[0].Value)
 						}
 					}
 
@@ -361,21 +566,44 @@ func (table *ResultsTable) tableInputCapture(event *tcell.EventKey) *tcell.Event
 		}
 		table.SetInputCapture(nil)
 	} else if command == commands.Edit {
-		table.StartEditingCell(selectedRowIndex, selectedColumnIndex, func(newValue string, row, col int) {
-			cellReference := table.GetCell(row, 0).GetReference()
+		if !table.isInsertedRow(selectedRowIndex) && table.GetPrimaryKeyValue(selectedRowIndex) == nil {
+			table.SetError(noRowIdentityError, nil)
+			return nil
+		}
 
-			if cellReference != nil {
-				table.MutateInsertedRowCell(cellReference.(uuid.UUID), col, newValue)
+		table.StartEditingCell(selectedRowIndex, selectedColumnIndex, func(newValue models.CellValue, row, col int) {
+			if insertID, ok := getInsertID(table.GetCell(row, 0)); ok {
+				table.MutateInsertedRowCell(insertID, col, newValue)
 			}
 		})
-	} else if command == commands.GotoNext {
-		if selectedColumnIndex+1 < colCount {
-			table.Select(selectedRowIndex, selectedColumnIndex+1)
+	} else if command == commands.MoveDown {
+		if selectedRowIndex+count > rowCount-1 {
+			table.Select(rowCount-1, selectedColumnIndex)
+		} else {
+			table.Select(selectedRowIndex+count, selectedColumnIndex)
+		}
+		return nil
+	} else if command == commands.MoveUp {
+		if selectedRowIndex-count < 1 {
+			table.Select(1, selectedColumnIndex)
+		} else {
+			table.Select(selectedRowIndex-count, selectedColumnIndex)
+		}
+		return nil
+	} else if command == commands.MoveRight || command == commands.GotoNext {
+		if selectedColumnIndex+count > colCount-1 {
+			table.Select(selectedRowIndex, colCount-1)
+		} else {
+			table.Select(selectedRowIndex, selectedColumnIndex+count)
 		}
-	} else if command == commands.GotoPrev {
-		if selectedColumnIndex > 0 {
-			table.Select(selectedRowIndex, selectedColumnIndex-1)
+		return nil
+	} else if command == commands.MoveLeft || command == commands.GotoPrev {
+		if selectedColumnIndex-count < 0 {
+			table.Select(selectedRowIndex, 0)
+		} else {
+			table.Select(selectedRowIndex, selectedColumnIndex-count)
 		}
+		return nil
 	} else if command == commands.GotoEnd {
 		table.Select(selectedRowIndex, colCount-1)
 	} else if command == commands.GotoStart {
@@ -384,17 +612,26 @@ func (table *ResultsTable) tableInputCapture(event *tcell.EventKey) *tcell.Event
 		go table.Select(1, selectedColumnIndex)
 	} else if command == commands.GotoTop {
 		go table.Select(rowCount-1, selectedColumnIndex)
-	} else if eventKey == 4 { // Ctrl + D
-		if selectedRowIndex+7 > rowCount-1 {
+	} else if command == commands.HalfPageDown {
+		if selectedRowIndex+7*count > rowCount-1 {
 			go table.Select(rowCount-1, selectedColumnIndex)
 		} else {
-			go table.Select(selectedRowIndex+7, selectedColumnIndex)
+			go table.Select(selectedRowIndex+7*count, selectedColumnIndex)
 		}
-	} else if eventKey == 21 { // Ctrl + U
-		if selectedRowIndex-7 < 1 {
+	} else if command == commands.HalfPageUp {
+		if selectedRowIndex-7*count < 1 {
 			go table.Select(1, selectedColumnIndex)
 		} else {
-			go table.Select(selectedRowIndex-7, selectedColumnIndex)
+			go table.Select(selectedRowIndex-7*count, selectedColumnIndex)
+		}
+	} else if mode, ok := visualModes[command]; ok {
+		table.state.visual = mode
+		table.state.visualRow, table.state.visualColumn = selectedRowIndex, selectedColumnIndex
+		return nil
+	} else if command == commands.Export {
+		if table.Menu == nil || table.Menu.GetSelectedOption() == 1 {
+			table.ShowExport()
+			return nil
 		}
 	} else if command == commands.Delete {
 		if table.Menu.GetSelectedOption() == 1 {
@@ -402,9 +639,9 @@ func (table *ResultsTable) tableInputCapture(event *tcell.EventKey) *tcell.Event
 			indexOfInsertedRow := -1
 
 			for i, insertedRow := range *table.state.listOfDbInserts {
-				cellReference := table.GetCell(selectedRowIndex, 0).GetReference()
+				insertID, ok := getInsertID(table.GetCell(selectedRowIndex, 0))
 
-				if cellReference != nil && insertedRow.PrimaryKeyValue.String() == cellReference.(uuid.UUID).String() {
+				if ok && insertedRow.PrimaryKeyValue == insertID {
 					isAnInsertedRow = true
 					indexOfInsertedRow = i
 				}
@@ -424,35 +661,40 @@ func (table *ResultsTable) tableInputCapture(event *tcell.EventKey) *tcell.Event
 				if len(*table.state.listOfDbChanges) == 0 && len(*table.state.listOfDbInserts) == 0 {
 					table.Tree.ForceRemoveHighlight()
 				} else if len(*table.state.listOfDbChanges) == 0 && len(*table.state.listOfDbInserts) > 0 {
-					table.Tree.GetCurrentNode().SetColor(InsertColor)
+					table.Tree.GetCurrentNode().SetColor(app.Styles.PendingInsert)
 				} else if len(*table.state.listOfDbChanges) > 0 && len(*table.state.listOfDbInserts) == 0 {
-					table.Tree.GetCurrentNode().SetColor(ChangeColor)
+					table.Tree.GetCurrentNode().SetColor(app.Styles.PendingUpdate)
 				}
+			} else if table.GetPrimaryKeyValue(selectedRowIndex) == nil {
+				table.SetError(noRowIdentityError, nil)
+				return nil
 			} else {
-				table.AppendNewChange("DELETE", table.GetDBReference(), selectedRowIndex, -1, "")
+				table.AppendNewChange("DELETE", selectedRowIndex, -1, models.CellValue{})
 			}
 
 		}
 	} else if command == commands.AppendNewRow {
 		if table.Menu.GetSelectedOption() == 1 {
 
-			newRow := make([]string, table.GetColumnCount())
+			newRow := make([]models.CellValue, table.GetColumnCount())
 			newRowIndex := table.GetRowCount()
 			newRowUuid := uuid.New()
 
 			for i := 0; i < table.GetColumnCount(); i++ {
-				newRow[i] = "Default"
+				newRow[i] = models.CellValue{Type: models.Default}
 			}
 
 			table.InsertRow(newRow, newRowIndex, newRowUuid)
 
 			for i := 0; i < table.GetColumnCount(); i++ {
-				table.GetCell(newRowIndex, i).SetBackgroundColor(tcell.ColorDarkGreen)
+				table.GetCell(newRowIndex, i).SetBackgroundColor(app.Styles.PendingInsert)
 			}
 
 			newInsert := models.DbInsert{
-				Table:           table.GetDBReference(),
-				Columns:         table.GetRecords()[0],
+				Database:        table.database,
+				Schema:          table.schema,
+				Table:           table.tableName,
+				Columns:         models.CellValueStrings(table.GetRecords()[0]),
 				Values:          newRow,
 				PrimaryKeyValue: newRowUuid,
 				Option:          1,
@@ -461,19 +703,17 @@ func (table *ResultsTable) tableInputCapture(event *tcell.EventKey) *tcell.Event
 			*table.state.listOfDbInserts = append(*table.state.listOfDbInserts, newInsert)
 
 			if table.Tree.GetCurrentNode().GetColor() == tview.Styles.InverseTextColor || table.Tree.GetCurrentNode().GetColor() == tview.Styles.PrimaryTextColor {
-				table.Tree.GetCurrentNode().SetColor(InsertColor)
-			} else if table.Tree.GetCurrentNode().GetColor() == DeleteColor {
-				table.Tree.GetCurrentNode().SetColor(ChangeColor)
+				table.Tree.GetCurrentNode().SetColor(app.Styles.PendingInsert)
+			} else if table.Tree.GetCurrentNode().GetColor() == app.Styles.PendingDelete {
+				table.Tree.GetCurrentNode().SetColor(app.Styles.PendingUpdate)
 			}
 
 			table.Select(newRowIndex, 1)
 
 			App.ForceDraw()
-			table.StartEditingCell(newRowIndex, 1, func(newValue string, row, col int) {
-				cellReference := table.GetCell(row, 0).GetReference()
-
-				if cellReference != nil {
-					table.MutateInsertedRowCell(cellReference.(uuid.UUID), col, newValue)
+			table.StartEditingCell(newRowIndex, 1, func(newValue models.CellValue, row, col int) {
+				if insertID, ok := getInsertID(table.GetCell(row, 0)); ok {
+					table.MutateInsertedRowCell(insertID, col, newValue)
 				}
 			})
 
@@ -481,28 +721,22 @@ func (table *ResultsTable) tableInputCapture(event *tcell.EventKey) *tcell.Event
 	}
 
 	if len(table.GetRecords()) > 0 {
-		if eventKey == 'J' {
+		if command == commands.SortDescending {
 			currentColumnName := table.GetColumnNameByIndex(selectedColumnIndex)
 			table.Pagination.SetOffset(0)
-			table.SetSortedBy(currentColumnName, "DESC")
+			go table.SetSortedBy(currentColumnName, "DESC")
 
-		} else if eventKey == 'K' {
+		} else if command == commands.SortAscending {
 			currentColumnName := table.GetColumnNameByIndex(selectedColumnIndex)
 			table.Pagination.SetOffset(0)
-			table.SetSortedBy(currentColumnName, "ASC")
+			go table.SetSortedBy(currentColumnName, "ASC")
 		} else if command == commands.Copy {
 			selectedCell := table.GetCell(selectedRowIndex, selectedColumnIndex)
 
+			// NULL and DEFAULT are copied empty, like in the TSV of the
+			// visual mode
 			if selectedCell != nil {
-				err := clipboard.Init()
-
-				if err == nil {
-					text := []byte(selectedCell.Text)
-
-					if text != nil {
-						clipboard.Write(clipboard.FmtText, text)
-					}
-				}
+				copyToClipboard(getCellValue(selectedCell).Value)
 			}
 		}
 	}
@@ -510,7 +744,8 @@ func (table *ResultsTable) tableInputCapture(event *tcell.EventKey) *tcell.Event
 	return event
 }
 
-func (table *ResultsTable) UpdateRows(rows [][]string) {
+func (table *ResultsTable) UpdateRows(rows [][]models.CellValue) {
+	table.state.visual = noVisual
 	table.Clear()
 	table.AddRows(rows)
 	table.AddInsertedRows()
@@ -518,6 +753,207 @@ func (table *ResultsTable) UpdateRows(rows [][]string) {
 	table.Select(1, 0)
 }
 
+// visualInputCapture handles the keys of the visual mode. It returns true for
+// the keys that move the selected cell, every other command is left out until
+// the selection is copied or the mode is left.
+func (table *ResultsTable) visualInputCapture(event *tcell.EventKey) bool {
+	command := app.Keymaps.Group("table").Resolve(event)
+
+	if mode, ok := visualModes[command]; ok {
+		if mode == table.state.visual {
+			table.state.visual = noVisual
+		} else {
+			table.state.visual = mode
+		}
+
+		return false
+	}
+
+	switch command {
+	case commands.Copy:
+		table.ShowYank()
+		return false
+	case commands.MoveDown, commands.MoveUp, commands.MoveLeft, commands.MoveRight,
+		commands.GotoNext, commands.GotoPrev, commands.GotoStart, commands.GotoEnd, commands.HalfPageDown, commands.HalfPageUp:
+		return true
+	case commands.Noop:
+		if event.Key() == tcell.KeyEscape {
+			table.state.visual = noVisual
+			return false
+		}
+
+		return true
+	}
+
+	// The menu, the results and the sort of the table stay as they are
+	return false
+}
+
+// visualRange returns the rows and columns of the visual selection, ok is
+// false when there is none.
+func (table *ResultsTable) visualRange() (top, left, bottom, right int, ok bool) {
+	if table.state.visual == noVisual {
+		return 0, 0, 0, 0, false
+	}
+
+	row, column := table.GetSelection()
+
+	top, bottom = table.state.visualRow, row
+	if top > bottom {
+		top, bottom = bottom, top
+	}
+
+	left, right = table.state.visualColumn, column
+	if left > right {
+		left, right = right, left
+	}
+
+	switch table.state.visual {
+	case visualRows:
+		left, right = 0, table.GetColumnCount()-1
+	case visualColumns:
+		top, bottom = 1, table.GetRowCount()-1
+	}
+
+	// The header isn't part of the rows
+	if top < 1 {
+		top = 1
+	}
+
+	if bottom > table.GetRowCount()-1 {
+		bottom = table.GetRowCount() - 1
+	}
+
+	return top, left, bottom, right, top <= bottom && left <= right
+}
+
+// highlightVisualSelection gives the cells of the visual selection their own
+// background, until the function returned puts their colors back.
+func (table *ResultsTable) highlightVisualSelection() func() {
+	top, left, bottom, right, ok := table.visualRange()
+	if !ok {
+		return func() {}
+	}
+
+	// Only the rows on screen are drawn
+	rowOffset, _ := table.GetOffset()
+	_, _, _, height := table.GetInnerRect()
+
+	if top < rowOffset {
+		top = rowOffset
+	}
+
+	if bottom > rowOffset+height {
+		bottom = rowOffset + height
+	}
+
+	type cellBackground struct {
+		cell        *tview.TableCell
+		color       tcell.Color
+		transparent bool
+	}
+
+	backgrounds := []cellBackground{}
+
+	for row := top; row <= bottom; row++ {
+		for column := left; column <= right; column++ {
+			cell := table.GetCell(row, column)
+			backgrounds = append(backgrounds, cellBackground{cell: cell, color: cell.BackgroundColor, transparent: cell.Transparent})
+			cell.SetBackgroundColor(app.Styles.Visual)
+		}
+	}
+
+	return func() {
+		for _, background := range backgrounds {
+			background.cell.BackgroundColor = background.color
+			background.cell.Transparent = background.transparent
+		}
+	}
+}
+
+// ShowYank opens the formats the visual selection can be copied in.
+func (table *ResultsTable) ShowYank() {
+	closeYank := func() {
+		table.Page.HidePage("yank")
+		App.SetFocus(table)
+	}
+
+	table.Yank.Show(func(format drivers.CopyFormat) {
+		closeYank()
+		table.yank(format)
+	}, closeYank)
+
+	table.Page.ShowPage("yank")
+	App.SetFocus(table.Yank)
+}
+
+// yank copies the visual selection to the clipboard and leaves the visual
+// mode. The DEFAULT of the rows not inserted yet is copied as NULL.
+func (table *ResultsTable) yank(format drivers.CopyFormat) {
+	top, left, bottom, right, ok := table.visualRange()
+	table.state.visual = noVisual
+
+	if !ok {
+		return
+	}
+
+	columns := []string{}
+	for column := left; column <= right; column++ {
+		columns = append(columns, table.GetCell(0, column).Text)
+	}
+
+	rows := [][]models.CellValue{}
+
+	for row := top; row <= bottom; row++ {
+		values := []models.CellValue{}
+
+		for column := left; column <= right; column++ {
+			value := getCellValue(table.GetCell(row, column))
+
+			if value.Type == models.Default {
+				value = models.CellValue{Type: models.Null}
+			}
+
+			values = append(values, value)
+		}
+
+		rows = append(rows, values)
+	}
+
+	dialect, err := table.dialect()
+	if err != nil {
+		table.SetError(err.Error(), nil)
+		return
+	}
+
+	name := dialect.QuoteIdentifier("results")
+	if table.tableName != "" {
+		name = dialect.QuoteTableName(table.schema, table.tableName)
+	}
+
+	text, err := dialect.FormatRows(format, columns, rows, name)
+	if err == nil {
+		err = copyToClipboard(text)
+	}
+
+	if err != nil {
+		table.SetError(err.Error(), nil)
+	}
+}
+
+// copyToClipboard writes text to the clipboard of the system, tests replace
+// it to read what is copied
+var copyToClipboard = func(text string) error {
+	err := clipboard.Init()
+	if err != nil {
+		return err
+	}
+
+	clipboard.Write(clipboard.FmtText, []byte(text))
+
+	return nil
+}
+
 func (table *ResultsTable) UpdateRowsColor(headerColor tcell.Color, rowColor tcell.Color) {
 	for i := 0; i < table.GetRowCount(); i++ {
 		for j := 0; j < table.GetColumnCount(); j++ {
@@ -549,7 +985,7 @@ func (table *ResultsTable) RemoveHighlightAll() {
 }
 
 func (table *ResultsTable) HighlightTable() {
-	table.SetBorderColor(tview.Styles.PrimaryTextColor)
+	table.SetBorderColor(app.Styles.Focus)
 	table.SetBordersColor(tview.Styles.PrimaryTextColor)
 	table.SetTitleColor(tview.Styles.PrimaryTextColor)
 	table.UpdateRowsColor(tview.Styles.PrimaryTextColor, tview.Styles.PrimaryTextColor)
@@ -612,61 +1048,10 @@ func (table *ResultsTable) subscribeToEditorChanges() {
 		case "Query":
 			query := stateChange.Value.(string)
 			if query != "" {
-				queryLower := strings.ToLower(query)
-
-				if strings.Contains(queryLower, "select") {
-					table.SetLoading(true)
-					App.Draw()
-					rows, err := table.DBDriver.ExecuteQuery(query)
-					table.Pagination.SetTotalRecords(len(rows))
-					table.Pagination.SetLimit(len(rows))
-
-					if err != nil {
-						table.SetLoading(false)
-						App.Draw()
-						table.SetError(err.Error(), nil)
-					} else {
-						table.UpdateRows(rows)
-						table.SetIsFiltering(false)
-
-						if len(rows) > 1 {
-							App.SetFocus(table)
-							table.HighlightTable()
-							table.Editor.SetBlur()
-							table.SetInputCapture(table.tableInputCapture)
-							App.Draw()
-						} else if len(rows) == 1 {
-							table.SetInputCapture(nil)
-							App.SetFocus(table.Editor)
-							table.Editor.Highlight()
-							table.RemoveHighlightTable()
-							table.SetIsFiltering(true)
-							App.Draw()
-						}
-						table.SetLoading(false)
-					}
-					table.EditorPages.SwitchToPage("Table")
-					App.Draw()
-				} else {
-					table.SetRecords([][]string{})
-					table.SetLoading(true)
-					App.Draw()
-
-					result, err := table.DBDriver.ExecuteDMLStatement(query)
-
-					if err != nil {
-						table.SetLoading(false)
-						App.Draw()
-						table.SetError(err.Error(), nil)
-					} else {
-						table.SetResultsInfo(result)
-						table.SetLoading(false)
-						table.EditorPages.SwitchToPage("ResultsInfo")
-						App.SetFocus(table.Editor)
-						App.Draw()
-					}
-				}
+				table.runScript(query)
 			}
+		case "History":
+			table.ShowHistory()
 		case "Escape":
 			table.SetIsFiltering(false)
 			App.SetFocus(table)
@@ -678,28 +1063,549 @@ func (table *ResultsTable) subscribeToEditorChanges() {
 	}
 }
 
+func (table *ResultsTable) subscribeToMetadataChanges() {
+	ch := table.metadata.Subscribe()
+
+	for range ch {
+		App.QueueUpdateDraw(table.Editor.RefreshCompletions)
+	}
+}
+
+// runScript runs every statement of the editor script in order, until one
+// fails, and shows the result of the last one.
+func (table *ResultsTable) runScript(script string) {
+	dialect, err := table.dialect()
+	if err != nil {
+		table.SetError(err.Error(), nil)
+		return
+	}
+
+	statements := dialect.SplitStatements(script)
+	if len(statements) == 0 {
+		return
+	}
+
+	if table.readOnly {
+		for i, statement := range statements {
+			if dialect.ReadsOnly(statement.Query) {
+				continue
+			}
+
+			if len(statements) > 1 {
+				table.SetError(fmt.Sprintf("statement %d: %s", i+1, readOnlyError), nil)
+			} else {
+				table.SetError(readOnlyError, nil)
+			}
+
+			return
+		}
+	}
+
+	// The script runs on the database selected in the tree
+	driver, err := drivers.ForDatabase(table.DBDriver, table.currentDatabase())
+	if err != nil {
+		table.SetError(err.Error(), nil)
+		return
+	}
+
+	// An open cursor may hold a connection or a lock the script needs
+	table.CloseQueryCursor()
+	table.SetLoading(true)
+	App.Draw()
+	ctx := table.Loading.Context()
+	maxQueryRows := helpers.LoadApplicationConfig().MaxQueryRows
+
+	results := []*queryResult{}
+	// historyErr is shown once the script has run without other errors
+	var historyErr error
+
+	for i, statement := range statements {
+		started := time.Now()
+		result, statementErr := table.runStatement(ctx, driver, statement, maxQueryRows, i == len(statements)-1)
+
+		if err := table.recordHistory(statement.Query, started, result, statementErr); err != nil && historyErr == nil {
+			historyErr = err
+		}
+
+		if ctx.Err() != nil {
+			if result != nil {
+				result.closeCursor()
+			}
+
+			break
+		}
+
+		if statementErr != nil {
+			err = statementErr
+			if len(statements) > 1 {
+				err = fmt.Errorf("statement %d: %w", i+1, statementErr)
+			}
+
+			break
+		}
+
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		table.SetLoading(false)
+
+		if err != nil {
+			App.Draw()
+			table.SetError(err.Error(), nil)
+		} else {
+			// Cancelled, the previous results stay
+			App.SetFocus(table.Editor)
+			App.Draw()
+		}
+
+		return
+	}
+
+	for _, statement := range statements[:len(results)] {
+		if dialect.ChangesSchema(statement.Query) {
+			table.metadata.Refresh()
+			break
+		}
+	}
+
+	table.setQueryResults(results, maxQueryRows)
+	table.SetLoading(false)
+
+	if err != nil {
+		App.Draw()
+		table.SetError(err.Error(), nil)
+		return
+	}
+
+	if records := results[len(results)-1].records; len(records) > 1 {
+		table.SetIsFiltering(false)
+		App.SetFocus(table)
+		table.HighlightTable()
+		table.Editor.SetBlur()
+		table.SetInputCapture(table.tableInputCapture)
+	} else {
+		table.SetInputCapture(nil)
+		App.SetFocus(table.Editor)
+		table.Editor.Highlight()
+		table.RemoveHighlightTable()
+		table.SetIsFiltering(true)
+	}
+
+	if historyErr != nil {
+		App.Draw()
+		table.SetError(historyErr.Error(), nil)
+		return
+	}
+
+	App.Draw()
+}
+
+// recordHistory adds a statement to the history of the connection. History is
+// best effort, a statement that can't be recorded is still run.
+func (table *ResultsTable) recordHistory(query string, started time.Time, result *queryResult, err error) error {
+	entry := models.HistoryEntry{
+		Connection: table.connection.Name,
+		Database:   table.currentDatabase(),
+		Query:      query,
+		Time:       started,
+		Duration:   time.Since(started),
+	}
+
+	if err != nil {
+		entry.Error = err.Error()
+	} else if result.records != nil {
+		entry.Rows = int64(result.rowCount())
+	} else {
+		entry.Rows = result.rowsAffected
+	}
+
+	err = helpers.AppendHistory(entry)
+	if err != nil {
+		return fmt.Errorf("the statement ran, but can't be kept in the history: %w", err)
+	}
+
+	return nil
+}
+
+// currentDatabase returns the database of the table, or else the database
+// selected in the tree, or the database of the connection when none is.
+func (table *ResultsTable) currentDatabase() string {
+	if table.database != "" {
+		return table.database
+	}
+
+	if table.Tree != nil && table.Tree.GetSelectedDatabase() != "" {
+		return table.Tree.GetSelectedDatabase()
+	}
+
+	return table.connection.DBName
+}
+
+// ShowHistory opens the history of the connection, the entry picked is loaded
+// into the editor.
+func (table *ResultsTable) ShowHistory() {
+	entries, err := helpers.LoadHistory(table.connection.Name)
+	if err != nil {
+		table.SetError(err.Error(), nil)
+		return
+	}
+
+	closeHistory := func() {
+		table.Page.HidePage("history")
+		App.SetFocus(table.Editor)
+	}
+
+	table.History.Show(entries, func(entry models.HistoryEntry) {
+		table.Editor.SetText(entry.Query, true)
+		closeHistory()
+	}, closeHistory)
+
+	table.Page.ShowPage("history")
+	App.SetFocus(table.History)
+	App.Draw()
+}
+
+// ShowExport opens the export of the results: the page shown or every row
+// matching the filter in a table tab, the result shown in the editor.
+func (table *ResultsTable) ShowExport() {
+	dialect, err := table.dialect()
+	if err != nil {
+		table.SetError(err.Error(), nil)
+		return
+	}
+
+	scopes := []ExportScope{PageScope, TableScope}
+	fileName, name := table.tableName, dialect.QuoteTableName(table.schema, table.tableName)
+
+	if table.Editor != nil {
+		scopes = []ExportScope{QueryResultScope}
+		fileName = "results"
+		name = dialect.QuoteIdentifier(fileName)
+	}
+
+	closeExport := func() {
+		table.Page.HidePage("export")
+		App.SetFocus(table)
+	}
+
+	table.Export.Show(scopes, fileName, name, func(options ExportOptions) {
+		go table.export(options)
+	}, closeExport)
+
+	table.Page.ShowPage("export")
+	App.SetFocus(table.Export)
+}
+
+// export writes the rows of the scope to the file, and shows how it went in
+// the export form. A file left incomplete by an error is removed.
+func (table *ResultsTable) export(options ExportOptions) {
+	table.Page.HidePage("export")
+	table.SetLoading(true)
+	ctx := table.Loading.Context()
+
+	rows, unread, err := table.writeExport(ctx, options)
+
+	table.SetLoading(false)
+	table.Page.ShowPage("export")
+	App.SetFocus(table.Export)
+
+	switch {
+	case ctx.Err() != nil:
+		os.Remove(options.Path)
+		table.Export.SetStatus("Export cancelled", app.Styles.Error)
+	case err != nil:
+		os.Remove(options.Path)
+		table.Export.SetStatus(err.Error(), app.Styles.Error)
+	case unread:
+		table.Export.SetStatus(fmt.Sprintf("Exported %d rows to %s, the rows not read yet were left out", rows, options.Path), app.Styles.Warning)
+	default:
+		table.Export.SetStatus(fmt.Sprintf("Exported %d rows to %s", rows, options.Path), app.Styles.Success)
+	}
+
+	App.Draw()
+}
+
+// writeExport writes the rows of the scope to the file and returns how many
+// were written. unread tells whether the editor result has rows that weren't
+// read from the database yet, and so weren't written.
+func (table *ResultsTable) writeExport(ctx context.Context, options ExportOptions) (rows int, unread bool, err error) {
+	dialect, err := table.dialect()
+	if err != nil {
+		return 0, false, err
+	}
+
+	var records [][]models.CellValue
+	var cursor drivers.Cursor
+
+	switch options.Scope {
+	case PageScope:
+		records = table.GetRecords()
+	case QueryResultScope:
+		table.state.cursorMutex.Lock()
+		result := table.state.queryResults[table.state.currentQueryResult]
+		records, unread = result.records, result.more
+		table.state.cursorMutex.Unlock()
+	case TableScope:
+		query := dialect.BuildSelect(dialect.QuoteTableName(table.schema, table.tableName), table.Filter.GetCurrentFilter(), table.GetCurrentSort())
+
+		cursor, err = table.DBDriver.ExecuteQuery(ctx, query)
+		if err != nil {
+			return 0, false, err
+		}
+
+		defer cursor.Close()
+
+		records = [][]models.CellValue{models.NewCellValues(cursor.Columns())}
+	}
+
+	file, err := os.Create(options.Path)
+	if err != nil {
+		return 0, false, err
+	}
+
+	defer file.Close()
+
+	writer, err := dialect.NewResultWriter(options.Format, file, models.CellValueStrings(records[0]), options.Table)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if cursor != nil {
+		rows, err = drivers.ExportCursor(writer, cursor, func(rows int) {
+			App.QueueUpdateDraw(func() {
+				table.Loading.SetMessage(fmt.Sprintf("Exported %d rows...", rows))
+			})
+		})
+	} else {
+		rows = len(records) - 1
+		err = writer.WriteRows(records[1:])
+	}
+
+	if err != nil {
+		return rows, false, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return rows, false, err
+	}
+
+	return rows, unread, file.Close()
+}
+
+// runStatement runs a statement of the editor script. Rows are read up to the
+// cap, except for the last statement of the script that keeps its cursor open
+// to read more rows as its results are scrolled.
+func (table *ResultsTable) runStatement(ctx context.Context, driver drivers.Driver, statement drivers.ScriptStatement, maxQueryRows int, last bool) (*queryResult, error) {
+	if !statement.ReturnsRows {
+		rowsAffected, err := driver.ExecuteDMLStatement(ctx, statement.Query)
+		if err != nil {
+			return nil, err
+		}
+
+		return &queryResult{rowsAffected: rowsAffected}, nil
+	}
+
+	cursor, err := driver.ExecuteQuery(ctx, statement.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &queryResult{records: [][]models.CellValue{models.NewCellValues(cursor.Columns())}, more: true}
+
+	for result.more && result.rowCount() < maxQueryRows {
+		var rows [][]models.CellValue
+
+		rows, result.more, err = cursor.Next(batchSize(result.rowCount(), maxQueryRows))
+		if err != nil {
+			cursor.Close()
+			return nil, err
+		}
+
+		result.records = append(result.records, rows...)
+
+		if last {
+			break
+		}
+	}
+
+	if last && result.more && result.rowCount() < maxQueryRows {
+		result.cursor = cursor
+	} else {
+		cursor.Close()
+	}
+
+	return result, nil
+}
+
+// batchSize returns how many rows to read next, without going over the cap.
+func batchSize(rowCount, maxQueryRows int) int {
+	if maxQueryRows-rowCount < defaultPageSize {
+		return maxQueryRows - rowCount
+	}
+
+	return defaultPageSize
+}
+
+// setQueryResults replaces the results of the previous editor script and
+// shows the last one.
+func (table *ResultsTable) setQueryResults(results []*queryResult, maxQueryRows int) {
+	table.state.cursorMutex.Lock()
+	table.state.queryResults = results
+	table.state.maxQueryRows = maxQueryRows
+	table.state.cursorMutex.Unlock()
+
+	if len(results) > 1 {
+		table.Wrapper.ResizeItem(table.QueryResultsMenu, 3, 0)
+	} else {
+		table.Wrapper.ResizeItem(table.QueryResultsMenu, 0, 0)
+	}
+
+	table.ShowQueryResult(len(results) - 1)
+}
+
+// ShowQueryResult shows the result of a statement of the last editor script.
+func (table *ResultsTable) ShowQueryResult(index int) {
+	table.state.cursorMutex.Lock()
+
+	if index < 0 || index >= len(table.state.queryResults) {
+		table.state.cursorMutex.Unlock()
+		return
+	}
+
+	table.state.currentQueryResult = index
+	result := table.state.queryResults[index]
+	records, more := result.records, result.more
+
+	menu := []string{}
+	for i, statementResult := range table.state.queryResults {
+		menu = append(menu, fmt.Sprintf(`["%d"][%d] %s[""]`, i, i+1, tview.Escape(statementResult.summary())))
+	}
+
+	table.state.cursorMutex.Unlock()
+
+	table.QueryResultsMenu.SetText(strings.Join(menu, " | "))
+	table.QueryResultsMenu.Highlight(fmt.Sprint(index))
+
+	if records == nil {
+		table.SetResultsInfo(result.summary())
+		table.EditorPages.SwitchToPage("ResultsInfo")
+	} else {
+		table.UpdateRows(records)
+		table.Pagination.SetStreamedRecords(len(records)-1, more)
+		table.EditorPages.SwitchToPage("Table")
+	}
+}
+
+// CloseQueryCursor stops reading the rows of the editor results.
+func (table *ResultsTable) CloseQueryCursor() {
+	table.state.cursorMutex.Lock()
+	defer table.state.cursorMutex.Unlock()
+
+	for _, result := range table.state.queryResults {
+		result.closeCursor()
+	}
+}
+
+// fetchMoreQueryRows reads the next batch of the editor result shown when the
+// given row is close to the last row read.
+func (table *ResultsTable) fetchMoreQueryRows(row int) {
+	state := table.state
+
+	state.cursorMutex.Lock()
+	defer state.cursorMutex.Unlock()
+
+	if state.isFetchingMore || state.currentQueryResult >= len(state.queryResults) {
+		return
+	}
+
+	result := state.queryResults[state.currentQueryResult]
+	cursor := result.cursor
+
+	if cursor == nil || row+fetchMoreRowsThreshold < result.rowCount() {
+		return
+	}
+
+	state.isFetchingMore = true
+	count := batchSize(result.rowCount(), state.maxQueryRows)
+
+	go func() {
+		rows, more, err := cursor.Next(count)
+
+		state.cursorMutex.Lock()
+
+		state.isFetchingMore = false
+
+		// The cursor was closed meanwhile
+		if result.cursor != cursor {
+			state.cursorMutex.Unlock()
+			return
+		}
+
+		index := len(result.records)
+		result.records = append(result.records, rows...)
+		result.more = more
+
+		if err != nil || !more || result.rowCount() >= state.maxQueryRows {
+			result.closeCursor()
+		}
+
+		if state.queryResults[state.currentQueryResult] == result {
+			table.addRowsAt(index, rows)
+			table.Pagination.SetStreamedRecords(result.rowCount(), more)
+		}
+
+		state.cursorMutex.Unlock()
+
+		if err != nil {
+			table.SetError(err.Error(), nil)
+		} else {
+			App.Draw()
+		}
+	}()
+}
+
+// Draw reads more rows of the editor query as the results are scrolled.
+func (table *ResultsTable) Draw(screen tcell.Screen) {
+	restoreColors := table.highlightVisualSelection()
+	table.Table.Draw(screen)
+	restoreColors()
+
+	if table.Editor != nil {
+		rowOffset, _ := table.GetOffset()
+		_, _, _, height := table.GetInnerRect()
+
+		table.fetchMoreQueryRows(rowOffset + height)
+	}
+}
+
 // Getters
 
-func (table *ResultsTable) GetRecords() [][]string {
+func (table *ResultsTable) GetRecords() [][]models.CellValue {
 	return table.state.records
 }
 
-func (table *ResultsTable) GetIndexes() [][]string {
+func (table *ResultsTable) GetIndexes() [][]models.CellValue {
 	return table.state.indexes
 }
 
-func (table *ResultsTable) GetColumns() [][]string {
+func (table *ResultsTable) GetColumns() [][]models.CellValue {
 	return table.state.columns
 }
 
-func (table *ResultsTable) GetConstraints() [][]string {
+func (table *ResultsTable) GetConstraints() [][]models.CellValue {
 	return table.state.constraints
 }
 
-func (table *ResultsTable) GetForeignKeys() [][]string {
+func (table *ResultsTable) GetForeignKeys() [][]models.CellValue {
 	return table.state.foreignKeys
 }
 
+func (table *ResultsTable) GetPrimaryKeyColumnNames() []string {
+	return table.state.primaryKeyColumnNames
+}
+
 func (table *ResultsTable) GetDBReference() string {
 	return table.state.dbReference
 }
@@ -717,7 +1623,7 @@ func (table *ResultsTable) GetColumnNameByIndex(index int) string {
 
 	for i, col := range columns {
 		if i > 0 && i == index+1 {
-			return col[0]
+			return col[0].Value
 		}
 	}
 
@@ -734,27 +1640,31 @@ func (table *ResultsTable) GetIsFiltering() bool {
 
 // Setters
 
-func (table *ResultsTable) SetRecords(rows [][]string) {
+func (table *ResultsTable) SetRecords(rows [][]models.CellValue) {
 	table.state.records = rows
 	table.UpdateRows(rows)
 }
 
-func (table *ResultsTable) SetColumns(columns [][]string) {
+func (table *ResultsTable) SetColumns(columns [][]models.CellValue) {
 	table.state.columns = columns
 }
 
-func (table *ResultsTable) SetConstraints(constraints [][]string) {
+func (table *ResultsTable) SetConstraints(constraints [][]models.CellValue) {
 	table.state.constraints = constraints
 }
 
-func (table *ResultsTable) SetForeignKeys(foreignKeys [][]string) {
+func (table *ResultsTable) SetForeignKeys(foreignKeys [][]models.CellValue) {
 	table.state.foreignKeys = foreignKeys
 }
 
-func (table *ResultsTable) SetIndexes(indexes [][]string) {
+func (table *ResultsTable) SetIndexes(indexes [][]models.CellValue) {
 	table.state.indexes = indexes
 }
 
+func (table *ResultsTable) SetPrimaryKeyColumnNames(primaryKeyColumnNames []string) {
+	table.state.primaryKeyColumnNames = primaryKeyColumnNames
+}
+
 func (table *ResultsTable) SetDBReference(dbReference string) {
 	table.state.dbReference = dbReference
 }
@@ -791,10 +1701,12 @@ func (table *ResultsTable) SetResultsInfo(text string) {
 func (table *ResultsTable) SetLoading(show bool) {
 	table.state.isLoading = show
 	if show {
+		table.Loading.Start()
 		table.Page.ShowPage("loading")
 		App.SetFocus(table.Loading)
 		App.ForceDraw()
 	} else {
+		table.Loading.Stop()
 		table.Page.HidePage("loading")
 		if table.state.error != "" {
 			App.SetFocus(table.Error)
@@ -826,13 +1738,18 @@ func (table *ResultsTable) SetSortedBy(column string, direction string) {
 			where = table.Filter.GetCurrentFilter()
 		}
 		table.SetLoading(true)
-		records, _, err := table.DBDriver.GetRecords(table.GetDBReference(), where, sort, table.Pagination.GetOffset(), table.Pagination.GetLimit())
+		ctx := table.Loading.Context()
+		records, _, err := table.DBDriver.GetRecords(ctx, table.GetDBReference(), where, sort, table.Pagination.GetOffset(), table.Pagination.GetLimit())
 		table.SetLoading(false)
 
+		if ctx.Err() != nil {
+			return
+		}
+
 		if err != nil {
 			table.SetError(err.Error(), nil)
 		} else {
-			table.SetRecords(records)
+			table.SetRecords(table.extractRowIDs(records))
 			App.ForceDraw()
 		}
 
@@ -847,13 +1764,13 @@ func (table *ResultsTable) SetSortedBy(column string, direction string) {
 
 		for i, col := range columns {
 			if i > 0 {
-				tableCell := tview.NewTableCell(col[0])
+				tableCell := tview.NewTableCell(col[0].Value)
 				tableCell.SetSelectable(false)
 				tableCell.SetExpansion(1)
 				tableCell.SetTextColor(tview.Styles.PrimaryTextColor)
 
-				if col[0] == column {
-					tableCell.SetText(fmt.Sprintf("%s %s", col[0], iconDirection))
+				if col[0].Value == column {
+					tableCell.SetText(fmt.Sprintf("%s %s", col[0].Value, iconDirection))
 					table.SetCell(0, i-1, tableCell)
 				} else {
 					table.SetCell(0, i-1, tableCell)
@@ -863,7 +1780,7 @@ func (table *ResultsTable) SetSortedBy(column string, direction string) {
 	}
 }
 
-func (table *ResultsTable) FetchRecords(onError func()) [][]string {
+func (table *ResultsTable) FetchRecords(onError func()) [][]models.CellValue {
 	tableName := table.GetDBReference()
 
 	table.SetLoading(true)
@@ -874,9 +1791,15 @@ func (table *ResultsTable) FetchRecords(onError func()) [][]string {
 	}
 	sort := table.GetCurrentSort()
 
-	records, totalRecords, err := table.DBDriver.GetRecords(tableName, where, sort, table.Pagination.GetOffset(), table.Pagination.GetLimit())
+	ctx := table.Loading.Context()
+	offset := table.Pagination.GetOffset()
 
-	if err != nil {
+	records, totalRecords, err := table.DBDriver.GetRecords(ctx, tableName, where, sort, offset, table.Pagination.GetLimit())
+
+	if ctx.Err() != nil {
+		table.restoreFetchedState()
+		table.SetLoading(false)
+	} else if err != nil {
 		table.SetError(err.Error(), onError)
 		table.SetLoading(false)
 	} else {
@@ -884,16 +1807,26 @@ func (table *ResultsTable) FetchRecords(onError func()) [][]string {
 			table.SetIsFiltering(false)
 		}
 
-		columns, _ := table.DBDriver.GetTableColumns(table.Tree.GetSelectedDatabase(), tableName)
-		constraints, _ := table.DBDriver.GetConstraints(tableName)
-		foreignKeys, _ := table.DBDriver.GetForeignKeys(tableName)
-		indexes, _ := table.DBDriver.GetIndexes(tableName)
+		columns, _ := table.DBDriver.GetTableColumns(ctx, table.currentDatabase(), tableName)
+		constraints, _ := table.DBDriver.GetConstraints(ctx, tableName)
+		foreignKeys, _ := table.DBDriver.GetForeignKeys(ctx, tableName)
+		indexes, _ := table.DBDriver.GetIndexes(ctx, tableName)
+		primaryKeyColumnNames, identityErr := table.DBDriver.GetPrimaryKeyColumnNames(ctx, table.currentDatabase(), tableName)
 
-		if len(records) > 0 {
-			table.SetRecords(records)
+		if ctx.Err() != nil {
+			table.restoreFetchedState()
+			table.SetLoading(false)
+
+			return [][]models.CellValue{}
 		}
 
 		table.SetColumns(columns)
+		table.SetPrimaryKeyColumnNames(primaryKeyColumnNames)
+
+		if len(records) > 0 {
+			table.SetRecords(table.extractRowIDs(records))
+		}
+
 		table.SetConstraints(constraints)
 		table.SetForeignKeys(foreignKeys)
 		table.SetIndexes(indexes)
@@ -901,42 +1834,66 @@ func (table *ResultsTable) FetchRecords(onError func()) [][]string {
 		table.Select(1, 0)
 
 		table.Pagination.SetTotalRecords(totalRecords)
+		table.state.fetchedOffset = offset
+		table.state.fetchedFilter = where
 
 		table.SetLoading(false)
 
+		// The rows are still shown, their editing stays disabled without an
+		// identity
+		if identityErr != nil {
+			table.SetError(identityErr.Error(), onError)
+		}
+
 		return records
 	}
 
-	return [][]string{}
+	return [][]models.CellValue{}
+}
+
+// restoreFetchedState puts back the page and filter of the records on display
+// after their replacement was cancelled.
+func (table *ResultsTable) restoreFetchedState() {
+	table.Pagination.SetOffset(table.state.fetchedOffset)
+
+	if table.Filter != nil {
+		table.Filter.SetCurrentFilter(table.state.fetchedFilter)
+	}
 }
 
-func (table *ResultsTable) StartEditingCell(row int, col int, callback func(newValue string, row, col int)) {
+func (table *ResultsTable) StartEditingCell(row int, col int, callback func(newValue models.CellValue, row, col int)) {
 	table.SetIsEditing(true)
 	table.SetInputCapture(nil)
 
 	cell := table.GetCell(row, col)
+	currentValue := getCellValue(cell)
+	newValue := currentValue
+
 	inputField := tview.NewInputField()
-	inputField.SetText(cell.Text)
-	inputField.SetFieldBackgroundColor(tview.Styles.PrimaryTextColor)
-	inputField.SetFieldTextColor(tcell.ColorBlack)
+	inputField.SetText(currentValue.Value)
+	inputField.SetFieldBackgroundColor(app.Styles.Field)
+	inputField.SetFieldTextColor(app.Styles.FieldText)
+
+	// A NULL or DEFAULT cell keeps its value until something is typed
+	inputField.SetChangedFunc(func(text string) {
+		newValue = models.CellValue{Type: models.String, Value: text}
+	})
 
-	inputField.SetDoneFunc(func(key tcell.Key) {
+	finishEditing := func(key tcell.Key) {
 		table.SetIsEditing(false)
-		currentValue := cell.Text
-		newValue := inputField.GetText()
 		if key == tcell.KeyEnter {
 			if currentValue != newValue {
 
-				cell.SetText(inputField.GetText())
+				setCellValue(cell, newValue)
 
-				table.AppendNewChange("UPDATE", table.GetDBReference(), row, col, newValue)
+				table.AppendNewChange("UPDATE", row, col, newValue)
 
 			}
 		} else if key == tcell.KeyTab {
 			nextEditableColumnIndex := col + 1
 
 			if nextEditableColumnIndex <= table.GetColumnCount()-1 {
-				cell.SetText(inputField.GetText())
+				setCellValue(cell, newValue)
 				table.Select(row, nextEditableColumnIndex)
 
 				table.StartEditingCell(row, nextEditableColumnIndex, callback)
@@ -946,7 +1903,7 @@ func (table *ResultsTable) StartEditingCell(row int, col int, callback func(newV
 			nextEditableColumnIndex := col - 1
 
 			if nextEditableColumnIndex >= 0 {
-				cell.SetText(inputField.GetText())
+				setCellValue(cell, newValue)
 				table.Select(row, nextEditableColumnIndex)
 
 				table.StartEditingCell(row, nextEditableColumnIndex, callback)
@@ -963,6 +1920,24 @@ func (table *ResultsTable) StartEditingCell(row int, col int, callback func(newV
 		if callback != nil {
 			callback(newValue, row, col)
 		}
+	}
+
+	inputField.SetDoneFunc(finishEditing)
+
+	inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		command := app.Keymaps.Group("table").Resolve(event)
+
+		if command == commands.SetValueNull {
+			newValue = models.CellValue{Type: models.Null}
+			finishEditing(tcell.KeyEnter)
+			return nil
+		} else if command == commands.SetValueDefault {
+			newValue = models.CellValue{Type: models.Default}
+			finishEditing(tcell.KeyEnter)
+			return nil
+		}
+
+		return event
 	})
 
 	x, y, width := cell.GetLastPosition()
@@ -971,6 +1946,61 @@ func (table *ResultsTable) StartEditingCell(row int, col int, callback func(newV
 	App.SetFocus(inputField)
 }
 
+// cellReference is the reference of the cells of the results
+type cellReference struct {
+	// valueType tells a NULL or DEFAULT cell from one holding that text
+	valueType models.CellValueType
+	// insertID is the pending insert of the row, on the first cell of a row
+	// that is inserted
+	insertID uuid.UUID
+}
+
+// setInsertID marks the first cell of a row as the row of a pending insert.
+func setInsertID(cell *tview.TableCell, insertID uuid.UUID) {
+	reference, _ := cell.GetReference().(cellReference)
+	reference.insertID = insertID
+	cell.SetReference(reference)
+}
+
+// getInsertID returns the pending insert of the row of a first cell, ok is
+// false when the row isn't inserted.
+func getInsertID(cell *tview.TableCell) (insertID uuid.UUID, ok bool) {
+	reference, _ := cell.GetReference().(cellReference)
+
+	return reference.insertID, reference.insertID != uuid.Nil
+}
+
+// setCellValue shows a cell value in a table cell.
+func setCellValue(cell *tview.TableCell, value models.CellValue) {
+	reference, _ := cell.GetReference().(cellReference)
+	reference.valueType = value.Type
+	cell.SetReference(reference)
+
+	switch value.Type {
+	case models.Null:
+		cell.SetText("NULL")
+		cell.SetAttributes(NullAttributes)
+	case models.Default:
+		cell.SetText("DEFAULT")
+		cell.SetAttributes(DefaultAttributes)
+	default:
+		cell.SetText(value.Value)
+		cell.SetAttributes(tcell.AttrNone)
+	}
+}
+
+// getCellValue reads back the value shown by setCellValue.
+func getCellValue(cell *tview.TableCell) models.CellValue {
+	reference, _ := cell.GetReference().(cellReference)
+
+	switch reference.valueType {
+	case models.Null, models.Default:
+		return models.CellValue{Type: reference.valueType}
+	default:
+		return models.CellValue{Type: models.String, Value: cell.Text}
+	}
+}
+
 func (table *ResultsTable) CheckIfRowIsInserted(rowId uuid.UUID) bool {
 	for _, insertedRow := range *table.state.listOfDbInserts {
 		if insertedRow.PrimaryKeyValue == rowId {
@@ -981,7 +2011,16 @@ func (table *ResultsTable) CheckIfRowIsInserted(rowId uuid.UUID) bool {
 	return false
 }
 
-func (table *ResultsTable) MutateInsertedRowCell(rowId uuid.UUID, colIndex int, newValue string) {
+func (table *ResultsTable) isInsertedRow(rowIndex int) bool {
+	insertID, ok := getInsertID(table.GetCell(rowIndex, 0))
+	if !ok {
+		return false
+	}
+
+	return table.CheckIfRowIsInserted(insertID)
+}
+
+func (table *ResultsTable) MutateInsertedRowCell(rowId uuid.UUID, colIndex int, newValue models.CellValue) {
 	for i, insertedRow := range *table.state.listOfDbInserts {
 		if insertedRow.PrimaryKeyValue == rowId {
 			(*table.state.listOfDbInserts)[i].Values[colIndex] = newValue
@@ -990,28 +2029,24 @@ func (table *ResultsTable) MutateInsertedRowCell(rowId uuid.UUID, colIndex int,
 }
 
 // TODO: encapsulate logic for different changeType
-func (table *ResultsTable) AppendNewChange(changeType string, tableName string, rowIndex int, colIndex int, value string) {
+func (table *ResultsTable) AppendNewChange(changeType string, rowIndex int, colIndex int, value models.CellValue) {
 	// check if there is already a change row in the listOfDbChanges variable
 	// if there is, update the value
 	// if there isn't, append a new change row
 	// if the value is the same as the original value, remove the change row
 
-	cellReference := table.GetCell(rowIndex, 0).GetReference()
-
-	isInsertedRow := false
+	if !table.isInsertedRow(rowIndex) {
+		primaryKeyInfo := table.GetPrimaryKeyValue(rowIndex)
 
-	if cellReference != nil {
-		isInsertedRow = table.CheckIfRowIsInserted(cellReference.(uuid.UUID))
-	}
-
-	if !isInsertedRow {
-		primaryKeyValue, primaryKeyColumnName := table.GetPrimaryKeyValue(rowIndex)
+		if primaryKeyInfo == nil {
+			return
+		}
 
 		alreadyExists := false
 		indexOfChange := -1
 
 		for i, change := range *table.state.listOfDbChanges {
-			if change.PrimaryKeyValue == primaryKeyValue && change.Column == table.GetColumnNameByIndex(colIndex) {
+			if change.Database == table.database && change.Schema == table.schema && change.Table == table.tableName && samePrimaryKey(change.PrimaryKeyInfo, primaryKeyInfo) && change.Column == table.GetColumnNameByIndex(colIndex) {
 				alreadyExists = true
 				indexOfChange = i
 			}
@@ -1033,34 +2068,35 @@ func (table *ResultsTable) AppendNewChange(changeType string, tableName string,
 					if len(*table.state.listOfDbChanges) == 0 && len(*table.state.listOfDbInserts) == 0 {
 						table.Tree.GetCurrentNode().SetColor(tview.Styles.InverseTextColor)
 					} else if len(*table.state.listOfDbChanges) == 0 && len(*table.state.listOfDbInserts) > 0 {
-						table.Tree.GetCurrentNode().SetColor(InsertColor)
+						table.Tree.GetCurrentNode().SetColor(app.Styles.PendingInsert)
 					} else if len(*table.state.listOfDbChanges) > 0 && len(*table.state.listOfDbInserts) == 0 {
-						table.Tree.GetCurrentNode().SetColor(ChangeColor)
+						table.Tree.GetCurrentNode().SetColor(app.Styles.PendingUpdate)
 					}
 
 				} else {
-					cell.SetBackgroundColor(tcell.ColorOrange.TrueColor())
-					cell.SetTextColor(tcell.ColorBlack.TrueColor())
-					table.Tree.GetCurrentNode().SetColor(ChangeColor)
+					cell.SetBackgroundColor(app.Styles.PendingUpdate)
+					cell.SetTextColor(tview.Styles.PrimaryTextColor)
+					table.Tree.GetCurrentNode().SetColor(app.Styles.PendingUpdate)
 
 					(*table.state.listOfDbChanges)[indexOfChange].Value = value
 				}
 			} else {
 				newChange := models.DbDmlChange{
-					Type:                 changeType,
-					Table:                tableName,
-					Column:               columnName,
-					Value:                value,
-					PrimaryKeyColumnName: primaryKeyColumnName,
-					PrimaryKeyValue:      primaryKeyValue,
-					Option:               1,
+					Type:           changeType,
+					Database:       table.database,
+					Schema:         table.schema,
+					Table:          table.tableName,
+					Column:         columnName,
+					Value:          value,
+					PrimaryKeyInfo: primaryKeyInfo,
+					Option:         1,
 				}
 
 				*table.state.listOfDbChanges = append(*table.state.listOfDbChanges, newChange)
 
-				cell.SetBackgroundColor(tcell.ColorOrange.TrueColor())
-				cell.SetTextColor(tcell.ColorBlack.TrueColor())
-				table.Tree.GetCurrentNode().SetColor(ChangeColor)
+				cell.SetBackgroundColor(app.Styles.PendingUpdate)
+				cell.SetTextColor(tview.Styles.PrimaryTextColor)
+				table.Tree.GetCurrentNode().SetColor(app.Styles.PendingUpdate)
 			}
 		case "DELETE":
 			if alreadyExists {
@@ -1070,9 +2106,9 @@ func (table *ResultsTable) AppendNewChange(changeType string, tableName string,
 				if len(*table.state.listOfDbChanges) == 0 && len(*table.state.listOfDbInserts) == 0 {
 					table.Tree.GetCurrentNode().SetColor(tview.Styles.InverseTextColor)
 				} else if len(*table.state.listOfDbChanges) == 0 && len(*table.state.listOfDbInserts) > 0 {
-					table.Tree.GetCurrentNode().SetColor(InsertColor)
+					table.Tree.GetCurrentNode().SetColor(app.Styles.PendingInsert)
 				} else if len(*table.state.listOfDbChanges) > 0 && len(*table.state.listOfDbInserts) == 0 {
-					table.Tree.GetCurrentNode().SetColor(ChangeColor)
+					table.Tree.GetCurrentNode().SetColor(app.Styles.PendingUpdate)
 				}
 
 				for i := 0; i < table.GetColumnCount(); i++ {
@@ -1082,124 +2118,133 @@ func (table *ResultsTable) AppendNewChange(changeType string, tableName string,
 			} else {
 
 				if table.Tree.GetCurrentNode().GetColor() == tview.Styles.InverseTextColor || table.Tree.GetCurrentNode().GetColor() == tview.Styles.PrimaryTextColor {
-					table.Tree.GetCurrentNode().SetColor(DeleteColor)
-				} else if table.Tree.GetCurrentNode().GetColor() == InsertColor {
-					table.Tree.GetCurrentNode().SetColor(ChangeColor)
+					table.Tree.GetCurrentNode().SetColor(app.Styles.PendingDelete)
+				} else if table.Tree.GetCurrentNode().GetColor() == app.Styles.PendingInsert {
+					table.Tree.GetCurrentNode().SetColor(app.Styles.PendingUpdate)
 				}
 
 				newChange := models.DbDmlChange{
-					Type:                 changeType,
-					Table:                tableName,
-					Column:               "",
-					Value:                "",
-					PrimaryKeyColumnName: primaryKeyColumnName,
-					PrimaryKeyValue:      primaryKeyValue,
-					Option:               1,
+					Type:           changeType,
+					Database:       table.database,
+					Schema:         table.schema,
+					Table:          table.tableName,
+					Column:         "",
+					Value:          models.CellValue{},
+					PrimaryKeyInfo: primaryKeyInfo,
+					Option:         1,
 				}
 
 				*table.state.listOfDbChanges = append(*table.state.listOfDbChanges, newChange)
 
 				for i := 0; i < table.GetColumnCount(); i++ {
-					table.GetCell(rowIndex, i).SetBackgroundColor(DeleteColor)
+					table.GetCell(rowIndex, i).SetBackgroundColor(app.Styles.PendingDelete)
 				}
 			}
 		}
 	}
 }
 
-func (table *ResultsTable) GetPrimaryKeyValue(rowIndex int) (string, string) {
-	provider := table.DBDriver.GetProvider()
-	columns := table.GetColumns()
-	constraints := table.GetConstraints()
-
-	primaryKeyColumnName := ""
-	primaryKeyValue := ""
+// GetPrimaryKeyValue returns the column/value pairs that identify the row. It
+// returns nil when there is no safe way to identify it.
+func (table *ResultsTable) GetPrimaryKeyValue(rowIndex int) []models.PrimaryKeyInfo {
+	records := table.GetRecords()
+	primaryKeyColumnNames := table.GetPrimaryKeyColumnNames()
 
-	switch provider {
-	case "mysql":
-		keyColumnIndex := -1
-		primaryKeyColumnIndex := -1
+	if len(primaryKeyColumnNames) == 0 || rowIndex <= 0 || rowIndex >= len(records) {
+		return nil
+	}
 
-		for i, col := range columns[0] {
-			if col == "Key" {
-				keyColumnIndex = i
-			}
-		}
+	rowIDColumnName := table.getRowIDColumnName()
+	primaryKeyInfo := make([]models.PrimaryKeyInfo, 0, len(primaryKeyColumnNames))
 
-		for i, col := range columns {
-			if col[keyColumnIndex] == "PRI" {
-				primaryKeyColumnIndex = i - 1
-				primaryKeyColumnName = col[0]
+	for _, primaryKeyColumnName := range primaryKeyColumnNames {
+		if primaryKeyColumnName == rowIDColumnName {
+			if rowIndex >= len(table.state.rowIDs) {
+				return nil
 			}
-		}
 
-		if primaryKeyColumnIndex != -1 {
-			primaryKeyValue = table.GetRecords()[rowIndex][primaryKeyColumnIndex]
+			primaryKeyInfo = append(primaryKeyInfo, models.PrimaryKeyInfo{Name: primaryKeyColumnName, Value: table.state.rowIDs[rowIndex]})
+			continue
 		}
 
-	case "postgres":
-		keyColumnIndex := -1
-		constraintTypeColumnIndex := -1
-		constraintNameColumnIndex := -1
-		pKeyName := ""
 		primaryKeyColumnIndex := -1
 
-		for i, constraint := range constraints[0] {
-			if constraint == "constraint_type" {
-				constraintTypeColumnIndex = i
-			}
-			if constraint == "column_name" {
-				constraintNameColumnIndex = i
-			}
-		}
-
-		for _, col := range constraints {
-			if col[constraintTypeColumnIndex] == "PRIMARY KEY" {
-				pKeyName = col[constraintNameColumnIndex]
+		for i, column := range records[0] {
+			if column.Value == primaryKeyColumnName {
+				primaryKeyColumnIndex = i
 				break
 			}
 		}
 
-		primaryKeyColumnName = pKeyName
-		for i, col := range columns[0] {
-			if col == "column_name" {
-				keyColumnIndex = i
-				break
-			}
+		if primaryKeyColumnIndex == -1 {
+			return nil
 		}
 
-		for i, col := range columns {
-			if col[keyColumnIndex] == pKeyName {
-				primaryKeyColumnIndex = i - 1
-				break
-			}
-		}
+		primaryKeyInfo = append(primaryKeyInfo, models.PrimaryKeyInfo{Name: primaryKeyColumnName, Value: records[rowIndex][primaryKeyColumnIndex].Value})
+	}
 
-		if primaryKeyColumnIndex != -1 {
-			primaryKeyValue = table.GetRecords()[rowIndex][primaryKeyColumnIndex]
-		}
+	return primaryKeyInfo
+}
 
-	case "sqlite3":
-		keyColumnIndex := -1
-		primaryKeyColumnIndex := -1
+// getRowIDColumnName returns the pseudo column, like ctid or rowid, that
+// identifies the rows of a table without a key. It is the only key column
+// and isn't one of the columns of the table.
+func (table *ResultsTable) getRowIDColumnName() string {
+	primaryKeyColumnNames := table.GetPrimaryKeyColumnNames()
 
-		for i, col := range columns[0] {
-			if col == "pk" {
-				keyColumnIndex = i
-			}
-		}
+	if len(primaryKeyColumnNames) != 1 {
+		return ""
+	}
 
-		for i, col := range columns {
-			if col[keyColumnIndex] == "1" {
-				primaryKeyColumnIndex = i - 1
-				primaryKeyColumnName = col[0]
-			}
+	for i, col := range table.GetColumns() {
+		if i > 0 && col[0].Value == primaryKeyColumnNames[0] {
+			return ""
 		}
+	}
+
+	return primaryKeyColumnNames[0]
+}
+
+// extractRowIDs removes the row id column that drivers append to the records
+// of tables without a key, and keeps its values aside to identify the rows.
+func (table *ResultsTable) extractRowIDs(records [][]models.CellValue) [][]models.CellValue {
+	table.state.rowIDs = nil
+
+	rowIDColumnName := table.getRowIDColumnName()
+
+	if rowIDColumnName == "" || len(records) == 0 || len(records[0]) == 0 {
+		return records
+	}
+
+	lastColumnIndex := len(records[0]) - 1
+
+	if records[0][lastColumnIndex].Value != rowIDColumnName {
+		return records
+	}
+
+	rowIDs := make([]string, 0, len(records))
+	recordsWithoutRowIDs := make([][]models.CellValue, 0, len(records))
+
+	for _, record := range records {
+		rowIDs = append(rowIDs, record[lastColumnIndex].Value)
+		recordsWithoutRowIDs = append(recordsWithoutRowIDs, record[:lastColumnIndex])
+	}
+
+	table.state.rowIDs = rowIDs
+
+	return recordsWithoutRowIDs
+}
+
+func samePrimaryKey(a, b []models.PrimaryKeyInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
 
-		if primaryKeyColumnIndex != -1 {
-			primaryKeyValue = table.GetRecords()[rowIndex][primaryKeyColumnIndex]
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
 	}
 
-	return primaryKeyValue, primaryKeyColumnName
+	return true
 }
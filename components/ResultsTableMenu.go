@@ -3,8 +3,9 @@ package components
 import (
 	"fmt"
 
-	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/app"
 )
 
 type ResultsTableMenuState struct {
@@ -89,7 +90,7 @@ func (menu *ResultsTableMenu) SetSelectedOption(option int) {
 }
 
 func (menu *ResultsTableMenu) SetBlur() {
-	menu.SetBorderColor(tcell.ColorDarkGray)
+	menu.SetBorderColor(tview.Styles.InverseTextColor)
 
 	for _, item := range menu.MenuItems {
 		item.SetTextColor(tview.Styles.InverseTextColor)
@@ -97,7 +98,7 @@ func (menu *ResultsTableMenu) SetBlur() {
 }
 
 func (menu *ResultsTableMenu) SetFocus() {
-	menu.SetBorderColor(tview.Styles.PrimaryTextColor)
+	menu.SetBorderColor(app.Styles.Focus)
 
 	for i, item := range menu.MenuItems {
 		if i+1 == menu.GetSelectedOption() {
@@ -0,0 +1,254 @@
+package components
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/google/uuid"
+	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/drivers"
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+func TestCellValue(t *testing.T) {
+	tests := []models.CellValue{
+		{Type: models.String, Value: "ada"},
+		{Type: models.String, Value: "NULL"},
+		{Type: models.String, Value: ""},
+		{Type: models.Null},
+		{Type: models.Default},
+	}
+
+	for _, value := range tests {
+		cell := tview.NewTableCell("")
+		setCellValue(cell, value)
+
+		// The value doesn't depend on how the cell is drawn
+		cell.SetAttributes(tcell.AttrBold)
+
+		if got := getCellValue(cell); got != value {
+			t.Errorf("cell set to %+v holds %+v", value, got)
+		}
+	}
+}
+
+func TestCellInsertID(t *testing.T) {
+	cell := tview.NewTableCell("")
+
+	if _, ok := getInsertID(cell); ok {
+		t.Fatal("a new cell belongs to an inserted row")
+	}
+
+	insertID := uuid.New()
+	setCellValue(cell, models.CellValue{Type: models.Default})
+	setInsertID(cell, insertID)
+	setCellValue(cell, models.CellValue{Type: models.String, Value: "ada"})
+
+	if got, ok := getInsertID(cell); !ok || got != insertID {
+		t.Errorf("insert id is %v, want %v", got, insertID)
+	}
+
+	if got := getCellValue(cell); got != (models.CellValue{Type: models.String, Value: "ada"}) {
+		t.Errorf("cell holds %+v, want ada", got)
+	}
+}
+
+func TestCopyCell(t *testing.T) {
+	var copied []string
+
+	defer func(copyFunc func(string) error) { copyToClipboard = copyFunc }(copyToClipboard)
+	copyToClipboard = func(text string) error {
+		copied = append(copied, text)
+		return nil
+	}
+
+	table := NewResultsTable(&[]models.DbDmlChange{}, &[]models.DbInsert{}, nil, nil)
+	table.SetRecords([][]models.CellValue{
+		models.NewCellValues([]string{"a", "b"}),
+		{{Type: models.Null}, {Type: models.String, Value: "NULL"}},
+	})
+
+	for column := 0; column < 2; column++ {
+		table.Select(1, column)
+		table.tableInputCapture(tcell.NewEventKey(tcell.KeyRune, 'y', tcell.ModNone))
+	}
+
+	want := []string{"", "NULL"}
+	if len(copied) != len(want) || copied[0] != want[0] || copied[1] != want[1] {
+		t.Errorf("copied %q, want %q", copied, want)
+	}
+
+	if got := getCellValue(table.GetCell(1, 1)); got.Type != models.String {
+		t.Errorf("the text NULL is read back as %+v", got)
+	}
+}
+
+func TestYank(t *testing.T) {
+	tests := []struct {
+		name      string
+		visual    visualMode
+		anchor    [2]int
+		selection [2]int
+		tableName string
+		format    drivers.CopyFormat
+		expected  string
+	}{
+		{
+			name:      "cells as INSERT statements",
+			visual:    visualCells,
+			anchor:    [2]int{3, 1},
+			selection: [2]int{2, 0},
+			tableName: "users",
+			format:    drivers.InsertCopy,
+			expected: `INSERT INTO "public"."users" ("id", "name") VALUES ('2', NULL);` + "\n" +
+				`INSERT INTO "public"."users" ("id", "name") VALUES ('3', NULL);` + "\n",
+		},
+		{
+			name:      "INSERT statements of a query",
+			visual:    visualCells,
+			anchor:    [2]int{1, 0},
+			selection: [2]int{1, 0},
+			format:    drivers.InsertCopy,
+			expected:  `INSERT INTO "results" ("id") VALUES ('1');` + "\n",
+		},
+		{
+			name:      "rows as TSV with headers",
+			visual:    visualRows,
+			anchor:    [2]int{1, 2},
+			selection: [2]int{2, 0},
+			format:    drivers.TSVWithHeadersCopy,
+			expected:  "id\tname\tnote\n1\tada\tx\n2\t\ty\n",
+		},
+		{
+			name:      "column as an IN list",
+			visual:    visualColumns,
+			anchor:    [2]int{2, 2},
+			selection: [2]int{2, 2},
+			format:    drivers.INListCopy,
+			expected:  "IN ('x', 'y', 'z')",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var copied []string
+
+			defer func(copyFunc func(string) error) { copyToClipboard = copyFunc }(copyToClipboard)
+			copyToClipboard = func(text string) error {
+				copied = append(copied, text)
+				return nil
+			}
+
+			table := NewResultsTable(&[]models.DbDmlChange{}, &[]models.DbInsert{}, nil, newFakeDriver())
+			table.schema, table.tableName = "public", test.tableName
+			table.SetRecords([][]models.CellValue{
+				models.NewCellValues([]string{"id", "name", "note"}),
+				models.NewCellValues([]string{"1", "ada", "x"}),
+				{{Value: "2"}, {Type: models.Null}, {Value: "y"}},
+				{{Value: "3"}, {Type: models.Default}, {Value: "z"}},
+			})
+
+			table.state.visual = test.visual
+			table.state.visualRow, table.state.visualColumn = test.anchor[0], test.anchor[1]
+			table.Select(test.selection[0], test.selection[1])
+
+			table.yank(test.format)
+
+			if len(copied) != 1 || copied[0] != test.expected {
+				t.Errorf("copied %q, want %q", copied, test.expected)
+			}
+
+			if table.state.visual != noVisual {
+				t.Error("the visual mode is still on after the copy")
+			}
+		})
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	tests := []struct {
+		rowCount     int
+		maxQueryRows int
+		expected     int
+	}{
+		{0, 10000, defaultPageSize},
+		{9000, 10000, defaultPageSize},
+		{9900, 10000, 100},
+		{0, 50, 50},
+		{50, 50, 0},
+	}
+
+	for _, test := range tests {
+		if size := batchSize(test.rowCount, test.maxQueryRows); size != test.expected {
+			t.Errorf("batchSize(%d, %d) = %d, want %d", test.rowCount, test.maxQueryRows, size, test.expected)
+		}
+	}
+}
+
+func TestFetchMoreQueryRows(t *testing.T) {
+	runApp(t)
+
+	cursor := &fakeCursor{count: 1000}
+	table := NewResultsTable(&[]models.DbDmlChange{}, &[]models.DbInsert{}, nil, nil)
+
+	rows, _, _ := cursor.Next(defaultPageSize)
+	result := &queryResult{records: append([][]models.CellValue{models.NewCellValues(cursor.Columns())}, rows...), more: true, cursor: cursor}
+	table.state.queryResults = []*queryResult{result}
+	table.state.maxQueryRows = 450
+	table.UpdateRows(result.records)
+
+	// fetched waits for the batch read in the background
+	fetched := func() int {
+		for {
+			table.state.cursorMutex.Lock()
+			fetching, count := table.state.isFetchingMore, result.rowCount()
+			table.state.cursorMutex.Unlock()
+
+			if !fetching {
+				return count
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Far from the last row read, nothing is read
+	table.fetchMoreQueryRows(10)
+
+	if count := fetched(); count != defaultPageSize {
+		t.Fatalf("%d rows after scrolling to row 10, want %d", count, defaultPageSize)
+	}
+
+	// Close to it, the next batch is read, up to the cap
+	table.fetchMoreQueryRows(defaultPageSize - fetchMoreRowsThreshold)
+
+	if count := fetched(); count != 450 {
+		t.Fatalf("%d rows after scrolling close to the end, want the cap, 450", count)
+	}
+
+	if !reflect.DeepEqual(cursor.batches, []int{defaultPageSize, 150}) {
+		t.Errorf("the batches read are %v, want [%d 150]", cursor.batches, defaultPageSize)
+	}
+
+	if !cursor.closed || result.cursor != nil {
+		t.Error("the cursor is still open once the cap is reached")
+	}
+
+	if rowCount := table.GetRowCount(); rowCount != 451 {
+		t.Errorf("the table shows %d rows, want the header and 450 rows", rowCount)
+	}
+
+	if text := table.GetCell(450, 0).Text; text != "450" {
+		t.Errorf("the last row shows %q, want 450", text)
+	}
+
+	// Nothing is left to read
+	table.fetchMoreQueryRows(450)
+
+	if count := fetched(); count != 450 || len(cursor.batches) != 2 {
+		t.Errorf("a closed cursor was read again, %d rows after %v", count, cursor.batches)
+	}
+}
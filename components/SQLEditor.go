@@ -1,15 +1,19 @@
 package components
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"github.com/rivo/uniseg"
 
 	"github.com/jorgerojas26/lazysql/app"
 	"github.com/jorgerojas26/lazysql/commands"
+	"github.com/jorgerojas26/lazysql/drivers"
 	"github.com/jorgerojas26/lazysql/models"
 )
 
@@ -20,26 +24,64 @@ type SQLEditorState struct {
 type SQLEditor struct {
 	*tview.TextArea
 	state       *SQLEditorState
+	dialect     drivers.Dialect
 	subscribers []chan models.StateChange
+	// completions lists the candidates for the word being typed, it is drawn
+	// over the text below the cursor
+	completions      *tview.List
+	completionItems  []drivers.Completion
+	completionStart  int
+	completionSource func() drivers.CompletionSource
+	// tokens are the tokens of highlightedText, kept between draws
+	tokens          []drivers.Token
+	highlightedText string
 }
 
+// maxCompletionRows is the height of the completion list when it has room
+const maxCompletionRows = 8
+
 func NewSQLEditor() *SQLEditor {
 	textarea := tview.NewTextArea()
 	textarea.SetBorder(true)
 	textarea.SetTitleAlign(tview.AlignLeft)
 	textarea.SetPlaceholder("Enter your SQL query here...")
+	// Long lines scroll instead of wrapping, so that every row of the editor
+	// is a line of the text and can be highlighted
+	textarea.SetWrap(false)
+
+	completions := tview.NewList()
+	completions.ShowSecondaryText(false)
+	completions.SetMainTextColor(tview.Styles.PrimaryTextColor)
+	completions.SetSelectedTextColor(app.Styles.SelectionText)
+	completions.SetSelectedBackgroundColor(app.Styles.Selection)
+	completions.SetBackgroundColor(app.Styles.ModalBackground)
+
 	sqlEditor := &SQLEditor{
 		TextArea: textarea,
 		state: &SQLEditorState{
 			isFocused: false,
 		},
+		completions: completions,
 	}
 	sqlEditor.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if sqlEditor.showingCompletions() && completionKeys[event.Key()] {
+			return event
+		}
+
 		command := app.Keymaps.Group("editor").Resolve(event)
 
 		if command == commands.Execute {
 			sqlEditor.Publish("Query", sqlEditor.GetText())
 			return nil
+		} else if command == commands.ExecuteStatement {
+			sqlEditor.Publish("Query", sqlEditor.GetCurrentStatement())
+			return nil
+		} else if command == commands.ShowHistory {
+			sqlEditor.Publish("History", "")
+			return nil
+		} else if command == commands.Help {
+			showHelp("editor")
+			return nil
 		} else if command == commands.Quit {
 			sqlEditor.Publish("Escape", "")
 		} else if command == commands.OpenInExternalEditor && runtime.GOOS == "linux" {
@@ -70,6 +112,304 @@ func (s *SQLEditor) Publish(key string, message string) {
 	}
 }
 
+// SetDialect sets the dialect the statements of the editor are split with
+func (s *SQLEditor) SetDialect(dialect drivers.Dialect) {
+	s.dialect = dialect
+}
+
+// GetCurrentStatement returns the selected text, or the statement under the
+// cursor when nothing is selected.
+func (s *SQLEditor) GetCurrentStatement() string {
+	selection, cursor, _ := s.GetSelection()
+	if selection != "" {
+		return selection
+	}
+
+	statement, ok := s.dialect.StatementAt(s.GetText(), cursor)
+	if !ok {
+		return ""
+	}
+
+	return statement.Query
+}
+
+// SetCompletionSource sets the function that returns where the schemas,
+// tables and columns are completed from. It can return nil.
+func (s *SQLEditor) SetCompletionSource(source func() drivers.CompletionSource) {
+	s.completionSource = source
+}
+
+// InputHandler gives the keys that pick a completion to the completion list
+// while it is shown, and updates the completions as the text is typed.
+func (s *SQLEditor) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if s.showingCompletions() && s.completionInputCapture(event) == nil {
+			return
+		}
+
+		s.TextArea.InputHandler()(event, setFocus)
+
+		switch event.Key() {
+		case tcell.KeyRune, tcell.KeyBackspace, tcell.KeyBackspace2:
+			s.updateCompletions()
+		default:
+			s.hideCompletions()
+		}
+	}
+}
+
+func (s *SQLEditor) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+	return func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+		if action == tview.MouseLeftDown {
+			s.hideCompletions()
+		}
+
+		return s.TextArea.MouseHandler()(action, event, setFocus)
+	}
+}
+
+// completionKeys are the keys the completion list takes while it's shown,
+// before the commands they're bound to in the editor
+var completionKeys = map[tcell.Key]bool{
+	tcell.KeyDown:   true,
+	tcell.KeyCtrlN:  true,
+	tcell.KeyUp:     true,
+	tcell.KeyCtrlP:  true,
+	tcell.KeyTab:    true,
+	tcell.KeyEscape: true,
+}
+
+func (s *SQLEditor) completionInputCapture(event *tcell.EventKey) *tcell.EventKey {
+	current := s.completions.GetCurrentItem()
+	count := s.completions.GetItemCount()
+
+	switch event.Key() {
+	case tcell.KeyDown, tcell.KeyCtrlN:
+		s.completions.SetCurrentItem((current + 1) % count)
+	case tcell.KeyUp, tcell.KeyCtrlP:
+		s.completions.SetCurrentItem((current - 1 + count) % count)
+	case tcell.KeyTab:
+		s.acceptCompletion(s.completionItems[current])
+	case tcell.KeyEscape:
+		s.hideCompletions()
+	default:
+		return event
+	}
+
+	return nil
+}
+
+// updateCompletions lists the completions of the word before the cursor. The
+// list is only shown once a word or a dot has been typed.
+func (s *SQLEditor) updateCompletions() {
+	_, start, end := s.GetSelection()
+	if start != end {
+		s.hideCompletions()
+		return
+	}
+
+	var source drivers.CompletionSource
+	if s.completionSource != nil {
+		source = s.completionSource()
+	}
+
+	text := s.GetText()
+	wordStart, items := s.dialect.Complete(text, end, source)
+	word := text[wordStart:end]
+
+	if word == "" && (end == 0 || text[end-1] != '.') {
+		s.hideCompletions()
+		return
+	}
+
+	// A word typed in full doesn't need its only completion
+	if len(items) == 0 || (len(items) == 1 && strings.EqualFold(items[0].Text, word)) {
+		s.hideCompletions()
+		return
+	}
+
+	width := 0
+	for _, item := range items {
+		if len(item.Text) > width {
+			width = len(item.Text)
+		}
+	}
+
+	s.completions.Clear()
+
+	for _, item := range items {
+		s.completions.AddItem(fmt.Sprintf("%s [::d]%s", tview.Escape(fmt.Sprintf("%-*s", width, item.Text)), item.Kind), "", 0, nil)
+	}
+
+	s.completionItems = items
+	s.completionStart = wordStart
+}
+
+// RefreshCompletions lists the completions again, e.g. once the columns they
+// were waiting for have been read.
+func (s *SQLEditor) RefreshCompletions() {
+	if s.showingCompletions() {
+		s.updateCompletions()
+	}
+}
+
+func (s *SQLEditor) acceptCompletion(completion drivers.Completion) {
+	_, _, end := s.GetSelection()
+	s.hideCompletions()
+	s.Replace(s.completionStart, end, completion.Text)
+}
+
+func (s *SQLEditor) showingCompletions() bool {
+	return len(s.completionItems) > 0
+}
+
+func (s *SQLEditor) hideCompletions() {
+	s.completionItems = nil
+	s.completions.Clear()
+}
+
+func (s *SQLEditor) Draw(screen tcell.Screen) {
+	s.TextArea.Draw(screen)
+	s.highlightSyntax(screen)
+
+	if !s.showingCompletions() {
+		return
+	}
+
+	x, y, width, height := s.GetInnerRect()
+	_, _, cursorRow, cursorColumn := s.GetCursor()
+	rowOffset, columnOffset := s.GetOffset()
+	_, start, _ := s.GetSelection()
+
+	textWidth, kindWidth := 0, 0
+	for _, item := range s.completionItems {
+		if len(item.Text) > textWidth {
+			textWidth = len(item.Text)
+		}
+
+		if len(item.Kind.String()) > kindWidth {
+			kindWidth = len(item.Kind.String())
+		}
+	}
+
+	listWidth := clamp(textWidth+1+kindWidth, 1, width)
+	listHeight := clamp(len(s.completionItems), 1, maxCompletionRows)
+
+	// Lined up with the start of the word being completed, below the cursor
+	// unless there is more room above it
+	listX := clamp(x+cursorColumn-columnOffset-(start-s.completionStart), x, x+width-listWidth)
+	cursorY := y + cursorRow - rowOffset
+	listY := cursorY + 1
+	roomBelow, roomAbove := y+height-listY, cursorY-y
+
+	if listHeight > roomBelow {
+		if roomAbove > roomBelow {
+			listHeight = clamp(listHeight, 0, roomAbove)
+			listY = cursorY - listHeight
+		} else {
+			listHeight = roomBelow
+		}
+	}
+
+	if listHeight <= 0 {
+		return
+	}
+
+	s.completions.SetRect(listX, listY, listWidth, listHeight)
+	s.completions.Draw(screen)
+}
+
+// highlightSyntax colors the text drawn by the text area after the tokens it
+// is made of. Selected text keeps the selection style.
+func (s *SQLEditor) highlightSyntax(screen tcell.Screen) {
+	text := s.GetText()
+	if text != s.highlightedText || s.tokens == nil {
+		s.tokens = s.dialect.Tokenize(text)
+		s.highlightedText = text
+	}
+
+	x, y, width, height := s.GetInnerRect()
+	rowOffset, columnOffset := s.GetOffset()
+	textStyle := s.GetTextStyle()
+
+	row, column, tokenIndex, state := 0, 0, 0, -1
+
+	for position := 0; position < len(text) && row < rowOffset+height; {
+		cluster, _, boundaries, newState := uniseg.StepString(text[position:], state)
+		state = newState
+
+		for tokenIndex < len(s.tokens)-1 && s.tokens[tokenIndex].End <= position {
+			tokenIndex++
+		}
+
+		position += len(cluster)
+
+		if cluster == "\n" || cluster == "\r\n" || cluster == "\r" {
+			row++
+			column = 0
+			continue
+		}
+
+		clusterWidth := boundaries >> uniseg.ShiftWidth
+		if cluster == "\t" {
+			clusterWidth = tview.TabSize
+		}
+
+		screenX, screenY := x+column-columnOffset, y+row-rowOffset
+		column += clusterWidth
+
+		if row < rowOffset || screenX < x || screenX+clusterWidth > x+width || clusterWidth == 0 {
+			continue
+		}
+
+		mainc, combc, style, _ := screen.GetContent(screenX, screenY)
+		if style != textStyle {
+			continue
+		}
+
+		screen.SetContent(screenX, screenY, mainc, combc, s.tokenStyle(s.tokens[tokenIndex], textStyle))
+	}
+}
+
+func (s *SQLEditor) tokenStyle(token drivers.Token, style tcell.Style) tcell.Style {
+	if token.Unterminated {
+		return style.Foreground(app.Styles.Syntax.ErrorColor).Underline(true)
+	}
+
+	switch token.Kind {
+	case drivers.WordToken:
+		if s.dialect.IsKeyword(token.Text) {
+			return style.Foreground(app.Styles.Syntax.KeywordColor).Bold(true)
+		}
+
+		return style.Foreground(app.Styles.Syntax.IdentifierColor)
+	case drivers.QuotedIdentifierToken:
+		return style.Foreground(app.Styles.Syntax.IdentifierColor)
+	case drivers.StringToken:
+		return style.Foreground(app.Styles.Syntax.StringColor)
+	case drivers.NumberToken:
+		return style.Foreground(app.Styles.Syntax.NumberColor)
+	case drivers.CommentToken:
+		return style.Foreground(app.Styles.Syntax.CommentColor).Italic(true)
+	case drivers.PlaceholderToken:
+		return style.Foreground(app.Styles.Syntax.PlaceholderColor)
+	}
+
+	return style
+}
+
+func clamp(value, low, high int) int {
+	if value > high {
+		value = high
+	}
+
+	if value < low {
+		value = low
+	}
+
+	return value
+}
+
 func (s *SQLEditor) GetIsFocused() bool {
 	return s.state.isFocused
 }
@@ -79,13 +419,13 @@ func (s *SQLEditor) SetIsFocused(isFocused bool) {
 }
 
 func (s *SQLEditor) Highlight() {
-	s.SetBorderColor(tview.Styles.PrimaryTextColor)
+	s.SetBorderColor(app.Styles.Focus)
 	s.SetTextStyle(tcell.StyleDefault.Foreground(tview.Styles.PrimaryTextColor))
 }
 
 func (s *SQLEditor) SetBlur() {
-	s.SetBorderColor(tcell.ColorWhite)
-	s.SetTextStyle(tcell.StyleDefault.Foreground(tcell.ColorWhite))
+	s.SetBorderColor(tview.Styles.BorderColor)
+	s.SetTextStyle(tcell.StyleDefault.Foreground(tview.Styles.PrimaryTextColor))
 }
 
 /*
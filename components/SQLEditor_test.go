@@ -0,0 +1,126 @@
+package components
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/app"
+	"github.com/jorgerojas26/lazysql/drivers"
+)
+
+func TestSQLEditorCompletionKeys(t *testing.T) {
+	editor := NewSQLEditor()
+	editor.SetDialect(drivers.PostgresDialect)
+	editor.SetText("CO", true)
+	editor.updateCompletions()
+
+	if !editor.showingCompletions() || editor.completions.GetItemCount() < 2 {
+		t.Fatal("CO has no completions to pick from")
+	}
+
+	ctrlP := tcell.NewEventKey(tcell.KeyCtrlP, 0, tcell.ModCtrl)
+
+	event := editor.GetInputCapture()(ctrlP)
+	if event == nil {
+		t.Fatal("Ctrl+P opens the history while the completions are shown")
+	}
+
+	editor.InputHandler()(event, func(tview.Primitive) {})
+
+	if current, last := editor.completions.GetCurrentItem(), editor.completions.GetItemCount()-1; current != last {
+		t.Errorf("selected completion is %d, want the last one, %d", current, last)
+	}
+
+	editor.hideCompletions()
+
+	if event := editor.GetInputCapture()(ctrlP); event != nil {
+		t.Error("Ctrl+P doesn't open the history once the completions are closed")
+	}
+}
+
+func TestSQLEditorHighlightSyntax(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	screen.SetSize(60, 4)
+
+	editor := NewSQLEditor()
+	editor.SetDialect(drivers.SQLiteDialect)
+	editor.SetText("SELECT name, 'a', 42, :id -- c\n'open", false)
+	editor.SetRect(0, 0, 60, 4)
+	editor.Draw(screen)
+
+	x, y, _, _ := editor.GetInnerRect()
+
+	tests := []struct {
+		name      string
+		column    int
+		row       int
+		color     tcell.Color
+		attribute tcell.AttrMask
+	}{
+		{"keyword", 0, 0, app.Styles.Syntax.KeywordColor, tcell.AttrBold},
+		{"identifier", 7, 0, app.Styles.Syntax.IdentifierColor, 0},
+		{"string", 13, 0, app.Styles.Syntax.StringColor, 0},
+		{"number", 18, 0, app.Styles.Syntax.NumberColor, 0},
+		{"placeholder", 22, 0, app.Styles.Syntax.PlaceholderColor, 0},
+		{"comment", 26, 0, app.Styles.Syntax.CommentColor, tcell.AttrItalic},
+		{"unterminated string", 0, 1, app.Styles.Syntax.ErrorColor, tcell.AttrUnderline},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mainc, _, style, _ := screen.GetContent(x+test.column, y+test.row)
+			foreground, _, attributes := style.Decompose()
+
+			if foreground != test.color || attributes&test.attribute != test.attribute {
+				t.Errorf("%q is drawn in %v with %v, want %v with %v", mainc, foreground, attributes, test.color, test.attribute)
+			}
+		})
+	}
+}
+
+func TestSQLEditorGetCurrentStatement(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	script := "SELECT 'é';\nSELECT 2; SELECT 3;\n\nUPDATE t SET a = ';'"
+
+	tests := []struct {
+		name  string
+		start int
+		end   int
+		want  string
+	}{
+		{"cursor in the first statement", 3, 3, "SELECT 'é'"},
+		{"cursor right after a delimiter", strings.Index(script, "2;") + 2, strings.Index(script, "2;") + 2, "SELECT 2"},
+		{"cursor on a blank line", strings.Index(script, "\n\n") + 1, strings.Index(script, "\n\n") + 1, "UPDATE t SET a = ';'"},
+		{"cursor in a string", len(script) - 2, len(script) - 2, "UPDATE t SET a = ';'"},
+		{"selection", strings.Index(script, "2;"), strings.Index(script, "3;") + 1, "2; SELECT 3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			editor := NewSQLEditor()
+			editor.SetDialect(drivers.PostgresDialect)
+			editor.SetText(script, false)
+			// The positions are found in the lines laid out when the editor is drawn
+			editor.SetRect(0, 0, 80, 10)
+			editor.Draw(screen)
+			editor.Select(test.start, test.end)
+
+			if statement := editor.GetCurrentStatement(); statement != test.want {
+				t.Errorf("GetCurrentStatement() = %q, want %q", statement, test.want)
+			}
+		})
+	}
+}
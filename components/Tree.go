@@ -1,11 +1,12 @@
 package components
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/jorgerojas26/lazysql/app"
 	"github.com/jorgerojas26/lazysql/commands"
 	"github.com/jorgerojas26/lazysql/drivers"
+	"github.com/jorgerojas26/lazysql/keymap"
 	"github.com/jorgerojas26/lazysql/models"
 
 	"github.com/gdamore/tcell/v2"
@@ -17,6 +18,24 @@ type TreeState struct {
 	selectedTable    string
 }
 
+// TableReference is a table of the tree along with its database, and its
+// schema in the databases that have schemas
+type TableReference struct {
+	Database string
+	Schema   string
+	Table    string
+}
+
+// Name returns the name the drivers read the table by, schema.table in the
+// databases that have schemas
+func (reference TableReference) Name() string {
+	if reference.Schema == "" {
+		return reference.Table
+	}
+
+	return reference.Schema + "." + reference.Table
+}
+
 type Tree struct {
 	*tview.TreeView
 	state       *TreeState
@@ -52,7 +71,7 @@ func NewTree(dbName string, dbdriver drivers.Driver) *Tree {
 		var databases []string
 
 		if dbName == "" {
-			dbs, err := tree.DBDriver.GetDatabases()
+			dbs, err := tree.DBDriver.GetDatabases(context.Background())
 			if err != nil {
 				panic(err.Error())
 			}
@@ -81,7 +100,7 @@ func NewTree(dbName string, dbdriver drivers.Driver) *Tree {
 				tree.SetSelectedDatabase(node.GetText())
 
 				if node.GetChildren() == nil {
-					tables, err := tree.DBDriver.GetTables(tree.GetSelectedDatabase())
+					tables, err := tree.DBDriver.GetTables(context.Background(), tree.GetSelectedDatabase())
 					if err != nil {
 						// TODO: Handle error
 						return
@@ -92,26 +111,12 @@ func NewTree(dbName string, dbdriver drivers.Driver) *Tree {
 				node.SetExpanded(true)
 
 			}
+		} else if table, ok := tree.tableOf(node); ok {
+			// The database expanded last isn't always the one of the table
+			tree.SetSelectedDatabase(table.Database)
+			tree.SetSelectedTable(table)
 		} else if node.GetLevel() == 2 {
-			if node.GetChildren() == nil {
-				tableName := fmt.Sprintf("%s.%s", node.GetReference(), node.GetText())
-
-				if tree.DBDriver.GetProvider() == "sqlite3" {
-					tableName = node.GetText()
-				}
-
-				tree.SetSelectedTable(tableName)
-			} else {
-				node.SetExpanded(!node.IsExpanded())
-			}
-		} else if node.GetLevel() == 3 {
-			tableName := fmt.Sprintf("%s.%s", node.GetReference(), node.GetText())
-
-			if tree.DBDriver.GetProvider() == "sqlite3" {
-				tableName = node.GetText()
-			}
-
-			tree.SetSelectedTable(tableName)
+			node.SetExpanded(!node.IsExpanded())
 		}
 	})
 
@@ -133,9 +138,16 @@ func NewTree(dbName string, dbdriver drivers.Driver) *Tree {
 		case commands.GotoTop:
 			tree.SetCurrentNode(rootNode)
 		case commands.MoveDown:
-			tree.Move(1)
+			tree.Move(keymap.Count(event))
 		case commands.MoveUp:
-			tree.Move(-1)
+			tree.Move(-keymap.Count(event))
+		case commands.Import:
+			if table, ok := tree.tableOf(tree.GetCurrentNode()); ok {
+				tree.Publish(models.StateChange{
+					Key:   "ImportTable",
+					Value: table,
+				})
+			}
 		case commands.Execute:
 			// Can't "select" the current node via TreeView api.
 			// So fake it by sending it a Enter key event
@@ -175,6 +187,27 @@ func (tree *Tree) updateNodes(children map[string][]string, node *tview.TreeNode
 	}
 }
 
+// tableOf returns the table a node stands for. Tables are under their
+// database, or under their schema in the databases that have schemas.
+func (tree *Tree) tableOf(node *tview.TreeNode) (TableReference, bool) {
+	path := tree.GetPath(node)
+
+	if len(path) < 3 || (len(path) == 3 && node.GetChildren() != nil) {
+		return TableReference{}, false
+	}
+
+	table := TableReference{
+		Database: path[1].GetText(),
+		Table:    node.GetText(),
+	}
+
+	if !drivers.CapabilitiesOf(tree.DBDriver.GetProvider()).PlainTableNames {
+		table.Schema = node.GetReference().(string)
+	}
+
+	return table, true
+}
+
 // Subscribe to changes in the tree state
 func (tree *Tree) Subscribe() chan models.StateChange {
 	subscriber := make(chan models.StateChange)
@@ -206,8 +239,8 @@ func (tree *Tree) SetSelectedDatabase(database string) {
 	})
 }
 
-func (tree *Tree) SetSelectedTable(table string) {
-	tree.state.selectedTable = table
+func (tree *Tree) SetSelectedTable(table TableReference) {
+	tree.state.selectedTable = table.Name()
 	tree.Publish(models.StateChange{
 		Key:   "SelectedTable",
 		Value: table,
@@ -267,7 +300,7 @@ func (tree *Tree) ForceRemoveHighlight() {
 
 // Focus func
 func (tree *Tree) Highlight() {
-	tree.SetBorderColor(tview.Styles.PrimaryTextColor)
+	tree.SetBorderColor(app.Styles.Focus)
 	tree.SetGraphicsColor(tview.Styles.SecondaryTextColor)
 	tree.SetTitleColor(tview.Styles.PrimaryTextColor)
 	tree.GetRoot().SetColor(tview.Styles.PrimaryTextColor)
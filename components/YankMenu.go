@@ -0,0 +1,66 @@
+package components
+
+import (
+	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/app"
+	"github.com/jorgerojas26/lazysql/drivers"
+)
+
+// yankShortcuts are the keys that pick a format in the menu, in the order of
+// drivers.CopyFormats
+var yankShortcuts = []rune{'t', 'T', 'c', 'j', 'i', 's'}
+
+// YankMenu lists the formats the visual selection of the results can be
+// copied in.
+type YankMenu struct {
+	*tview.Flex
+	List     *tview.List
+	onSelect func(format drivers.CopyFormat)
+	onClose  func()
+}
+
+func NewYankMenu() *YankMenu {
+	list := tview.NewList()
+	list.ShowSecondaryText(false)
+	list.SetBorder(true)
+	list.SetTitle(" Copy as (Esc to cancel) ")
+	list.SetBackgroundColor(app.Styles.ModalBackground)
+	list.SetMainTextColor(tview.Styles.PrimaryTextColor)
+	list.SetShortcutColor(tview.Styles.SecondaryTextColor)
+	list.SetSelectedTextColor(app.Styles.SelectionText)
+	list.SetSelectedBackgroundColor(app.Styles.Selection)
+
+	// Centers the list over the results
+	menu := &YankMenu{
+		Flex: tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(list, len(drivers.CopyFormats)+2, 0, true).
+				AddItem(nil, 0, 1, false), 30, 0, true).
+			AddItem(nil, 0, 1, false),
+		List: list,
+	}
+
+	for i, format := range drivers.CopyFormats {
+		format := format
+
+		list.AddItem(string(format), "", yankShortcuts[i], func() {
+			menu.onSelect(format)
+		})
+	}
+
+	list.SetDoneFunc(func() {
+		menu.onClose()
+	})
+
+	return menu
+}
+
+// Show resets the menu, onSelect is called with the format picked
+func (menu *YankMenu) Show(onSelect func(format drivers.CopyFormat), onClose func()) {
+	menu.onSelect = onSelect
+	menu.onClose = onClose
+	menu.List.SetCurrentItem(0)
+}
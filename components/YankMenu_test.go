@@ -0,0 +1,43 @@
+package components
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/jorgerojas26/lazysql/drivers"
+)
+
+func TestYankMenu(t *testing.T) {
+	menu := NewYankMenu()
+
+	var selected []drivers.CopyFormat
+	closed := false
+
+	menu.Show(func(format drivers.CopyFormat) {
+		selected = append(selected, format)
+	}, func() {
+		closed = true
+	})
+
+	for _, shortcut := range yankShortcuts {
+		menu.List.InputHandler()(tcell.NewEventKey(tcell.KeyRune, shortcut, tcell.ModNone), func(tview.Primitive) {})
+	}
+
+	if len(selected) != len(drivers.CopyFormats) {
+		t.Fatalf("the shortcuts picked %q, want %q", selected, drivers.CopyFormats)
+	}
+
+	for i, format := range drivers.CopyFormats {
+		if selected[i] != format {
+			t.Errorf("%c picks %s, want %s", yankShortcuts[i], selected[i], format)
+		}
+	}
+
+	menu.List.InputHandler()(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone), func(tview.Primitive) {})
+
+	if !closed {
+		t.Error("Esc doesn't close the menu")
+	}
+}
@@ -0,0 +1,128 @@
+package components
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+
+	"github.com/jorgerojas26/lazysql/drivers"
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// fakeDriver is a driver whose connections are bound to their database, like
+// the Postgres ones. It records the changes saved through it, by database.
+// The methods the tests don't need panic.
+type fakeDriver struct {
+	drivers.Driver
+	database string
+	saves    *fakeSaves
+}
+
+type fakeSaves struct {
+	mutex sync.Mutex
+	// changes are the changes saved in each database
+	changes map[string][]models.DbDmlChange
+	// errors are returned by the saves in their database
+	errors map[string]error
+	// started receives the database of every save, when it isn't nil
+	started chan string
+	// block makes the saves wait for their context to be done
+	block bool
+}
+
+func newFakeDriver() *fakeDriver {
+	return &fakeDriver{saves: &fakeSaves{changes: map[string][]models.DbDmlChange{}, errors: map[string]error{}}}
+}
+
+func (driver *fakeDriver) GetProvider() string {
+	return "postgres"
+}
+
+func (driver *fakeDriver) ForDatabase(database string) (drivers.Driver, error) {
+	return &fakeDriver{database: database, saves: driver.saves}, nil
+}
+
+func (driver *fakeDriver) ExecutePendingChanges(ctx context.Context, changes []models.DbDmlChange, _ []models.DbInsert) error {
+	if driver.saves.started != nil {
+		driver.saves.started <- driver.database
+	}
+
+	if driver.saves.block {
+		<-ctx.Done()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	driver.saves.mutex.Lock()
+	defer driver.saves.mutex.Unlock()
+
+	if err := driver.saves.errors[driver.database]; err != nil {
+		return err
+	}
+
+	driver.saves.changes[driver.database] = append(driver.saves.changes[driver.database], changes...)
+
+	return nil
+}
+
+// fakeCursor returns the rows 1 to count, and records the batches asked
+type fakeCursor struct {
+	mutex   sync.Mutex
+	next    int
+	count   int
+	batches []int
+	closed  bool
+}
+
+func (cursor *fakeCursor) Columns() []string {
+	return []string{"n"}
+}
+
+func (cursor *fakeCursor) Next(count int) (rows [][]models.CellValue, more bool, err error) {
+	cursor.mutex.Lock()
+	defer cursor.mutex.Unlock()
+
+	cursor.batches = append(cursor.batches, count)
+
+	for len(rows) < count && cursor.next < cursor.count {
+		cursor.next++
+		rows = append(rows, models.NewCellValues([]string{strconv.Itoa(cursor.next)}))
+	}
+
+	return rows, cursor.next < cursor.count, nil
+}
+
+func (cursor *fakeCursor) Close() error {
+	cursor.mutex.Lock()
+	defer cursor.mutex.Unlock()
+
+	cursor.closed = true
+
+	return nil
+}
+
+// runApp runs the event loop of the application on a simulated screen, for
+// the code that waits on it, like App.Draw
+func runApp(t *testing.T) {
+	t.Helper()
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	App.SetScreen(screen)
+
+	done := make(chan struct{})
+
+	go func() {
+		_ = App.Run()
+		close(done)
+	}()
+
+	t.Cleanup(func() {
+		App.Stop()
+		<-done
+	})
+}
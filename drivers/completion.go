@@ -0,0 +1,490 @@
+package drivers
+
+import (
+	"sort"
+	"strings"
+)
+
+// CompletionKind is what a completion of the editor stands for.
+type CompletionKind int8
+
+const (
+	KeywordCompletion CompletionKind = iota
+	FunctionCompletion
+	SchemaCompletion
+	TableCompletion
+	ColumnCompletion
+)
+
+func (kind CompletionKind) String() string {
+	switch kind {
+	case FunctionCompletion:
+		return "function"
+	case SchemaCompletion:
+		return "schema"
+	case TableCompletion:
+		return "table"
+	case ColumnCompletion:
+		return "column"
+	}
+
+	return "keyword"
+}
+
+// Completion is a candidate for the word being typed.
+type Completion struct {
+	// Text replaces the word being typed, quoted when it has to be
+	Text string
+	Kind CompletionKind
+}
+
+// CompletionSource tells which schemas, tables and columns can be completed.
+type CompletionSource interface {
+	Schemas() []string
+	// Tables returns the tables of a schema, or of every schema when it is empty
+	Tables(schema string) []string
+	// Columns returns the columns of a table, named with or without its schema
+	Columns(table string) []string
+}
+
+var (
+	commonKeywords = []string{
+		"ADD", "ALL", "ALTER", "AND", "AS", "ASC", "BEGIN", "BETWEEN", "BY", "CASE", "CHECK", "COLUMN", "COMMIT",
+		"CONSTRAINT", "CREATE", "CROSS", "DEFAULT", "DELETE", "DESC", "DISTINCT", "DROP", "ELSE", "END", "EXCEPT",
+		"EXISTS", "EXPLAIN", "FALSE", "FOREIGN", "FROM", "FULL", "GROUP", "HAVING", "IN", "INDEX", "INNER", "INSERT",
+		"INTERSECT", "INTO", "IS", "JOIN", "KEY", "LEFT", "LIKE", "NOT", "NULL", "ON", "OR", "ORDER", "OUTER",
+		"PRIMARY", "REFERENCES", "RIGHT", "ROLLBACK", "SELECT", "SET", "TABLE", "THEN", "TRUE", "TRUNCATE", "UNION",
+		"UNIQUE", "UPDATE", "USING", "VALUES", "VIEW", "WHEN", "WHERE", "WITH",
+	}
+	commonFunctions = []string{
+		"ABS", "AVG", "CAST", "COALESCE", "COUNT", "DENSE_RANK", "FLOOR", "LAG", "LEAD", "LOWER", "MAX", "MIN",
+		"NULLIF", "RANK", "REPLACE", "ROUND", "ROW_NUMBER", "SUBSTRING", "SUM", "TRIM", "UPPER",
+	}
+
+	mysqlKeywords = []string{
+		"AUTO_INCREMENT", "DATABASE", "DESCRIBE", "DUPLICATE", "ENGINE", "IGNORE", "LIMIT", "OFFSET", "SHOW", "TABLES",
+	}
+	mysqlFunctions = []string{
+		"CONCAT", "DATE_ADD", "DATE_FORMAT", "DATEDIFF", "GROUP_CONCAT", "IFNULL", "JSON_EXTRACT", "LENGTH", "NOW",
+		"UNIX_TIMESTAMP",
+	}
+	postgresKeywords = []string{
+		"ILIKE", "LIMIT", "OFFSET", "RETURNING", "SCHEMA", "SERIAL",
+	}
+	postgresFunctions = []string{
+		"ARRAY_AGG", "CONCAT", "DATE_TRUNC", "GENERATE_SERIES", "JSONB_BUILD_OBJECT", "LENGTH", "NOW", "STRING_AGG",
+		"TO_CHAR",
+	}
+	sqliteKeywords = []string{
+		"AUTOINCREMENT", "GLOB", "LIMIT", "OFFSET", "PRAGMA", "RETURNING", "VACUUM",
+	}
+	sqliteFunctions = []string{
+		"DATETIME", "GROUP_CONCAT", "IFNULL", "JSON_EXTRACT", "LENGTH", "STRFTIME",
+	}
+	mssqlKeywords = []string{
+		"EXEC", "FETCH", "IDENTITY", "NOLOCK", "OFFSET", "OUTPUT", "ROWS", "TOP",
+	}
+	mssqlFunctions = []string{
+		"CONVERT", "DATEADD", "DATEDIFF", "FORMAT", "GETDATE", "ISNULL", "LEN", "STRING_AGG",
+	}
+)
+
+// tableClauseKeywords are followed by table names
+var tableClauseKeywords = map[string]bool{
+	"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true, "TABLE": true,
+}
+
+// columnClauseKeywords are followed by expressions over the columns
+var columnClauseKeywords = map[string]bool{
+	"SELECT": true, "WHERE": true, "ON": true, "SET": true, "BY": true, "HAVING": true, "AND": true, "OR": true,
+	"NOT": true, "CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "RETURNING": true, "DISTINCT": true,
+}
+
+// Complete returns the completions of the word being typed at offset, along
+// with the offset that word starts at. Columns are completed after the alias or
+// the name of a table of the statement followed by a dot, tables after FROM,
+// JOIN... or after the name of a schema followed by a dot. Only keywords and
+// functions are completed when source is nil.
+func (d Dialect) Complete(script string, offset int, source CompletionSource) (start int, completions []Completion) {
+	tokens := d.Tokenize(script[:offset])
+	start = offset
+
+	if len(tokens) > 0 {
+		last := tokens[len(tokens)-1]
+
+		switch last.Kind {
+		case WordToken:
+			start = last.Start
+			tokens = tokens[:len(tokens)-1]
+		case CommentToken, StringToken, QuotedIdentifierToken, NumberToken, PlaceholderToken:
+			// Nothing is completed inside them
+			return start, nil
+		}
+	}
+
+	prefix := script[start:offset]
+	statement := d.significantTokens(d.currentStatement(script, offset))
+	before := d.significantTokens(tokens)
+	completer := &completer{dialect: d, prefix: prefix}
+
+	if qualifier := d.qualifier(before, start); len(qualifier) > 0 {
+		if source == nil {
+			return start, nil
+		}
+
+		table := d.resolveTable(qualifier, d.tableReferences(statement))
+		completer.addIdentifiers(source.Columns(table), ColumnCompletion)
+
+		if len(qualifier) == 1 {
+			completer.addIdentifiers(source.Tables(qualifier[0]), TableCompletion)
+		}
+
+		return start, completer.completions
+	}
+
+	if len(before) > 0 && d.endsExpression(before[len(before)-1]) {
+		// After a name or a value comes an alias or a keyword
+		completer.addKeywords(d.keywords(), KeywordCompletion)
+
+		return start, completer.completions
+	}
+
+	switch d.clause(before) {
+	case "table":
+		if source != nil {
+			completer.addIdentifiers(source.Tables(""), TableCompletion)
+			completer.addIdentifiers(source.Schemas(), SchemaCompletion)
+		}
+	case "column":
+		if source != nil {
+			for _, reference := range d.tableReferences(statement) {
+				completer.addIdentifiers(source.Columns(reference.name), ColumnCompletion)
+			}
+		}
+
+		completer.addKeywords(d.functions(), FunctionCompletion)
+	}
+
+	completer.addKeywords(d.keywords(), KeywordCompletion)
+
+	return start, completer.completions
+}
+
+func (d Dialect) keywords() []string {
+	return sortedUnion(commonKeywords, d.extraKeywords)
+}
+
+func (d Dialect) functions() []string {
+	return sortedUnion(commonFunctions, d.extraFunctions)
+}
+
+func sortedUnion(common, extra []string) []string {
+	words := append(append([]string{}, common...), extra...)
+	sort.Strings(words)
+
+	return words
+}
+
+// IsKeyword tells whether a word is a keyword of the dialect, which can't be
+// an alias nor an unquoted name.
+func (d Dialect) IsKeyword(word string) bool {
+	upper := strings.ToUpper(word)
+
+	for _, keywords := range [][]string{commonKeywords, d.extraKeywords} {
+		for _, keyword := range keywords {
+			if keyword == upper {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type completer struct {
+	dialect     Dialect
+	prefix      string
+	completions []Completion
+	seen        map[string]bool
+}
+
+func (c *completer) matches(text string) bool {
+	return strings.HasPrefix(strings.ToLower(text), strings.ToLower(c.prefix))
+}
+
+func (c *completer) add(text string, kind CompletionKind) {
+	if c.seen == nil {
+		c.seen = make(map[string]bool)
+	}
+
+	if c.seen[text] {
+		return
+	}
+
+	c.seen[text] = true
+	c.completions = append(c.completions, Completion{Text: text, Kind: kind})
+}
+
+// addKeywords adds keywords, or functions, in the case of the typed word
+func (c *completer) addKeywords(keywords []string, kind CompletionKind) {
+	lower := c.prefix != "" && c.prefix == strings.ToLower(c.prefix)
+
+	for _, keyword := range keywords {
+		if !c.matches(keyword) {
+			continue
+		}
+
+		if lower {
+			keyword = strings.ToLower(keyword)
+		}
+
+		c.add(keyword, kind)
+	}
+}
+
+func (c *completer) addIdentifiers(identifiers []string, kind CompletionKind) {
+	for _, identifier := range identifiers {
+		if c.matches(identifier) {
+			c.add(c.dialect.quoteIfNeeded(identifier), kind)
+		}
+	}
+}
+
+// quoteIfNeeded quotes an identifier that wouldn't be read back as is
+// without its quotes.
+func (d Dialect) quoteIfNeeded(identifier string) string {
+	if identifier == "" || isDigit(identifier[0]) || d.IsKeyword(identifier) {
+		return d.QuoteIdentifier(identifier)
+	}
+
+	for i := 0; i < len(identifier); i++ {
+		char := identifier[i]
+
+		if !isWordChar(char) || (d.lowerCaseIdentifiers && char >= 'A' && char <= 'Z') {
+			return d.QuoteIdentifier(identifier)
+		}
+	}
+
+	return identifier
+}
+
+// currentStatement returns the tokens of the statement around offset. The
+// statements are told apart by their semicolons only, which is enough to
+// find the tables they use.
+func (d Dialect) currentStatement(script string, offset int) []Token {
+	tokens := d.Tokenize(script)
+	start, end := 0, len(tokens)
+
+	for i, token := range tokens {
+		if token.Kind != PunctuationToken || token.Text != ";" {
+			continue
+		}
+
+		if token.End <= offset {
+			start = i + 1
+		} else {
+			end = i
+			break
+		}
+	}
+
+	return tokens[start:end]
+}
+
+func (d Dialect) significantTokens(tokens []Token) []Token {
+	significant := make([]Token, 0, len(tokens))
+
+	for _, token := range tokens {
+		if token.Kind != WhitespaceToken && token.Kind != CommentToken {
+			significant = append(significant, token)
+		}
+	}
+
+	return significant
+}
+
+// identifier returns the name of a word or quoted identifier token. Keywords
+// aren't names.
+func (d Dialect) identifier(token Token) (name string, ok bool) {
+	switch token.Kind {
+	case WordToken:
+		if d.IsKeyword(token.Text) {
+			return "", false
+		}
+
+		return token.Text, true
+	case QuotedIdentifierToken:
+		if token.Unterminated || len(token.Text) < 2 {
+			return "", false
+		}
+
+		closeQuote := d.closeQuote
+		if token.Text[0] == '"' {
+			closeQuote = `"`
+		}
+
+		content := token.Text[1 : len(token.Text)-1]
+
+		return strings.ReplaceAll(content, closeQuote+closeQuote, closeQuote), true
+	}
+
+	return "", false
+}
+
+// qualifier returns the dotted names right before the word being typed, that
+// starts at start, e.g. [schema table] for "schema.table.", or nothing when
+// there is no dot.
+func (d Dialect) qualifier(tokens []Token, start int) []string {
+	parts := []string{}
+
+	for i := len(tokens) - 1; i >= 1; i -= 2 {
+		dot := tokens[i]
+		if dot.Kind != PunctuationToken || dot.Text != "." || dot.End != start {
+			break
+		}
+
+		name, ok := d.identifier(tokens[i-1])
+		if !ok || tokens[i-1].End != dot.Start {
+			break
+		}
+
+		parts = append([]string{name}, parts...)
+		start = tokens[i-1].Start
+	}
+
+	return parts
+}
+
+// endsExpression tells whether a token is a name or a value, after which the
+// word being typed can't be a name.
+func (d Dialect) endsExpression(token Token) bool {
+	switch token.Kind {
+	case StringToken, NumberToken, QuotedIdentifierToken, PlaceholderToken:
+		return true
+	case WordToken:
+		return !d.IsKeyword(token.Text)
+	case PunctuationToken:
+		return token.Text == ")"
+	}
+
+	return false
+}
+
+// clause returns "table" or "column" when the last clause keyword before the
+// word being typed is followed by table names or by column expressions.
+func (d Dialect) clause(tokens []Token) string {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].Kind != WordToken {
+			continue
+		}
+
+		word := strings.ToUpper(tokens[i].Text)
+
+		if tableClauseKeywords[word] {
+			return "table"
+		} else if columnClauseKeywords[word] {
+			return "column"
+		}
+	}
+
+	return ""
+}
+
+// tableReference is a table used by a statement, along with its alias.
+type tableReference struct {
+	// name is the table name as written, e.g. schema.table
+	name  string
+	alias string
+}
+
+// tableReferences returns the tables named after FROM, JOIN, UPDATE and INTO,
+// comma separated lists of FROM included.
+func (d Dialect) tableReferences(tokens []Token) []tableReference {
+	references := []tableReference{}
+
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != WordToken {
+			continue
+		}
+
+		keyword := strings.ToUpper(tokens[i].Text)
+		if keyword != "FROM" && keyword != "JOIN" && keyword != "UPDATE" && keyword != "INTO" {
+			continue
+		}
+
+		for position := i + 1; ; {
+			parts, next := d.qualifiedName(tokens, position)
+			if len(parts) == 0 {
+				break
+			}
+
+			reference := tableReference{name: strings.Join(parts, ".")}
+
+			if next < len(tokens) && tokens[next].Kind == WordToken && strings.EqualFold(tokens[next].Text, "AS") {
+				next++
+			}
+
+			if next < len(tokens) {
+				if alias, ok := d.identifier(tokens[next]); ok {
+					reference.alias = alias
+					next++
+				}
+			}
+
+			references = append(references, reference)
+			i = next - 1
+
+			if keyword != "FROM" || next >= len(tokens) || tokens[next].Text != "," {
+				break
+			}
+
+			position = next + 1
+		}
+	}
+
+	return references
+}
+
+// qualifiedName reads a dotted name starting at position, and returns its
+// parts and the position after it.
+func (d Dialect) qualifiedName(tokens []Token, position int) (parts []string, next int) {
+	for next = position; next < len(tokens); next += 2 {
+		name, ok := d.identifier(tokens[next])
+		if !ok {
+			break
+		}
+
+		parts = append(parts, name)
+
+		if next+1 >= len(tokens) || tokens[next+1].Text != "." {
+			return parts, next + 1
+		}
+	}
+
+	return parts, next
+}
+
+// resolveTable returns the table a qualifier stands for: the table of an alias,
+// a table of the statement named by itself or the qualifier as is.
+func (d Dialect) resolveTable(qualifier []string, references []tableReference) string {
+	name := strings.Join(qualifier, ".")
+
+	if len(qualifier) > 1 {
+		return name
+	}
+
+	for _, reference := range references {
+		if strings.EqualFold(reference.alias, name) {
+			return reference.name
+		}
+	}
+
+	for _, reference := range references {
+		parts := strings.Split(reference.name, ".")
+		if reference.alias == "" && strings.EqualFold(parts[len(parts)-1], name) {
+			return reference.name
+		}
+	}
+
+	return name
+}
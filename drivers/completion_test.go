@@ -0,0 +1,221 @@
+package drivers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeCompletionSource struct {
+	schemas []string
+	// tables are by schema, every table is under ""
+	tables  map[string][]string
+	columns map[string][]string
+}
+
+func (source fakeCompletionSource) Schemas() []string {
+	return source.schemas
+}
+
+func (source fakeCompletionSource) Tables(schema string) []string {
+	return source.tables[schema]
+}
+
+func (source fakeCompletionSource) Columns(table string) []string {
+	return source.columns[table]
+}
+
+var completionSource = fakeCompletionSource{
+	schemas: []string{"public", "Sales"},
+	tables: map[string][]string{
+		"":       {"users", "orders", "Order Items"},
+		"public": {"users", "orders"},
+		"Sales":  {"invoices"},
+	},
+	columns: map[string][]string{
+		"users":          {"id", "name", "Email"},
+		"public.users":   {"id", "name", "Email"},
+		"orders":         {"id", "user_id", "select"},
+		"Sales.invoices": {"total"},
+	},
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		// ‸ marks the cursor in the scripts
+		script   string
+		noSource bool
+		// expected are the kinds and the texts of the completions
+		expected []string
+	}{
+		{
+			name:     "tables and keywords after FROM",
+			dialect:  PostgresDialect,
+			script:   "SELECT * FROM u‸",
+			expected: []string{"table users", "keyword union", "keyword unique", "keyword update", "keyword using"},
+		},
+		{
+			name:     "schemas after FROM",
+			dialect:  PostgresDialect,
+			script:   "SELECT * FROM pub‸",
+			expected: []string{"schema public"},
+		},
+		{
+			name:     "columns of an alias",
+			dialect:  PostgresDialect,
+			script:   "SELECT u.‸ FROM users u",
+			expected: []string{"column id", "column name", `column "Email"`},
+		},
+		{
+			name:     "columns of a quoted alias",
+			dialect:  PostgresDialect,
+			script:   `SELECT "o".‸ FROM orders AS "o"`,
+			expected: []string{"column id", "column user_id", `column "select"`},
+		},
+		{
+			name:     "columns of a table named without its schema",
+			dialect:  PostgresDialect,
+			script:   "SELECT users.na‸ FROM public.users",
+			expected: []string{"column name"},
+		},
+		{
+			name:     "columns of a table named with its schema",
+			dialect:  PostgresDialect,
+			script:   "SELECT Sales.invoices.t‸ FROM Sales.invoices",
+			expected: []string{"column total"},
+		},
+		{
+			name:     "tables of a schema",
+			dialect:  PostgresDialect,
+			script:   "SELECT * FROM Sales.‸",
+			expected: []string{"table invoices"},
+		},
+		{
+			name:     "columns of the joined tables",
+			dialect:  PostgresDialect,
+			script:   "SELECT user‸ FROM users u JOIN orders o ON u.id = o.user_id",
+			expected: []string{"column user_id"},
+		},
+		{
+			name:     "columns of the statement under the cursor only",
+			dialect:  PostgresDialect,
+			script:   "SELECT * FROM orders; SELECT user‸",
+			expected: nil,
+		},
+		{
+			name:     "functions",
+			dialect:  PostgresDialect,
+			script:   "SELECT coal‸",
+			expected: []string{"function coalesce"},
+		},
+		{
+			name:     "keywords in upper case",
+			dialect:  PostgresDialect,
+			script:   "SEL‸",
+			expected: []string{"keyword SELECT"},
+		},
+		{
+			name:     "keywords in mixed case",
+			dialect:  PostgresDialect,
+			script:   "Sel‸",
+			expected: []string{"keyword SELECT"},
+		},
+		{
+			name:     "keywords after a name",
+			dialect:  PostgresDialect,
+			script:   "SELECT * FROM users w‸",
+			expected: []string{"keyword when", "keyword where", "keyword with"},
+		},
+		{
+			name:     "keywords of the dialect",
+			dialect:  MySQLDialect,
+			script:   "SELECT * FROM users LIM‸",
+			expected: []string{"keyword LIMIT"},
+		},
+		{
+			name:     "identifiers quoted by the dialect",
+			dialect:  MySQLDialect,
+			script:   "SELECT * FROM O‸",
+			expected: []string{"table orders", "table `Order Items`", "keyword OFFSET", "keyword ON", "keyword OR", "keyword ORDER", "keyword OUTER"},
+		},
+		{
+			name:     "keywords only without a source",
+			dialect:  PostgresDialect,
+			script:   "SELECT * FROM us‸",
+			noSource: true,
+			expected: []string{"keyword using"},
+		},
+		{
+			name:     "nothing after a dot without a source",
+			dialect:  PostgresDialect,
+			script:   "SELECT u.‸ FROM users u",
+			noSource: true,
+			expected: nil,
+		},
+		{
+			name:     "nothing in a string",
+			dialect:  PostgresDialect,
+			script:   "SELECT 'us‸",
+			expected: nil,
+		},
+		{
+			name:     "nothing in a comment",
+			dialect:  PostgresDialect,
+			script:   "SELECT * FROM -- us‸",
+			expected: nil,
+		},
+		{
+			name:     "nothing in a quoted identifier",
+			dialect:  PostgresDialect,
+			script:   `SELECT * FROM "us‸`,
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			offset := strings.Index(test.script, "‸")
+			script := strings.Replace(test.script, "‸", "", 1)
+
+			var source CompletionSource = completionSource
+			if test.noSource {
+				source = nil
+			}
+
+			_, completions := test.dialect.Complete(script, offset, source)
+
+			var got []string
+			for _, completion := range completions {
+				got = append(got, completion.Kind.String()+" "+completion.Text)
+			}
+
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Complete(%q) = %q, want %q", test.script, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestCompleteStart(t *testing.T) {
+	tests := []struct {
+		script string
+		typed  string
+	}{
+		{"SELECT * FROM us‸", "us"},
+		{"SELECT u.na‸ FROM users u", "na"},
+		{"SELECT u.‸ FROM users u", ""},
+		{"SELECT ‸", ""},
+	}
+
+	for _, test := range tests {
+		offset := strings.Index(test.script, "‸")
+		script := strings.Replace(test.script, "‸", "", 1)
+
+		start, _ := PostgresDialect.Complete(script, offset, completionSource)
+		if typed := script[start:offset]; typed != test.typed {
+			t.Errorf("Complete(%q) replaces %q, want %q", test.script, typed, test.typed)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package drivers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/xo/dburl"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// NewConnection returns the connection of a url handled by one of the
+// registered drivers, under the given name.
+func NewConnection(name, urlstr string) (models.Connection, error) {
+	parsed, err := dburl.Parse(urlstr)
+	if err != nil {
+		return models.Connection{}, err
+	}
+
+	if _, err := Lookup(parsed.Driver); err != nil {
+		return models.Connection{}, err
+	}
+
+	dbName := strings.Split(parsed.Normalize(",", "NULL", 0), ",")[3]
+
+	if dbName == "NULL" {
+		dbName = ""
+	}
+
+	// dburl moves the database of some urls, like SQL Server ones, to the query string
+	if CapabilitiesOf(parsed.Driver).DatabaseInQuery {
+		dbName = ""
+
+		if dsn, err := url.Parse(parsed.DSN); err == nil {
+			dbName = dsn.Query().Get("database")
+		}
+	}
+
+	return models.Connection{
+		Name:     name,
+		Provider: parsed.Driver,
+		DBName:   dbName,
+		URL:      urlstr,
+	}, nil
+}
+
+// WithDatabase returns a url that connects to another database of the same
+// server, the rest of the url is kept.
+func WithDatabase(urlstr, database string) (string, error) {
+	parsed, err := dburl.Parse(urlstr)
+	if err != nil {
+		return "", err
+	}
+
+	capabilities := CapabilitiesOf(parsed.Driver)
+
+	switch {
+	case capabilities.PlainTableNames:
+		return "", fmt.Errorf("a %s connection has a single database", parsed.Driver)
+	case parsed.Opaque != "":
+		return "", fmt.Errorf("the database of %s can't be changed", parsed.URL.Redacted())
+	case capabilities.DatabaseInQuery:
+		query := parsed.Query()
+
+		// Without the database query parameter, dburl reads the database
+		// from the last segment of the path, after the instance
+		if !query.Has("database") {
+			segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+			parsed.Path = "/" + strings.Join(segments[:len(segments)-1], "/")
+		}
+
+		query.Set("database", database)
+		parsed.RawQuery = query.Encode()
+	default:
+		parsed.Path = "/" + database
+		parsed.RawPath = ""
+	}
+
+	return parsed.URL.String(), nil
+}
@@ -0,0 +1,86 @@
+package drivers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// CopyFormat is a format rows are copied to the clipboard in.
+type CopyFormat string
+
+const (
+	TSVCopy            CopyFormat = "TSV"
+	TSVWithHeadersCopy CopyFormat = "TSV with headers"
+	CSVCopy            CopyFormat = "CSV"
+	JSONCopy           CopyFormat = "JSON"
+	INListCopy         CopyFormat = "IN list"
+	InsertCopy         CopyFormat = "INSERT statements"
+)
+
+// CopyFormats lists the formats in the order they are offered.
+var CopyFormats = []CopyFormat{TSVCopy, TSVWithHeadersCopy, CSVCopy, JSONCopy, INListCopy, InsertCopy}
+
+// FormatRows returns rows as text to paste elsewhere. TSV is quoted the way
+// spreadsheets read it, the IN list holds every distinct value but NULL and
+// table is the quoted name of the table the INSERT statements insert into.
+func (d Dialect) FormatRows(format CopyFormat, columns []string, rows [][]models.CellValue, table string) (string, error) {
+	var text strings.Builder
+
+	switch format {
+	case TSVCopy, TSVWithHeadersCopy:
+		writer := csv.NewWriter(&text)
+		writer.Comma = '\t'
+
+		if format == TSVWithHeadersCopy {
+			writer.Write(columns)
+		}
+
+		for _, row := range rows {
+			writer.Write(models.CellValueStrings(row))
+		}
+
+		writer.Flush()
+
+		if err := writer.Error(); err != nil {
+			return "", err
+		}
+	case INListCopy:
+		values := []string{}
+		seen := map[string]bool{}
+
+		for _, row := range rows {
+			for _, value := range row {
+				if value.Type == models.String && !seen[value.Value] {
+					seen[value.Value] = true
+					values = append(values, d.QuoteLiteral(value.Value))
+				}
+			}
+		}
+
+		return "IN (" + strings.Join(values, ", ") + ")", nil
+	case CSVCopy, JSONCopy, InsertCopy:
+		exportFormat := map[CopyFormat]ExportFormat{CSVCopy: CSVExport, JSONCopy: JSONExport, InsertCopy: SQLExport}[format]
+
+		writer, err := d.NewResultWriter(exportFormat, &text, columns, table)
+		if err != nil {
+			return "", err
+		}
+
+		err = writer.WriteRows(rows)
+		if err != nil {
+			return "", err
+		}
+
+		err = writer.Close()
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unknown copy format %q", format)
+	}
+
+	return text.String(), nil
+}
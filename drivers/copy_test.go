@@ -0,0 +1,88 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+func TestFormatRows(t *testing.T) {
+	columns := []string{"id", "name"}
+	rows := [][]models.CellValue{
+		models.NewCellValues([]string{"1", "a\tb"}),
+		{{Value: "2"}, {Type: models.Null}},
+		models.NewCellValues([]string{"1", `say "hi"`}),
+	}
+
+	tests := []struct {
+		format   CopyFormat
+		dialect  Dialect
+		expected string
+	}{
+		{
+			format:   TSVCopy,
+			dialect:  PostgresDialect,
+			expected: "1\t\"a\tb\"\n2\t\n1\t\"say \"\"hi\"\"\"\n",
+		},
+		{
+			format:   TSVWithHeadersCopy,
+			dialect:  PostgresDialect,
+			expected: "id\tname\n1\t\"a\tb\"\n2\t\n1\t\"say \"\"hi\"\"\"\n",
+		},
+		{
+			format:   CSVCopy,
+			dialect:  PostgresDialect,
+			expected: "id,name\n1,a\tb\n2,\n1,\"say \"\"hi\"\"\"\n",
+		},
+		{
+			format:  JSONCopy,
+			dialect: PostgresDialect,
+			expected: "[\n" +
+				`  {"id": "1", "name": "a\tb"},` + "\n" +
+				`  {"id": "2", "name": null},` + "\n" +
+				`  {"id": "1", "name": "say \"hi\""}` + "\n" +
+				"]\n",
+		},
+		{
+			format:   INListCopy,
+			dialect:  PostgresDialect,
+			expected: "IN ('1', 'a\tb', '2', 'say \"hi\"')",
+		},
+		{
+			format:   INListCopy,
+			dialect:  MSSQLDialect,
+			expected: "IN (N'1', N'a\tb', N'2', N'say \"hi\"')",
+		},
+		{
+			format:  InsertCopy,
+			dialect: PostgresDialect,
+			expected: `INSERT INTO "users" ("id", "name") VALUES ('1', 'a	b');` + "\n" +
+				`INSERT INTO "users" ("id", "name") VALUES ('2', NULL);` + "\n" +
+				`INSERT INTO "users" ("id", "name") VALUES ('1', 'say "hi"');` + "\n",
+		},
+		{
+			format:  InsertCopy,
+			dialect: MySQLDialect,
+			expected: "INSERT INTO `users` (`id`, `name`) VALUES ('1', 'a\tb');\n" +
+				"INSERT INTO `users` (`id`, `name`) VALUES ('2', NULL);\n" +
+				"INSERT INTO `users` (`id`, `name`) VALUES ('1', 'say \"hi\"');\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.format), func(t *testing.T) {
+			text, err := test.dialect.FormatRows(test.format, columns, rows, test.dialect.QuoteIdentifier("users"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if text != test.expected {
+				t.Errorf("%s copy is\n%s\nwant\n%s", test.format, text, test.expected)
+			}
+		})
+	}
+
+	if _, err := PostgresDialect.FormatRows("XML", columns, rows, ""); err == nil {
+		t.Error("an unknown format didn't fail")
+	}
+}
@@ -0,0 +1,146 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// Cursor reads the rows of a query a batch at a time, so that the results
+// don't have to fit in memory at once.
+type Cursor interface {
+	Columns() []string
+	// Next reads up to count rows. more is false once every row was read, the
+	// cursor is closed by then.
+	Next(count int) (rows [][]models.CellValue, more bool, err error)
+	Close() error
+}
+
+type rowsCursor struct {
+	mutex   sync.Mutex
+	rows    *sql.Rows
+	columns []string
+	scan    rowScanner
+	// peeked is true when rows.Next was already called for the next row
+	peeked bool
+	closed bool
+	// cancel cancels the context of the query, so that closing the cursor
+	// early doesn't wait for the remaining rows
+	cancel context.CancelFunc
+	// release frees what the rows were read with, e.g. a dedicated connection
+	release func()
+}
+
+// newRowsCursor wraps rows into a cursor. scan reads the values of a row,
+// they are read as text when it's nil. cancel must cancel the context the
+// rows were queried with. release, when not nil, is called once the rows are
+// closed.
+func newRowsCursor(rows *sql.Rows, scan rowScanner, cancel context.CancelFunc, release func()) (Cursor, error) {
+	cursor := &rowsCursor{rows: rows, scan: scan, cancel: cancel, release: release}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		cursor.close(false)
+		return nil, err
+	}
+
+	cursor.columns = columns
+
+	if cursor.scan == nil {
+		cursor.scan = textScanner(len(columns))
+	}
+
+	return cursor, nil
+}
+
+func (cursor *rowsCursor) Columns() []string {
+	return cursor.columns
+}
+
+func (cursor *rowsCursor) Next(count int) (results [][]models.CellValue, more bool, err error) {
+	cursor.mutex.Lock()
+	defer cursor.mutex.Unlock()
+
+	if cursor.closed {
+		return results, false, nil
+	}
+
+	for len(results) < count {
+		if !cursor.advance() {
+			return results, false, cursor.finish()
+		}
+
+		row, err := cursor.scan(cursor.rows)
+		if err != nil {
+			cursor.close(true)
+			return results, false, err
+		}
+
+		results = append(results, row)
+	}
+
+	// Look ahead so that the caller knows whether to ask for more
+	if !cursor.advance() {
+		return results, false, cursor.finish()
+	}
+
+	cursor.peeked = true
+
+	return results, true, nil
+}
+
+func (cursor *rowsCursor) advance() bool {
+	if cursor.peeked {
+		cursor.peeked = false
+		return true
+	}
+
+	return cursor.rows.Next()
+}
+
+// finish closes the cursor once the rows are exhausted and returns the error
+// that ended the iteration, if any.
+func (cursor *rowsCursor) finish() error {
+	err := cursor.rows.Err()
+	cursor.close(false)
+
+	return err
+}
+
+func (cursor *rowsCursor) Close() error {
+	cursor.mutex.Lock()
+	defer cursor.mutex.Unlock()
+
+	return cursor.close(true)
+}
+
+// close closes the rows. When early is true the query still has rows, it is
+// cancelled instead of being read until its end.
+func (cursor *rowsCursor) close(early bool) error {
+	if cursor.closed {
+		return nil
+	}
+
+	cursor.closed = true
+
+	if early {
+		cursor.cancel()
+	}
+
+	err := cursor.rows.Close()
+
+	if cursor.release != nil {
+		cursor.release()
+	}
+
+	cursor.cancel()
+
+	if early {
+		// The error comes from cancelling the query
+		return nil
+	}
+
+	return err
+}
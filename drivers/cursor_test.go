@@ -0,0 +1,168 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// openFakeCursor returns a cursor over the rows 1 to count of a fake database,
+// and counts how many times its query is cancelled and its rows released
+func openFakeCursor(t *testing.T, count int) (cursor Cursor, cancels, releases *int) {
+	t.Helper()
+
+	rows := make([][]driver.Value, count)
+	for i := range rows {
+		rows[i] = []driver.Value{int64(i + 1)}
+	}
+
+	connection, _ := openFakeDatabase(t, fakeResult{match: "SELECT", columns: []string{"n"}, rows: rows})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sqlRows, err := connection.QueryContext(ctx, "SELECT n")
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+
+	cancels, releases = new(int), new(int)
+
+	cursor, err = newRowsCursor(sqlRows, nil, func() {
+		*cancels++
+		cancel()
+	}, func() {
+		*releases++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cursor, cancels, releases
+}
+
+func numberRows(from, to int) [][]models.CellValue {
+	rows := [][]models.CellValue{}
+	for n := from; n <= to; n++ {
+		rows = append(rows, models.NewCellValues([]string{fmt.Sprint(n)}))
+	}
+
+	return rows
+}
+
+func TestCursorBatches(t *testing.T) {
+	cursor, _, releases := openFakeCursor(t, 5)
+
+	if columns := cursor.Columns(); !reflect.DeepEqual(columns, []string{"n"}) {
+		t.Errorf("columns = %v, want [n]", columns)
+	}
+
+	batches := []struct {
+		rows [][]models.CellValue
+		more bool
+	}{
+		{numberRows(1, 2), true},
+		{numberRows(3, 4), true},
+		{numberRows(5, 5), false},
+	}
+
+	for i, batch := range batches {
+		rows, more, err := cursor.Next(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(rows, batch.rows) || more != batch.more {
+			t.Errorf("batch %d = %v, %t, want %v, %t", i, rows, more, batch.rows, batch.more)
+		}
+	}
+
+	if *releases != 1 {
+		t.Errorf("the rows were released %d times once read, want 1", *releases)
+	}
+
+	// A closed cursor has no more rows
+	rows, more, err := cursor.Next(2)
+	if len(rows) != 0 || more || err != nil {
+		t.Errorf("Next() after the last row = %v, %t, %v", rows, more, err)
+	}
+
+	if err := cursor.Close(); err != nil {
+		t.Errorf("closing a cursor twice = %v", err)
+	}
+
+	if *releases != 1 {
+		t.Errorf("the rows were released %d times, want 1", *releases)
+	}
+}
+
+func TestCursorExactBatch(t *testing.T) {
+	cursor, _, _ := openFakeCursor(t, 2)
+
+	// The cursor looks ahead, so that a full batch tells there is no more
+	rows, more, err := cursor.Next(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || more {
+		t.Errorf("Next(2) over 2 rows = %d rows, more %t, want 2 rows and no more", len(rows), more)
+	}
+}
+
+func TestCursorCloseEarly(t *testing.T) {
+	cursor, cancels, releases := openFakeCursor(t, 10)
+
+	_, more, err := cursor.Next(3)
+	if err != nil || !more {
+		t.Fatalf("Next(3) over 10 rows = more %t, %v", more, err)
+	}
+
+	if *cancels != 0 {
+		t.Errorf("the query was cancelled before the cursor was closed")
+	}
+
+	err = cursor.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *cancels == 0 || *releases != 1 {
+		t.Errorf("closing early cancelled the query %d times and released its rows %d times", *cancels, *releases)
+	}
+}
+
+func TestCursorScanError(t *testing.T) {
+	connection, _ := openFakeDatabase(t, fakeResult{match: "SELECT", columns: []string{"n"}, rows: [][]driver.Value{{int64(1)}}})
+
+	rows, err := connection.Query("SELECT n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failing := func(*sql.Rows) ([]models.CellValue, error) {
+		return nil, errors.New("can't convert")
+	}
+
+	cancelled := false
+
+	cursor, err := newRowsCursor(rows, failing, func() { cancelled = true }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, more, err := cursor.Next(10)
+	if err == nil || more {
+		t.Errorf("Next() = more %t, %v, want the error of the scan", more, err)
+	}
+
+	if !cancelled {
+		t.Error("the query wasn't cancelled after the error")
+	}
+}
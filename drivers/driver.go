@@ -1,24 +1,50 @@
 package drivers
 
 import (
+	"context"
+
 	"github.com/jorgerojas26/lazysql/models"
 )
 
 type Driver interface {
-	Connect(urlstr string) error
-	TestConnection(urlstr string) error
-	GetDatabases() ([]string, error)
-	GetTables(database string) (map[string][]string, error)
-	GetTableColumns(database, table string) ([][]string, error)
-	GetConstraints(table string) ([][]string, error)
-	GetForeignKeys(table string) ([][]string, error)
-	GetIndexes(table string) ([][]string, error)
-	GetRecords(table, where, sort string, offset, limit int) ([][]string, int, error)
-	UpdateRecord(table, column, value, primaryKeyColumnName, primaryKeyValue string) error
-	DeleteRecord(table string, primaryKeyColumnName, primaryKeyValue string) error
-	ExecuteDMLStatement(query string) (string, error)
-	ExecuteQuery(query string) ([][]string, error)
-	ExecutePendingChanges(changes []models.DbDmlChange, inserts []models.DbInsert) error
+	Connect(ctx context.Context, urlstr string) error
+	TestConnection(ctx context.Context, urlstr string) error
+	GetDatabases(ctx context.Context) ([]string, error)
+	GetTables(ctx context.Context, database string) (map[string][]string, error)
+	GetTableColumns(ctx context.Context, database, table string) ([][]models.CellValue, error)
+	GetConstraints(ctx context.Context, table string) ([][]models.CellValue, error)
+	GetForeignKeys(ctx context.Context, table string) ([][]models.CellValue, error)
+	GetIndexes(ctx context.Context, table string) ([][]models.CellValue, error)
+	// GetPrimaryKeyColumnNames returns the columns that identify a row: the
+	// primary key, else a unique index over NOT NULL columns, else the row id
+	// of the database. It's empty when the table has none of them, its rows
+	// can't be edited then.
+	GetPrimaryKeyColumnNames(ctx context.Context, database, table string) ([]string, error)
+	GetRecords(ctx context.Context, table, where, sort string, offset, limit int) ([][]models.CellValue, int, error)
+	UpdateRecord(ctx context.Context, table, column, value string, primaryKeyInfo []models.PrimaryKeyInfo) error
+	DeleteRecord(ctx context.Context, table string, primaryKeyInfo []models.PrimaryKeyInfo) error
+	ExecuteDMLStatement(ctx context.Context, query string) (int64, error)
+	ExecuteQuery(ctx context.Context, query string) (Cursor, error)
+	ExecutePendingChanges(ctx context.Context, changes []models.DbDmlChange, inserts []models.DbInsert) error
 	SetProvider(provider string)
 	GetProvider() string
+	// Close closes the connection and what it was opened with, e.g. its SSH
+	// tunnel
+	Close() error
+}
+
+// databaseDriver is a driver whose connections only query the database they
+// were opened on, see Capabilities.ConnectionPerDatabase.
+type databaseDriver interface {
+	ForDatabase(database string) (Driver, error)
+}
+
+// ForDatabase returns a driver querying a database of the server of driver.
+// It's driver itself unless its connections are bound to their database.
+func ForDatabase(driver Driver, database string) (Driver, error) {
+	if databaseDriver, ok := driver.(databaseDriver); ok && database != "" {
+		return databaseDriver.ForDatabase(database)
+	}
+
+	return driver, nil
 }
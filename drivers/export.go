@@ -0,0 +1,549 @@
+package drivers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rivo/uniseg"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// ExportFormat is a file format result sets are exported to.
+type ExportFormat string
+
+const (
+	CSVExport      ExportFormat = "CSV"
+	TSVExport      ExportFormat = "TSV"
+	JSONExport     ExportFormat = "JSON"
+	NDJSONExport   ExportFormat = "NDJSON"
+	MarkdownExport ExportFormat = "Markdown"
+	SQLExport      ExportFormat = "SQL INSERT"
+	// TableExport aligns the columns for a terminal, it isn't offered to
+	// export to a file
+	TableExport ExportFormat = "Table"
+)
+
+// ExportFormats lists the formats in the order they are offered.
+var ExportFormats = []ExportFormat{CSVExport, TSVExport, JSONExport, NDJSONExport, MarkdownExport, SQLExport}
+
+// Extension returns the file extension of the format, without the dot.
+func (format ExportFormat) Extension() string {
+	switch format {
+	case TSVExport:
+		return "tsv"
+	case JSONExport:
+		return "json"
+	case NDJSONExport:
+		return "ndjson"
+	case MarkdownExport:
+		return "md"
+	case SQLExport:
+		return "sql"
+	case TableExport:
+		return "txt"
+	}
+
+	return "csv"
+}
+
+// ResultWriter writes the rows of a result set in an export format. NULL is
+// written as an empty field in CSV and TSV, as null in JSON and as NULL
+// otherwise.
+type ResultWriter interface {
+	WriteRows(rows [][]models.CellValue) error
+	// Close ends the output and flushes it, the underlying writer stays open
+	Close() error
+}
+
+// NewResultWriter returns a writer of the rows of a result set with the given
+// columns. table names the table the INSERT statements of the SQL format
+// insert into, it's written as it is, quoted like QuoteTableName does or the
+// way it was typed.
+func (d Dialect) NewResultWriter(format ExportFormat, output io.Writer, columns []string, table string) (ResultWriter, error) {
+	buffered := bufio.NewWriter(output)
+
+	var writer resultWriter
+
+	switch format {
+	case CSVExport:
+		writer = &csvResultWriter{writer: csv.NewWriter(buffered)}
+	case TSVExport:
+		writer = &tsvResultWriter{output: buffered}
+	case JSONExport, NDJSONExport:
+		writer = &jsonResultWriter{output: buffered, columns: columns, array: format == JSONExport}
+	case MarkdownExport:
+		writer = &markdownResultWriter{output: buffered}
+	case SQLExport:
+		writer = &sqlResultWriter{output: buffered, dialect: d, columns: columns, table: table}
+	case TableExport:
+		writer = &tableResultWriter{output: buffered}
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+
+	err := writer.writeHeader(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flushingResultWriter{ResultWriter: writer, buffered: buffered}, nil
+}
+
+// ExportCursor writes every remaining row of a cursor, a batch at a time, and
+// returns how many rows were written. progress, when not nil, is called after
+// every batch.
+func ExportCursor(writer ResultWriter, cursor Cursor, progress func(rows int)) (int, error) {
+	const batchSize = 1000
+
+	written := 0
+
+	for {
+		rows, more, err := cursor.Next(batchSize)
+		if err != nil {
+			return written, err
+		}
+
+		err = writer.WriteRows(rows)
+		if err != nil {
+			return written, err
+		}
+
+		written += len(rows)
+
+		if progress != nil {
+			progress(written)
+		}
+
+		if !more {
+			return written, nil
+		}
+	}
+}
+
+// resultWriter is a ResultWriter that starts its output with the columns
+type resultWriter interface {
+	ResultWriter
+	writeHeader(columns []string) error
+}
+
+type flushingResultWriter struct {
+	ResultWriter
+	buffered *bufio.Writer
+}
+
+func (writer *flushingResultWriter) Close() error {
+	err := writer.ResultWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	return writer.buffered.Flush()
+}
+
+type csvResultWriter struct {
+	writer *csv.Writer
+}
+
+func (writer *csvResultWriter) writeHeader(columns []string) error {
+	return writer.writer.Write(columns)
+}
+
+func (writer *csvResultWriter) WriteRows(rows [][]models.CellValue) error {
+	for _, row := range rows {
+		err := writer.writer.Write(models.CellValueStrings(row))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (writer *csvResultWriter) Close() error {
+	writer.writer.Flush()
+
+	return writer.writer.Error()
+}
+
+// tsvResultWriter escapes tabs, line breaks and backslashes with a backslash,
+// like the text format of COPY in PostgreSQL.
+type tsvResultWriter struct {
+	output io.Writer
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (writer *tsvResultWriter) writeLine(fields []models.CellValue) error {
+	escaped := make([]string, len(fields))
+
+	for i, field := range fields {
+		escaped[i] = tsvEscaper.Replace(field.Value)
+	}
+
+	_, err := io.WriteString(writer.output, strings.Join(escaped, "\t")+"\n")
+
+	return err
+}
+
+func (writer *tsvResultWriter) writeHeader(columns []string) error {
+	return writer.writeLine(models.NewCellValues(columns))
+}
+
+func (writer *tsvResultWriter) WriteRows(rows [][]models.CellValue) error {
+	for _, row := range rows {
+		err := writer.writeLine(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (writer *tsvResultWriter) Close() error {
+	return nil
+}
+
+// jsonResultWriter writes an object per row, keyed by the columns in their
+// order. Every value is a string, since the drivers return text.
+type jsonResultWriter struct {
+	output  io.Writer
+	columns []string
+	// array is true for a JSON array, false for one object per line
+	array   bool
+	written int
+}
+
+func (writer *jsonResultWriter) writeHeader(_ []string) error {
+	if !writer.array {
+		return nil
+	}
+
+	_, err := io.WriteString(writer.output, "[")
+
+	return err
+}
+
+func (writer *jsonResultWriter) WriteRows(rows [][]models.CellValue) error {
+	for _, row := range rows {
+		var line strings.Builder
+
+		if writer.array {
+			if writer.written > 0 {
+				line.WriteString(",")
+			}
+
+			line.WriteString("\n  ")
+		}
+
+		line.WriteString("{")
+
+		for i, column := range writer.columns {
+			if i > 0 {
+				line.WriteString(", ")
+			}
+
+			key, _ := json.Marshal(column)
+			line.Write(key)
+			line.WriteString(": ")
+
+			if i >= len(row) || row[i].Type == models.Null {
+				line.WriteString("null")
+			} else {
+				value, _ := json.Marshal(row[i].Value)
+				line.Write(value)
+			}
+		}
+
+		line.WriteString("}")
+
+		if !writer.array {
+			line.WriteString("\n")
+		}
+
+		_, err := io.WriteString(writer.output, line.String())
+		if err != nil {
+			return err
+		}
+
+		writer.written++
+	}
+
+	return nil
+}
+
+func (writer *jsonResultWriter) Close() error {
+	if !writer.array {
+		return nil
+	}
+
+	closing := "\n]\n"
+	if writer.written == 0 {
+		closing = "]\n"
+	}
+
+	_, err := io.WriteString(writer.output, closing)
+
+	return err
+}
+
+type markdownResultWriter struct {
+	output io.Writer
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+func (writer *markdownResultWriter) writeLine(fields []models.CellValue) error {
+	escaped := make([]string, len(fields))
+
+	for i, field := range fields {
+		if field.Type == models.Null {
+			escaped[i] = "NULL"
+		} else {
+			escaped[i] = markdownEscaper.Replace(field.Value)
+		}
+	}
+
+	_, err := io.WriteString(writer.output, "| "+strings.Join(escaped, " | ")+" |\n")
+
+	return err
+}
+
+func (writer *markdownResultWriter) writeHeader(columns []string) error {
+	err := writer.writeLine(models.NewCellValues(columns))
+	if err != nil {
+		return err
+	}
+
+	separators := make([]string, len(columns))
+	for i := range separators {
+		separators[i] = "---"
+	}
+
+	return writer.writeLine(models.NewCellValues(separators))
+}
+
+func (writer *markdownResultWriter) WriteRows(rows [][]models.CellValue) error {
+	for _, row := range rows {
+		err := writer.writeLine(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (writer *markdownResultWriter) Close() error {
+	return nil
+}
+
+// sqlResultWriter writes an INSERT statement per row. Values are written as
+// string literals, which every database converts to the type of the column.
+type sqlResultWriter struct {
+	output  io.Writer
+	dialect Dialect
+	columns []string
+	// table is the quoted name of the table
+	table string
+}
+
+func (writer *sqlResultWriter) writeHeader(_ []string) error {
+	return nil
+}
+
+func (writer *sqlResultWriter) WriteRows(rows [][]models.CellValue) error {
+	quotedColumns := make([]string, len(writer.columns))
+	for i, column := range writer.columns {
+		quotedColumns[i] = writer.dialect.QuoteIdentifier(column)
+	}
+
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", writer.table, strings.Join(quotedColumns, ", "))
+
+	for _, row := range rows {
+		values := make([]string, len(row))
+
+		for i, value := range row {
+			values[i] = writer.dialect.valueLiteral(value)
+		}
+
+		_, err := io.WriteString(writer.output, prefix+strings.Join(values, ", ")+");\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (writer *sqlResultWriter) Close() error {
+	return nil
+}
+
+// tableWidthRows is how many rows the columns of a table are sized for,
+// the rows after them are written as they come
+const tableWidthRows = 1000
+
+// tableResultWriter aligns the columns to their widest value among the first
+// rows, which are kept until there are enough of them to size the columns.
+// Wider values of the later rows push the columns of their line. Line breaks
+// and tabs are escaped with a backslash.
+type tableResultWriter struct {
+	output  io.Writer
+	columns []string
+	// rows are kept until the columns are sized, widths is nil until then
+	rows   [][]string
+	widths []int
+	count  int
+}
+
+var tableEscaper = strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (writer *tableResultWriter) writeHeader(columns []string) error {
+	writer.columns = columns
+
+	return nil
+}
+
+func (writer *tableResultWriter) WriteRows(rows [][]models.CellValue) error {
+	for _, row := range rows {
+		fields := make([]string, len(row))
+
+		for i, value := range row {
+			if value.Type == models.Null {
+				fields[i] = "NULL"
+			} else {
+				fields[i] = tableEscaper.Replace(value.Value)
+			}
+		}
+
+		writer.count++
+
+		if writer.widths != nil {
+			_, err := io.WriteString(writer.output, writer.line(fields))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		writer.rows = append(writer.rows, fields)
+
+		if len(writer.rows) == tableWidthRows {
+			err := writer.writeSized()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeSized sizes the columns for the rows kept, and writes the columns and
+// the rows
+func (writer *tableResultWriter) writeSized() error {
+	writer.widths = make([]int, len(writer.columns))
+
+	for i, column := range writer.columns {
+		writer.widths[i] = uniseg.StringWidth(column)
+	}
+
+	for _, row := range writer.rows {
+		for i, field := range row {
+			if i < len(writer.widths) && uniseg.StringWidth(field) > writer.widths[i] {
+				writer.widths[i] = uniseg.StringWidth(field)
+			}
+		}
+	}
+
+	separators := make([]string, len(writer.widths))
+	for i, width := range writer.widths {
+		separators[i] = strings.Repeat("-", width)
+	}
+
+	var output strings.Builder
+
+	output.WriteString(writer.line(writer.columns))
+	output.WriteString(strings.Join(separators, "-+-") + "\n")
+
+	for _, row := range writer.rows {
+		output.WriteString(writer.line(row))
+	}
+
+	writer.rows = nil
+
+	_, err := io.WriteString(writer.output, output.String())
+
+	return err
+}
+
+// line returns the fields of a line padded to the widths of their columns
+func (writer *tableResultWriter) line(fields []string) string {
+	padded := make([]string, len(fields))
+
+	for i, field := range fields {
+		padded[i] = field
+
+		// The last column isn't padded, to leave no trailing spaces
+		if i < len(fields)-1 && i < len(writer.widths) {
+			if width := uniseg.StringWidth(field); width < writer.widths[i] {
+				padded[i] += strings.Repeat(" ", writer.widths[i]-width)
+			}
+		}
+	}
+
+	return strings.Join(padded, " | ") + "\n"
+}
+
+func (writer *tableResultWriter) Close() error {
+	if writer.widths == nil {
+		err := writer.writeSized()
+		if err != nil {
+			return err
+		}
+	}
+
+	footer := fmt.Sprintf("(%d rows)\n", writer.count)
+	if writer.count == 1 {
+		footer = "(1 row)\n"
+	}
+
+	_, err := io.WriteString(writer.output, footer)
+
+	return err
+}
+
+// valueLiteral returns a cell value as NULL, DEFAULT or a string literal
+func (d Dialect) valueLiteral(value models.CellValue) string {
+	switch value.Type {
+	case models.Null:
+		return "NULL"
+	case models.Default:
+		return "DEFAULT"
+	}
+
+	return d.QuoteLiteral(value.Value)
+}
+
+// QuoteLiteral returns a value as a string literal. Quotes are doubled, and so
+// are backslashes when the dialect treats them as escapes.
+func (d Dialect) QuoteLiteral(value string) string {
+	escaped := strings.ReplaceAll(value, "'", "''")
+
+	if d.backslashEscapes {
+		escaped = strings.ReplaceAll(escaped, `\`, `\\`)
+	}
+
+	if d.nationalStrings {
+		return "N'" + escaped + "'"
+	}
+
+	return "'" + escaped + "'"
+}
@@ -0,0 +1,243 @@
+package drivers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// exportRows hold a quote and a comma, NULL, and a tab, a line break, a pipe
+// and a backslash
+var exportRows = [][]models.CellValue{
+	models.NewCellValues([]string{"1", `O'Brien, "Al"`}),
+	{{Value: "2"}, {Type: models.Null}},
+	models.NewCellValues([]string{"3", "a\tb\nc|d\\"}),
+}
+
+func TestResultWriter(t *testing.T) {
+	tests := []struct {
+		format   ExportFormat
+		dialect  Dialect
+		table    string
+		expected string
+	}{
+		{
+			format: CSVExport,
+			expected: "id,name\n" +
+				"1,\"O'Brien, \"\"Al\"\"\"\n" +
+				"2,\n" +
+				"3,\"a\tb\nc|d\\\"\n",
+		},
+		{
+			format: TSVExport,
+			expected: "id\tname\n" +
+				"1\tO'Brien, \"Al\"\n" +
+				"2\t\n" +
+				"3\ta\\tb\\nc|d\\\\\n",
+		},
+		{
+			format: JSONExport,
+			expected: "[\n" +
+				`  {"id": "1", "name": "O'Brien, \"Al\""},` + "\n" +
+				`  {"id": "2", "name": null},` + "\n" +
+				`  {"id": "3", "name": "a\tb\nc|d\\"}` + "\n" +
+				"]\n",
+		},
+		{
+			format: NDJSONExport,
+			expected: `{"id": "1", "name": "O'Brien, \"Al\""}` + "\n" +
+				`{"id": "2", "name": null}` + "\n" +
+				`{"id": "3", "name": "a\tb\nc|d\\"}` + "\n",
+		},
+		{
+			format: MarkdownExport,
+			expected: "| id | name |\n" +
+				"| --- | --- |\n" +
+				"| 1 | O'Brien, \"Al\" |\n" +
+				"| 2 | NULL |\n" +
+				"| 3 | a\tb<br>c\\|d\\\\ |\n",
+		},
+		{
+			format:  SQLExport,
+			dialect: MySQLDialect,
+			table:   "`users`",
+			expected: "INSERT INTO `users` (`id`, `name`) VALUES ('1', 'O''Brien, \"Al\"');\n" +
+				"INSERT INTO `users` (`id`, `name`) VALUES ('2', NULL);\n" +
+				"INSERT INTO `users` (`id`, `name`) VALUES ('3', 'a\tb\nc|d\\\\');\n",
+		},
+		{
+			format:  SQLExport,
+			dialect: PostgresDialect,
+			table:   `"public"."users"`,
+			expected: `INSERT INTO "public"."users" ("id", "name") VALUES ('1', 'O''Brien, "Al"');` + "\n" +
+				`INSERT INTO "public"."users" ("id", "name") VALUES ('2', NULL);` + "\n" +
+				"INSERT INTO \"public\".\"users\" (\"id\", \"name\") VALUES ('3', 'a\tb\nc|d\\');\n",
+		},
+		{
+			format:  SQLExport,
+			dialect: MSSQLDialect,
+			table:   "[dbo].[users]",
+			expected: "INSERT INTO [dbo].[users] ([id], [name]) VALUES (N'1', N'O''Brien, \"Al\"');\n" +
+				"INSERT INTO [dbo].[users] ([id], [name]) VALUES (N'2', NULL);\n" +
+				"INSERT INTO [dbo].[users] ([id], [name]) VALUES (N'3', N'a\tb\nc|d\\');\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.format), func(t *testing.T) {
+			var output bytes.Buffer
+
+			writer, err := test.dialect.NewResultWriter(test.format, &output, []string{"id", "name"}, test.table)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Rows come in batches when they are read from a cursor
+			for _, batch := range [][][]models.CellValue{exportRows[:1], exportRows[1:]} {
+				err = writer.WriteRows(batch)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = writer.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if output.String() != test.expected {
+				t.Errorf("%s export is\n%s\nwant\n%s", test.format, output.String(), test.expected)
+			}
+		})
+	}
+}
+
+func TestResultWriterWithoutRows(t *testing.T) {
+	tests := []struct {
+		format   ExportFormat
+		expected string
+	}{
+		{CSVExport, "id,name\n"},
+		{TSVExport, "id\tname\n"},
+		{JSONExport, "[]\n"},
+		{NDJSONExport, ""},
+		{MarkdownExport, "| id | name |\n| --- | --- |\n"},
+		{SQLExport, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.format), func(t *testing.T) {
+			var output bytes.Buffer
+
+			writer, err := PostgresDialect.NewResultWriter(test.format, &output, []string{"id", "name"}, `"users"`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = writer.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if output.String() != test.expected {
+				t.Errorf("%s export is %q, want %q", test.format, output.String(), test.expected)
+			}
+		})
+	}
+}
+
+func TestExportFormatExtension(t *testing.T) {
+	expected := map[ExportFormat]string{
+		CSVExport:      "csv",
+		TSVExport:      "tsv",
+		JSONExport:     "json",
+		NDJSONExport:   "ndjson",
+		MarkdownExport: "md",
+		SQLExport:      "sql",
+	}
+
+	for _, format := range ExportFormats {
+		if extension := format.Extension(); extension != expected[format] {
+			t.Errorf("%s files end with .%s, want .%s", format, extension, expected[format])
+		}
+	}
+
+	if _, err := PostgresDialect.NewResultWriter("XML", &bytes.Buffer{}, nil, ""); err == nil {
+		t.Error("an unknown format didn't fail")
+	}
+}
+
+func TestTableResultWriter(t *testing.T) {
+	var output bytes.Buffer
+
+	writer, err := PostgresDialect.NewResultWriter(TableExport, &output, []string{"id", "name"}, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writer.WriteRows([][]models.CellValue{
+		models.NewCellValues([]string{"1", "ada"}),
+		{{Value: "10"}, {Type: models.Null}},
+		models.NewCellValues([]string{"2", "two\nlines"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id | name\n" +
+		"---+-----------\n" +
+		"1  | ada\n" +
+		"10 | NULL\n" +
+		"2  | two\\nlines\n" +
+		"(3 rows)\n"
+
+	if output.String() != want {
+		t.Errorf("table is\n%s\nwant\n%s", output.String(), want)
+	}
+}
+
+func TestTableResultWriterStreams(t *testing.T) {
+	var output bytes.Buffer
+
+	writer := &tableResultWriter{output: &output}
+	writer.writeHeader([]string{"n"})
+
+	rows := make([][]models.CellValue, tableWidthRows)
+	for i := range rows {
+		rows[i] = []models.CellValue{{Value: "1"}}
+	}
+
+	err := writer.WriteRows(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writer.WriteRows([][]models.CellValue{{{Value: "12345"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(writer.rows) != 0 {
+		t.Errorf("%d rows are kept after the columns are sized", len(writer.rows))
+	}
+
+	if !strings.HasSuffix(output.String(), "\n12345\n") {
+		t.Errorf("the rows after the first %d aren't written as they come", tableWidthRows)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(output.String(), "(1001 rows)\n") {
+		t.Errorf("table ends with %q", output.String()[output.Len()-20:])
+	}
+}
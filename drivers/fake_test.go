@@ -0,0 +1,193 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDatabase is a database/sql connector of a database that answers the
+// queries with the results set for them, and records every statement it's
+// sent
+type fakeDatabase struct {
+	mutex   sync.Mutex
+	results []fakeResult
+	// errors are returned by the statements holding their key
+	errors map[string]error
+	// statements are the statements sent, BEGIN, COMMIT and ROLLBACK
+	// included
+	statements []fakeStatement
+}
+
+// fakeResult holds the rows returned by the queries holding match
+type fakeResult struct {
+	match   string
+	columns []string
+	// types are the database types of the columns
+	types []string
+	rows  [][]driver.Value
+}
+
+type fakeStatement struct {
+	query string
+	args  []driver.Value
+}
+
+// openFakeDatabase returns a connection to a fake database, closed when the
+// test ends
+func openFakeDatabase(t *testing.T, results ...fakeResult) (*sql.DB, *fakeDatabase) {
+	t.Helper()
+
+	database := &fakeDatabase{results: results, errors: map[string]error{}}
+
+	connection := sql.OpenDB(database)
+	t.Cleanup(func() { connection.Close() })
+
+	return connection, database
+}
+
+// queries returns the statements sent, without their arguments
+func (database *fakeDatabase) queries() []string {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	queries := make([]string, len(database.statements))
+	for i, statement := range database.statements {
+		queries[i] = statement.query
+	}
+
+	return queries
+}
+
+func (database *fakeDatabase) record(query string, args []driver.Value) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.statements = append(database.statements, fakeStatement{query: query, args: args})
+
+	for match, err := range database.errors {
+		if strings.Contains(query, match) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (database *fakeDatabase) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{database: database}, nil
+}
+
+func (database *fakeDatabase) Driver() driver.Driver {
+	return fakeDriver{database: database}
+}
+
+type fakeDriver struct {
+	database *fakeDatabase
+}
+
+func (fake fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{database: fake.database}, nil
+}
+
+type fakeConn struct {
+	database *fakeDatabase
+}
+
+func (conn *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{database: conn.database, query: query}, nil
+}
+
+func (conn *fakeConn) Close() error {
+	return nil
+}
+
+func (conn *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{database: conn.database}, conn.database.record("BEGIN", nil)
+}
+
+type fakeTx struct {
+	database *fakeDatabase
+}
+
+func (tx fakeTx) Commit() error {
+	return tx.database.record("COMMIT", nil)
+}
+
+func (tx fakeTx) Rollback() error {
+	return tx.database.record("ROLLBACK", nil)
+}
+
+type fakeStmt struct {
+	database *fakeDatabase
+	query    string
+}
+
+func (stmt *fakeStmt) Close() error {
+	return nil
+}
+
+func (stmt *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (stmt *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	err := stmt.database.record(stmt.query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (stmt *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	err := stmt.database.record(stmt.query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range stmt.database.results {
+		if strings.Contains(stmt.query, result.match) {
+			return &fakeRows{result: result}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no result for %s", stmt.query)
+}
+
+type fakeRows struct {
+	result fakeResult
+	next   int
+}
+
+func (rows *fakeRows) Columns() []string {
+	return rows.result.columns
+}
+
+func (rows *fakeRows) ColumnTypeDatabaseTypeName(index int) string {
+	if index < len(rows.result.types) {
+		return rows.result.types[index]
+	}
+
+	return ""
+}
+
+func (rows *fakeRows) Close() error {
+	return nil
+}
+
+func (rows *fakeRows) Next(dest []driver.Value) error {
+	if rows.next == len(rows.result.rows) {
+		return io.EOF
+	}
+
+	copy(dest, rows.result.rows[rows.next])
+	rows.next++
+
+	return nil
+}
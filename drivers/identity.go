@@ -0,0 +1,110 @@
+package drivers
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// rowIdentity is how the rows of a table are identified: by its primary key
+// columns, or else by the row id of the database
+type rowIdentity struct {
+	primaryKeys []string
+	rowID       string
+}
+
+// identityCache keeps the identity of the tables of a driver, so that the
+// catalog is read once per table instead of on every load of its rows
+type identityCache struct {
+	mutex      sync.Mutex
+	identities map[string]rowIdentity
+}
+
+// get returns the identity of a table, read by lookup the first time. Errors
+// aren't kept, the identity is read again next time.
+func (cache *identityCache) get(table string, lookup func() ([]string, string, error)) ([]string, string, error) {
+	cache.mutex.Lock()
+	identity, ok := cache.identities[table]
+	cache.mutex.Unlock()
+
+	if ok {
+		return identity.primaryKeys, identity.rowID, nil
+	}
+
+	primaryKeys, rowID, err := lookup()
+	if err != nil {
+		return nil, "", err
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.identities == nil {
+		cache.identities = make(map[string]rowIdentity)
+	}
+
+	cache.identities[table] = rowIdentity{primaryKeys: primaryKeys, rowID: rowID}
+
+	return primaryKeys, rowID, nil
+}
+
+// firstSafeUniqueIndex reads rows of (index name, column name, not null)
+// ordered by index and column position, and returns the columns of the first
+// unique index whose columns are all NOT NULL. Such an index identifies a row
+// as safely as a primary key does.
+func firstSafeUniqueIndex(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	indexOrder := []string{}
+	indexColumns := make(map[string][]string)
+	unsafeIndexes := make(map[string]bool)
+
+	for rows.Next() {
+		var indexName, columnName string
+		var notNull bool
+
+		err := rows.Scan(&indexName, &columnName, &notNull)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := indexColumns[indexName]; !ok {
+			indexOrder = append(indexOrder, indexName)
+		}
+
+		indexColumns[indexName] = append(indexColumns[indexName], columnName)
+
+		if !notNull {
+			unsafeIndexes[indexName] = true
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, indexName := range indexOrder {
+		if !unsafeIndexes[indexName] {
+			return indexColumns[indexName], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// scanColumnNames reads a single column of names
+func scanColumnNames(rows *sql.Rows) (names []string, err error) {
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
@@ -0,0 +1,221 @@
+package drivers
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFirstSafeUniqueIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     [][]driver.Value
+		expected []string
+	}{
+		{
+			name:     "no unique index",
+			expected: nil,
+		},
+		{
+			name: "first index over NOT NULL columns",
+			rows: [][]driver.Value{
+				{"a_nullable", "a", false},
+				{"b_composite", "c", true},
+				{"b_composite", "b", true},
+				{"c_single", "d", true},
+			},
+			expected: []string{"c", "b"},
+		},
+		{
+			name: "one nullable column makes the index unsafe",
+			rows: [][]driver.Value{
+				{"a_composite", "a", true},
+				{"a_composite", "b", false},
+			},
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connection, _ := openFakeDatabase(t, fakeResult{
+				match:   "SELECT",
+				columns: []string{"index_name", "column_name", "not_null"},
+				rows:    test.rows,
+			})
+
+			rows, err := connection.Query("SELECT")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			columns, err := firstSafeUniqueIndex(rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(columns, test.expected) {
+				t.Errorf("firstSafeUniqueIndex() = %v, want %v", columns, test.expected)
+			}
+		})
+	}
+}
+
+func TestIdentityCache(t *testing.T) {
+	var cache identityCache
+
+	lookups := 0
+	failing := func() ([]string, string, error) {
+		lookups++
+		return nil, "", errors.New("catalog unavailable")
+	}
+	succeeding := func() ([]string, string, error) {
+		lookups++
+		return nil, "ctid", nil
+	}
+
+	_, _, err := cache.get("t", failing)
+	if err == nil {
+		t.Fatal("the error of the lookup was lost")
+	}
+
+	for i := 0; i < 2; i++ {
+		_, rowID, err := cache.get("t", succeeding)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rowID != "ctid" {
+			t.Errorf("rowID = %q, want ctid", rowID)
+		}
+	}
+
+	if lookups != 2 {
+		t.Errorf("lookups = %d, want 2: errors are read again, identities once", lookups)
+	}
+}
+
+func TestSQLiteGetPrimaryKeyColumnNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   []string
+		expected []string
+	}{
+		{
+			name:     "primary key, in its order",
+			schema:   []string{"CREATE TABLE t (a INTEGER, b TEXT NOT NULL UNIQUE, PRIMARY KEY (b, a))"},
+			expected: []string{"b", "a"},
+		},
+		{
+			name:     "unique index over NOT NULL columns",
+			schema:   []string{"CREATE TABLE t (a INTEGER UNIQUE, b INTEGER NOT NULL, c INTEGER NOT NULL)", "CREATE UNIQUE INDEX b_c ON t (c, b)"},
+			expected: []string{"c", "b"},
+		},
+		{
+			name:     "rowid when the unique index is nullable",
+			schema:   []string{"CREATE TABLE t (a INTEGER UNIQUE)"},
+			expected: []string{"rowid"},
+		},
+		{
+			name:     "rowid when the unique index is partial",
+			schema:   []string{"CREATE TABLE t (a INTEGER NOT NULL)", "CREATE UNIQUE INDEX a ON t (a) WHERE a > 0"},
+			expected: []string{"rowid"},
+		},
+		{
+			name:     "alias of the rowid not taken by a column",
+			schema:   []string{"CREATE TABLE t (rowid TEXT, a INTEGER)"},
+			expected: []string{"_rowid_"},
+		},
+		{
+			name:     "no identity for a view",
+			schema:   []string{"CREATE TABLE u (a INTEGER)", "CREATE VIEW t AS SELECT a FROM u"},
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := &SQLite{}
+
+			err := db.Connect(context.Background(), "sqlite:"+filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			for _, statement := range test.schema {
+				_, err = db.Connection.Exec(statement)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			columns, err := db.GetPrimaryKeyColumnNames(context.Background(), "", "t")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(columns, test.expected) {
+				t.Errorf("GetPrimaryKeyColumnNames() = %v, want %v", columns, test.expected)
+			}
+		})
+	}
+}
+
+func TestPostgresGetPrimaryKeyColumnNames(t *testing.T) {
+	primaryKey := func(rows ...[]driver.Value) fakeResult {
+		return fakeResult{match: "indisprimary", columns: []string{"attname"}, rows: rows}
+	}
+	uniqueIndexes := func(rows ...[]driver.Value) fakeResult {
+		return fakeResult{match: "indisunique", columns: []string{"indexrelid", "attname", "attnotnull"}, rows: rows}
+	}
+	relkind := func(kind string) fakeResult {
+		return fakeResult{match: "relkind", columns: []string{"relkind"}, rows: [][]driver.Value{{kind}}}
+	}
+
+	tests := []struct {
+		name     string
+		results  []fakeResult
+		expected []string
+	}{
+		{
+			name:     "primary key",
+			results:  []fakeResult{primaryKey([]driver.Value{"id"}), uniqueIndexes([]driver.Value{"u", "email", true}), relkind("r")},
+			expected: []string{"id"},
+		},
+		{
+			name:     "unique index over NOT NULL columns",
+			results:  []fakeResult{primaryKey(), uniqueIndexes([]driver.Value{"u", "email", true}), relkind("r")},
+			expected: []string{"email"},
+		},
+		{
+			name:     "ctid of a plain table",
+			results:  []fakeResult{primaryKey(), uniqueIndexes([]driver.Value{"u", "email", false}), relkind("r")},
+			expected: []string{"ctid"},
+		},
+		{
+			name:     "no identity for a view",
+			results:  []fakeResult{primaryKey(), uniqueIndexes(), relkind("v")},
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connection, _ := openFakeDatabase(t, test.results...)
+			db := &Postgres{Connection: connection}
+
+			columns, err := db.GetPrimaryKeyColumnNames(context.Background(), "app", "public.t")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(columns, test.expected) {
+				t.Errorf("GetPrimaryKeyColumnNames() = %v, want %v", columns, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,479 @@
+package drivers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// ImportFormats lists the formats files are imported from. JSON files hold
+// an array of objects or an object per line.
+var ImportFormats = []ExportFormat{CSVExport, TSVExport, JSONExport}
+
+// ImportFormatOf returns the format of a file to import from its extension,
+// CSV when the extension isn't known.
+func ImportFormatOf(path string) ExportFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsv", ".tab":
+		return TSVExport
+	case ".json", ".ndjson", ".jsonl":
+		return JSONExport
+	}
+
+	return CSVExport
+}
+
+// ReadRows reads the columns and the rows of a file to import. CSV and TSV
+// files start with the column names when header is true, otherwise columns
+// are named column1, column2 and so on. Their empty fields are read as NULL
+// when emptyIsNull is true. The columns of a JSON file are the keys of its
+// objects in the order they are first seen, null is read as NULL and nested
+// objects and arrays as their JSON text. Missing fields are read as NULL.
+func ReadRows(format ExportFormat, input io.Reader, header, emptyIsNull bool) (columns []string, rows [][]models.CellValue, err error) {
+	switch format {
+	case CSVExport:
+		reader := csv.NewReader(input)
+		reader.FieldsPerRecord = -1
+
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		columns, rows = delimitedRows(records, header, emptyIsNull)
+	case TSVExport:
+		records, err := readTSV(input)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		columns, rows = delimitedRows(records, header, emptyIsNull)
+	case JSONExport:
+		columns, rows, err = readJSON(input)
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("can't import %s files", format)
+	}
+
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("the file has no columns")
+	}
+
+	return columns, rows, nil
+}
+
+// delimitedRows splits the records of a CSV or TSV file into columns and rows
+func delimitedRows(records [][]string, header, emptyIsNull bool) ([]string, [][]models.CellValue) {
+	columns := []string{}
+
+	if header && len(records) > 0 {
+		columns = records[0]
+		records = records[1:]
+	}
+
+	for _, record := range records {
+		for len(columns) < len(record) {
+			columns = append(columns, fmt.Sprintf("column%d", len(columns)+1))
+		}
+	}
+
+	rows := make([][]models.CellValue, len(records))
+
+	for i, record := range records {
+		row := make([]models.CellValue, len(columns))
+
+		for j := range row {
+			if j >= len(record) || (emptyIsNull && record[j] == "") {
+				row[j] = models.CellValue{Type: models.Null}
+			} else {
+				row[j] = models.CellValue{Type: models.String, Value: record[j]}
+			}
+		}
+
+		rows[i] = row
+	}
+
+	return columns, rows
+}
+
+// readTSV reads tab separated lines, undoing the escapes of the TSV export
+func readTSV(input io.Reader) ([][]string, error) {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	records := [][]string{}
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		for i, field := range fields {
+			fields[i] = tsvUnescape(field)
+		}
+
+		records = append(records, fields)
+	}
+
+	return records, scanner.Err()
+}
+
+func tsvUnescape(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var unescaped strings.Builder
+
+	for i := 0; i < len(field); i++ {
+		if field[i] != '\\' || i == len(field)-1 {
+			unescaped.WriteByte(field[i])
+			continue
+		}
+
+		i++
+
+		switch field[i] {
+		case 't':
+			unescaped.WriteByte('\t')
+		case 'n':
+			unescaped.WriteByte('\n')
+		case 'r':
+			unescaped.WriteByte('\r')
+		default:
+			unescaped.WriteByte(field[i])
+		}
+	}
+
+	return unescaped.String()
+}
+
+// readJSON reads an array of objects, or a sequence of objects such as one
+// per line
+func readJSON(input io.Reader) ([]string, [][]models.CellValue, error) {
+	decoder := json.NewDecoder(bufio.NewReader(input))
+	decoder.UseNumber()
+
+	objects := []map[string]json.RawMessage{}
+	columns := []string{}
+	seen := map[string]bool{}
+
+	// The keys are read one by one to keep them in the order of the file.
+	// opened is true when the opening brace was already read.
+	readObject := func(opened bool) error {
+		if !opened {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+
+			if delim, ok := token.(json.Delim); !ok || delim != '{' {
+				return fmt.Errorf("expected an object, found %v", token)
+			}
+		}
+
+		object := map[string]json.RawMessage{}
+
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+
+			key := token.(string)
+
+			var value json.RawMessage
+
+			err = decoder.Decode(&value)
+			if err != nil {
+				return err
+			}
+
+			object[key] = value
+
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+
+		// The closing brace
+		_, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, object)
+
+		return nil
+	}
+
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("the file is empty")
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+
+	switch {
+	case ok && delim == '[':
+		for decoder.More() {
+			err = readObject(false)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	case ok && delim == '{':
+		err = readObject(true)
+
+		for err == nil && decoder.More() {
+			err = readObject(false)
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("expected an array or objects, found %v", token)
+	}
+
+	rows := make([][]models.CellValue, len(objects))
+
+	for i, object := range objects {
+		row := make([]models.CellValue, len(columns))
+
+		for j, column := range columns {
+			row[j] = jsonValue(object[column])
+		}
+
+		rows[i] = row
+	}
+
+	return columns, rows, nil
+}
+
+// jsonValue returns the cell value a JSON value is imported as
+func jsonValue(value json.RawMessage) models.CellValue {
+	if value == nil || string(value) == "null" {
+		return models.CellValue{Type: models.Null}
+	}
+
+	var text string
+	if json.Unmarshal(value, &text) == nil {
+		return models.CellValue{Type: models.String, Value: text}
+	}
+
+	return models.CellValue{Type: models.String, Value: string(value)}
+}
+
+// CheckValue tells whether a value can be stored in a column of the given
+// type, as returned by GetTableColumns. NULL, DEFAULT and the values of types
+// that aren't known are accepted, nullability is left to the database.
+func (d Dialect) CheckValue(columnType string, cell models.CellValue) error {
+	if cell.Type != models.String {
+		return nil
+	}
+
+	value := cell.Value
+
+	name, length := parseColumnType(columnType)
+	unsigned := strings.Contains(strings.ToLower(columnType), "unsigned")
+	trimmed := strings.TrimSpace(value)
+
+	switch name {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "int2", "int4", "int8", "serial", "smallserial", "bigserial":
+		bits := integerBits[name]
+		if d.typeAffinity {
+			bits = 64
+		}
+
+		var err error
+		if unsigned {
+			_, err = strconv.ParseUint(trimmed, 10, bits)
+		} else {
+			_, err = strconv.ParseInt(trimmed, 10, bits)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%q isn't an integer of %d bits", value, bits)
+		}
+	case "decimal", "numeric", "real", "float", "float4", "float8", "double", "money", "smallmoney":
+		if _, err := strconv.ParseFloat(trimmed, 64); err != nil {
+			return fmt.Errorf("%q isn't a number", value)
+		}
+	case "bool", "boolean", "bit":
+		if name == "bit" && length > 1 {
+			return nil
+		}
+
+		switch strings.ToLower(trimmed) {
+		case "0", "1", "t", "f", "true", "false", "y", "n", "yes", "no", "on", "off":
+		default:
+			return fmt.Errorf("%q isn't a boolean", value)
+		}
+	case "date":
+		if !parsesAs(trimmed, dateLayouts) {
+			return fmt.Errorf("%q isn't a date, e.g. 2006-01-02", value)
+		}
+	case "datetime", "datetime2", "smalldatetime", "datetimeoffset", "timestamp", "timestamptz":
+		if !parsesAs(trimmed, timestampLayouts) {
+			return fmt.Errorf("%q isn't a timestamp, e.g. 2006-01-02 15:04:05", value)
+		}
+	case "time", "timetz":
+		if !parsesAs(trimmed, timeLayouts) {
+			return fmt.Errorf("%q isn't a time, e.g. 15:04:05", value)
+		}
+	case "uuid", "uniqueidentifier":
+		if _, err := uuid.Parse(trimmed); err != nil {
+			return fmt.Errorf("%q isn't a UUID", value)
+		}
+	case "json", "jsonb":
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("%q isn't valid JSON", value)
+		}
+	case "char", "varchar", "nchar", "nvarchar", "character", "varying":
+		if length > 0 && !d.typeAffinity && utf8.RuneCountInString(value) > length {
+			return fmt.Errorf("%q is longer than %d characters", value, length)
+		}
+	}
+
+	return nil
+}
+
+var integerBits = map[string]int{
+	"tinyint":     8,
+	"smallint":    16,
+	"int2":        16,
+	"smallserial": 16,
+	"mediumint":   32,
+	"int":         32,
+	"integer":     32,
+	"int4":        32,
+	"serial":      32,
+	"bigint":      64,
+	"int8":        64,
+	"bigserial":   64,
+}
+
+var (
+	dateLayouts      = []string{"2006-01-02"}
+	timestampLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02 15:04:05.999999999Z07:00",
+		"2006-01-02 15:04:05.999999999 Z07:00",
+		"2006-01-02 15:04:05.999999999-07",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+	timeLayouts = []string{"15:04:05.999999999", "15:04:05.999999999Z07:00", "15:04:05.999999999-07", "15:04"}
+)
+
+func parsesAs(value string, layouts []string) bool {
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseColumnType returns the lower case name of a column type and its
+// length, e.g. varchar and 255 for VARCHAR(255). The length is 0 when the
+// type has none.
+func parseColumnType(columnType string) (string, int) {
+	columnType = strings.ToLower(strings.TrimSpace(columnType))
+
+	name := columnType
+	if end := strings.IndexAny(name, "( "); end >= 0 {
+		name = name[:end]
+	}
+
+	// character varying(255) is named after its second word
+	if name == "character" && strings.HasPrefix(columnType, "character varying") {
+		name = "varying"
+	}
+
+	length := 0
+
+	if start := strings.Index(columnType, "("); start >= 0 {
+		if end := strings.IndexAny(columnType[start:], ",)"); end > 0 {
+			length, _ = strconv.Atoi(strings.TrimSpace(columnType[start+1 : start+end]))
+		}
+	}
+
+	return name, length
+}
+
+// ImportError is a row that couldn't be inserted.
+type ImportError struct {
+	// Row is the index of the row among the rows imported
+	Row int
+	Err error
+}
+
+// ImportRows inserts rows through the pending changes of the driver, in a
+// transaction per batch of rows. When a batch fails its rows are inserted one
+// at a time, so that only the rows that fail are left out. progress, when not
+// nil, is called after every batch with how many rows were tried. The error
+// is the error of the context, when it ends the import early.
+func ImportRows(ctx context.Context, driver Driver, inserts []models.DbInsert, batchSize int, progress func(done int)) (inserted int, failed []ImportError, err error) {
+	for start := 0; start < len(inserts); start += batchSize {
+		end := start + batchSize
+		if end > len(inserts) {
+			end = len(inserts)
+		}
+
+		batchErr := driver.ExecutePendingChanges(ctx, nil, inserts[start:end])
+
+		if ctx.Err() != nil {
+			return inserted, failed, ctx.Err()
+		}
+
+		if batchErr == nil {
+			inserted += end - start
+		} else if end-start == 1 {
+			failed = append(failed, ImportError{Row: start, Err: batchErr})
+		} else {
+			for i := start; i < end; i++ {
+				rowErr := driver.ExecutePendingChanges(ctx, nil, inserts[i:i+1])
+
+				if ctx.Err() != nil {
+					return inserted, failed, ctx.Err()
+				}
+
+				if rowErr != nil {
+					failed = append(failed, ImportError{Row: i, Err: rowErr})
+				} else {
+					inserted++
+				}
+			}
+		}
+
+		if progress != nil {
+			progress(end)
+		}
+	}
+
+	return inserted, failed, nil
+}
@@ -0,0 +1,299 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+func TestImportFormatOf(t *testing.T) {
+	tests := map[string]ExportFormat{
+		"users.csv":    CSVExport,
+		"users.TSV":    TSVExport,
+		"users.tab":    TSVExport,
+		"users.json":   JSONExport,
+		"users.ndjson": JSONExport,
+		"users.jsonl":  JSONExport,
+		"users.txt":    CSVExport,
+		"users":        CSVExport,
+	}
+
+	for path, expected := range tests {
+		if format := ImportFormatOf(path); format != expected {
+			t.Errorf("ImportFormatOf(%s) = %s, want %s", path, format, expected)
+		}
+	}
+}
+
+func TestReadRows(t *testing.T) {
+	null := models.CellValue{Type: models.Null}
+
+	tests := []struct {
+		name            string
+		format          ExportFormat
+		input           string
+		header          bool
+		emptyIsNull     bool
+		expectedColumns []string
+		expectedRows    [][]models.CellValue
+	}{
+		{
+			name:            "CSV with a header",
+			format:          CSVExport,
+			input:           "id,name\n1,\"O'Brien, \"\"Al\"\"\"\n2,\n",
+			header:          true,
+			expectedColumns: []string{"id", "name"},
+			expectedRows: [][]models.CellValue{
+				{{Value: "1"}, {Value: `O'Brien, "Al"`}},
+				{{Value: "2"}, {Value: ""}},
+			},
+		},
+		{
+			name:            "CSV without a header, empty fields as NULL",
+			format:          CSVExport,
+			input:           "1,a\n2,,x\n3\n",
+			emptyIsNull:     true,
+			expectedColumns: []string{"column1", "column2", "column3"},
+			expectedRows: [][]models.CellValue{
+				{{Value: "1"}, {Value: "a"}, null},
+				{{Value: "2"}, null, {Value: "x"}},
+				{{Value: "3"}, null, null},
+			},
+		},
+		{
+			name:            "TSV escapes",
+			format:          TSVExport,
+			input:           "id\tname\r\n1\ta\\tb\\nc\\\\\n\n2\t\n",
+			header:          true,
+			expectedColumns: []string{"id", "name"},
+			expectedRows: [][]models.CellValue{
+				{{Value: "1"}, {Value: "a\tb\nc\\"}},
+				{{Value: "2"}, {Value: ""}},
+			},
+		},
+		{
+			name:            "TSV empty fields as NULL",
+			format:          TSVExport,
+			input:           "1\t\n",
+			emptyIsNull:     true,
+			expectedColumns: []string{"column1", "column2"},
+			expectedRows:    [][]models.CellValue{{{Value: "1"}, null}},
+		},
+		{
+			name:            "JSON array",
+			format:          JSONExport,
+			input:           `[{"id": 1, "name": "a", "tags": ["x"]}, {"name": null, "id": 2.5, "extra": {"k": true}}]`,
+			expectedColumns: []string{"id", "name", "tags", "extra"},
+			expectedRows: [][]models.CellValue{
+				{{Value: "1"}, {Value: "a"}, {Value: `["x"]`}, null},
+				{{Value: "2.5"}, null, null, {Value: `{"k": true}`}},
+			},
+		},
+		{
+			name:            "JSON object per line",
+			format:          JSONExport,
+			input:           "{\"id\": \"1\"}\n{\"id\": \"2\", \"ok\": false}\n",
+			expectedColumns: []string{"id", "ok"},
+			expectedRows: [][]models.CellValue{
+				{{Value: "1"}, null},
+				{{Value: "2"}, {Value: "false"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			columns, rows, err := ReadRows(test.format, strings.NewReader(test.input), test.header, test.emptyIsNull)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(columns, test.expectedColumns) {
+				t.Errorf("columns = %q, want %q", columns, test.expectedColumns)
+			}
+
+			if !reflect.DeepEqual(rows, test.expectedRows) {
+				t.Errorf("rows = %v, want %v", rows, test.expectedRows)
+			}
+		})
+	}
+}
+
+func TestReadRowsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format ExportFormat
+		input  string
+	}{
+		{"empty CSV", CSVExport, ""},
+		{"unterminated CSV quote", CSVExport, "id\n\"1\n"},
+		{"empty JSON", JSONExport, ""},
+		{"JSON of a value", JSONExport, "42"},
+		{"JSON array of values", JSONExport, "[1, 2]"},
+		{"truncated JSON", JSONExport, `[{"id": 1}`},
+		{"unknown format", MarkdownExport, "| id |"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := ReadRows(test.format, strings.NewReader(test.input), true, false)
+			if err == nil {
+				t.Errorf("ReadRows(%q) didn't fail", test.input)
+			}
+		})
+	}
+}
+
+func TestCheckValue(t *testing.T) {
+	tests := []struct {
+		dialect    Dialect
+		columnType string
+		value      string
+		valid      bool
+	}{
+		{PostgresDialect, "integer", " 42 ", true},
+		{PostgresDialect, "integer", "4.2", false},
+		{PostgresDialect, "integer", "99999999999", false},
+		{SQLiteDialect, "INTEGER", "99999999999", true},
+		{MySQLDialect, "tinyint(4)", "300", false},
+		{MySQLDialect, "tinyint unsigned", "255", true},
+		{MySQLDialect, "int unsigned", "-1", false},
+		{PostgresDialect, "numeric(10,2)", "1e3", true},
+		{PostgresDialect, "numeric(10,2)", "ten", false},
+		{PostgresDialect, "boolean", "Yes", true},
+		{PostgresDialect, "boolean", "maybe", false},
+		{MSSQLDialect, "bit", "2", false},
+		{PostgresDialect, "bit(8)", "10100101", true},
+		{PostgresDialect, "date", "2024-02-29", true},
+		{PostgresDialect, "date", "2023-02-29", false},
+		{PostgresDialect, "timestamp with time zone", "2024-01-02 03:04:05+02", true},
+		{MySQLDialect, "datetime", "2024-01-02T03:04:05Z", true},
+		{MySQLDialect, "datetime", "yesterday", false},
+		{PostgresDialect, "time", "15:04", true},
+		{PostgresDialect, "time", "25:00", false},
+		{MSSQLDialect, "uniqueidentifier", "6F9619FF-8B86-D011-B42D-00C04FC964FF", true},
+		{PostgresDialect, "uuid", "6f9619ff", false},
+		{PostgresDialect, "jsonb", `{"a": [1]}`, true},
+		{PostgresDialect, "jsonb", "{a}", false},
+		{PostgresDialect, "varchar(3)", "abé", true},
+		{PostgresDialect, "varchar(3)", "abcd", false},
+		{PostgresDialect, "character varying(2)", "abc", false},
+		{SQLiteDialect, "VARCHAR(3)", "abcd", true},
+		{PostgresDialect, "geometry", "anything", true},
+	}
+
+	for _, test := range tests {
+		err := test.dialect.CheckValue(test.columnType, models.CellValue{Value: test.value})
+
+		if (err == nil) != test.valid {
+			t.Errorf("CheckValue(%s, %q) = %v, want valid: %t", test.columnType, test.value, err, test.valid)
+		}
+	}
+
+	for _, cell := range []models.CellValue{{Type: models.Null}, {Type: models.Default}} {
+		if err := PostgresDialect.CheckValue("integer", cell); err != nil {
+			t.Errorf("CheckValue(integer) of a cell of type %d = %v, want it accepted", cell.Type, err)
+		}
+	}
+}
+
+// fakeImporter is a driver whose inserts fail when they hold a value of
+// failing, and records the first value of the rows of every call
+type fakeImporter struct {
+	Driver
+	failing map[string]bool
+	calls   [][]string
+	// cancel, when set, is called on the call of that index
+	cancel     func()
+	cancelCall int
+}
+
+func (driver *fakeImporter) ExecutePendingChanges(_ context.Context, _ []models.DbDmlChange, inserts []models.DbInsert) error {
+	call := []string{}
+	var err error
+
+	for _, insert := range inserts {
+		call = append(call, insert.Values[0].Value)
+
+		if driver.failing[insert.Values[0].Value] {
+			err = errors.New("rejected " + insert.Values[0].Value)
+		}
+	}
+
+	driver.calls = append(driver.calls, call)
+
+	if driver.cancel != nil && len(driver.calls)-1 == driver.cancelCall {
+		driver.cancel()
+	}
+
+	return err
+}
+
+func importInserts(count int) []models.DbInsert {
+	inserts := make([]models.DbInsert, count)
+
+	for i := range inserts {
+		inserts[i] = models.DbInsert{Table: "t", Columns: []string{"n"}, Values: []models.CellValue{{Value: string(rune('a' + i))}}}
+	}
+
+	return inserts
+}
+
+func TestImportRows(t *testing.T) {
+	driver := &fakeImporter{failing: map[string]bool{"b": true, "e": true}}
+
+	var progress []int
+
+	inserted, failed, err := ImportRows(context.Background(), driver, importInserts(5), 2, func(done int) {
+		progress = append(progress, done)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inserted != 3 {
+		t.Errorf("inserted = %d, want 3", inserted)
+	}
+
+	var failedRows []int
+	for _, failure := range failed {
+		failedRows = append(failedRows, failure.Row)
+	}
+
+	if !reflect.DeepEqual(failedRows, []int{1, 4}) || failed[0].Err.Error() != "rejected b" {
+		t.Errorf("failed = %v, want the rows 1 and 4", failed)
+	}
+
+	// The failed batch is inserted again a row at a time, the last batch of a
+	// single row isn't
+	expectedCalls := [][]string{{"a", "b"}, {"a"}, {"b"}, {"c", "d"}, {"e"}}
+	if !reflect.DeepEqual(driver.calls, expectedCalls) {
+		t.Errorf("calls = %q, want %q", driver.calls, expectedCalls)
+	}
+
+	if !reflect.DeepEqual(progress, []int{2, 4, 5}) {
+		t.Errorf("progress = %v, want [2 4 5]", progress)
+	}
+}
+
+func TestImportRowsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The import is cancelled while the rows of the failed batch are
+	// inserted one at a time
+	driver := &fakeImporter{failing: map[string]bool{"a": true}, cancel: cancel, cancelCall: 1}
+
+	inserted, failed, err := ImportRows(ctx, driver, importInserts(4), 2, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	if inserted != 0 || len(failed) != 0 || len(driver.calls) != 2 {
+		t.Errorf("%d inserted, %d failed in %d calls, want the import to stop at the second call", inserted, len(failed), len(driver.calls))
+	}
+}
@@ -0,0 +1,340 @@
+package drivers
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// metadataTimeout bounds every query the metadata cache runs.
+const metadataTimeout = 30 * time.Second
+
+// MetadataCache keeps the tables and columns of the databases of a connection
+// for the completion of the editor. They are read in the background the first
+// time they are asked for, and refreshed every interval, so that completing
+// never waits on the database.
+type MetadataCache struct {
+	driver    Driver
+	mutex     sync.Mutex
+	databases map[string]*databaseMetadata
+	// loading holds the databases and tables being read
+	loading     map[string]bool
+	refreshing  bool
+	subscribers []chan models.StateChange
+}
+
+type databaseMetadata struct {
+	// tables are the tables by schema, as returned by GetTables
+	tables map[string][]string
+	// columns are the column names by table name, as passed to GetTableColumns
+	columns map[string][]string
+}
+
+// NewMetadataCache returns an empty cache that refreshes what it has read
+// every interval.
+func NewMetadataCache(driver Driver, interval time.Duration) *MetadataCache {
+	cache := &MetadataCache{
+		driver:    driver,
+		databases: make(map[string]*databaseMetadata),
+		loading:   make(map[string]bool),
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			cache.Refresh()
+		}
+	}()
+
+	return cache
+}
+
+// Subscribe returns a channel that is published to when tables or columns
+// have been read.
+func (cache *MetadataCache) Subscribe() chan models.StateChange {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	subscriber := make(chan models.StateChange)
+	cache.subscribers = append(cache.subscribers, subscriber)
+
+	return subscriber
+}
+
+// Source returns the completion source of a database.
+func (cache *MetadataCache) Source(database string) CompletionSource {
+	return &metadataSource{cache: cache, database: database}
+}
+
+// Refresh reads again, in the background, the tables and columns read so far.
+func (cache *MetadataCache) Refresh() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.refreshing {
+		return
+	}
+
+	cache.refreshing = true
+
+	go func() {
+		cache.mutex.Lock()
+		databases := make(map[string][]string, len(cache.databases))
+		for database, metadata := range cache.databases {
+			databases[database] = []string{}
+			for table := range metadata.columns {
+				databases[database] = append(databases[database], table)
+			}
+		}
+		cache.mutex.Unlock()
+
+		for database, tables := range databases {
+			cache.readTables(database, tables)
+		}
+
+		cache.mutex.Lock()
+		cache.refreshing = false
+		cache.mutex.Unlock()
+
+		cache.notify()
+	}()
+}
+
+// tables returns the tables of a database by schema, and starts reading them
+// when they aren't known yet.
+func (cache *MetadataCache) tables(database string) map[string][]string {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if metadata, ok := cache.databases[database]; ok {
+		return metadata.tables
+	}
+
+	cache.load(database, func() {
+		cache.readTables(database, nil)
+	})
+
+	return nil
+}
+
+// columns returns the columns of a table, and starts reading them when they
+// aren't known yet.
+func (cache *MetadataCache) columns(database, table string) []string {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	metadata, ok := cache.databases[database]
+	if !ok {
+		return nil
+	}
+
+	if columns, ok := metadata.columns[table]; ok {
+		return columns
+	}
+
+	cache.load(database+"."+table, func() {
+		columns := cache.readColumns(database, table)
+
+		cache.mutex.Lock()
+		if metadata, ok := cache.databases[database]; ok {
+			metadata.columns[table] = columns
+		}
+		cache.mutex.Unlock()
+	})
+
+	return nil
+}
+
+// load runs read in the background, unless the same key is being read. It is
+// called with the mutex locked.
+func (cache *MetadataCache) load(key string, read func()) {
+	if cache.loading[key] {
+		return
+	}
+
+	cache.loading[key] = true
+
+	go func() {
+		read()
+
+		cache.mutex.Lock()
+		delete(cache.loading, key)
+		cache.mutex.Unlock()
+
+		cache.notify()
+	}()
+}
+
+// readTables reads the tables of a database, then the columns of the given
+// tables that still exist.
+func (cache *MetadataCache) readTables(database string, columnsOf []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	tables, err := cache.driver.GetTables(ctx, database)
+	cancel()
+
+	if err != nil {
+		// Completion goes on with what is known, the next refresh tries again
+		cache.mutex.Lock()
+		if _, ok := cache.databases[database]; !ok {
+			cache.databases[database] = &databaseMetadata{
+				tables:  make(map[string][]string),
+				columns: make(map[string][]string),
+			}
+		}
+		cache.mutex.Unlock()
+
+		return
+	}
+
+	metadata := &databaseMetadata{
+		tables:  tables,
+		columns: make(map[string][]string),
+	}
+
+	for _, table := range columnsOf {
+		if metadata.hasTable(table, cache.plainTableNames()) {
+			metadata.columns[table] = cache.readColumns(database, table)
+		}
+	}
+
+	cache.mutex.Lock()
+	cache.databases[database] = metadata
+	cache.mutex.Unlock()
+}
+
+func (cache *MetadataCache) readColumns(database, table string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+
+	rows, err := cache.driver.GetTableColumns(ctx, database, table)
+	if err != nil {
+		return nil
+	}
+
+	columns := []string{}
+
+	// The first row holds the headers
+	for i, row := range rows {
+		if i > 0 && len(row) > 0 {
+			columns = append(columns, row[0].Value)
+		}
+	}
+
+	return columns
+}
+
+func (cache *MetadataCache) notify() {
+	cache.mutex.Lock()
+	subscribers := cache.subscribers
+	cache.mutex.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber <- models.StateChange{Key: "Metadata"}
+	}
+}
+
+func (cache *MetadataCache) plainTableNames() bool {
+	return CapabilitiesOf(cache.driver.GetProvider()).PlainTableNames
+}
+
+// hasTable tells whether a table, named as passed to GetTableColumns, exists.
+func (metadata *databaseMetadata) hasTable(table string, plainTableNames bool) bool {
+	for schema, tables := range metadata.tables {
+		for _, name := range tables {
+			if (plainTableNames && name == table) || schema+"."+name == table {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// metadataSource completes the schemas, tables and columns of a database.
+type metadataSource struct {
+	cache    *MetadataCache
+	database string
+}
+
+func (source *metadataSource) Schemas() []string {
+	if source.cache.plainTableNames() {
+		return nil
+	}
+
+	schemas := []string{}
+	for schema := range source.cache.tables(source.database) {
+		schemas = append(schemas, schema)
+	}
+
+	sort.Strings(schemas)
+
+	return schemas
+}
+
+func (source *metadataSource) Tables(schema string) []string {
+	tables := []string{}
+
+	for name, schemaTables := range source.cache.tables(source.database) {
+		if schema == "" || strings.EqualFold(name, schema) {
+			tables = append(tables, schemaTables...)
+		}
+	}
+
+	sort.Strings(tables)
+
+	return tables
+}
+
+func (source *metadataSource) Columns(table string) []string {
+	name, ok := source.tableName(table)
+	if !ok {
+		return nil
+	}
+
+	return source.cache.columns(source.database, name)
+}
+
+// tableName returns the name a table is read with by GetTableColumns, e.g.
+// schema.table, in the case of the database.
+func (source *metadataSource) tableName(table string) (string, bool) {
+	plainTableNames := source.cache.plainTableNames()
+	parts := strings.Split(table, ".")
+	name := parts[len(parts)-1]
+	schema := ""
+	if len(parts) > 1 {
+		schema = parts[len(parts)-2]
+	}
+
+	tables := source.cache.tables(source.database)
+
+	// Sorted, so that the same table is picked among several schemas
+	schemas := make([]string, 0, len(tables))
+	for key := range tables {
+		schemas = append(schemas, key)
+	}
+
+	sort.Strings(schemas)
+
+	for _, key := range schemas {
+		if schema != "" && !plainTableNames && !strings.EqualFold(key, schema) {
+			continue
+		}
+
+		for _, tableName := range tables[key] {
+			if !strings.EqualFold(tableName, name) {
+				continue
+			}
+
+			if plainTableNames {
+				return tableName, true
+			}
+
+			return key + "." + tableName, true
+		}
+	}
+
+	return "", false
+}
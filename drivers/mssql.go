@@ -0,0 +1,490 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jorgerojas26/lazysql/models"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/xo/dburl"
+)
+
+type MSSQL struct {
+	Connection *sql.DB
+	Provider   string
+	// CurrentDatabase is the database the tables are in, a single connection
+	// browses every database of the server
+	CurrentDatabase string
+	// borrowed is true for the drivers returned by ForDatabase, the
+	// connection is closed by the driver that returned them
+	borrowed bool
+}
+
+func init() {
+	Register(Registration{
+		Provider:     "sqlserver",
+		Schemes:      []string{"sqlserver", "ms", "mssql"},
+		Dialect:      MSSQLDialect,
+		Capabilities: Capabilities{DatabaseInQuery: true, SchemaTableNames: true},
+		DefaultPort:  "1433",
+		New: func() Driver {
+			return &MSSQL{}
+		},
+	})
+}
+
+func (db *MSSQL) TestConnection(ctx context.Context, urlstr string) (err error) {
+	return db.Connect(ctx, urlstr)
+}
+
+func (db *MSSQL) Connect(ctx context.Context, urlstr string) (err error) {
+	db.SetProvider("sqlserver")
+
+	db.Connection, err = dburl.Open(urlstr)
+	if err != nil {
+		return err
+	}
+
+	err = db.Connection.PingContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Connection.QueryRowContext(ctx, "SELECT DB_NAME()").Scan(&db.CurrentDatabase)
+}
+
+func (db *MSSQL) GetDatabases(ctx context.Context) ([]string, error) {
+	var databases []string
+
+	// The first four databases are master, tempdb, model and msdb
+	rows, err := db.Connection.QueryContext(ctx, "SELECT name FROM sys.databases WHERE database_id > 4 AND HAS_DBACCESS(name) = 1 ORDER BY name")
+	if err != nil {
+		return databases, err
+	}
+
+	databases, err = scanColumnNames(rows)
+
+	return databases, err
+}
+
+func (db *MSSQL) GetTables(ctx context.Context, database string) (map[string][]string, error) {
+	tables := make(map[string][]string)
+
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf(`
+		SELECT s.name, o.name
+		FROM %[1]s.sys.objects o
+		JOIN %[1]s.sys.schemas s ON s.schema_id = o.schema_id
+		WHERE o.type IN ('U', 'V') AND o.is_ms_shipped = 0
+		ORDER BY s.name, o.name`, MSSQLDialect.QuoteIdentifier(database)))
+	if err != nil {
+		return tables, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableSchema string
+		var tableName string
+
+		err = rows.Scan(&tableSchema, &tableName)
+		if err != nil {
+			return tables, err
+		}
+
+		tables[tableSchema] = append(tables[tableSchema], tableName)
+	}
+
+	return tables, rows.Err()
+}
+
+func (db *MSSQL) GetTableColumns(ctx context.Context, database, table string) (results [][]models.CellValue, err error) {
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf(`
+		SELECT c.name AS column_name, t.name AS data_type, c.is_nullable, d.definition AS column_default
+		FROM %[1]s.sys.columns c
+		JOIN %[1]s.sys.types t ON t.user_type_id = c.user_type_id
+		LEFT JOIN %[1]s.sys.default_constraints d ON d.object_id = c.default_object_id
+		WHERE c.object_id = OBJECT_ID(@p1)
+		ORDER BY c.column_id`, MSSQLDialect.QuoteIdentifier(database)), db.objectNameIn(database, table))
+	if err != nil {
+		return results, err
+	}
+
+	return db.scanRecords(rows)
+}
+
+func (db *MSSQL) GetConstraints(ctx context.Context, table string) (results [][]models.CellValue, err error) {
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf(`
+		SELECT k.name AS constraint_name, c.name AS column_name, k.type_desc AS constraint_type
+		FROM %[1]s.sys.key_constraints k
+		JOIN %[1]s.sys.index_columns ic ON ic.object_id = k.parent_object_id AND ic.index_id = k.unique_index_id
+		JOIN %[1]s.sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE k.parent_object_id = OBJECT_ID(@p1)
+		UNION ALL
+		SELECT k.name, c.name, k.type_desc
+		FROM %[1]s.sys.check_constraints k
+		LEFT JOIN %[1]s.sys.columns c ON c.object_id = k.parent_object_id AND c.column_id = k.parent_column_id
+		WHERE k.parent_object_id = OBJECT_ID(@p1)`, db.catalog()), db.objectName(table))
+	if err != nil {
+		return results, err
+	}
+
+	return db.scanRecords(rows)
+}
+
+func (db *MSSQL) GetForeignKeys(ctx context.Context, table string) (results [][]models.CellValue, err error) {
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf(`
+		SELECT
+			fk.name AS constraint_name,
+			pc.name AS column_name,
+			rs.name + '.' + rt.name AS foreign_table_name,
+			rc.name AS foreign_column_name
+		FROM %[1]s.sys.foreign_keys fk
+		JOIN %[1]s.sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+		JOIN %[1]s.sys.columns pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+		JOIN %[1]s.sys.tables rt ON rt.object_id = fkc.referenced_object_id
+		JOIN %[1]s.sys.schemas rs ON rs.schema_id = rt.schema_id
+		JOIN %[1]s.sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+		WHERE fk.parent_object_id = OBJECT_ID(@p1)
+		ORDER BY fk.name, fkc.constraint_column_id`, db.catalog()), db.objectName(table))
+	if err != nil {
+		return results, err
+	}
+
+	return db.scanRecords(rows)
+}
+
+func (db *MSSQL) GetIndexes(ctx context.Context, table string) (results [][]models.CellValue, err error) {
+	// Heaps have an index row without a name, index_id 0
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf(`
+		SELECT i.name AS index_name, c.name AS column_name, i.type_desc AS type, i.is_unique
+		FROM %[1]s.sys.indexes i
+		JOIN %[1]s.sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN %[1]s.sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE i.object_id = OBJECT_ID(@p1) AND i.index_id > 0
+		ORDER BY i.name, ic.key_ordinal`, db.catalog()), db.objectName(table))
+	if err != nil {
+		return results, err
+	}
+
+	return db.scanRecords(rows)
+}
+
+// GetPrimaryKeyColumnNames reads the keys from the sys views of the current
+// database. The row locator of a heap changes when its row moves, so it isn't
+// used as a row id.
+func (db *MSSQL) GetPrimaryKeyColumnNames(ctx context.Context, database, table string) ([]string, error) {
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf(`
+		SELECT c.name
+		FROM %[1]s.sys.indexes i
+		JOIN %[1]s.sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN %[1]s.sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE i.object_id = OBJECT_ID(@p1) AND i.is_primary_key = 1 AND ic.is_included_column = 0
+		ORDER BY ic.key_ordinal`, db.catalog()), db.objectName(table))
+	if err != nil {
+		return nil, err
+	}
+
+	primaryKeys, err := scanColumnNames(rows)
+	if err != nil || len(primaryKeys) > 0 {
+		return primaryKeys, err
+	}
+
+	rows, err = db.Connection.QueryContext(ctx, fmt.Sprintf(`
+		SELECT i.name, c.name, CAST(CASE WHEN c.is_nullable = 0 THEN 1 ELSE 0 END AS bit)
+		FROM %[1]s.sys.indexes i
+		JOIN %[1]s.sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN %[1]s.sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE i.object_id = OBJECT_ID(@p1) AND i.is_unique = 1 AND i.has_filter = 0 AND ic.is_included_column = 0
+		ORDER BY i.name, ic.key_ordinal`, db.catalog()), db.objectName(table))
+	if err != nil {
+		return nil, err
+	}
+
+	return firstSafeUniqueIndex(rows)
+}
+
+func (db *MSSQL) GetRecords(ctx context.Context, table, where, sort string, offset, limit int) (paginatedResults [][]models.CellValue, totalRecords int, err error) {
+	table = db.objectName(table)
+	defaultLimit := 300
+
+	isPaginationEnabled := offset >= 0 && limit >= 0
+
+	if limit != 0 {
+		defaultLimit = limit
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s %s", table, where)
+
+	if isPaginationEnabled {
+		// OFFSET/FETCH needs an ORDER BY, ordering by a constant keeps the
+		// natural order of the table
+		if sort == "" {
+			sort = "(SELECT NULL)"
+		}
+
+		query = fmt.Sprintf("%s ORDER BY %s OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", query, sort, offset, defaultLimit)
+	} else if sort != "" {
+		query = fmt.Sprintf("%s ORDER BY %s", query, sort)
+	}
+
+	paginatedRows, err := db.Connection.QueryContext(ctx, query)
+	if err != nil {
+		return paginatedResults, totalRecords, err
+	}
+
+	if isPaginationEnabled {
+		queryWithoutLimit := fmt.Sprintf("SELECT COUNT(*) FROM %s %s", table, where)
+
+		err = db.Connection.QueryRowContext(ctx, queryWithoutLimit).Scan(&totalRecords)
+		if err != nil {
+			paginatedRows.Close()
+			return paginatedResults, totalRecords, err
+		}
+	}
+
+	paginatedResults, err = db.scanRecords(paginatedRows)
+
+	return paginatedResults, totalRecords, err
+}
+
+func (db *MSSQL) ExecuteQuery(ctx context.Context, query string) (Cursor, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	rows, err := db.Connection.QueryContext(ctx, query)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	scan, err := mssqlScanner(rows)
+	if err != nil {
+		rows.Close()
+		cancel()
+		return nil, err
+	}
+
+	return newRowsCursor(rows, scan, cancel, nil)
+}
+
+func (db *MSSQL) UpdateRecord(ctx context.Context, table, column, value string, primaryKeyInfo []models.PrimaryKeyInfo) error {
+	statement := MSSQLDialect.BuildUpdate(db.objectName(table), []ColumnValue{{Column: column, Value: value}}, PrimaryKeyWhere(primaryKeyInfo))
+	_, err := db.Connection.ExecContext(ctx, statement.Query, statement.Args...)
+
+	return err
+}
+
+func (db *MSSQL) DeleteRecord(ctx context.Context, table string, primaryKeyInfo []models.PrimaryKeyInfo) error {
+	statement := MSSQLDialect.BuildDelete(db.objectName(table), PrimaryKeyWhere(primaryKeyInfo))
+	_, err := db.Connection.ExecContext(ctx, statement.Query, statement.Args...)
+
+	return err
+}
+
+func (db *MSSQL) ExecuteDMLStatement(ctx context.Context, query string) (rowsAffected int64, err error) {
+	res, err := db.Connection.ExecContext(ctx, query)
+	if err != nil {
+		return rowsAffected, err
+	}
+
+	rowsAffected, _ = res.RowsAffected()
+
+	return rowsAffected, nil
+}
+
+func (db *MSSQL) ExecutePendingChanges(ctx context.Context, changes []models.DbDmlChange, inserts []models.DbInsert) error {
+	// The changes of every database run in one transaction, their tables are
+	// prefixed with their database
+	qualifiedChanges := make([]models.DbDmlChange, len(changes))
+	for i, change := range changes {
+		if change.Database == "" {
+			change.Database = db.CurrentDatabase
+		}
+
+		qualifiedChanges[i] = change
+	}
+
+	qualifiedInserts := make([]models.DbInsert, len(inserts))
+	for i, insert := range inserts {
+		if insert.Database == "" {
+			insert.Database = db.CurrentDatabase
+		}
+
+		qualifiedInserts[i] = insert
+	}
+
+	return execInTransaction(ctx, db.Connection, MSSQLDialect.BuildPendingChanges(qualifiedChanges, qualifiedInserts))
+}
+
+func (db *MSSQL) SetProvider(provider string) {
+	db.Provider = provider
+}
+
+func (db *MSSQL) GetProvider() string {
+	return db.Provider
+}
+
+// Close closes the connection, unless the driver was returned by ForDatabase
+// and shares it.
+func (db *MSSQL) Close() error {
+	if db.Connection == nil || db.borrowed {
+		return nil
+	}
+
+	return db.Connection.Close()
+}
+
+// ForDatabase returns a driver for the tables of another database of the
+// server, sharing the connection of db.
+func (db *MSSQL) ForDatabase(database string) (Driver, error) {
+	if database == db.CurrentDatabase {
+		return db, nil
+	}
+
+	return &MSSQL{
+		Connection:      db.Connection,
+		Provider:        db.Provider,
+		CurrentDatabase: database,
+		borrowed:        true,
+	}, nil
+}
+
+// objectName returns the quoted name of a schema.table, prefixed with the
+// database of the driver, since a single connection browses every database
+// of the server.
+func (db *MSSQL) objectName(table string) string {
+	return db.objectNameIn(db.CurrentDatabase, table)
+}
+
+// objectNameIn returns the quoted name of a schema.table prefixed with a
+// database, or with the database of the driver when it's empty
+func (db *MSSQL) objectNameIn(database, table string) string {
+	if database == "" {
+		database = db.CurrentDatabase
+	}
+
+	if database == "" {
+		return MSSQLDialect.quoteDriverTableName(table)
+	}
+
+	return MSSQLDialect.QuoteIdentifier(database) + "." + MSSQLDialect.quoteDriverTableName(table)
+}
+
+// catalog returns the quoted name of the database whose sys views describe
+// the tables of the tree
+func (db *MSSQL) catalog() string {
+	return MSSQLDialect.QuoteIdentifier(db.CurrentDatabase)
+}
+
+func (db *MSSQL) scanRecords(rows *sql.Rows) (results [][]models.CellValue, err error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return results, err
+	}
+
+	results = append(results, models.NewCellValues(columns))
+
+	scan, err := mssqlScanner(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		row, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// mssqlScanner returns a scanner reading the values of the rows as
+// go-mssqldb returns them. It returns the uniqueidentifiers, the binaries and
+// the decimals all as bytes, which are told apart by the types of their
+// columns.
+func mssqlScanner(rows *sql.Rows) (rowScanner, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	databaseTypes := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		databaseTypes[i] = columnType.DatabaseTypeName()
+	}
+
+	return func(rows *sql.Rows) ([]models.CellValue, error) {
+		values := make([]interface{}, len(databaseTypes))
+		rowValues := make([]interface{}, len(databaseTypes))
+		for i := range values {
+			rowValues[i] = &values[i]
+		}
+
+		err := rows.Scan(rowValues...)
+		if err != nil {
+			return nil, err
+		}
+
+		row := make([]models.CellValue, len(values))
+		for i, value := range values {
+			row[i] = mssqlCellValue(value, databaseTypes[i])
+		}
+
+		return row, nil
+	}, nil
+}
+
+// mssqlCellValue returns the cell value of a value returned by go-mssqldb for
+// a column of the given type. A uniqueidentifier is written the way SQL
+// Server writes it, so that it's found again when it identifies a row, and
+// the binaries are written in hexadecimal like 0x1F.
+func mssqlCellValue(value interface{}, databaseType string) models.CellValue {
+	var text string
+
+	switch value := value.(type) {
+	case nil:
+		return models.CellValue{Type: models.Null}
+	case []byte:
+		switch databaseType {
+		case "UNIQUEIDENTIFIER":
+			var id mssql.UniqueIdentifier
+
+			if id.Scan(value) == nil {
+				text = id.String()
+			} else {
+				text = "0x" + strings.ToUpper(hex.EncodeToString(value))
+			}
+		case "BINARY", "VARBINARY", "IMAGE":
+			text = "0x" + strings.ToUpper(hex.EncodeToString(value))
+		default:
+			// The decimals and the money are returned as text
+			text = string(value)
+		}
+	case mssql.UniqueIdentifier:
+		text = value.String()
+	case string:
+		text = value
+	case int64:
+		text = strconv.FormatInt(value, 10)
+	case float64:
+		text = strconv.FormatFloat(value, 'g', -1, 64)
+	case bool:
+		text = strconv.FormatBool(value)
+	case time.Time:
+		text = value.Format(time.RFC3339Nano)
+	default:
+		text = fmt.Sprint(value)
+	}
+
+	return models.CellValue{Type: models.String, Value: text}
+}
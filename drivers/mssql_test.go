@@ -0,0 +1,249 @@
+package drivers
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// mssqlGUID is 6F9619FF-8B86-D011-B42D-00C04FC964FF as SQL Server sends it,
+// its first three groups are little-endian
+var mssqlGUID = []byte{0xFF, 0x19, 0x96, 0x6F, 0x86, 0x8B, 0x11, 0xD0, 0xB4, 0x2D, 0x00, 0xC0, 0x4F, 0xC9, 0x64, 0xFF}
+
+var mssqlOrders = fakeResult{
+	match:   "SELECT * FROM [shop].[dbo].[orders]",
+	columns: []string{"id", "total", "data", "note", "quantity"},
+	types:   []string{"UNIQUEIDENTIFIER", "DECIMAL", "VARBINARY", "NVARCHAR", "INT"},
+	rows: [][]driver.Value{
+		{mssqlGUID, []byte("12.50"), []byte{0x1F, 0xA0}, nil, int64(3)},
+	},
+}
+
+var mssqlOrdersRows = [][]models.CellValue{
+	models.NewCellValues([]string{"id", "total", "data", "note", "quantity"}),
+	{
+		{Type: models.String, Value: "6F9619FF-8B86-D011-B42D-00C04FC964FF"},
+		{Type: models.String, Value: "12.50"},
+		{Type: models.String, Value: "0x1FA0"},
+		{Type: models.Null},
+		{Type: models.String, Value: "3"},
+	},
+}
+
+func TestMSSQLCellValue(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        interface{}
+		databaseType string
+		expected     models.CellValue
+	}{
+		{"null", nil, "NVARCHAR", models.CellValue{Type: models.Null}},
+		{"uniqueidentifier", mssqlGUID, "UNIQUEIDENTIFIER", models.CellValue{Value: "6F9619FF-8B86-D011-B42D-00C04FC964FF"}},
+		{"malformed uniqueidentifier", []byte{0x01, 0x02}, "UNIQUEIDENTIFIER", models.CellValue{Value: "0x0102"}},
+		{"binary", []byte{0x00, 0xAB}, "BINARY", models.CellValue{Value: "0x00AB"}},
+		{"image", []byte{}, "IMAGE", models.CellValue{Value: "0x"}},
+		{"decimal", []byte("-0.0100"), "DECIMAL", models.CellValue{Value: "-0.0100"}},
+		{"money", []byte("9.9900"), "MONEY", models.CellValue{Value: "9.9900"}},
+		{"text", "héllo", "NVARCHAR", models.CellValue{Value: "héllo"}},
+		{"integer", int64(-42), "BIGINT", models.CellValue{Value: "-42"}},
+		{"float", 1.5, "FLOAT", models.CellValue{Value: "1.5"}},
+		{"bit", true, "BIT", models.CellValue{Value: "true"}},
+		{"datetime", time.Date(2024, 2, 29, 13, 4, 5, 0, time.UTC), "DATETIME2", models.CellValue{Value: "2024-02-29T13:04:05Z"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value := mssqlCellValue(test.value, test.databaseType)
+			if value != test.expected {
+				t.Errorf("mssqlCellValue(%v, %s) = %#v, want %#v", test.value, test.databaseType, value, test.expected)
+			}
+		})
+	}
+}
+
+func TestMSSQLGetRecords(t *testing.T) {
+	count := fakeResult{match: "SELECT COUNT(*)", columns: []string{""}, rows: [][]driver.Value{{int64(1)}}}
+
+	tests := []struct {
+		name           string
+		offset         int
+		limit          int
+		expectedQuery  string
+		expectedTotal  int
+		expectedCounts bool
+	}{
+		{
+			name:           "paginated",
+			offset:         0,
+			limit:          0,
+			expectedQuery:  "SELECT * FROM [shop].[dbo].[orders]  ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 300 ROWS ONLY",
+			expectedTotal:  1,
+			expectedCounts: true,
+		},
+		{
+			name:          "not paginated",
+			offset:        -1,
+			limit:         -1,
+			expectedQuery: "SELECT * FROM [shop].[dbo].[orders] ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connection, database := openFakeDatabase(t, count, mssqlOrders)
+			db := &MSSQL{Connection: connection, Provider: "sqlserver", CurrentDatabase: "shop"}
+
+			records, total, err := db.GetRecords(context.Background(), "dbo.orders", "", "", test.offset, test.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(records, mssqlOrdersRows) {
+				t.Errorf("records = %v, want %v", records, mssqlOrdersRows)
+			}
+
+			if total != test.expectedTotal {
+				t.Errorf("total = %d, want %d", total, test.expectedTotal)
+			}
+
+			queries := database.queries()
+			if queries[0] != test.expectedQuery {
+				t.Errorf("query = %q, want %q", queries[0], test.expectedQuery)
+			}
+
+			counted := len(queries) > 1 && strings.HasPrefix(queries[1], "SELECT COUNT(*)")
+			if counted != test.expectedCounts {
+				t.Errorf("counted = %t, want %t", counted, test.expectedCounts)
+			}
+		})
+	}
+}
+
+func TestMSSQLExecuteQuery(t *testing.T) {
+	connection, _ := openFakeDatabase(t, mssqlOrders)
+	db := &MSSQL{Connection: connection, Provider: "sqlserver", CurrentDatabase: "shop"}
+
+	cursor, err := db.ExecuteQuery(context.Background(), "SELECT * FROM [shop].[dbo].[orders]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cursor.Close()
+
+	if columns := cursor.Columns(); !reflect.DeepEqual(columns, mssqlOrders.columns) {
+		t.Errorf("columns = %v, want %v", columns, mssqlOrders.columns)
+	}
+
+	rows, more, err := cursor.Next(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if more {
+		t.Error("more = true after the last row")
+	}
+
+	if !reflect.DeepEqual(rows, mssqlOrdersRows[1:]) {
+		t.Errorf("rows = %v, want %v", rows, mssqlOrdersRows[1:])
+	}
+}
+
+// TestMSSQLGUIDPrimaryKey checks that the uniqueidentifier read from a row
+// finds that row again when it's changed or deleted
+func TestMSSQLGUIDPrimaryKey(t *testing.T) {
+	connection, database := openFakeDatabase(t, mssqlOrders)
+	db := &MSSQL{Connection: connection, Provider: "sqlserver", CurrentDatabase: "shop"}
+
+	records, _, err := db.GetRecords(context.Background(), "dbo.orders", "", "", -1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := []models.PrimaryKeyInfo{{Name: "id", Value: records[1][0].Value}}
+
+	err = db.UpdateRecord(context.Background(), "dbo.orders", "note", "paid", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.DeleteRecord(context.Background(), "dbo.orders", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []fakeStatement{
+		{
+			query: "UPDATE [shop].[dbo].[orders] SET [note] = @p1 WHERE [id] = @p2",
+			args:  []driver.Value{"paid", "6F9619FF-8B86-D011-B42D-00C04FC964FF"},
+		},
+		{
+			query: "DELETE FROM [shop].[dbo].[orders] WHERE [id] = @p1",
+			args:  []driver.Value{"6F9619FF-8B86-D011-B42D-00C04FC964FF"},
+		},
+	}
+
+	if !reflect.DeepEqual(database.statements[1:], expected) {
+		t.Errorf("statements = %#v, want %#v", database.statements[1:], expected)
+	}
+}
+
+func TestMSSQLExecutePendingChanges(t *testing.T) {
+	connection, database := openFakeDatabase(t)
+	db := &MSSQL{Connection: connection, Provider: "sqlserver", CurrentDatabase: "shop"}
+
+	changes := []models.DbDmlChange{
+		{Type: "DELETE", Schema: "dbo", Table: "orders", PrimaryKeyInfo: []models.PrimaryKeyInfo{{Name: "id", Value: "1"}}},
+		{Type: "DELETE", Database: "archive", Schema: "dbo", Table: "orders", PrimaryKeyInfo: []models.PrimaryKeyInfo{{Name: "id", Value: "2"}}},
+	}
+
+	err := db.ExecutePendingChanges(context.Background(), changes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"BEGIN",
+		"DELETE FROM [shop].[dbo].[orders] WHERE [id] = @p1",
+		"DELETE FROM [archive].[dbo].[orders] WHERE [id] = @p1",
+		"COMMIT",
+	}
+
+	if queries := database.queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("queries = %q, want %q", queries, expected)
+	}
+}
+
+func TestMSSQLGetTables(t *testing.T) {
+	connection, database := openFakeDatabase(t, fakeResult{
+		match:   "sys.objects",
+		columns: []string{"name", "name"},
+		rows: [][]driver.Value{
+			{"dbo", "customers"},
+			{"dbo", "orders"},
+			{"sales", "invoices"},
+		},
+	})
+	db := &MSSQL{Connection: connection, Provider: "sqlserver", CurrentDatabase: "shop"}
+
+	tables, err := db.GetTables(context.Background(), "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"dbo":   {"customers", "orders"},
+		"sales": {"invoices"},
+	}
+
+	if !reflect.DeepEqual(tables, expected) {
+		t.Errorf("tables = %v, want %v", tables, expected)
+	}
+
+	if query := database.queries()[0]; !strings.Contains(query, "FROM [shop].sys.objects") {
+		t.Errorf("query = %q, want it to read the objects of [shop]", query)
+	}
+}
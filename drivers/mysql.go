@@ -1,35 +1,59 @@
 package drivers
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/jorgerojas26/lazysql/models"
 
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/xo/dburl"
 )
 
 type MySQL struct {
 	Connection *sql.DB
 	Provider   string
+	// readOnly opens the sessions read-only
+	readOnly bool
+	// connectionIDs are the ids of the connections of the pool on the
+	// server, by driver connection
+	connectionIDs struct {
+		mutex sync.Mutex
+		ids   map[driver.Conn]int64
+	}
+}
+
+// mysqlReadOnlySession makes the transactions of a session refuse to write
+const mysqlReadOnlySession = "SET SESSION TRANSACTION READ ONLY"
+
+func init() {
+	Register(Registration{
+		Provider:    "mysql",
+		Schemes:     []string{"mysql", "my", "mariadb", "maria", "percona", "aurora"},
+		Dialect:     MySQLDialect,
+		DefaultPort: "3306",
+		New: func() Driver {
+			return &MySQL{}
+		},
+	})
 }
 
-func (db *MySQL) TestConnection(urlstr string) (err error) {
-	return db.Connect(urlstr)
+func (db *MySQL) TestConnection(ctx context.Context, urlstr string) (err error) {
+	return db.Connect(ctx, urlstr)
 }
 
-func (db *MySQL) Connect(urlstr string) (err error) {
+func (db *MySQL) Connect(ctx context.Context, urlstr string) (err error) {
 	db.SetProvider("mysql")
 
-	db.Connection, err = dburl.Open(urlstr)
+	db.Connection, err = openDB(urlstr, db.readOnly, mysqlReadOnlySession)
 	if err != nil {
 		return err
 	}
 
-	err = db.Connection.Ping()
+	err = db.Connection.PingContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -37,10 +61,10 @@ func (db *MySQL) Connect(urlstr string) (err error) {
 	return nil
 }
 
-func (db *MySQL) GetDatabases() ([]string, error) {
+func (db *MySQL) GetDatabases(ctx context.Context) ([]string, error) {
 	var databases []string
 
-	rows, err := db.Connection.Query("SHOW DATABASES")
+	rows, err := db.Connection.QueryContext(ctx, "SHOW DATABASES")
 	if err != nil {
 		return databases, err
 	}
@@ -59,8 +83,8 @@ func (db *MySQL) GetDatabases() ([]string, error) {
 	return databases, nil
 }
 
-func (db *MySQL) GetTables(database string) (map[string][]string, error) {
-	rows, err := db.Connection.Query(fmt.Sprintf("SHOW TABLES FROM `%s`", database))
+func (db *MySQL) GetTables(ctx context.Context, database string) (map[string][]string, error) {
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf("SHOW TABLES FROM `%s`", database))
 
 	tables := make(map[string][]string)
 
@@ -78,10 +102,10 @@ func (db *MySQL) GetTables(database string) (map[string][]string, error) {
 	return tables, nil
 }
 
-func (db *MySQL) GetTableColumns(database, table string) (results [][]string, err error) {
+func (db *MySQL) GetTableColumns(ctx context.Context, database, table string) (results [][]models.CellValue, err error) {
 	table = db.formatTableName(table)
 
-	rows, err := db.Connection.Query("DESCRIBE " + table)
+	rows, err := db.Connection.QueryContext(ctx, "DESCRIBE "+table)
 	if err != nil {
 		return results, err
 	}
@@ -92,19 +116,19 @@ func (db *MySQL) GetTableColumns(database, table string) (results [][]string, er
 		return results, err
 	}
 
-	results = append(results, columns)
+	results = append(results, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		results = append(results, row)
@@ -113,14 +137,14 @@ func (db *MySQL) GetTableColumns(database, table string) (results [][]string, er
 	return
 }
 
-func (db *MySQL) GetConstraints(table string) (results [][]string, err error) {
+func (db *MySQL) GetConstraints(ctx context.Context, table string) (results [][]models.CellValue, err error) {
 	table = db.formatTableName(table)
 
 	splitTableString := strings.Split(table, ".")
 	database := splitTableString[0]
 	tableName := splitTableString[1]
 
-	rows, err := db.Connection.Query(fmt.Sprintf("SELECT CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE where TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s'", database, tableName))
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf("SELECT CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE where TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s'", database, tableName))
 	if err != nil {
 		return results, err
 	}
@@ -132,19 +156,19 @@ func (db *MySQL) GetConstraints(table string) (results [][]string, err error) {
 		return results, err
 	}
 
-	results = append(results, columns)
+	results = append(results, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		results = append(results, row)
@@ -153,13 +177,13 @@ func (db *MySQL) GetConstraints(table string) (results [][]string, err error) {
 	return
 }
 
-func (db *MySQL) GetForeignKeys(table string) (results [][]string, err error) {
+func (db *MySQL) GetForeignKeys(ctx context.Context, table string) (results [][]models.CellValue, err error) {
 	table = db.formatTableName(table)
 	splitTableString := strings.Split(table, ".")
 	database := splitTableString[0]
 	tableName := splitTableString[1]
 
-	rows, err := db.Connection.Query(fmt.Sprintf("SELECT TABLE_NAME, COLUMN_NAME, CONSTRAINT_NAME, REFERENCED_COLUMN_NAME, REFERENCED_TABLE_NAME FROM information_schema.KEY_COLUMN_USAGE where REFERENCED_TABLE_SCHEMA = '%s' AND REFERENCED_TABLE_NAME = '%s'", database, tableName))
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf("SELECT TABLE_NAME, COLUMN_NAME, CONSTRAINT_NAME, REFERENCED_COLUMN_NAME, REFERENCED_TABLE_NAME FROM information_schema.KEY_COLUMN_USAGE where REFERENCED_TABLE_SCHEMA = '%s' AND REFERENCED_TABLE_NAME = '%s'", database, tableName))
 	if err != nil {
 		return results, err
 	}
@@ -171,19 +195,19 @@ func (db *MySQL) GetForeignKeys(table string) (results [][]string, err error) {
 		return results, err
 	}
 
-	results = append(results, columns)
+	results = append(results, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		results = append(results, row)
@@ -192,9 +216,9 @@ func (db *MySQL) GetForeignKeys(table string) (results [][]string, err error) {
 	return
 }
 
-func (db *MySQL) GetIndexes(table string) (results [][]string, err error) {
+func (db *MySQL) GetIndexes(ctx context.Context, table string) (results [][]models.CellValue, err error) {
 	table = db.formatTableName(table)
-	rows, err := db.Connection.Query("SHOW INDEX FROM " + table)
+	rows, err := db.Connection.QueryContext(ctx, "SHOW INDEX FROM "+table)
 	if err != nil {
 		return results, err
 	}
@@ -202,19 +226,19 @@ func (db *MySQL) GetIndexes(table string) (results [][]string, err error) {
 
 	columns, _ := rows.Columns()
 
-	results = append(results, columns)
+	results = append(results, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		results = append(results, row)
@@ -223,7 +247,43 @@ func (db *MySQL) GetIndexes(table string) (results [][]string, err error) {
 	return
 }
 
-func (db *MySQL) GetRecords(table, where, sort string, offset, limit int) (paginatedResults [][]string, totalRecords int, err error) {
+// GetPrimaryKeyColumnNames reads the keys from information_schema. The row id
+// InnoDB generates for a table without a key can't be queried, so there is
+// none to fall back to.
+func (db *MySQL) GetPrimaryKeyColumnNames(ctx context.Context, database, table string) ([]string, error) {
+	splitTableString := strings.Split(table, ".")
+	database = splitTableString[0]
+	tableName := splitTableString[1]
+
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER BY ORDINAL_POSITION`, database, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	primaryKeys, err := scanColumnNames(rows)
+	if err != nil || len(primaryKeys) > 0 {
+		return primaryKeys, err
+	}
+
+	rows, err = db.Connection.QueryContext(ctx, `
+		SELECT s.INDEX_NAME, COALESCE(s.COLUMN_NAME, ''), COALESCE(c.IS_NULLABLE = 'NO', FALSE)
+		FROM information_schema.STATISTICS s
+		LEFT JOIN information_schema.COLUMNS c
+			ON c.TABLE_SCHEMA = s.TABLE_SCHEMA AND c.TABLE_NAME = s.TABLE_NAME AND c.COLUMN_NAME = s.COLUMN_NAME
+		WHERE s.TABLE_SCHEMA = ? AND s.TABLE_NAME = ? AND s.NON_UNIQUE = 0
+		ORDER BY s.INDEX_NAME, s.SEQ_IN_INDEX`, database, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return firstSafeUniqueIndex(rows)
+}
+
+func (db *MySQL) GetRecords(ctx context.Context, table, where, sort string, offset, limit int) (paginatedResults [][]models.CellValue, totalRecords int, err error) {
 	table = db.formatTableName(table)
 	defaultLimit := 300
 
@@ -243,203 +303,193 @@ func (db *MySQL) GetRecords(table, where, sort string, offset, limit int) (pagin
 		query = fmt.Sprintf("SELECT * FROM %s %s ORDER BY %s LIMIT %d,%d", table, where, sort, offset, defaultLimit)
 	}
 
-	paginatedRows, err := db.Connection.Query(query)
+	conn, release, err := db.killQueryOnCancel(ctx)
 	if err != nil {
 		return paginatedResults, totalRecords, err
 	}
 
-	if isPaginationEnabled {
-		queryWithoutLimit := fmt.Sprintf("SELECT COUNT(*) FROM %s %s", table, where)
-
-		rows := db.Connection.QueryRow(queryWithoutLimit)
-
-		if err != nil {
-			return paginatedResults, totalRecords, err
-		}
-
-		rows.Scan(&totalRecords)
+	defer release()
 
-		defer paginatedRows.Close()
+	paginatedRows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return paginatedResults, totalRecords, err
 	}
 
+	defer paginatedRows.Close()
+
 	columns, _ := paginatedRows.Columns()
 
-	paginatedResults = append(paginatedResults, columns)
+	paginatedResults = append(paginatedResults, models.NewCellValues(columns))
 
 	for paginatedRows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		paginatedRows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		paginatedResults = append(paginatedResults, row)
 
 	}
 
-	return
-}
-
-func (db *MySQL) ExecuteQuery(query string) (results [][]string, err error) {
-	rows, err := db.Connection.Query(query)
-	if err != nil {
-		return results, err
-	}
-
-	defer rows.Close()
+	// The connection can only run the count once the records are read
+	paginatedRows.Close()
 
-	columns, _ := rows.Columns()
-
-	results = append(results, columns)
+	if isPaginationEnabled {
+		queryWithoutLimit := fmt.Sprintf("SELECT COUNT(*) FROM %s %s", table, where)
 
-	for rows.Next() {
-		rowValues := make([]interface{}, len(columns))
-		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+		err = conn.QueryRowContext(ctx, queryWithoutLimit).Scan(&totalRecords)
+		if err != nil {
+			return paginatedResults, totalRecords, err
 		}
+	}
 
-		rows.Scan(rowValues...)
+	return
+}
 
-		var row []string
-		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
-		}
+func (db *MySQL) ExecuteQuery(ctx context.Context, query string) (Cursor, error) {
+	ctx, cancel := context.WithCancel(ctx)
 
-		results = append(results, row)
+	conn, release, err := db.killQueryOnCancel(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		release()
+		cancel()
+		return nil, err
 	}
 
-	return
+	// The connection is kept until the cursor is closed
+	return newRowsCursor(rows, nil, cancel, release)
 }
 
-// TODO: Rewrites this logic to use the primary key instead of the id
-func (db *MySQL) UpdateRecord(table, column, value, primaryKeyColumnName, primaryKeyValue string) error {
-	table = db.formatTableName(table)
-	query := fmt.Sprintf("UPDATE %s SET %s = \"%s\" WHERE %s = \"%s\"", table, column, value, primaryKeyColumnName, primaryKeyValue)
-	_, err := db.Connection.Exec(query)
+func (db *MySQL) UpdateRecord(ctx context.Context, table, column, value string, primaryKeyInfo []models.PrimaryKeyInfo) error {
+	statement := MySQLDialect.BuildUpdate(MySQLDialect.quoteDriverTableName(table), []ColumnValue{{Column: column, Value: value}}, PrimaryKeyWhere(primaryKeyInfo))
+	_, err := db.Connection.ExecContext(ctx, statement.Query, statement.Args...)
 
 	return err
 }
 
-// TODO: Rewrites this logic to use the primary key instead of the id
-func (db *MySQL) DeleteRecord(table, primaryKeyColumnName, primaryKeyValue string) error {
-	table = db.formatTableName(table)
-	query := fmt.Sprintf("DELETE FROM %s WHERE %s = \"%s\"", table, primaryKeyColumnName, primaryKeyValue)
-	_, err := db.Connection.Exec(query)
+func (db *MySQL) DeleteRecord(ctx context.Context, table string, primaryKeyInfo []models.PrimaryKeyInfo) error {
+	statement := MySQLDialect.BuildDelete(MySQLDialect.quoteDriverTableName(table), PrimaryKeyWhere(primaryKeyInfo))
+	_, err := db.Connection.ExecContext(ctx, statement.Query, statement.Args...)
 
 	return err
 }
 
-func (db *MySQL) ExecuteDMLStatement(query string) (result string, err error) {
-	res, error := db.Connection.Exec(query)
+func (db *MySQL) ExecuteDMLStatement(ctx context.Context, query string) (rowsAffected int64, err error) {
+	conn, release, err := db.killQueryOnCancel(ctx)
+	if err != nil {
+		return rowsAffected, err
+	}
+
+	defer release()
+
+	res, error := conn.ExecContext(ctx, query)
 
 	if error != nil {
-		return result, error
+		return rowsAffected, error
 	} else {
-		rowsAffected, _ := res.RowsAffected()
+		rowsAffected, _ = res.RowsAffected()
 
-		return fmt.Sprintf("%d rows affected", rowsAffected), error
+		return rowsAffected, error
 	}
 }
 
-func (db *MySQL) ExecutePendingChanges(changes []models.DbDmlChange, inserts []models.DbInsert) (err error) {
-	queries := make([]string, 0, len(changes)+len(inserts))
-
-	// This will hold grouped changes by their RowId and Table
-	groupedUpdated := make(map[string][]models.DbDmlChange)
-	groupedDeletes := make([]models.DbDmlChange, 0, len(changes))
-
-	// Group changes by RowId and Table
-	for _, change := range changes {
-		switch change.Type {
-		case "UPDATE":
-			key := fmt.Sprintf("%s|%s|%s", change.Table, change.PrimaryKeyColumnName, change.PrimaryKeyValue)
-			groupedUpdated[key] = append(groupedUpdated[key], change)
-		case "DELETE":
-			groupedDeletes = append(groupedDeletes, change)
-		}
-	}
-
-	// Combine individual changes to SQL statements
-	for key, changes := range groupedUpdated {
-		columns := []string{}
-
-		// Split key into table and rowId
-		splitted := strings.Split(key, "|")
-		table := db.formatTableName(splitted[0])
-		primaryKeyColumnName := splitted[1]
-		primaryKeyValue := splitted[2]
-
-		for _, change := range changes {
-			columns = append(columns, fmt.Sprintf("%s='%s'", change.Column, change.Value))
-		}
-
-		// Merge all column updates
-		updateClause := strings.Join(columns, ", ")
-
-		query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = '%s';", table, updateClause, primaryKeyColumnName, primaryKeyValue)
+func (db *MySQL) ExecutePendingChanges(ctx context.Context, changes []models.DbDmlChange, inserts []models.DbInsert) error {
+	return execInTransaction(ctx, db.Connection, MySQLDialect.BuildPendingChanges(changes, inserts))
+}
 
-		queries = append(queries, query)
+// killQueryOnCancel takes a connection from the pool and kills the query it
+// runs when ctx is cancelled. On its own, the mysql driver only closes its end
+// of the connection, which leaves the query running on the server.
+func (db *MySQL) killQueryOnCancel(ctx context.Context) (conn *sql.Conn, release func(), err error) {
+	conn, err = db.Connection.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	for _, delete := range groupedDeletes {
-		statementType := ""
-		query := ""
+	connectionID, err := db.connectionID(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
 
-		statementType = "DELETE FROM"
+	done := make(chan struct{})
+	stopped := make(chan struct{})
 
-		query = fmt.Sprintf("%s %s WHERE %s = \"%s\"", statementType, db.formatTableName(delete.Table), delete.PrimaryKeyColumnName, delete.PrimaryKeyValue)
+	go func() {
+		defer close(stopped)
 
-		if query != "" {
-			queries = append(queries, query)
+		select {
+		case <-ctx.Done():
+			db.Connection.ExecContext(context.Background(), fmt.Sprintf("KILL QUERY %d", connectionID))
+		case <-done:
 		}
+	}()
+
+	// The connection goes back to the pool only once the watcher is gone, so
+	// that it can't kill a query that isn't ours
+	release = func() {
+		close(done)
+		<-stopped
+		conn.Close()
 	}
 
-	for _, insert := range inserts {
-		values := make([]string, 0, len(insert.Values))
+	return conn, release, nil
+}
 
-		for _, value := range insert.Values {
-			_, error := strconv.ParseFloat(value, 64)
+// connectionID returns the id of a connection of the pool on the server. It's
+// read once per connection, a connection running a query can't read it when
+// the query has to be killed.
+func (db *MySQL) connectionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var key driver.Conn
 
-			if strings.ToLower(value) != "default" && error != nil {
-				values = append(values, fmt.Sprintf("\"%s\"", value))
-			} else {
-				values = append(values, value)
-			}
-		}
+	err := conn.Raw(func(driverConn interface{}) error {
+		key, _ = driverConn.(driver.Conn)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
 
-		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", db.formatTableName(insert.Table), strings.Join(insert.Columns, ", "), strings.Join(values, ", "))
+	db.connectionIDs.mutex.Lock()
+	connectionID, ok := db.connectionIDs.ids[key]
+	db.connectionIDs.mutex.Unlock()
 
-		queries = append(queries, query)
+	if ok {
+		return connectionID, nil
 	}
 
-	tx, error := db.Connection.Begin()
-	if error != nil {
-		return error
+	err = conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&connectionID)
+	if err != nil {
+		return 0, err
 	}
 
-	for _, query := range queries {
+	open := db.Connection.Stats().OpenConnections
 
-		_, err = tx.Exec(query)
-		if err != nil {
-			tx.Rollback()
+	db.connectionIDs.mutex.Lock()
+	defer db.connectionIDs.mutex.Unlock()
 
-			return err
-		}
+	// The ids of the connections the pool closed are dropped along with the
+	// others once there are more ids than open connections
+	if db.connectionIDs.ids == nil || len(db.connectionIDs.ids) >= open {
+		db.connectionIDs.ids = make(map[driver.Conn]int64)
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
-	}
+	db.connectionIDs.ids[key] = connectionID
 
-	return err
+	return connectionID, nil
 }
 
 func (db *MySQL) SetProvider(provider string) {
@@ -450,6 +500,18 @@ func (db *MySQL) GetProvider() string {
 	return db.Provider
 }
 
+func (db *MySQL) SetReadOnly(readOnly bool) {
+	db.readOnly = readOnly
+}
+
+func (db *MySQL) Close() error {
+	if db.Connection == nil {
+		return nil
+	}
+
+	return db.Connection.Close()
+}
+
 func (db *MySQL) formatTableName(tableName string) string {
 	splittedTableName := strings.Split(tableName, ".")
 
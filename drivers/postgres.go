@@ -1,38 +1,75 @@
 package drivers
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/jorgerojas26/lazysql/models"
-	"github.com/xo/dburl"
 
 	_ "github.com/lib/pq"
 )
 
 type Postgres struct {
-	Connection       *sql.DB
-	Provider         string
-	CurrentDatabase  string
-	PreviousDatabase string
-	Urlstr           string
+	Connection      *sql.DB
+	Provider        string
+	CurrentDatabase string
+	Urlstr          string
+	// readOnly opens the sessions read-only
+	readOnly bool
+	// identities are how the rows of each table are identified
+	identities identityCache
+	// pools are the connections to each database of the server, shared with
+	// the drivers returned by ForDatabase
+	pools *postgresPools
+	// borrowed is true for the drivers returned by ForDatabase, the pools
+	// are closed by the driver that returned them
+	borrowed bool
+}
+
+// postgresPools keeps a connection pool per database, since a Postgres
+// connection can't change its database
+type postgresPools struct {
+	mutex       sync.Mutex
+	connections map[string]*sql.DB
+}
+
+func init() {
+	Register(Registration{
+		Provider: "postgres",
+		Schemes:  []string{"postgres", "pg", "postgresql", "pgsql"},
+		Dialect:  PostgresDialect,
+		Capabilities: Capabilities{
+			ConnectionPerDatabase: true,
+			SchemaTableNames:      true,
+		},
+		DefaultPort: DEFAULT_PORT,
+		New: func() Driver {
+			return &Postgres{}
+		},
+	})
 }
 
 const (
 	DEFAULT_PORT = "5432"
 )
 
-func (db *Postgres) Connect(urlstr string) (err error) {
+// postgresReadOnlySession makes the transactions of a session refuse to
+// write
+const postgresReadOnlySession = "SET SESSION CHARACTERISTICS AS TRANSACTION READ ONLY"
+
+func (db *Postgres) Connect(ctx context.Context, urlstr string) (err error) {
 	db.SetProvider("postgres")
 
-	db.Connection, err = dburl.Open(urlstr)
+	db.Connection, err = openDB(urlstr, db.readOnly, postgresReadOnlySession)
 	if err != nil {
 		return err
 	}
 
-	err = db.Connection.Ping()
+	err = db.Connection.PingContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -41,27 +78,30 @@ func (db *Postgres) Connect(urlstr string) (err error) {
 
 	// get current database
 
-	rows := db.Connection.QueryRow("SELECT current_database();")
+	rows := db.Connection.QueryRowContext(ctx, "SELECT current_database();")
 
 	database := ""
 
 	err = rows.Scan(&database)
 
 	db.CurrentDatabase = database
-	db.PreviousDatabase = database
 	if err != nil {
 		return err
 	}
 
+	db.pools = &postgresPools{
+		connections: map[string]*sql.DB{database: db.Connection},
+	}
+
 	return nil
 }
 
-func (db *Postgres) TestConnection(urlstr string) error {
-	return db.Connect(urlstr)
+func (db *Postgres) TestConnection(ctx context.Context, urlstr string) error {
+	return db.Connect(ctx, urlstr)
 }
 
-func (db *Postgres) GetDatabases() (databases []string, err error) {
-	rows, err := db.Connection.Query("SELECT datname FROM pg_database;")
+func (db *Postgres) GetDatabases(ctx context.Context) (databases []string, err error) {
+	rows, err := db.Connection.QueryContext(ctx, "SELECT datname FROM pg_database;")
 	if err != nil {
 		return databases, err
 	}
@@ -78,29 +118,19 @@ func (db *Postgres) GetDatabases() (databases []string, err error) {
 	return databases, nil
 }
 
-func (db *Postgres) GetTables(database string) (tables map[string][]string, err error) {
+func (db *Postgres) GetTables(ctx context.Context, database string) (tables map[string][]string, err error) {
 	tables = make(map[string][]string)
 
-	switchDatabase := false
-
-	if database != db.CurrentDatabase {
-		err = db.SwitchDatabase(database)
-		if err != nil {
-			return tables, err
-		}
-		switchDatabase = true
+	connection, err := db.pool(database)
+	if err != nil {
+		return tables, err
 	}
 
-	rows, err := db.Connection.Query(fmt.Sprintf("SELECT table_name, table_schema FROM information_schema.tables WHERE table_catalog = '%s'", database))
+	rows, err := connection.QueryContext(ctx, fmt.Sprintf("SELECT table_name, table_schema FROM information_schema.tables WHERE table_catalog = '%s'", database))
 	if err != nil {
-		if switchDatabase {
-			err = db.SwitchDatabase(db.PreviousDatabase)
-			if err != nil {
-				return tables, err
-			}
-		}
 		return tables, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var tableName string
@@ -115,10 +145,16 @@ func (db *Postgres) GetTables(database string) (tables map[string][]string, err
 	return tables, nil
 }
 
-func (db *Postgres) GetTableColumns(database, table string) (results [][]string, error error) {
+func (db *Postgres) GetTableColumns(ctx context.Context, database, table string) (results [][]models.CellValue, error error) {
 	tableSchema := strings.Split(table, ".")[0]
 	tableName := strings.Split(table, ".")[1]
-	rows, err := db.Connection.Query(fmt.Sprintf("SELECT column_name, data_type, is_nullable, column_default FROM information_schema.columns WHERE table_catalog = '%s' AND table_schema = '%s' AND table_name = '%s' ORDER by ordinal_position", database, tableSchema, tableName))
+
+	connection, err := db.pool(database)
+	if err != nil {
+		return results, err
+	}
+
+	rows, err := connection.QueryContext(ctx, fmt.Sprintf("SELECT column_name, data_type, is_nullable, column_default FROM information_schema.columns WHERE table_catalog = '%s' AND table_schema = '%s' AND table_name = '%s' ORDER by ordinal_position", database, tableSchema, tableName))
 	if err != nil {
 		return results, err
 	}
@@ -129,19 +165,19 @@ func (db *Postgres) GetTableColumns(database, table string) (results [][]string,
 		return results, err
 	}
 
-	results = append(results, columns)
+	results = append(results, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		results = append(results, row)
@@ -150,12 +186,12 @@ func (db *Postgres) GetTableColumns(database, table string) (results [][]string,
 	return
 }
 
-func (db *Postgres) GetConstraints(table string) (constraints [][]string, error error) {
+func (db *Postgres) GetConstraints(ctx context.Context, table string) (constraints [][]models.CellValue, error error) {
 	splitTableString := strings.Split(table, ".")
 	tableSchema := splitTableString[0]
 	tableName := splitTableString[1]
 
-	rows, err := db.Connection.Query(fmt.Sprintf(`
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf(`
   SELECT
             tc.constraint_name,
             kcu.column_name,
@@ -182,19 +218,19 @@ func (db *Postgres) GetConstraints(table string) (constraints [][]string, error
 		return constraints, err
 	}
 
-	constraints = append(constraints, columns)
+	constraints = append(constraints, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		constraints = append(constraints, row)
@@ -203,12 +239,12 @@ func (db *Postgres) GetConstraints(table string) (constraints [][]string, error
 	return
 }
 
-func (db *Postgres) GetForeignKeys(table string) (foreignKeys [][]string, error error) {
+func (db *Postgres) GetForeignKeys(ctx context.Context, table string) (foreignKeys [][]models.CellValue, error error) {
 	splitTableString := strings.Split(table, ".")
 	tableSchema := splitTableString[0]
 	tableName := splitTableString[1]
 
-	rows, err := db.Connection.Query(fmt.Sprintf(`
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf(`
   SELECT
             tc.constraint_name,
             kcu.column_name,
@@ -236,19 +272,19 @@ func (db *Postgres) GetForeignKeys(table string) (foreignKeys [][]string, error
 		return foreignKeys, err
 	}
 
-	foreignKeys = append(foreignKeys, columns)
+	foreignKeys = append(foreignKeys, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		foreignKeys = append(foreignKeys, row)
@@ -257,12 +293,12 @@ func (db *Postgres) GetForeignKeys(table string) (foreignKeys [][]string, error
 	return
 }
 
-func (db *Postgres) GetIndexes(table string) (indexes [][]string, error error) {
+func (db *Postgres) GetIndexes(ctx context.Context, table string) (indexes [][]models.CellValue, error error) {
 	splitTableString := strings.Split(table, ".")
 	tableSchema := splitTableString[0]
 	tableName := splitTableString[1]
 
-	rows, err := db.Connection.Query(fmt.Sprintf(`
+	rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf(`
   SELECT
             i.relname AS index_name,
             a.attname AS column_name,
@@ -295,19 +331,19 @@ func (db *Postgres) GetIndexes(table string) (indexes [][]string, error error) {
 
 	columns, _ := rows.Columns()
 
-	indexes = append(indexes, columns)
+	indexes = append(indexes, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		indexes = append(indexes, row)
@@ -316,7 +352,89 @@ func (db *Postgres) GetIndexes(table string) (indexes [][]string, error error) {
 	return
 }
 
-func (db *Postgres) GetRecords(table, where, sort string, offset, limit int) (records [][]string, totalRecords int, err error) {
+// GetPrimaryKeyColumnNames returns the columns that identify a row: the
+// primary key, then a unique index over NOT NULL columns, then the ctid of
+// plain tables.
+func (db *Postgres) GetPrimaryKeyColumnNames(ctx context.Context, database, table string) ([]string, error) {
+	primaryKeys, rowID, err := db.getRowIdentity(ctx, table)
+
+	if rowID != "" {
+		return []string{rowID}, err
+	}
+
+	return primaryKeys, err
+}
+
+// getRowIdentity returns the identity of the rows of a table, read once per
+// table
+func (db *Postgres) getRowIdentity(ctx context.Context, table string) (primaryKeys []string, rowID string, err error) {
+	return db.identities.get(table, func() ([]string, string, error) {
+		return db.readRowIdentity(ctx, table)
+	})
+}
+
+func (db *Postgres) readRowIdentity(ctx context.Context, table string) (primaryKeys []string, rowID string, err error) {
+	regclass := PostgresDialect.quoteDriverTableName(table)
+
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT a.attname
+		FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = $1::regclass AND i.indisprimary
+		ORDER BY array_position(i.indkey::int2[], a.attnum)`, regclass)
+	if err != nil {
+		return nil, "", err
+	}
+
+	primaryKeys, err = scanColumnNames(rows)
+	if err != nil || len(primaryKeys) > 0 {
+		return primaryKeys, "", err
+	}
+
+	rows, err = db.Connection.QueryContext(ctx, `
+		SELECT i.indexrelid::regclass::text, a.attname, a.attnotnull
+		FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = $1::regclass AND i.indisunique AND i.indpred IS NULL AND i.indexprs IS NULL
+		ORDER BY i.indexrelid::regclass::text, array_position(i.indkey::int2[], a.attnum)`, regclass)
+	if err != nil {
+		return nil, "", err
+	}
+
+	primaryKeys, err = firstSafeUniqueIndex(rows)
+	if err != nil || len(primaryKeys) > 0 {
+		return primaryKeys, "", err
+	}
+
+	// Only plain tables have a ctid that is unique, views and partitioned tables don't.
+	var relkind string
+
+	err = db.Connection.QueryRowContext(ctx, "SELECT relkind FROM pg_class WHERE oid = $1::regclass", regclass).Scan(&relkind)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if relkind == "r" {
+		return nil, "ctid", nil
+	}
+
+	return nil, "", nil
+}
+
+func (db *Postgres) GetRecords(ctx context.Context, table, where, sort string, offset, limit int) (records [][]models.CellValue, totalRecords int, err error) {
+	// Tables without a key are edited through their ctid, which is
+	// returned as the last column.
+	selectedColumns := "*"
+
+	_, rowID, err := db.getRowIdentity(ctx, table)
+	if err != nil {
+		return nil, 0, fmt.Errorf("can't identify the rows of %s: %w", table, err)
+	}
+
+	if rowID != "" {
+		selectedColumns = "*, " + rowID
+	}
+
 	table = db.formatTableName(table)
 	defaultLimit := 300
 	isPaginationEnabled := offset >= 0 && limit >= 0
@@ -325,17 +443,17 @@ func (db *Postgres) GetRecords(table, where, sort string, offset, limit int) (re
 		defaultLimit = limit
 	}
 
-	query := fmt.Sprintf("SELECT * FROM %s s LIMIT %d OFFSET %d", table, defaultLimit, offset)
+	query := fmt.Sprintf("SELECT %s FROM %s s LIMIT %d OFFSET %d", selectedColumns, table, defaultLimit, offset)
 
 	if where != "" {
-		query = fmt.Sprintf("SELECT * FROM %s %s LIMIT %d OFFSET %d", table, where, defaultLimit, offset)
+		query = fmt.Sprintf("SELECT %s FROM %s %s LIMIT %d OFFSET %d", selectedColumns, table, where, defaultLimit, offset)
 	}
 
 	if sort != "" {
-		query = fmt.Sprintf("SELECT * FROM %s %s ORDER BY %s LIMIT %d OFFSET %d", table, where, sort, defaultLimit, offset)
+		query = fmt.Sprintf("SELECT %s FROM %s %s ORDER BY %s LIMIT %d OFFSET %d", selectedColumns, table, where, sort, defaultLimit, offset)
 	}
 
-	paginatedRows, err := db.Connection.Query(query)
+	paginatedRows, err := db.Connection.QueryContext(ctx, query)
 	if err != nil {
 		return records, totalRecords, err
 	}
@@ -343,7 +461,7 @@ func (db *Postgres) GetRecords(table, where, sort string, offset, limit int) (re
 	if isPaginationEnabled {
 		queryWithoutLimit := fmt.Sprintf("SELECT COUNT(*) FROM %s %s", table, where)
 
-		rows := db.Connection.QueryRow(queryWithoutLimit)
+		rows := db.Connection.QueryRowContext(ctx, queryWithoutLimit)
 
 		if err != nil {
 			return records, totalRecords, err
@@ -359,19 +477,19 @@ func (db *Postgres) GetRecords(table, where, sort string, offset, limit int) (re
 
 	columns, _ := paginatedRows.Columns()
 
-	records = append(records, columns)
+	records = append(records, models.NewCellValues(columns))
 
 	for paginatedRows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		paginatedRows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		records = append(records, row)
@@ -381,198 +499,140 @@ func (db *Postgres) GetRecords(table, where, sort string, offset, limit int) (re
 	return
 }
 
-func (db *Postgres) UpdateRecord(table, column, value, primaryKeyColumnName, primaryKeyValue string) (err error) {
-	table = db.formatTableName(table)
-	query := fmt.Sprintf("UPDATE %s SET %s = '%s' WHERE \"%s\" = '%s'", table, column, value, primaryKeyColumnName, primaryKeyValue)
-	_, err = db.Connection.Exec(query)
+func (db *Postgres) UpdateRecord(ctx context.Context, table, column, value string, primaryKeyInfo []models.PrimaryKeyInfo) (err error) {
+	statement := PostgresDialect.BuildUpdate(PostgresDialect.quoteDriverTableName(table), []ColumnValue{{Column: column, Value: value}}, PrimaryKeyWhere(primaryKeyInfo))
+	_, err = db.Connection.ExecContext(ctx, statement.Query, statement.Args...)
 
 	return err
 }
 
-func (db *Postgres) DeleteRecord(table, primaryKeyColumnName, primaryKeyValue string) (err error) {
-	table = db.formatTableName(table)
-	query := fmt.Sprintf("DELETE FROM %s WHERE \"%s\" = '%s'", table, primaryKeyColumnName, primaryKeyValue)
-	_, err = db.Connection.Exec(query)
+func (db *Postgres) DeleteRecord(ctx context.Context, table string, primaryKeyInfo []models.PrimaryKeyInfo) (err error) {
+	statement := PostgresDialect.BuildDelete(PostgresDialect.quoteDriverTableName(table), PrimaryKeyWhere(primaryKeyInfo))
+	_, err = db.Connection.ExecContext(ctx, statement.Query, statement.Args...)
 
 	return err
 }
 
-func (db *Postgres) ExecuteDMLStatement(query string) (result string, err error) {
-	res, err := db.Connection.Exec(query)
+func (db *Postgres) ExecuteDMLStatement(ctx context.Context, query string) (rowsAffected int64, err error) {
+	res, err := db.Connection.ExecContext(ctx, query)
 
 	if err != nil {
-		return result, err
+		return rowsAffected, err
 	} else {
-		rowsAffected, _ := res.RowsAffected()
+		rowsAffected, _ = res.RowsAffected()
 
-		return fmt.Sprintf("%d rows affected", rowsAffected), err
+		return rowsAffected, err
 	}
 }
 
-func (db *Postgres) ExecuteQuery(query string) (results [][]string, err error) {
-	rows, err := db.Connection.Query(query)
-	if err != nil {
-		return results, err
-	}
-
-	defer rows.Close()
-
-	columns, _ := rows.Columns()
-
-	results = append(results, columns)
-
-	for rows.Next() {
-		rowValues := make([]interface{}, len(columns))
-		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
-		}
-
-		err = rows.Scan(rowValues...)
-		if err != nil {
-			return nil, err
-		}
-
-		var row []string
-		for _, col := range rowValues {
-			row = append(row, string(*col.(*sql.RawBytes)))
-		}
-
-		results = append(results, row)
+func (db *Postgres) ExecuteQuery(ctx context.Context, query string) (Cursor, error) {
+	ctx, cancel := context.WithCancel(ctx)
 
+	rows, err := db.Connection.QueryContext(ctx, query)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
 
-	return
+	return newRowsCursor(rows, nil, cancel, nil)
 }
 
-func (db *Postgres) ExecutePendingChanges(changes []models.DbDmlChange, inserts []models.DbInsert) (err error) {
-	queries := make([]string, 0, len(changes)+len(inserts))
-
-	// This will hold grouped changes by their RowId and Table
-	groupedUpdated := make(map[string][]models.DbDmlChange)
-	groupedDeletes := make([]models.DbDmlChange, 0, len(changes))
-
-	// Group changes by RowId and Table
-	for _, change := range changes {
-		if change.Type == "UPDATE" {
-			key := fmt.Sprintf("%s|%s|%s", db.formatTableName(change.Table), change.PrimaryKeyColumnName, change.PrimaryKeyValue)
-			groupedUpdated[key] = append(groupedUpdated[key], change)
-		} else if change.Type == "DELETE" {
-			groupedDeletes = append(groupedDeletes, change)
-		}
-	}
-
-	// Combine individual changes to SQL statements
-	for key, changes := range groupedUpdated {
-		columns := []string{}
-
-		// Split key into table and rowId
-		splitted := strings.Split(key, "|")
-		table := db.formatTableName(splitted[0])
-		PrimaryKeyColumnName := splitted[1]
-		primaryKeyValue := splitted[2]
-
-		for _, change := range changes {
-			columns = append(columns, fmt.Sprintf("%s='%s'", change.Column, change.Value))
-		}
-
-		// Merge all column updates
-		updateClause := strings.Join(columns, ", ")
-
-		query := fmt.Sprintf("UPDATE %s SET %s WHERE \"%s\" = '%s';", table, updateClause, PrimaryKeyColumnName, primaryKeyValue)
+func (db *Postgres) ExecutePendingChanges(ctx context.Context, changes []models.DbDmlChange, inserts []models.DbInsert) error {
+	return execInTransaction(ctx, db.Connection, PostgresDialect.BuildPendingChanges(changes, inserts))
+}
 
-		queries = append(queries, query)
-	}
+func (db *Postgres) SetProvider(provider string) {
+	db.Provider = provider
+}
 
-	for _, del := range groupedDeletes {
-		statementType := ""
-		query := ""
+func (db *Postgres) GetProvider() string {
+	return db.Provider
+}
 
-		statementType = "DELETE FROM"
-		query = fmt.Sprintf("%s %s WHERE \"%s\" = '%s'", statementType, db.formatTableName(del.Table), del.PrimaryKeyColumnName, del.PrimaryKeyValue)
+func (db *Postgres) SetReadOnly(readOnly bool) {
+	db.readOnly = readOnly
+}
 
-		if query != "" {
-			queries = append(queries, query)
-		}
+// Close closes the connections to every database of the server, including
+// the ones of the drivers returned by ForDatabase. Closing one of those
+// doesn't close anything, their connections stay open for the others.
+func (db *Postgres) Close() error {
+	if db.borrowed {
+		return nil
 	}
 
-	for _, insert := range inserts {
-		values := make([]string, 0, len(insert.Values))
-
-		for _, value := range insert.Values {
-			_, err := strconv.ParseFloat(value, 64)
-
-			if strings.ToLower(value) != "default" && err != nil {
-				values = append(values, fmt.Sprintf("'%s'", value))
-			} else {
-				values = append(values, value)
-			}
+	if db.pools == nil {
+		if db.Connection == nil {
+			return nil
 		}
 
-		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", db.formatTableName(insert.Table), strings.Join(insert.Columns, ", "), strings.Join(values, ", "))
-		queries = append(queries, query)
+		return db.Connection.Close()
 	}
 
-	tx, err := db.Connection.Begin()
-	if err != nil {
-		return err
-	}
+	db.pools.mutex.Lock()
+	defer db.pools.mutex.Unlock()
 
-	for _, query := range queries {
-		_, err = tx.Exec(query)
-		if err != nil {
-			tx.Rollback()
-
-			return err
-		}
-	}
+	var err error
 
-	err = tx.Commit()
-	if err != nil {
-		return err
+	for database, connection := range db.pools.connections {
+		err = errors.Join(err, connection.Close())
+		delete(db.pools.connections, database)
 	}
 
 	return err
 }
 
-func (db *Postgres) SetProvider(provider string) {
-	db.Provider = provider
-}
+// ForDatabase returns a driver querying another database of the server. It
+// shares the connections of db, the connection to each database is opened
+// once with the options of the url of db, e.g. its sslmode.
+func (db *Postgres) ForDatabase(database string) (Driver, error) {
+	if database == db.CurrentDatabase {
+		return db, nil
+	}
 
-func (db *Postgres) GetProvider() string {
-	return db.Provider
+	connection, err := db.pool(database)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Postgres{
+		Connection:      connection,
+		Provider:        db.Provider,
+		CurrentDatabase: database,
+		Urlstr:          db.Urlstr,
+		readOnly:        db.readOnly,
+		pools:           db.pools,
+		borrowed:        true,
+	}, nil
 }
 
-func (db *Postgres) SwitchDatabase(database string) error {
-	parsedConn, err := dburl.Parse(db.Urlstr)
-	if err != nil {
-		return err
+// pool returns the connection to a database, opened from the url of the
+// driver with only its database changed
+func (db *Postgres) pool(database string) (*sql.DB, error) {
+	if database == db.CurrentDatabase || db.pools == nil {
+		return db.Connection, nil
 	}
 
-	user := parsedConn.User.Username()
-	password, _ := parsedConn.User.Password()
-	host := parsedConn.Host
-	port := parsedConn.Port()
-	dbname := parsedConn.Path
+	db.pools.mutex.Lock()
+	defer db.pools.mutex.Unlock()
 
-	if port == "" {
-		port = DEFAULT_PORT
+	if connection, ok := db.pools.connections[database]; ok {
+		return connection, nil
 	}
 
-	if dbname == "" {
-		dbname = database
+	urlstr, err := WithDatabase(db.Urlstr, database)
+	if err != nil {
+		return nil, err
 	}
 
-	connection, err := sql.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s password=%s dbname='%s' sslmode=disable", host, port, user, password, dbname))
+	connection, err := openDB(urlstr, db.readOnly, postgresReadOnlySession)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	db.Connection.Close()
-	db.Connection = connection
-	db.PreviousDatabase = db.CurrentDatabase
-	db.CurrentDatabase = database
+	db.pools.connections[database] = connection
 
-	return nil
+	return connection, nil
 }
 
 func (db *Postgres) formatTableName(table string) string {
@@ -0,0 +1,114 @@
+package drivers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xo/dburl"
+)
+
+// Capabilities describes the differences between databases that the UI has to
+// know about.
+type Capabilities struct {
+	// PlainTableNames is true when tables are named by themselves, like in
+	// SQLite, instead of being prefixed with their database or schema.
+	PlainTableNames bool
+	// DatabaseInQuery is true when the database of a url is given by its
+	// database query parameter instead of its path.
+	DatabaseInQuery bool
+	// ConnectionPerDatabase is true when a connection only queries the
+	// database it was opened on, like in Postgres, so that each database is
+	// queried through a driver of its own, see ForDatabase.
+	ConnectionPerDatabase bool
+	// SchemaTableNames is true when tables are named schema.table, without
+	// their database, while the tree lists every database of the server, like
+	// in Postgres and SQL Server.
+	SchemaTableNames bool
+}
+
+// Registration describes a driver to the registry.
+type Registration struct {
+	// Provider is the dburl driver name saved with the connections, e.g. postgres
+	Provider string
+	// Schemes are the url schemes handled by the driver, dburl aliases included
+	Schemes      []string
+	Dialect      Dialect
+	Capabilities Capabilities
+	// DefaultPort is the port of the server when a url has none, e.g. 5432
+	DefaultPort string
+	New         func() Driver
+}
+
+var registry = make(map[string]Registration)
+
+// Register makes a driver available under its provider name. Schemes that
+// dburl doesn't know yet are registered as aliases of the provider.
+func Register(registration Registration) {
+	if _, ok := registry[registration.Provider]; ok {
+		panic(fmt.Sprintf("driver %s already registered", registration.Provider))
+	}
+
+	for _, scheme := range registration.Schemes {
+		if driver, _ := dburl.SchemeDriverAndAliases(scheme); driver == "" {
+			dburl.RegisterAlias(registration.Provider, scheme)
+		}
+	}
+
+	registry[registration.Provider] = registration
+}
+
+// Lookup returns the registration of a provider.
+func Lookup(provider string) (Registration, error) {
+	registration, ok := registry[provider]
+	if !ok {
+		return Registration{}, fmt.Errorf("unsupported database provider %q, supported schemes are: %s", provider, strings.Join(Schemes(), ", "))
+	}
+
+	return registration, nil
+}
+
+// New returns a new, not yet connected, driver for the provider.
+func New(provider string) (Driver, error) {
+	registration, err := Lookup(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return registration.New(), nil
+}
+
+// CapabilitiesOf returns the capabilities of a provider, or the zero value
+// when it isn't registered.
+func CapabilitiesOf(provider string) Capabilities {
+	return registry[provider].Capabilities
+}
+
+// DefaultPortOf returns the default port of a provider, or an empty string
+// when it doesn't listen on one.
+func DefaultPortOf(provider string) string {
+	return registry[provider].DefaultPort
+}
+
+// Schemes returns every url scheme handled by the registered drivers.
+func Schemes() []string {
+	schemes := []string{}
+
+	for _, registration := range registry {
+		schemes = append(schemes, registration.Schemes...)
+	}
+
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// DialectOf returns the dialect of a provider.
+func DialectOf(provider string) (Dialect, error) {
+	registration, err := Lookup(provider)
+	if err != nil {
+		return Dialect{}, err
+	}
+
+	return registration.Dialect, nil
+}
@@ -0,0 +1,139 @@
+package drivers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xo/dburl"
+)
+
+func TestRegister(t *testing.T) {
+	registration := Registration{
+		Provider:     "clickhouse",
+		Schemes:      []string{"clickhouse", "ch", "lazyclick"},
+		Dialect:      MySQLDialect,
+		Capabilities: Capabilities{PlainTableNames: true},
+		DefaultPort:  "9000",
+		New: func() Driver {
+			return &MySQL{}
+		},
+	}
+
+	Register(registration)
+	t.Cleanup(func() { delete(registry, "clickhouse") })
+
+	// The schemes dburl doesn't know open the provider
+	parsed, err := dburl.Parse("lazyclick://localhost/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Driver != "clickhouse" {
+		t.Errorf("lazyclick:// opens %q, want clickhouse", parsed.Driver)
+	}
+
+	if schemes := Schemes(); !strings.Contains(strings.Join(schemes, " "), "lazyclick") {
+		t.Errorf("Schemes() = %v, want lazyclick among them", schemes)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a provider twice didn't panic")
+		}
+	}()
+
+	Register(registration)
+}
+
+func TestLookup(t *testing.T) {
+	registration, err := Lookup("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if registration.Provider != "postgres" || registration.DefaultPort != "5432" {
+		t.Errorf("Lookup(postgres) = %s on %s, want postgres on 5432", registration.Provider, registration.DefaultPort)
+	}
+
+	_, err = Lookup("oracle")
+	if err == nil {
+		t.Fatal("Lookup(oracle) didn't fail")
+	}
+
+	if !strings.Contains(err.Error(), `"oracle"`) || !strings.Contains(err.Error(), "postgres") {
+		t.Errorf("Lookup(oracle) = %q, want it to name the provider and the supported schemes", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		provider string
+		expected Driver
+	}{
+		{"mysql", &MySQL{}},
+		{"postgres", &Postgres{}},
+		{"sqlite3", &SQLite{}},
+		{"sqlserver", &MSSQL{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.provider, func(t *testing.T) {
+			driver, err := New(test.provider)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if reflect.TypeOf(driver) != reflect.TypeOf(test.expected) {
+				t.Errorf("New(%s) = %T, want %T", test.provider, driver, test.expected)
+			}
+
+			other, _ := New(test.provider)
+			if other == driver {
+				t.Errorf("New(%s) returned the same driver twice", test.provider)
+			}
+		})
+	}
+
+	_, err := New("oracle")
+	if err == nil {
+		t.Error("New(oracle) didn't fail")
+	}
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	tests := []struct {
+		provider string
+		expected Capabilities
+	}{
+		{"mysql", Capabilities{}},
+		{"postgres", Capabilities{ConnectionPerDatabase: true, SchemaTableNames: true}},
+		{"sqlite3", Capabilities{PlainTableNames: true}},
+		{"sqlserver", Capabilities{DatabaseInQuery: true, SchemaTableNames: true}},
+		{"oracle", Capabilities{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.provider, func(t *testing.T) {
+			if capabilities := CapabilitiesOf(test.provider); capabilities != test.expected {
+				t.Errorf("CapabilitiesOf(%s) = %+v, want %+v", test.provider, capabilities, test.expected)
+			}
+		})
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	dialect, err := DialectOf("sqlserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if quoted := dialect.QuoteIdentifier("id"); quoted != "[id]" {
+		t.Errorf("QuoteIdentifier(id) = %s, want [id]", quoted)
+	}
+
+	_, err = DialectOf("oracle")
+	if err == nil {
+		t.Error("DialectOf(oracle) didn't fail")
+	}
+}
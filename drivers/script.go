@@ -0,0 +1,422 @@
+package drivers
+
+import "strings"
+
+// ScriptStatement is a statement of a script split by SplitStatements.
+type ScriptStatement struct {
+	Query string
+	// Start and End are the byte offsets of the statement in the script
+	Start int
+	End   int
+	// ReturnsRows tells whether the statement is run as a query or executed
+	ReturnsRows bool
+}
+
+// SplitStatements splits a script into its statements. Delimiters inside
+// strings, quoted identifiers, comments and trigger bodies don't end a
+// statement, and the DELIMITER command of the MySQL client is followed, as
+// is the GO batch separator of the SQL Server tools. Comments and whitespace
+// between statements are left out.
+func (d Dialect) SplitStatements(script string) []ScriptStatement {
+	statements := []ScriptStatement{}
+	delimiter := ";"
+
+	// start is -1 between statements
+	start, end := -1, -1
+	words := []string{}
+	blockDepth := 0
+
+	for position := 0; position < len(script); {
+		if start == -1 && d.delimiterCommand {
+			if newDelimiter, lineEnd, ok := delimiterCommand(script, position); ok {
+				delimiter = newDelimiter
+				position = lineEnd
+				continue
+			}
+		}
+
+		if blockDepth == 0 && strings.HasPrefix(script[position:], delimiter) {
+			if start != -1 {
+				statements = append(statements, d.newScriptStatement(script, start, end))
+			}
+
+			start, end = -1, -1
+			words = words[:0]
+			position += len(delimiter)
+			continue
+		}
+
+		token := d.nextToken(script, position)
+		position = token.End
+
+		if token.Kind == WhitespaceToken || token.Kind == CommentToken {
+			continue
+		}
+
+		if d.batchSeparator && token.Kind == WordToken && strings.EqualFold(token.Text, "GO") && aloneOnLine(script, token.Start, token.End) {
+			if start != -1 {
+				statements = append(statements, d.newScriptStatement(script, start, end))
+			}
+
+			start, end = -1, -1
+			words = words[:0]
+			blockDepth = 0
+			continue
+		}
+
+		if start == -1 {
+			start = token.Start
+		}
+
+		end = token.End
+
+		if token.Kind == WordToken {
+			word := strings.ToUpper(token.Text)
+			blockDepth += triggerBlockDepthChange(words, word)
+			if blockDepth < 0 {
+				blockDepth = 0
+			}
+
+			words = append(words, word)
+		}
+	}
+
+	if start != -1 {
+		statements = append(statements, d.newScriptStatement(script, start, end))
+	}
+
+	return statements
+}
+
+// StatementAt returns the statement of the script found at the given offset.
+// Past the end of a statement it is still returned while the offset is on the
+// same line, so that a statement can be run with the cursor right after its
+// delimiter. Otherwise the next statement is returned, or the last one at the
+// end of the script.
+func (d Dialect) StatementAt(script string, offset int) (statement ScriptStatement, ok bool) {
+	statements := d.SplitStatements(script)
+
+	for i, statement := range statements {
+		if offset <= statement.End {
+			if offset >= statement.Start || i == 0 {
+				return statement, true
+			}
+
+			previous := statements[i-1]
+			if !strings.Contains(script[previous.End:offset], "\n") {
+				return previous, true
+			}
+
+			return statement, true
+		}
+	}
+
+	if len(statements) == 0 {
+		return statement, false
+	}
+
+	return statements[len(statements)-1], true
+}
+
+func (d Dialect) newScriptStatement(script string, start, end int) ScriptStatement {
+	query := script[start:end]
+
+	return ScriptStatement{
+		Query:       query,
+		Start:       start,
+		End:         end,
+		ReturnsRows: d.ReturnsRows(query),
+	}
+}
+
+// delimiterCommand reads a DELIMITER command at position, which must be
+// between statements. It returns the new delimiter and the end of the line.
+func delimiterCommand(script string, position int) (delimiter string, lineEnd int, ok bool) {
+	const command = "delimiter"
+
+	rest := script[position:]
+	if len(rest) <= len(command) || !strings.EqualFold(rest[:len(command)], command) || !isSpace(rest[len(command)]) {
+		return "", 0, false
+	}
+
+	lineEnd = len(script)
+	if newline := strings.IndexByte(rest, '\n'); newline != -1 {
+		lineEnd = position + newline
+	}
+
+	fields := strings.Fields(script[position+len(command) : lineEnd])
+	if len(fields) == 0 {
+		return "", 0, false
+	}
+
+	return fields[0], lineEnd, true
+}
+
+// aloneOnLine tells whether the text between start and end is the only text
+// of its line, whitespace aside
+func aloneOnLine(script string, start, end int) bool {
+	for i := start - 1; i >= 0 && script[i] != '\n'; i-- {
+		if !isSpace(script[i]) {
+			return false
+		}
+	}
+
+	for i := end; i < len(script) && script[i] != '\n'; i++ {
+		if !isSpace(script[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// triggerBlockDepthChange tracks the BEGIN ... END body of a CREATE TRIGGER
+// statement, whose inner statements end with the delimiter too. words are the
+// previous words of the statement.
+func triggerBlockDepthChange(words []string, word string) int {
+	if len(words) == 0 || words[0] != "CREATE" || !containsWord(words, "TRIGGER") {
+		return 0
+	}
+
+	// END IF, END LOOP... close blocks whose opening word isn't counted
+	afterEnd := words[len(words)-1] == "END"
+
+	switch word {
+	case "BEGIN":
+		return 1
+	case "CASE":
+		if afterEnd {
+			return 0
+		}
+
+		return 1
+	case "IF", "LOOP", "WHILE", "REPEAT":
+		if afterEnd {
+			return 1
+		}
+	case "END":
+		return -1
+	}
+
+	return 0
+}
+
+func containsWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReturnsRows tells whether a statement returns rows, so that it has to be
+// run as a query instead of being executed.
+func (d Dialect) ReturnsRows(query string) bool {
+	words := d.topLevelWords(query)
+	if len(words) == 0 {
+		return false
+	}
+
+	if words[0] == "WITH" {
+		// The statement that follows the common table expressions decides
+		for i, word := range words[1:] {
+			switch word {
+			case "SELECT", "INSERT", "UPDATE", "DELETE", "MERGE":
+				return returnsRows(words[i+1:])
+			}
+		}
+
+		return false
+	}
+
+	return returnsRows(words)
+}
+
+// writeWords are the words of the statements that write, wherever they are
+// in a statement, e.g. in a common table expression or in SELECT ... INTO
+var writeWords = map[string]bool{
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"MERGE":    true,
+	"INTO":     true,
+	"CREATE":   true,
+	"ALTER":    true,
+	"DROP":     true,
+	"TRUNCATE": true,
+	"GRANT":    true,
+	"REVOKE":   true,
+}
+
+// ReadsOnly tells whether a statement only reads, so that it can run on a
+// read-only connection. It errs on the side of caution: a statement holding
+// a word that writes, e.g. SELECT ... FOR UPDATE, doesn't.
+func (d Dialect) ReadsOnly(query string) bool {
+	words := d.topLevelWords(query)
+	if len(words) == 0 {
+		return false
+	}
+
+	switch words[0] {
+	case "SELECT", "WITH", "VALUES", "TABLE", "SHOW", "DESCRIBE", "DESC", "EXPLAIN":
+	case "PRAGMA":
+		if !d.readsPragma(query) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	for _, token := range d.Tokenize(query) {
+		if token.Kind == WordToken && writeWords[strings.ToUpper(token.Text)] {
+			return false
+		}
+
+		// set_config changes a setting of the session, e.g. the one that
+		// makes it read-only
+		if (token.Kind == WordToken || token.Kind == QuotedIdentifierToken) && strings.EqualFold(strings.Trim(token.Text, `"`), "set_config") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// readOnlyPragmas are the pragmas that only read, and whether they read with
+// an argument, e.g. PRAGMA table_info(name). The others, and an argument given
+// to a pragma that doesn't read with one, may change the database or the
+// session, e.g. PRAGMA query_only(0).
+var readOnlyPragmas = map[string]bool{
+	"TABLE_INFO":        true,
+	"TABLE_XINFO":       true,
+	"TABLE_LIST":        true,
+	"INDEX_INFO":        true,
+	"INDEX_XINFO":       true,
+	"INDEX_LIST":        true,
+	"FOREIGN_KEY_LIST":  true,
+	"FOREIGN_KEY_CHECK": true,
+	"INTEGRITY_CHECK":   true,
+	"QUICK_CHECK":       true,
+	"DATABASE_LIST":     false,
+	"COLLATION_LIST":    false,
+	"COMPILE_OPTIONS":   false,
+	"FUNCTION_LIST":     false,
+	"MODULE_LIST":       false,
+	"PRAGMA_LIST":       false,
+	"DATA_VERSION":      false,
+	"FREELIST_COUNT":    false,
+	"PAGE_COUNT":        false,
+	"PAGE_SIZE":         false,
+	"SCHEMA_VERSION":    false,
+	"USER_VERSION":      false,
+	"APPLICATION_ID":    false,
+	"ENCODING":          false,
+	"JOURNAL_MODE":      false,
+	"FOREIGN_KEYS":      false,
+	"QUERY_ONLY":        false,
+}
+
+// readsPragma tells whether a PRAGMA statement only reads
+func (d Dialect) readsPragma(query string) bool {
+	tokens := []Token{}
+
+	for _, token := range d.Tokenize(query) {
+		if token.Kind != WhitespaceToken && token.Kind != CommentToken && token.Text != ";" {
+			tokens = append(tokens, token)
+		}
+	}
+
+	// PRAGMA schema.name leaves out the schema
+	if len(tokens) > 3 && tokens[2].Text == "." {
+		tokens = tokens[2:]
+	}
+
+	if len(tokens) < 2 || tokens[1].Kind != WordToken {
+		return false
+	}
+
+	withArgument, ok := readOnlyPragmas[strings.ToUpper(tokens[1].Text)]
+
+	switch {
+	case !ok:
+		return false
+	case len(tokens) == 2:
+		return true
+	default:
+		// Only PRAGMA name(argument) may follow, PRAGMA name = value sets
+		// the pragma
+		return withArgument && tokens[2].Text == "(" && tokens[len(tokens)-1].Text == ")"
+	}
+}
+
+// ChangesSchema tells whether a statement creates, alters or drops tables or
+// other objects, after which the metadata read from the database is stale.
+func (d Dialect) ChangesSchema(query string) bool {
+	words := d.topLevelWords(query)
+	if len(words) == 0 {
+		return false
+	}
+
+	switch words[0] {
+	case "CREATE", "ALTER", "DROP", "RENAME":
+		return true
+	}
+
+	return false
+}
+
+func returnsRows(words []string) bool {
+	switch words[0] {
+	case "SELECT":
+		// SELECT ... INTO stores the rows instead of returning them
+		return !containsWord(words, "INTO")
+	case "PRAGMA":
+		// PRAGMA name = value only sets the pragma
+		return !containsWord(words, "=")
+	case "VALUES", "TABLE", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "CALL", "EXEC", "EXECUTE":
+		return true
+	case "INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE":
+		for i, word := range words {
+			switch word {
+			case "RETURNING":
+				return true
+			case "OUTPUT":
+				// OUTPUT ... INTO stores the rows instead of returning them
+				return !containsWord(words[i:], "INTO")
+			}
+		}
+	}
+
+	return false
+}
+
+// topLevelWords returns the upper cased words and operators of a statement
+// that aren't inside parentheses, leading parentheses aside.
+func (d Dialect) topLevelWords(query string) []string {
+	words := []string{}
+	depth := 0
+
+	for _, token := range d.Tokenize(query) {
+		switch token.Kind {
+		case WordToken:
+			if depth == 0 || len(words) == 0 {
+				words = append(words, strings.ToUpper(token.Text))
+			}
+		case PunctuationToken:
+			switch token.Text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			case "=":
+				if depth == 0 {
+					words = append(words, token.Text)
+				}
+			}
+		}
+	}
+
+	return words
+}
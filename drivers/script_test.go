@@ -0,0 +1,211 @@
+package drivers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		script  string
+		want    []string
+	}{
+		{
+			name:    "delimiters",
+			dialect: PostgresDialect,
+			script:  "SELECT 1;\n\nSELECT 2 ;  SELECT 3",
+			want:    []string{"SELECT 1", "SELECT 2", "SELECT 3"},
+		},
+		{
+			name:    "comments between statements",
+			dialect: PostgresDialect,
+			script:  "-- first\nSELECT 1; /* second */ SELECT 2;\n-- done",
+			want:    []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:    "delimiters in strings and identifiers",
+			dialect: PostgresDialect,
+			script:  `SELECT ';' AS "a;b"; SELECT $x$ ; $x$`,
+			want:    []string{`SELECT ';' AS "a;b"`, `SELECT $x$ ; $x$`},
+		},
+		{
+			name:    "hash comment",
+			dialect: MySQLDialect,
+			script:  "SELECT 1 # one; two\n; SELECT 'it\\'s;'",
+			want:    []string{"SELECT 1", `SELECT 'it\'s;'`},
+		},
+		{
+			name:    "delimiter command",
+			dialect: MySQLDialect,
+			script:  "DELIMITER //\nCREATE PROCEDURE p() BEGIN SELECT 1; END//\nDELIMITER ;\nCALL p();",
+			want:    []string{"CREATE PROCEDURE p() BEGIN SELECT 1; END", "CALL p()"},
+		},
+		{
+			name:    "trigger body",
+			dialect: SQLiteDialect,
+			script:  "CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = n + 1; END; SELECT 1",
+			want:    []string{"CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = n + 1; END", "SELECT 1"},
+		},
+		{
+			name:    "go batch separator",
+			dialect: MSSQLDialect,
+			script:  "CREATE TABLE a (go int)\nGO\nSELECT 'x\nGO\n' FROM a\n  go  \nSELECT 1; SELECT go FROM a\nGO",
+			want:    []string{"CREATE TABLE a (go int)", "SELECT 'x\nGO\n' FROM a", "SELECT 1", "SELECT go FROM a"},
+		},
+		{
+			name:    "go is a word elsewhere",
+			dialect: PostgresDialect,
+			script:  "SELECT 1\nGO\n",
+			want:    []string{"SELECT 1\nGO"},
+		},
+		{
+			name:    "empty",
+			dialect: SQLiteDialect,
+			script:  " ;\n-- nothing\n;",
+			want:    []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queries := []string{}
+
+			for _, statement := range test.dialect.SplitStatements(test.script) {
+				if statement.Query != test.script[statement.Start:statement.End] {
+					t.Errorf("query %q isn't at %d:%d of the script", statement.Query, statement.Start, statement.End)
+				}
+
+				queries = append(queries, statement.Query)
+			}
+
+			if !reflect.DeepEqual(queries, test.want) {
+				t.Errorf("statements are %q, want %q", queries, test.want)
+			}
+		})
+	}
+}
+
+func TestReadsOnly(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		query   string
+		want    bool
+	}{
+		{PostgresDialect, "SELECT * FROM a", true},
+		{PostgresDialect, "(SELECT 1) UNION (SELECT 2)", true},
+		{PostgresDialect, "WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{PostgresDialect, "WITH x AS (DELETE FROM a RETURNING *) SELECT * FROM x", false},
+		{PostgresDialect, "SELECT * INTO b FROM a", false},
+		{PostgresDialect, "SELECT * FROM a FOR UPDATE", false},
+		{PostgresDialect, "SELECT 'delete' FROM a", true},
+		{PostgresDialect, `SELECT "update" FROM a`, true},
+		{PostgresDialect, "EXPLAIN SELECT 1", true},
+		{PostgresDialect, "UPDATE a SET b = 1", false},
+		{MySQLDialect, "SHOW TABLES", true},
+		{MySQLDialect, "DESCRIBE a", true},
+		{MySQLDialect, "CALL p()", false},
+		{SQLiteDialect, "PRAGMA table_info(a)", true},
+		{SQLiteDialect, "PRAGMA query_only = OFF", false},
+		{SQLiteDialect, "PRAGMA query_only(0)", false},
+		{SQLiteDialect, "pragma query_only (false);", false},
+		{SQLiteDialect, "PRAGMA main.query_only(0)", false},
+		{SQLiteDialect, "PRAGMA query_only", true},
+		{SQLiteDialect, "PRAGMA main.table_info(a)", true},
+		{SQLiteDialect, "PRAGMA user_version(5)", false},
+		{SQLiteDialect, "PRAGMA writable_schema", false},
+		{SQLiteDialect, "PRAGMA optimize", false},
+		{PostgresDialect, "SELECT set_config('default_transaction_read_only', 'off', false)", false},
+		{PostgresDialect, `SELECT pg_catalog."set_config"('transaction_read_only', 'off', true)`, false},
+		{PostgresDialect, "SET default_transaction_read_only = off", false},
+		{PostgresDialect, "SET SESSION CHARACTERISTICS AS TRANSACTION READ WRITE", false},
+		{PostgresDialect, "RESET ALL", false},
+		{PostgresDialect, "SELECT current_setting('default_transaction_read_only')", true},
+		{MySQLDialect, "SET SESSION TRANSACTION READ WRITE", false},
+		{MSSQLDialect, "EXEC sp_who", false},
+		{MSSQLDialect, "-- only a comment", false},
+	}
+
+	for _, test := range tests {
+		if got := test.dialect.ReadsOnly(test.query); got != test.want {
+			t.Errorf("ReadsOnly(%q) is %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestStatementAt(t *testing.T) {
+	// ‸ marks the cursor in the scripts
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{
+			name:   "start of a statement",
+			script: "‸SELECT 1; SELECT 2",
+			want:   "SELECT 1",
+		},
+		{
+			name:   "end of a statement",
+			script: "SELECT 1‸; SELECT 2",
+			want:   "SELECT 1",
+		},
+		{
+			name:   "after the delimiter on the same line",
+			script: "SELECT 1;‸ SELECT 2",
+			want:   "SELECT 1",
+		},
+		{
+			name:   "start of the next line",
+			script: "SELECT 1;\n‸SELECT 2;",
+			want:   "SELECT 2",
+		},
+		{
+			name:   "blank line between statements",
+			script: "SELECT 1;\n\n‸\nSELECT 2;",
+			want:   "SELECT 2",
+		},
+		{
+			name:   "delimiter inside a string",
+			script: "SELECT 'a;‸b'; SELECT 2",
+			want:   "SELECT 'a;b'",
+		},
+		{
+			name:   "delimiter inside a comment",
+			script: "SELECT 1 -- not; ‸here\n; SELECT 2",
+			want:   "SELECT 1",
+		},
+		{
+			name:   "comment after the last statement",
+			script: "SELECT 1; SELECT 2;\n-- done‸",
+			want:   "SELECT 2",
+		},
+		{
+			name:   "multiple-byte characters",
+			script: "SELECT 'é'; SELECT 'ü‸'",
+			want:   "SELECT 'ü'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			offset := strings.Index(test.script, "‸")
+			script := strings.Replace(test.script, "‸", "", 1)
+
+			statement, ok := PostgresDialect.StatementAt(script, offset)
+			if !ok || statement.Query != test.want {
+				t.Errorf("StatementAt(%q) = %q, %t, want %q", test.script, statement.Query, ok, test.want)
+			}
+
+			if script[statement.Start:statement.End] != statement.Query {
+				t.Errorf("the statement is at %d-%d, which holds %q", statement.Start, statement.End, script[statement.Start:statement.End])
+			}
+		})
+	}
+
+	if _, ok := PostgresDialect.StatementAt("  -- nothing\n", 3); ok {
+		t.Error("a script without statements has a statement")
+	}
+}
@@ -0,0 +1,142 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+
+	"github.com/xo/dburl"
+)
+
+// readOnlyDriver is a driver that can open read-only sessions, so that the
+// database itself refuses the statements that write.
+type readOnlyDriver interface {
+	SetReadOnly(readOnly bool)
+}
+
+// SetReadOnly makes the sessions a driver opens read-only, when its database
+// supports it. It has to be set before connecting.
+func SetReadOnly(driver Driver, readOnly bool) {
+	if readOnlyDriver, ok := driver.(readOnlyDriver); ok {
+		readOnlyDriver.SetReadOnly(readOnly)
+	}
+}
+
+// OpensReadOnlySessions tells whether the database of a provider refuses the
+// statements that write on a read-only connection. Otherwise only the
+// interface refuses them.
+func OpensReadOnlySessions(provider string) bool {
+	driver, err := New(provider)
+	if err != nil {
+		return false
+	}
+
+	_, ok := driver.(readOnlyDriver)
+
+	return ok
+}
+
+// openDB opens a url like dburl.Open. When readOnly is true, statement is run
+// on each connection of the pool as it's opened, to make its session
+// read-only.
+func openDB(urlstr string, readOnly bool, statement string) (*sql.DB, error) {
+	if !readOnly {
+		return dburl.Open(urlstr)
+	}
+
+	parsed, err := dburl.Parse(urlstr)
+	if err != nil {
+		return nil, err
+	}
+
+	name := parsed.Driver
+	if parsed.GoDriver != "" {
+		name = parsed.GoDriver
+	}
+
+	// The driver registered under the name is only reachable through a
+	// database
+	db, err := sql.Open(name, parsed.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDriver := db.Driver()
+	db.Close()
+
+	var connector driver.Connector = dsnConnector{driver: sqlDriver, dsn: parsed.DSN}
+
+	if driverContext, ok := sqlDriver.(driver.DriverContext); ok {
+		connector, err = driverContext.OpenConnector(parsed.DSN)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sql.OpenDB(sessionConnector{Connector: connector, statement: statement}), nil
+}
+
+// sessionConnector runs a statement on each connection it opens
+type sessionConnector struct {
+	driver.Connector
+	statement string
+}
+
+func (connector sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := connector.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = execConn(ctx, conn, connector.statement)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// Close closes the connector it wraps when it has to be closed, sql.DB
+// closes its connector only when it implements io.Closer
+func (connector sessionConnector) Close() error {
+	if closer, ok := connector.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// execConn runs a statement without arguments on a connection of a driver
+func execConn(ctx context.Context, conn driver.Conn, statement string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, statement, nil)
+		return err
+	}
+
+	stmt, err := conn.Prepare(statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(nil)
+
+	return err
+}
+
+// dsnConnector opens the connections of a driver that has no connector of
+// its own, like sql.Open does
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (connector dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return connector.driver.Open(connector.dsn)
+}
+
+func (connector dsnConnector) Driver() driver.Driver {
+	return connector.driver
+}
@@ -0,0 +1,108 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenDBReadOnly(t *testing.T) {
+	urlstr := "sqlite:" + filepath.Join(t.TempDir(), "test.db")
+
+	db, err := openDB(urlstr, false, sqliteReadOnlySession)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec("CREATE TABLE t (id INTEGER)")
+	db.Close()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = openDB(urlstr, true, sqliteReadOnlySession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO t VALUES (1)")
+	if err == nil {
+		t.Error("a read-only session wrote")
+	}
+
+	_, err = db.Exec("SELECT * FROM t")
+	if err != nil {
+		t.Errorf("a read-only session can't read: %v", err)
+	}
+}
+
+// closingConnector records whether it was closed
+type closingConnector struct {
+	driver.Connector
+	closed *bool
+}
+
+func (connector closingConnector) Close() error {
+	*connector.closed = true
+	return nil
+}
+
+func TestSessionConnectorClose(t *testing.T) {
+	var closed bool
+
+	db := sql.OpenDB(sessionConnector{Connector: closingConnector{closed: &closed}})
+
+	err := db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !closed {
+		t.Error("the connector wrapped wasn't closed")
+	}
+
+	// A connector without Close has nothing to close
+	db = sql.OpenDB(sessionConnector{Connector: dsnConnector{}})
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecInTransactionRollsBack(t *testing.T) {
+	db, err := openDB("sqlite:"+filepath.Join(t.TempDir(), "test.db"), false, sqliteReadOnlySession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE t (id INTEGER)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = execInTransaction(context.Background(), db, []Statement{
+		{Query: "INSERT INTO t VALUES (1)"},
+		{Query: `DELETE FROM "t" WHERE "_rowid_" = ?`, Args: []interface{}{"7"}, ByRowID: true},
+	})
+	if !errors.Is(err, errRowIDChanged) {
+		t.Fatalf("error is %v, want %v", err, errRowIDChanged)
+	}
+
+	var count int
+
+	err = db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 0 {
+		t.Errorf("%d rows were kept after the rollback", count)
+	}
+}
@@ -1,35 +1,53 @@
 package drivers
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/jorgerojas26/lazysql/models"
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/xo/dburl"
 )
 
 type SQLite struct {
 	Connection *sql.DB
 	Provider   string
+	// readOnly opens the sessions read-only
+	readOnly bool
+	// identities are how the rows of each table are identified
+	identities identityCache
 }
 
-func (db *SQLite) TestConnection(urlstr string) (err error) {
-	return db.Connect(urlstr)
+// sqliteReadOnlySession makes a connection refuse the statements that write
+const sqliteReadOnlySession = "PRAGMA query_only = ON"
+
+func init() {
+	Register(Registration{
+		Provider:     "sqlite3",
+		Schemes:      []string{"sqlite3", "sqlite", "sq"},
+		Dialect:      SQLiteDialect,
+		Capabilities: Capabilities{PlainTableNames: true},
+		New: func() Driver {
+			return &SQLite{}
+		},
+	})
 }
 
-func (db *SQLite) Connect(urlstr string) (err error) {
+func (db *SQLite) TestConnection(ctx context.Context, urlstr string) (err error) {
+	return db.Connect(ctx, urlstr)
+}
+
+func (db *SQLite) Connect(ctx context.Context, urlstr string) (err error) {
 	db.SetProvider("sqlite3")
 
-	db.Connection, err = dburl.Open(urlstr)
+	db.Connection, err = openDB(urlstr, db.readOnly, sqliteReadOnlySession)
 
 	if err != nil {
 		return err
 	}
 
-	err = db.Connection.Ping()
+	err = db.Connection.PingContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -37,10 +55,10 @@ func (db *SQLite) Connect(urlstr string) (err error) {
 	return nil
 }
 
-func (db *SQLite) GetDatabases() ([]string, error) {
+func (db *SQLite) GetDatabases(ctx context.Context) ([]string, error) {
 	var databases []string
 
-	rows, err := db.Connection.Query("SELECT file FROM pragma_database_list WHERE name='main'")
+	rows, err := db.Connection.QueryContext(ctx, "SELECT file FROM pragma_database_list WHERE name='main'")
 	if err != nil {
 		return databases, err
 	}
@@ -61,8 +79,8 @@ func (db *SQLite) GetDatabases() ([]string, error) {
 	return databases, nil
 }
 
-func (db *SQLite) GetTables(database string) (map[string][]string, error) {
-	rows, err := db.Connection.Query("SELECT name FROM sqlite_master WHERE type='table'")
+func (db *SQLite) GetTables(ctx context.Context, database string) (map[string][]string, error) {
+	rows, err := db.Connection.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type='table'")
 
 	tables := make(map[string][]string)
 
@@ -80,8 +98,8 @@ func (db *SQLite) GetTables(database string) (map[string][]string, error) {
 	return tables, nil
 }
 
-func (db *SQLite) GetTableColumns(database, table string) (results [][]string, err error) {
-	rows, err := db.Connection.Query("PRAGMA table_info(" + table + ")")
+func (db *SQLite) GetTableColumns(ctx context.Context, database, table string) (results [][]models.CellValue, err error) {
+	rows, err := db.Connection.QueryContext(ctx, "PRAGMA table_info("+table+")")
 	if err != nil {
 		return results, err
 	}
@@ -92,24 +110,20 @@ func (db *SQLite) GetTableColumns(database, table string) (results [][]string, e
 		return results, err
 	}
 
-	results = append(results, columns[1:])
+	results = append(results, models.NewCellValues(columns[1:]))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 
 		for _, col := range rowValues {
-			if col == nil {
-				row = append(row, "NULL")
-			} else {
-				row = append(row, string(*col.(*sql.RawBytes)))
-			}
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		results = append(results, row[1:])
@@ -118,8 +132,8 @@ func (db *SQLite) GetTableColumns(database, table string) (results [][]string, e
 	return
 }
 
-func (db *SQLite) GetConstraints(table string) (results [][]string, err error) {
-	rows, err := db.Connection.Query("SELECT sql FROM sqlite_master WHERE type='table' AND name = '" + table + "'")
+func (db *SQLite) GetConstraints(ctx context.Context, table string) (results [][]models.CellValue, err error) {
+	rows, err := db.Connection.QueryContext(ctx, "SELECT sql FROM sqlite_master WHERE type='table' AND name = '"+table+"'")
 	if err != nil {
 		return results, err
 	}
@@ -131,23 +145,19 @@ func (db *SQLite) GetConstraints(table string) (results [][]string, err error) {
 		return results, err
 	}
 
-	results = append(results, columns)
+	results = append(results, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			if col == nil {
-				row = append(row, "NULL")
-			} else {
-				row = append(row, string(*col.(*sql.RawBytes)))
-			}
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		results = append(results, row)
@@ -156,8 +166,8 @@ func (db *SQLite) GetConstraints(table string) (results [][]string, err error) {
 	return
 }
 
-func (db *SQLite) GetForeignKeys(table string) (results [][]string, err error) {
-	rows, err := db.Connection.Query("PRAGMA foreign_key_list(" + table + ")")
+func (db *SQLite) GetForeignKeys(ctx context.Context, table string) (results [][]models.CellValue, err error) {
+	rows, err := db.Connection.QueryContext(ctx, "PRAGMA foreign_key_list("+table+")")
 	if err != nil {
 		return results, err
 	}
@@ -169,23 +179,19 @@ func (db *SQLite) GetForeignKeys(table string) (results [][]string, err error) {
 		return results, err
 	}
 
-	results = append(results, columns)
+	results = append(results, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			if col == nil {
-				row = append(row, "NULL")
-			} else {
-				row = append(row, string(*col.(*sql.RawBytes)))
-			}
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		results = append(results, row)
@@ -194,8 +200,8 @@ func (db *SQLite) GetForeignKeys(table string) (results [][]string, err error) {
 	return
 }
 
-func (db *SQLite) GetIndexes(table string) (results [][]string, err error) {
-	rows, err := db.Connection.Query("PRAGMA index_list(" + table + ")")
+func (db *SQLite) GetIndexes(ctx context.Context, table string) (results [][]models.CellValue, err error) {
+	rows, err := db.Connection.QueryContext(ctx, "PRAGMA index_list("+table+")")
 	if err != nil {
 		return results, err
 	}
@@ -203,23 +209,19 @@ func (db *SQLite) GetIndexes(table string) (results [][]string, err error) {
 
 	columns, _ := rows.Columns()
 
-	results = append(results, columns)
+	results = append(results, models.NewCellValues(columns))
 
 	for rows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		rows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			if col == nil {
-				row = append(row, "NULL")
-			} else {
-				row = append(row, string(*col.(*sql.RawBytes)))
-			}
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		results = append(results, row)
@@ -228,7 +230,100 @@ func (db *SQLite) GetIndexes(table string) (results [][]string, err error) {
 	return
 }
 
-func (db *SQLite) GetRecords(table, where, sort string, offset, limit int) (paginatedResults [][]string, totalRecords int, err error) {
+// GetPrimaryKeyColumnNames returns the columns that identify a row: the
+// primary key, then a unique index over NOT NULL columns, then the rowid.
+func (db *SQLite) GetPrimaryKeyColumnNames(ctx context.Context, database, table string) ([]string, error) {
+	primaryKeys, rowID, err := db.getRowIdentity(ctx, table)
+
+	if rowID != "" {
+		return []string{rowID}, err
+	}
+
+	return primaryKeys, err
+}
+
+// getRowIdentity returns the identity of the rows of a table, read once per
+// table
+func (db *SQLite) getRowIdentity(ctx context.Context, table string) (primaryKeys []string, rowID string, err error) {
+	return db.identities.get(table, func() ([]string, string, error) {
+		return db.readRowIdentity(ctx, table)
+	})
+}
+
+func (db *SQLite) readRowIdentity(ctx context.Context, table string) (primaryKeys []string, rowID string, err error) {
+	rows, err := db.Connection.QueryContext(ctx, "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", table)
+	if err != nil {
+		return nil, "", err
+	}
+
+	primaryKeys, err = scanColumnNames(rows)
+	if err != nil || len(primaryKeys) > 0 {
+		return primaryKeys, "", err
+	}
+
+	rows, err = db.Connection.QueryContext(ctx, `
+		SELECT il.name, COALESCE(ii.name, ''), COALESCE(ti."notnull", 0)
+		FROM pragma_index_list(?) il
+		JOIN pragma_index_info(il.name) ii
+		LEFT JOIN pragma_table_info(?) ti ON ti.name = ii.name
+		WHERE il."unique" = 1 AND il.partial = 0
+		ORDER BY il.name, ii.seqno`, table, table)
+	if err != nil {
+		return nil, "", err
+	}
+
+	primaryKeys, err = firstSafeUniqueIndex(rows)
+	if err != nil || len(primaryKeys) > 0 {
+		return primaryKeys, "", err
+	}
+
+	// Views have no rowid. Tables without rowid always have a primary key.
+	var isTable bool
+
+	err = db.Connection.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&isTable)
+	if err != nil || !isTable {
+		return nil, "", err
+	}
+
+	rows, err = db.Connection.QueryContext(ctx, "SELECT LOWER(name) FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, "", err
+	}
+
+	columnNames, err := scanColumnNames(rows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	shadowed := make(map[string]bool)
+	for _, name := range columnNames {
+		shadowed[name] = true
+	}
+
+	// A column with one of these names hides the rowid behind it.
+	for _, alias := range []string{"rowid", "_rowid_", "oid"} {
+		if !shadowed[alias] {
+			return nil, alias, nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+func (db *SQLite) GetRecords(ctx context.Context, table, where, sort string, offset, limit int) (paginatedResults [][]models.CellValue, totalRecords int, err error) {
+	// Tables without a key are edited through their rowid, which is
+	// returned as the last column.
+	selectedColumns := "*"
+
+	_, rowID, err := db.getRowIdentity(ctx, table)
+	if err != nil {
+		return nil, 0, fmt.Errorf("can't identify the rows of %s: %w", table, err)
+	}
+
+	if rowID != "" {
+		selectedColumns = "*, " + rowID
+	}
+
 	defaultLimit := 300
 
 	isPaginationEnabled := offset >= 0 && limit >= 0
@@ -237,17 +332,17 @@ func (db *SQLite) GetRecords(table, where, sort string, offset, limit int) (pagi
 		defaultLimit = limit
 	}
 
-	query := fmt.Sprintf("SELECT * FROM %s s LIMIT %d,%d", table, offset, defaultLimit)
+	query := fmt.Sprintf("SELECT %s FROM %s s LIMIT %d,%d", selectedColumns, table, offset, defaultLimit)
 
 	if where != "" {
-		query = fmt.Sprintf("SELECT * FROM %s %s LIMIT %d,%d", table, where, offset, defaultLimit)
+		query = fmt.Sprintf("SELECT %s FROM %s %s LIMIT %d,%d", selectedColumns, table, where, offset, defaultLimit)
 	}
 
 	if sort != "" {
-		query = fmt.Sprintf("SELECT * FROM %s %s ORDER BY %s LIMIT %d,%d", table, where, sort, offset, defaultLimit)
+		query = fmt.Sprintf("SELECT %s FROM %s %s ORDER BY %s LIMIT %d,%d", selectedColumns, table, where, sort, offset, defaultLimit)
 	}
 
-	paginatedRows, err := db.Connection.Query(query)
+	paginatedRows, err := db.Connection.QueryContext(ctx, query)
 	if err != nil {
 		return paginatedResults, totalRecords, err
 	}
@@ -255,7 +350,7 @@ func (db *SQLite) GetRecords(table, where, sort string, offset, limit int) (pagi
 	if isPaginationEnabled {
 		queryWithoutLimit := fmt.Sprintf("SELECT COUNT(*) FROM %s %s", table, where)
 
-		rows := db.Connection.QueryRow(queryWithoutLimit)
+		rows := db.Connection.QueryRowContext(ctx, queryWithoutLimit)
 
 		if err != nil {
 			return paginatedResults, totalRecords, err
@@ -268,23 +363,19 @@ func (db *SQLite) GetRecords(table, where, sort string, offset, limit int) (pagi
 
 	columns, _ := paginatedRows.Columns()
 
-	paginatedResults = append(paginatedResults, columns)
+	paginatedResults = append(paginatedResults, models.NewCellValues(columns))
 
 	for paginatedRows.Next() {
 		rowValues := make([]interface{}, len(columns))
 		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
+			rowValues[i] = new(sql.NullString)
 		}
 
 		paginatedRows.Scan(rowValues...)
 
-		var row []string
+		var row []models.CellValue
 		for _, col := range rowValues {
-			if col == nil {
-				row = append(row, "NULL")
-			} else {
-				row = append(row, string(*col.(*sql.RawBytes)))
-			}
+			row = append(row, cellValue(col.(*sql.NullString)))
 		}
 
 		paginatedResults = append(paginatedResults, row)
@@ -294,163 +385,46 @@ func (db *SQLite) GetRecords(table, where, sort string, offset, limit int) (pagi
 	return
 }
 
-func (db *SQLite) ExecuteQuery(query string) (results [][]string, err error) {
-	rows, err := db.Connection.Query(query)
-	if err != nil {
-		return results, err
-	}
-
-	defer rows.Close()
-
-	columns, _ := rows.Columns()
-
-	results = append(results, columns)
-
-	for rows.Next() {
-		rowValues := make([]interface{}, len(columns))
-		for i := range columns {
-			rowValues[i] = new(sql.RawBytes)
-		}
-
-		rows.Scan(rowValues...)
-
-		var row []string
-		for _, col := range rowValues {
-			if col == nil {
-				row = append(row, "NULL")
-			} else {
-				row = append(row, string(*col.(*sql.RawBytes)))
-			}
-		}
-
-		results = append(results, row)
+func (db *SQLite) ExecuteQuery(ctx context.Context, query string) (Cursor, error) {
+	ctx, cancel := context.WithCancel(ctx)
 
+	rows, err := db.Connection.QueryContext(ctx, query)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
 
-	return
+	return newRowsCursor(rows, nil, cancel, nil)
 }
 
-func (db *SQLite) UpdateRecord(table, column, value, primaryKeyColumnName, primaryKeyValue string) error {
-	query := fmt.Sprintf("UPDATE %s SET %s = \"%s\" WHERE %s = %s;", table, column, value, primaryKeyColumnName, primaryKeyValue)
-	_, err := db.Connection.Exec(query)
+func (db *SQLite) UpdateRecord(ctx context.Context, table, column, value string, primaryKeyInfo []models.PrimaryKeyInfo) error {
+	statement := SQLiteDialect.BuildUpdate(SQLiteDialect.QuoteIdentifier(table), []ColumnValue{{Column: column, Value: value}}, PrimaryKeyWhere(primaryKeyInfo))
+	_, err := db.Connection.ExecContext(ctx, statement.Query, statement.Args...)
 
 	return err
 }
 
-func (db *SQLite) DeleteRecord(table, primaryKeyColumnName, primaryKeyValue string) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE %s = \"%s\"", table, primaryKeyColumnName, primaryKeyValue)
-	_, err := db.Connection.Exec(query)
+func (db *SQLite) DeleteRecord(ctx context.Context, table string, primaryKeyInfo []models.PrimaryKeyInfo) error {
+	statement := SQLiteDialect.BuildDelete(SQLiteDialect.QuoteIdentifier(table), PrimaryKeyWhere(primaryKeyInfo))
+	_, err := db.Connection.ExecContext(ctx, statement.Query, statement.Args...)
 
 	return err
 }
 
-func (db *SQLite) ExecuteDMLStatement(query string) (result string, err error) {
-	res, error := db.Connection.Exec(query)
+func (db *SQLite) ExecuteDMLStatement(ctx context.Context, query string) (rowsAffected int64, err error) {
+	res, error := db.Connection.ExecContext(ctx, query)
 
 	if error != nil {
-		return result, error
+		return rowsAffected, error
 	} else {
-		rowsAffected, _ := res.RowsAffected()
+		rowsAffected, _ = res.RowsAffected()
 
-		return fmt.Sprintf("%d rows affected", rowsAffected), error
+		return rowsAffected, error
 	}
 }
 
-func (db *SQLite) ExecutePendingChanges(changes []models.DbDmlChange, inserts []models.DbInsert) (err error) {
-	queries := make([]string, 0, len(changes)+len(inserts))
-
-	// This will hold grouped changes by their RowId and Table
-	groupedUpdated := make(map[string][]models.DbDmlChange)
-	groupedDeletes := make([]models.DbDmlChange, 0, len(changes))
-
-	// Group changes by RowId and Table
-	for _, change := range changes {
-		if change.Type == "UPDATE" {
-			key := fmt.Sprintf("%s|%s|%s", change.Table, change.PrimaryKeyColumnName, change.PrimaryKeyValue)
-			groupedUpdated[key] = append(groupedUpdated[key], change)
-		} else if change.Type == "DELETE" {
-			groupedDeletes = append(groupedDeletes, change)
-		}
-	}
-
-	// Combine individual changes to SQL statements
-	for key, changes := range groupedUpdated {
-		columns := []string{}
-
-		// Split key into table and rowId
-		splitted := strings.Split(key, "|")
-		table := splitted[0]
-		primaryKeyColumnName := splitted[1]
-		primaryKeyValue := splitted[2]
-
-		for _, change := range changes {
-			columns = append(columns, fmt.Sprintf("%s='%s'", change.Column, change.Value))
-		}
-
-		// Merge all column updates
-		updateClause := strings.Join(columns, ", ")
-
-		query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = '%s';", table, updateClause, primaryKeyColumnName, primaryKeyValue)
-
-		queries = append(queries, query)
-	}
-
-	for _, delete := range groupedDeletes {
-		statementType := ""
-		query := ""
-
-		statementType = "DELETE FROM"
-
-		query = fmt.Sprintf("%s %s WHERE %s = \"%s\"", statementType, delete.Table, delete.PrimaryKeyColumnName, delete.PrimaryKeyValue)
-
-		if query != "" {
-			queries = append(queries, query)
-		}
-	}
-
-	for _, insert := range inserts {
-		values := make([]string, 0, len(insert.Values))
-
-		columnsToBeInserted := insert.Columns
-
-		for _, value := range insert.Values {
-			_, error := strconv.ParseFloat(value, 64)
-
-			if error != nil {
-				values = append(values, fmt.Sprintf("\"%s\"", value))
-			} else {
-				values = append(values, value)
-			}
-		}
-
-		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", insert.Table, strings.Join(columnsToBeInserted, ", "), strings.Join(values, ", "))
-
-		queries = append(queries, query)
-	}
-
-	tx, error := db.Connection.Begin()
-	if error != nil {
-		return error
-	}
-
-	for _, query := range queries {
-		fmt.Printf("LS -> drivers/sqlite.go:440 -> query: %+v\n", query)
-		_, err = tx.Exec(query)
-
-		if err != nil {
-			tx.Rollback()
-
-			return err
-		}
-	}
-
-	err = tx.Commit()
-
-	if err != nil {
-		return err
-	}
-
-	return err
+func (db *SQLite) ExecutePendingChanges(ctx context.Context, changes []models.DbDmlChange, inserts []models.DbInsert) error {
+	return execInTransaction(ctx, db.Connection, SQLiteDialect.BuildPendingChanges(changes, inserts))
 }
 
 func (db *SQLite) SetProvider(provider string) {
@@ -460,3 +434,15 @@ func (db *SQLite) SetProvider(provider string) {
 func (db *SQLite) GetProvider() string {
 	return db.Provider
 }
+
+func (db *SQLite) SetReadOnly(readOnly bool) {
+	db.readOnly = readOnly
+}
+
+func (db *SQLite) Close() error {
+	if db.Connection == nil {
+		return nil
+	}
+
+	return db.Connection.Close()
+}
@@ -0,0 +1,438 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// Dialect describes how a database quotes identifiers and how it numbers
+// the placeholders of a parameterized statement, along with the lexical
+// rules that scripts are split with.
+type Dialect struct {
+	openQuote   string
+	closeQuote  string
+	placeholder func(position int) string
+	// placeholderPrefixes are the characters bind parameters start with
+	placeholderPrefixes string
+	// emptyInsert is appended to INSERT INTO when every column takes its default
+	emptyInsert string
+
+	// hashComments is true when # starts a comment until the end of the line
+	hashComments bool
+	// nestedComments is true when block comments can be nested
+	nestedComments bool
+	// backslashEscapes is true when a backslash escapes the next character of a string
+	backslashEscapes bool
+	// doubleQuotedStrings is true when "text" is a string instead of an identifier
+	doubleQuotedStrings bool
+	// escapeStrings is true for E'text' strings, where backslashes are escapes
+	escapeStrings bool
+	// dollarQuotes is true for $tag$text$tag$ strings
+	dollarQuotes bool
+	// delimiterCommand is true when scripts can change the statement
+	// delimiter with the DELIMITER client command
+	delimiterCommand bool
+	// batchSeparator is true when a line with only GO ends a statement
+	batchSeparator bool
+
+	// extraKeywords and extraFunctions are completed along with the common ones
+	extraKeywords  []string
+	extraFunctions []string
+	// lowerCaseIdentifiers is true when unquoted identifiers are folded to
+	// lower case, so that names with upper case letters have to be quoted
+	lowerCaseIdentifiers bool
+	// nationalStrings is true when string literals need the N prefix to hold
+	// any unicode character
+	nationalStrings bool
+	// typeAffinity is true when column types are only preferences: integers
+	// are 64 bits and the length of text isn't enforced
+	typeAffinity bool
+	// rowIDs are the names of the row ids the rows of a table without a key
+	// are found by, e.g. the ctid of Postgres
+	rowIDs []string
+	// databaseTableNames is true when the tables of every database are
+	// reached through one connection, so that the tables of the pending
+	// changes are prefixed with their database
+	databaseTableNames bool
+}
+
+var (
+	MySQLDialect = Dialect{
+		openQuote:           "`",
+		closeQuote:          "`",
+		placeholder:         questionMarkPlaceholder,
+		placeholderPrefixes: "?",
+		emptyInsert:         "() VALUES ()",
+		hashComments:        true,
+		backslashEscapes:    true,
+		doubleQuotedStrings: true,
+		delimiterCommand:    true,
+		extraKeywords:       mysqlKeywords,
+		extraFunctions:      mysqlFunctions,
+	}
+	PostgresDialect = Dialect{
+		openQuote:            `"`,
+		closeQuote:           `"`,
+		placeholder:          dollarPlaceholder,
+		placeholderPrefixes:  "$",
+		emptyInsert:          "DEFAULT VALUES",
+		nestedComments:       true,
+		escapeStrings:        true,
+		dollarQuotes:         true,
+		extraKeywords:        postgresKeywords,
+		extraFunctions:       postgresFunctions,
+		lowerCaseIdentifiers: true,
+		rowIDs:               []string{"ctid"},
+	}
+	SQLiteDialect = Dialect{
+		openQuote:           `"`,
+		closeQuote:          `"`,
+		placeholder:         questionMarkPlaceholder,
+		placeholderPrefixes: "?:@$",
+		emptyInsert:         "DEFAULT VALUES",
+		extraKeywords:       sqliteKeywords,
+		extraFunctions:      sqliteFunctions,
+		typeAffinity:        true,
+		rowIDs:              []string{"rowid", "_rowid_", "oid"},
+	}
+	MSSQLDialect = Dialect{
+		openQuote:           "[",
+		closeQuote:          "]",
+		placeholder:         namedPlaceholder,
+		placeholderPrefixes: "@",
+		emptyInsert:         "DEFAULT VALUES",
+		extraKeywords:       mssqlKeywords,
+		extraFunctions:      mssqlFunctions,
+		nationalStrings:     true,
+		batchSeparator:      true,
+		databaseTableNames:  true,
+	}
+)
+
+func questionMarkPlaceholder(_ int) string {
+	return "?"
+}
+
+func dollarPlaceholder(position int) string {
+	return fmt.Sprintf("$%d", position)
+}
+
+func namedPlaceholder(position int) string {
+	return fmt.Sprintf("@p%d", position)
+}
+
+// QuoteIdentifier quotes a single identifier, doubling any quote character
+// it contains so that it can't terminate the identifier early.
+func (d Dialect) QuoteIdentifier(identifier string) string {
+	escaped := strings.ReplaceAll(identifier, d.closeQuote, d.closeQuote+d.closeQuote)
+
+	return d.openQuote + escaped + d.closeQuote
+}
+
+// QuoteTableName quotes the name of a table, prefixed with its schema when it
+// has one. Neither name is split on its dots, a table name can hold some.
+func (d Dialect) QuoteTableName(schema, table string) string {
+	if schema == "" {
+		return d.QuoteIdentifier(table)
+	}
+
+	return d.QuoteIdentifier(schema) + "." + d.QuoteIdentifier(table)
+}
+
+// quoteDriverTableName quotes a table named the way the drivers are given it,
+// schema.table in the databases with schemas. The schema ends at the first
+// dot, the dots after it are part of the table name.
+func (d Dialect) quoteDriverTableName(name string) string {
+	schema, table, ok := strings.Cut(name, ".")
+	if !ok {
+		return d.QuoteIdentifier(name)
+	}
+
+	return d.QuoteTableName(schema, table)
+}
+
+// pendingTableName returns the quoted name of the table of a pending change
+func (d Dialect) pendingTableName(database, schema, table string) string {
+	if d.databaseTableNames && database != "" {
+		return d.QuoteIdentifier(database) + "." + d.QuoteTableName(schema, table)
+	}
+
+	return d.QuoteTableName(schema, table)
+}
+
+// Placeholder returns the bind parameter marker for the argument at the
+// given 1-based position.
+func (d Dialect) Placeholder(position int) string {
+	return d.placeholder(position)
+}
+
+// Statement is a query along with the arguments bound to its placeholders.
+type Statement struct {
+	Query string
+	Args  []interface{}
+	// ByRowID is true when the statement finds its row by a row id, which
+	// can change along with the row, so that it has to change a row
+	ByRowID bool
+}
+
+// ColumnValue pairs a column with the value it is set to or compared against.
+type ColumnValue struct {
+	Column string
+	Value  interface{}
+}
+
+// PrimaryKeyWhere turns the identity of a row into where pairs.
+func PrimaryKeyWhere(primaryKeyInfo []models.PrimaryKeyInfo) []ColumnValue {
+	where := make([]ColumnValue, 0, len(primaryKeyInfo))
+
+	for _, info := range primaryKeyInfo {
+		where = append(where, ColumnValue{Column: info.Name, Value: info.Value})
+	}
+
+	return where
+}
+
+// byRowID tells whether the identity of a row is a row id of the dialect
+func (d Dialect) byRowID(primaryKeyInfo []models.PrimaryKeyInfo) bool {
+	if len(primaryKeyInfo) != 1 {
+		return false
+	}
+
+	for _, rowID := range d.rowIDs {
+		if primaryKeyInfo[0].Name == rowID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func primaryKeyString(primaryKeyInfo []models.PrimaryKeyInfo) string {
+	parts := make([]string, 0, len(primaryKeyInfo))
+
+	for _, info := range primaryKeyInfo {
+		parts = append(parts, fmt.Sprintf("%q=%q", info.Name, info.Value))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// defaultValue is bound in place of a value to emit the DEFAULT keyword
+// instead of a placeholder.
+type defaultValue struct{}
+
+// bindValue returns what is bound in place of a cell value: nil for NULL,
+// defaultValue for DEFAULT and the literal otherwise.
+func bindValue(value models.CellValue) interface{} {
+	switch value.Type {
+	case models.Null:
+		return nil
+	case models.Default:
+		return defaultValue{}
+	default:
+		return value.Value
+	}
+}
+
+type statementBuilder struct {
+	dialect Dialect
+	args    []interface{}
+}
+
+func (b *statementBuilder) bind(value interface{}) string {
+	if _, ok := value.(defaultValue); ok {
+		return "DEFAULT"
+	}
+
+	b.args = append(b.args, value)
+
+	return b.dialect.Placeholder(len(b.args))
+}
+
+func (b *statementBuilder) assignments(values []ColumnValue, separator string) string {
+	clauses := make([]string, 0, len(values))
+
+	for _, value := range values {
+		clauses = append(clauses, fmt.Sprintf("%s = %s", b.dialect.QuoteIdentifier(value.Column), b.bind(value.Value)))
+	}
+
+	return strings.Join(clauses, separator)
+}
+
+// BuildSelect builds the query of every row of a table, filtered by a WHERE
+// clause and sorted by an ORDER BY expression like the results table. The
+// table is quoted, see QuoteTableName.
+func (d Dialect) BuildSelect(table, where, sort string) string {
+	query := fmt.Sprintf("SELECT * FROM %s", table)
+
+	if where != "" {
+		query += " " + where
+	}
+
+	if sort != "" {
+		query += " ORDER BY " + sort
+	}
+
+	return query
+}
+
+// BuildUpdate builds an UPDATE statement that sets the given columns on the
+// rows matching every where pair. The table is quoted, see QuoteTableName.
+func (d Dialect) BuildUpdate(table string, set, where []ColumnValue) Statement {
+	builder := &statementBuilder{dialect: d}
+
+	setClause := builder.assignments(set, ", ")
+	whereClause := builder.assignments(where, " AND ")
+
+	return Statement{
+		Query: fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, setClause, whereClause),
+		Args:  builder.args,
+	}
+}
+
+// BuildDelete builds a DELETE statement for the rows matching every where
+// pair. The table is quoted, see QuoteTableName.
+func (d Dialect) BuildDelete(table string, where []ColumnValue) Statement {
+	builder := &statementBuilder{dialect: d}
+
+	whereClause := builder.assignments(where, " AND ")
+
+	return Statement{
+		Query: fmt.Sprintf("DELETE FROM %s WHERE %s", table, whereClause),
+		Args:  builder.args,
+	}
+}
+
+// BuildInsert builds an INSERT statement for a single row. Columns bound to
+// their default value are left out, since not every database accepts DEFAULT
+// inside VALUES. The table is quoted, see QuoteTableName.
+func (d Dialect) BuildInsert(table string, columns []string, values []interface{}) Statement {
+	builder := &statementBuilder{dialect: d}
+
+	quotedColumns := make([]string, 0, len(columns))
+	placeholders := make([]string, 0, len(values))
+
+	for i, column := range columns {
+		if _, ok := values[i].(defaultValue); ok {
+			continue
+		}
+
+		quotedColumns = append(quotedColumns, d.QuoteIdentifier(column))
+		placeholders = append(placeholders, builder.bind(values[i]))
+	}
+
+	if len(quotedColumns) == 0 {
+		return Statement{
+			Query: fmt.Sprintf("INSERT INTO %s %s", table, d.emptyInsert),
+		}
+	}
+
+	return Statement{
+		Query: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(quotedColumns, ", "), strings.Join(placeholders, ", ")),
+		Args:  builder.args,
+	}
+}
+
+// BuildPendingChanges turns the pending changes of the results table into
+// statements. Updates to the same row are merged into a single statement,
+// and left out when the row is deleted too.
+func (d Dialect) BuildPendingChanges(changes []models.DbDmlChange, inserts []models.DbInsert) []Statement {
+	statements := make([]Statement, 0, len(changes)+len(inserts))
+
+	// This will hold grouped changes by their RowId and Table
+	groupedUpdates := make(map[string][]models.DbDmlChange)
+	groupOrder := []string{}
+	deletes := make([]models.DbDmlChange, 0, len(changes))
+	deleted := make(map[string]bool)
+
+	for _, change := range changes {
+		key := d.pendingTableName(change.Database, change.Schema, change.Table) + "|" + primaryKeyString(change.PrimaryKeyInfo)
+
+		switch change.Type {
+		case "UPDATE":
+			if _, ok := groupedUpdates[key]; !ok {
+				groupOrder = append(groupOrder, key)
+			}
+
+			groupedUpdates[key] = append(groupedUpdates[key], change)
+		case "DELETE":
+			deletes = append(deletes, change)
+			deleted[key] = true
+		}
+	}
+
+	for _, key := range groupOrder {
+		// Updating the row first would change its row id, the delete
+		// wouldn't find it anymore
+		if deleted[key] {
+			continue
+		}
+
+		group := groupedUpdates[key]
+		set := make([]ColumnValue, 0, len(group))
+
+		for _, change := range group {
+			set = append(set, ColumnValue{Column: change.Column, Value: bindValue(change.Value)})
+		}
+
+		table := d.pendingTableName(group[0].Database, group[0].Schema, group[0].Table)
+		statement := d.BuildUpdate(table, set, PrimaryKeyWhere(group[0].PrimaryKeyInfo))
+		statement.ByRowID = d.byRowID(group[0].PrimaryKeyInfo)
+
+		statements = append(statements, statement)
+	}
+
+	for _, del := range deletes {
+		statement := d.BuildDelete(d.pendingTableName(del.Database, del.Schema, del.Table), PrimaryKeyWhere(del.PrimaryKeyInfo))
+		statement.ByRowID = d.byRowID(del.PrimaryKeyInfo)
+
+		statements = append(statements, statement)
+	}
+
+	for _, insert := range inserts {
+		values := make([]interface{}, 0, len(insert.Values))
+
+		for _, value := range insert.Values {
+			values = append(values, bindValue(value))
+		}
+
+		statements = append(statements, d.BuildInsert(d.pendingTableName(insert.Database, insert.Schema, insert.Table), insert.Columns, values))
+	}
+
+	return statements
+}
+
+// errRowIDChanged is returned when a row found by its row id isn't there
+// anymore, the row was changed or deleted since it was read
+var errRowIDChanged = errors.New("a row without a key was changed or deleted since it was read, reload the table and make the changes again")
+
+// execInTransaction runs every statement inside a single transaction and
+// rolls it back on the first failure.
+func execInTransaction(ctx context.Context, connection *sql.DB, statements []Statement) error {
+	tx, err := connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		var result sql.Result
+
+		result, err = tx.ExecContext(ctx, statement.Query, statement.Args...)
+		if err == nil && statement.ByRowID {
+			if affected, affectedErr := result.RowsAffected(); affectedErr == nil && affected == 0 {
+				err = errRowIDChanged
+			}
+		}
+
+		if err != nil {
+			return errors.Join(err, tx.Rollback())
+		}
+	}
+
+	return tx.Commit()
+}
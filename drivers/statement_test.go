@@ -0,0 +1,245 @@
+package drivers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+func TestQuoteTableName(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		schema  string
+		table   string
+		want    string
+	}{
+		{PostgresDialect, "public", "users", `"public"."users"`},
+		{PostgresDialect, "", "users", `"users"`},
+		{PostgresDialect, "public", "v1.users", `"public"."v1.users"`},
+		{PostgresDialect, "my.schema", "users", `"my.schema"."users"`},
+		{MySQLDialect, "app", "we`ird", "`app`.`we``ird`"},
+		{SQLiteDialect, "", "a.b", `"a.b"`},
+		{MSSQLDialect, "dbo", "odd]name", "[dbo].[odd]]name]"},
+	}
+
+	for _, test := range tests {
+		if got := test.dialect.QuoteTableName(test.schema, test.table); got != test.want {
+			t.Errorf("QuoteTableName(%q, %q) is %s, want %s", test.schema, test.table, got, test.want)
+		}
+	}
+}
+
+func TestBuildUpdate(t *testing.T) {
+	set := []ColumnValue{{Column: "name", Value: "ada"}, {Column: "note", Value: nil}}
+	where := []ColumnValue{{Column: "id", Value: "1"}, {Column: "tenant", Value: "2"}}
+
+	tests := []struct {
+		name    string
+		dialect Dialect
+		schema  string
+		table   string
+		want    string
+	}{
+		{"mysql", MySQLDialect, "", "users", "UPDATE `users` SET `name` = ?, `note` = ? WHERE `id` = ? AND `tenant` = ?"},
+		{"postgres", PostgresDialect, "public", "users", `UPDATE "public"."users" SET "name" = $1, "note" = $2 WHERE "id" = $3 AND "tenant" = $4`},
+		{"sqlite", SQLiteDialect, "", "users", `UPDATE "users" SET "name" = ?, "note" = ? WHERE "id" = ? AND "tenant" = ?`},
+		{"mssql", MSSQLDialect, "dbo", "users", "UPDATE [dbo].[users] SET [name] = @p1, [note] = @p2 WHERE [id] = @p3 AND [tenant] = @p4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statement := test.dialect.BuildUpdate(test.dialect.QuoteTableName(test.schema, test.table), set, where)
+
+			if statement.Query != test.want {
+				t.Errorf("query is %s, want %s", statement.Query, test.want)
+			}
+
+			wantArgs := []interface{}{"ada", nil, "1", "2"}
+			if !reflect.DeepEqual(statement.Args, wantArgs) {
+				t.Errorf("args are %v, want %v", statement.Args, wantArgs)
+			}
+		})
+	}
+}
+
+func TestBuildDelete(t *testing.T) {
+	where := []ColumnValue{{Column: "id", Value: "1"}}
+
+	tests := []struct {
+		name    string
+		dialect Dialect
+		schema  string
+		table   string
+		want    string
+	}{
+		{"mysql", MySQLDialect, "", "users", "DELETE FROM `users` WHERE `id` = ?"},
+		{"postgres", PostgresDialect, "public", "users", `DELETE FROM "public"."users" WHERE "id" = $1`},
+		{"sqlite", SQLiteDialect, "", "users", `DELETE FROM "users" WHERE "id" = ?`},
+		{"mssql", MSSQLDialect, "dbo", "users", "DELETE FROM [dbo].[users] WHERE [id] = @p1"},
+		{"quote in name", MySQLDialect, "", "we`ird", "DELETE FROM `we``ird` WHERE `id` = ?"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statement := test.dialect.BuildDelete(test.dialect.QuoteTableName(test.schema, test.table), where)
+
+			if statement.Query != test.want {
+				t.Errorf("query is %s, want %s", statement.Query, test.want)
+			}
+
+			if !reflect.DeepEqual(statement.Args, []interface{}{"1"}) {
+				t.Errorf("args are %v, want [1]", statement.Args)
+			}
+		})
+	}
+}
+
+func TestBuildPendingChanges(t *testing.T) {
+	update := func(key models.PrimaryKeyInfo, column string, value models.CellValue) models.DbDmlChange {
+		return models.DbDmlChange{Type: "UPDATE", Table: "t", Column: column, Value: value, PrimaryKeyInfo: []models.PrimaryKeyInfo{key}}
+	}
+	del := func(key models.PrimaryKeyInfo) models.DbDmlChange {
+		return models.DbDmlChange{Type: "DELETE", Table: "t", PrimaryKeyInfo: []models.PrimaryKeyInfo{key}}
+	}
+
+	id1 := models.PrimaryKeyInfo{Name: "id", Value: "1"}
+	id2 := models.PrimaryKeyInfo{Name: "id", Value: "2"}
+
+	tests := []struct {
+		name    string
+		dialect Dialect
+		changes []models.DbDmlChange
+		inserts []models.DbInsert
+		want    []Statement
+	}{
+		{
+			name:    "updates of a row are merged",
+			dialect: MySQLDialect,
+			changes: []models.DbDmlChange{
+				update(id1, "a", models.CellValue{Value: "x"}),
+				update(id2, "a", models.CellValue{Type: models.Null}),
+				update(id1, "b", models.CellValue{Type: models.Default}),
+			},
+			want: []Statement{
+				{Query: "UPDATE `t` SET `a` = ?, `b` = DEFAULT WHERE `id` = ?", Args: []interface{}{"x", "1"}},
+				{Query: "UPDATE `t` SET `a` = ? WHERE `id` = ?", Args: []interface{}{nil, "2"}},
+			},
+		},
+		{
+			name:    "deletes follow the updates",
+			dialect: PostgresDialect,
+			changes: []models.DbDmlChange{
+				del(id2),
+				update(id1, "a", models.CellValue{Value: "x"}),
+			},
+			want: []Statement{
+				{Query: `UPDATE "t" SET "a" = $1 WHERE "id" = $2`, Args: []interface{}{"x", "1"}},
+				{Query: `DELETE FROM "t" WHERE "id" = $1`, Args: []interface{}{"2"}},
+			},
+		},
+		{
+			name:    "update of a deleted row is left out",
+			dialect: SQLiteDialect,
+			changes: []models.DbDmlChange{
+				update(id1, "a", models.CellValue{Value: "x"}),
+				del(id1),
+			},
+			want: []Statement{
+				{Query: `DELETE FROM "t" WHERE "id" = ?`, Args: []interface{}{"1"}},
+			},
+		},
+		{
+			name:    "ctid",
+			dialect: PostgresDialect,
+			changes: []models.DbDmlChange{
+				update(models.PrimaryKeyInfo{Name: "ctid", Value: "(0,1)"}, "a", models.CellValue{Value: "x"}),
+				del(models.PrimaryKeyInfo{Name: "ctid", Value: "(0,2)"}),
+			},
+			want: []Statement{
+				{Query: `UPDATE "t" SET "a" = $1 WHERE "ctid" = $2`, Args: []interface{}{"x", "(0,1)"}, ByRowID: true},
+				{Query: `DELETE FROM "t" WHERE "ctid" = $1`, Args: []interface{}{"(0,2)"}, ByRowID: true},
+			},
+		},
+		{
+			name:    "rowid",
+			dialect: SQLiteDialect,
+			changes: []models.DbDmlChange{
+				del(models.PrimaryKeyInfo{Name: "_rowid_", Value: "7"}),
+			},
+			want: []Statement{
+				{Query: `DELETE FROM "t" WHERE "_rowid_" = ?`, Args: []interface{}{"7"}, ByRowID: true},
+			},
+		},
+		{
+			name:    "ctid is a column elsewhere",
+			dialect: MSSQLDialect,
+			changes: []models.DbDmlChange{
+				del(models.PrimaryKeyInfo{Name: "ctid", Value: "1"}),
+			},
+			want: []Statement{
+				{Query: "DELETE FROM [t] WHERE [ctid] = @p1", Args: []interface{}{"1"}},
+			},
+		},
+		{
+			name:    "schema and dotted table",
+			dialect: PostgresDialect,
+			changes: []models.DbDmlChange{
+				{Type: "DELETE", Database: "app", Schema: "public", Table: "v1.t", PrimaryKeyInfo: []models.PrimaryKeyInfo{id1}},
+				{Type: "DELETE", Database: "app", Schema: "public.v1", Table: "t", PrimaryKeyInfo: []models.PrimaryKeyInfo{id1}},
+			},
+			want: []Statement{
+				{Query: `DELETE FROM "public"."v1.t" WHERE "id" = $1`, Args: []interface{}{"1"}},
+				{Query: `DELETE FROM "public.v1"."t" WHERE "id" = $1`, Args: []interface{}{"1"}},
+			},
+		},
+		{
+			name:    "database of the table",
+			dialect: MSSQLDialect,
+			changes: []models.DbDmlChange{
+				{Type: "UPDATE", Database: "sales", Schema: "dbo", Table: "t", Column: "a", Value: models.CellValue{Value: "x"}, PrimaryKeyInfo: []models.PrimaryKeyInfo{id1}},
+				{Type: "UPDATE", Database: "hr", Schema: "dbo", Table: "t", Column: "a", Value: models.CellValue{Value: "y"}, PrimaryKeyInfo: []models.PrimaryKeyInfo{id1}},
+			},
+			inserts: []models.DbInsert{
+				{Database: "hr", Schema: "dbo", Table: "t", Columns: []string{"a"}, Values: []models.CellValue{{Value: "z"}}},
+			},
+			want: []Statement{
+				{Query: "UPDATE [sales].[dbo].[t] SET [a] = @p1 WHERE [id] = @p2", Args: []interface{}{"x", "1"}},
+				{Query: "UPDATE [hr].[dbo].[t] SET [a] = @p1 WHERE [id] = @p2", Args: []interface{}{"y", "1"}},
+				{Query: "INSERT INTO [hr].[dbo].[t] ([a]) VALUES (@p1)", Args: []interface{}{"z"}},
+			},
+		},
+		{
+			name:    "inserts",
+			dialect: MSSQLDialect,
+			inserts: []models.DbInsert{
+				{Table: "t", Columns: []string{"a", "b", "c"}, Values: []models.CellValue{{Value: "x"}, {Type: models.Default}, {Type: models.Null}}},
+				{Table: "t", Columns: []string{"a"}, Values: []models.CellValue{{Type: models.Default}}},
+			},
+			want: []Statement{
+				{Query: "INSERT INTO [t] ([a], [c]) VALUES (@p1, @p2)", Args: []interface{}{"x", nil}},
+				{Query: "INSERT INTO [t] DEFAULT VALUES"},
+			},
+		},
+		{
+			name:    "insert of defaults only",
+			dialect: MySQLDialect,
+			inserts: []models.DbInsert{
+				{Table: "t", Columns: []string{"a"}, Values: []models.CellValue{{Type: models.Default}}},
+			},
+			want: []Statement{
+				{Query: "INSERT INTO `t` () VALUES ()"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements := test.dialect.BuildPendingChanges(test.changes, test.inserts)
+
+			if !reflect.DeepEqual(statements, test.want) {
+				t.Errorf("statements are\n%#v\nwant\n%#v", statements, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package drivers
+
+import "strings"
+
+// TokenKind is the lexical class of a token of a SQL script.
+type TokenKind int8
+
+const (
+	WhitespaceToken TokenKind = iota
+	CommentToken
+	StringToken
+	// QuotedIdentifierToken is an identifier between the quotes of the dialect
+	QuotedIdentifierToken
+	// WordToken is a keyword or an unquoted identifier
+	WordToken
+	NumberToken
+	// PlaceholderToken is a bind parameter, e.g. ? or $1
+	PlaceholderToken
+	// PunctuationToken is any other character: operators, parentheses, commas...
+	PunctuationToken
+)
+
+// Token is a lexical token of a SQL script.
+type Token struct {
+	Kind TokenKind
+	Text string
+	// Start and End are the byte offsets of the token in the script
+	Start int
+	End   int
+	// Unterminated is true for a string, quoted identifier or block comment
+	// that runs until the end of the script
+	Unterminated bool
+}
+
+// Tokenize splits a script into tokens. Every byte of the script belongs to a
+// token, so that the tokens can be joined back into the script.
+func (d Dialect) Tokenize(script string) []Token {
+	tokens := []Token{}
+
+	for position := 0; position < len(script); {
+		token := d.nextToken(script, position)
+		tokens = append(tokens, token)
+		position = token.End
+	}
+
+	return tokens
+}
+
+// nextToken reads the token starting at the given offset of the script.
+func (d Dialect) nextToken(script string, start int) Token {
+	kind, end, unterminated := d.scanToken(script, start)
+
+	return Token{
+		Kind:         kind,
+		Text:         script[start:end],
+		Start:        start,
+		End:          end,
+		Unterminated: unterminated,
+	}
+}
+
+func (d Dialect) scanToken(script string, start int) (kind TokenKind, end int, unterminated bool) {
+	char := script[start]
+	rest := script[start:]
+
+	switch {
+	case isSpace(char):
+		end = start
+		for end < len(script) && isSpace(script[end]) {
+			end++
+		}
+
+		return WhitespaceToken, end, false
+	case strings.HasPrefix(rest, "--"), char == '#' && d.hashComments:
+		end = strings.IndexByte(rest, '\n')
+		if end == -1 {
+			return CommentToken, len(script), false
+		}
+
+		return CommentToken, start + end, false
+	case strings.HasPrefix(rest, "/*"):
+		end, unterminated = d.scanBlockComment(script, start)
+
+		return CommentToken, end, unterminated
+	case char == '\'':
+		end, unterminated = scanQuoted(script, start+1, '\'', d.backslashEscapes)
+
+		return StringToken, end, unterminated
+	case char == '"' && d.doubleQuotedStrings:
+		end, unterminated = scanQuoted(script, start+1, '"', d.backslashEscapes)
+
+		return StringToken, end, unterminated
+	case d.openQuote != "" && strings.HasPrefix(rest, d.openQuote):
+		end, unterminated = scanQuoted(script, start+len(d.openQuote), d.closeQuote[0], false)
+
+		return QuotedIdentifierToken, end, unterminated
+	case char == '"':
+		// Standard identifier quotes, e.g. in MSSQL along with the brackets
+		end, unterminated = scanQuoted(script, start+1, '"', false)
+
+		return QuotedIdentifierToken, end, unterminated
+	case (char == 'e' || char == 'E') && d.escapeStrings && strings.HasPrefix(rest[1:], "'"):
+		end, unterminated = scanQuoted(script, start+2, '\'', true)
+
+		return StringToken, end, unterminated
+	case char == '$' && d.dollarQuotes:
+		if tag := dollarQuoteTag(rest); tag != "" {
+			end = strings.Index(script[start+len(tag):], tag)
+			if end == -1 {
+				return StringToken, len(script), true
+			}
+
+			return StringToken, start + len(tag) + end + len(tag), false
+		}
+	case isDigit(char), char == '.' && len(rest) > 1 && isDigit(rest[1]):
+		end = start + 1
+		for end < len(script) {
+			next := script[end]
+
+			if isWordChar(next) || next == '.' {
+				end++
+			} else if (next == '+' || next == '-') && (script[end-1] == 'e' || script[end-1] == 'E') {
+				// The sign of an exponent
+				end++
+			} else {
+				break
+			}
+		}
+
+		return NumberToken, end, false
+	case isWordStart(char):
+		end = start + 1
+		for end < len(script) && (isWordChar(script[end]) || script[end] == '$') {
+			end++
+		}
+
+		return WordToken, end, false
+	}
+
+	if end = d.scanPlaceholder(script, start); end > start {
+		return PlaceholderToken, end, false
+	}
+
+	return PunctuationToken, start + 1, false
+}
+
+// scanPlaceholder returns the end of the bind parameter starting at start, or
+// start when there is none. ? can be followed by a number, $ by a number or a
+// name, : and @ by a name.
+func (d Dialect) scanPlaceholder(script string, start int) int {
+	char := script[start]
+	if !strings.ContainsRune(d.placeholderPrefixes, rune(char)) {
+		return start
+	}
+
+	// A :: cast or a @@ system variable isn't a parameter
+	if (char == ':' || char == '@') && start > 0 && script[start-1] == char {
+		return start
+	}
+
+	end := start + 1
+	for end < len(script) && isDigit(script[end]) {
+		end++
+	}
+
+	if char == '?' || end > start+1 {
+		return end
+	}
+
+	if char == '$' && d.dollarQuotes {
+		// Only $1, $2... in PostgreSQL, $name would be a dollar quote
+		return start
+	}
+
+	if end == len(script) || !isWordStart(script[end]) {
+		return start
+	}
+
+	for end < len(script) && isWordChar(script[end]) {
+		end++
+	}
+
+	return end
+}
+
+// scanBlockComment returns the end of the block comment starting at start.
+func (d Dialect) scanBlockComment(script string, start int) (end int, unterminated bool) {
+	depth := 0
+
+	for end = start; end < len(script)-1; end++ {
+		switch script[end : end+2] {
+		case "/*":
+			if depth == 0 || d.nestedComments {
+				depth++
+			}
+			end++
+		case "*/":
+			depth--
+			end++
+
+			if depth == 0 {
+				return end + 1, false
+			}
+		}
+	}
+
+	return len(script), true
+}
+
+// scanQuoted returns the end of a quoted string or identifier whose content
+// starts at start. A doubled closing quote doesn't close it, neither does an
+// escaped one when backslashEscapes is true.
+func scanQuoted(script string, start int, closeQuote byte, backslashEscapes bool) (end int, unterminated bool) {
+	for end = start; end < len(script); end++ {
+		switch script[end] {
+		case '\\':
+			if backslashEscapes {
+				end++
+			}
+		case closeQuote:
+			if end+1 < len(script) && script[end+1] == closeQuote {
+				end++
+				continue
+			}
+
+			return end + 1, false
+		}
+	}
+
+	return len(script), true
+}
+
+// dollarQuoteTag returns the $tag$ that opens a dollar-quoted string at the
+// start of text, or an empty string when there is none.
+func dollarQuoteTag(text string) string {
+	for i := 1; i < len(text); i++ {
+		char := text[i]
+
+		if char == '$' {
+			return text[:i+1]
+		}
+
+		if !isWordChar(char) || (i == 1 && isDigit(char)) {
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func isSpace(char byte) bool {
+	return char == ' ' || char == '\t' || char == '\n' || char == '\r' || char == '\f' || char == '\v'
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+// isWordStart treats any non ASCII byte as a letter, so that identifiers in
+// other alphabets are read as words.
+func isWordStart(char byte) bool {
+	return char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || char >= 0x80
+}
+
+func isWordChar(char byte) bool {
+	return isWordStart(char) || isDigit(char)
+}
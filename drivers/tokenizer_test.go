@@ -0,0 +1,169 @@
+package drivers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tokenTexts returns the texts of the tokens of a kind
+func tokenTexts(tokens []Token, kind TokenKind) []string {
+	texts := []string{}
+
+	for _, token := range tokens {
+		if token.Kind == kind {
+			texts = append(texts, token.Text)
+		}
+	}
+
+	return texts
+}
+
+func TestTokenizePlaceholders(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		script   string
+		expected []string
+	}{
+		{
+			name:     "MySQL question marks",
+			dialect:  MySQLDialect,
+			script:   "SELECT * FROM t WHERE a = ? AND b = ?",
+			expected: []string{"?", "?"},
+		},
+		{
+			name:     "MySQL user variables",
+			dialect:  MySQLDialect,
+			script:   "SELECT @total, @@version",
+			expected: []string{},
+		},
+		{
+			name:     "Postgres positions",
+			dialect:  PostgresDialect,
+			script:   "SELECT * FROM t WHERE a = $1 AND b = $12",
+			expected: []string{"$1", "$12"},
+		},
+		{
+			name:     "Postgres casts",
+			dialect:  PostgresDialect,
+			script:   "SELECT $1::int, created::date",
+			expected: []string{"$1"},
+		},
+		{
+			name:     "Postgres dollar quotes",
+			dialect:  PostgresDialect,
+			script:   "SELECT $body$ $1 $body$, $tag",
+			expected: []string{},
+		},
+		{
+			name:     "SQLite every form",
+			dialect:  SQLiteDialect,
+			script:   "SELECT ?, ?2, :name, @name, $name",
+			expected: []string{"?", "?2", ":name", "@name", "$name"},
+		},
+		{
+			name:     "SQLite lone prefixes",
+			dialect:  SQLiteDialect,
+			script:   "SELECT a::b, @@c, : d",
+			expected: []string{},
+		},
+		{
+			name:     "SQL Server names",
+			dialect:  MSSQLDialect,
+			script:   "SELECT * FROM t WHERE a = @p1 AND b = @name",
+			expected: []string{"@p1", "@name"},
+		},
+		{
+			name:     "SQL Server system variables",
+			dialect:  MSSQLDialect,
+			script:   "SELECT @@ROWCOUNT, @@version",
+			expected: []string{},
+		},
+		{
+			name:     "inside strings and comments",
+			dialect:  SQLiteDialect,
+			script:   "SELECT '?', \":name\" -- @name\n/* $name */",
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens := test.dialect.Tokenize(test.script)
+
+			placeholders := tokenTexts(tokens, PlaceholderToken)
+			if !reflect.DeepEqual(placeholders, test.expected) {
+				t.Errorf("placeholders of %q are %q, want %q", test.script, placeholders, test.expected)
+			}
+
+			// Every byte belongs to a token
+			var joined strings.Builder
+			for _, token := range tokens {
+				joined.WriteString(token.Text)
+			}
+
+			if joined.String() != test.script {
+				t.Errorf("tokens join into %q", joined.String())
+			}
+		})
+	}
+}
+
+func TestTokenizeKinds(t *testing.T) {
+	tokens := PostgresDialect.Tokenize(`SELECT "id", E'it\'s', 1.5e-3 /* note */ FROM t -- end`)
+
+	expected := []struct {
+		kind TokenKind
+		text string
+	}{
+		{WordToken, "SELECT"},
+		{QuotedIdentifierToken, `"id"`},
+		{PunctuationToken, ","},
+		{StringToken, `E'it\'s'`},
+		{PunctuationToken, ","},
+		{NumberToken, "1.5e-3"},
+		{CommentToken, "/* note */"},
+		{WordToken, "FROM"},
+		{WordToken, "t"},
+		{CommentToken, "-- end"},
+	}
+
+	significant := []Token{}
+	for _, token := range tokens {
+		if token.Kind != WhitespaceToken {
+			significant = append(significant, token)
+		}
+	}
+
+	if len(significant) != len(expected) {
+		t.Fatalf("got %d tokens, want %d: %+v", len(significant), len(expected), significant)
+	}
+
+	for i, token := range significant {
+		if token.Kind != expected[i].kind || token.Text != expected[i].text {
+			t.Errorf("token %d is %d %q, want %d %q", i, token.Kind, token.Text, expected[i].kind, expected[i].text)
+		}
+	}
+}
+
+func TestTokenizeUnterminated(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		script  string
+	}{
+		{PostgresDialect, "SELECT 'open"},
+		{PostgresDialect, "SELECT /* open /* nested */"},
+		{PostgresDialect, "SELECT $$ open"},
+		{MSSQLDialect, "SELECT [open"},
+	}
+
+	for _, test := range tests {
+		tokens := test.dialect.Tokenize(test.script)
+		last := tokens[len(tokens)-1]
+
+		if !last.Unterminated || last.End != len(test.script) {
+			t.Errorf("the last token of %q is %+v, want an unterminated one until the end", test.script, last)
+		}
+	}
+}
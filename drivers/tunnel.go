@@ -0,0 +1,404 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// Tunnel forwards a local port to a database through an SSH server.
+type Tunnel struct {
+	client   *ssh.Client
+	listener net.Listener
+	// target is the host and port of the database, as the SSH server reaches it
+	target string
+	// connections are the forwarded connections still open
+	mutex       sync.Mutex
+	connections map[net.Conn]bool
+	closed      bool
+}
+
+// OpenTunnel connects to an SSH server and forwards a local port to the
+// database of a url. It returns the url rewritten to connect through the
+// tunnel.
+func OpenTunnel(ctx context.Context, settings models.SSHTunnel, provider, urlstr string) (*Tunnel, string, error) {
+	parsed, err := url.Parse(urlstr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if parsed.Host == "" {
+		return nil, "", fmt.Errorf("ssh: the url has no host to reach through the tunnel")
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = DefaultPortOf(provider)
+	}
+
+	if port == "" {
+		return nil, "", fmt.Errorf("ssh: the url has no port to reach through the tunnel")
+	}
+
+	config, closeAgent, err := sshClientConfig(settings)
+	if err != nil {
+		return nil, "", err
+	}
+	defer closeAgent()
+
+	address := settings.Host
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "22")
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, "", fmt.Errorf("ssh: %w", err)
+	}
+
+	// The handshake is abandoned when the context is done
+	handshaken := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-handshaken:
+		}
+	}()
+
+	clientConn, channels, requests, err := ssh.NewClientConn(conn, address, config)
+	close(handshaken)
+
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, "", err
+	}
+
+	client := ssh.NewClient(clientConn, channels, requests)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		client.Close()
+		return nil, "", fmt.Errorf("ssh: %w", err)
+	}
+
+	tunnel := &Tunnel{
+		client:      client,
+		listener:    listener,
+		target:      net.JoinHostPort(parsed.Hostname(), port),
+		connections: make(map[net.Conn]bool),
+	}
+
+	go tunnel.accept()
+
+	parsed.Host = tunnel.Address()
+
+	return tunnel, parsed.String(), nil
+}
+
+// Address returns the local host and port forwarded to the database.
+func (tunnel *Tunnel) Address() string {
+	return tunnel.listener.Addr().String()
+}
+
+// sshClientConfig returns the user, the authentication methods and the host
+// key check of the settings of a tunnel. closeAgent closes the connection to
+// the agent once the handshake is over.
+func sshClientConfig(settings models.SSHTunnel) (config *ssh.ClientConfig, closeAgent func(), err error) {
+	closeAgent = func() {}
+
+	username := settings.User
+	if username == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, closeAgent, fmt.Errorf("ssh: %w", err)
+		}
+
+		username = current.Username
+	}
+
+	methods := []ssh.AuthMethod{}
+
+	if settings.KeyFile != "" {
+		key, err := os.ReadFile(expandHome(settings.KeyFile))
+		if err != nil {
+			return nil, closeAgent, fmt.Errorf("ssh: %w", err)
+		}
+
+		signer, err := ssh.ParsePrivateKey(key)
+
+		var passphraseMissing *ssh.PassphraseMissingError
+		if errors.As(err, &passphraseMissing) {
+			return nil, closeAgent, fmt.Errorf("ssh: %s is protected by a passphrase, add it to the agent instead", settings.KeyFile)
+		} else if err != nil {
+			return nil, closeAgent, fmt.Errorf("ssh: %s: %w", settings.KeyFile, err)
+		}
+
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	if settings.Agent {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, closeAgent, errors.New("ssh: no agent is running, SSH_AUTH_SOCK isn't set")
+		}
+
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, closeAgent, fmt.Errorf("ssh: agent: %w", err)
+		}
+
+		// The signers of the agent sign through the connection
+		closeAgent = func() {
+			conn.Close()
+		}
+
+		methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+
+	if len(methods) == 0 {
+		return nil, closeAgent, errors.New("ssh: a key file or the agent is needed to authenticate")
+	}
+
+	knownHostsFile := settings.KnownHosts
+	if knownHostsFile == "" {
+		knownHostsFile = "~/.ssh/known_hosts"
+	}
+
+	hostKeyCallback, err := knownhosts.New(expandHome(knownHostsFile))
+	if err != nil {
+		closeAgent()
+		return nil, func() {}, fmt.Errorf("ssh: known_hosts: %w", err)
+	}
+
+	return &ssh.ClientConfig{
+		User:            username,
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback,
+	}, closeAgent, nil
+}
+
+// expandHome replaces the ~ at the start of a path with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
+
+func (tunnel *Tunnel) accept() {
+	for {
+		local, err := tunnel.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go tunnel.forward(local)
+	}
+}
+
+// forward copies the data of a local connection to the database and back
+// until either side closes
+func (tunnel *Tunnel) forward(local net.Conn) {
+	remote, err := tunnel.client.Dial("tcp", tunnel.target)
+	if err != nil {
+		local.Close()
+		return
+	}
+
+	if !tunnel.track(local, remote) {
+		return
+	}
+
+	done := make(chan struct{}, 2)
+
+	go func() {
+		_, _ = io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+
+	go func() {
+		_, _ = io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+
+	<-done
+
+	local.Close()
+	remote.Close()
+	tunnel.untrack(local, remote)
+}
+
+// track keeps the connections to close them along with the tunnel, it
+// closes them right away when the tunnel is already closed
+func (tunnel *Tunnel) track(connections ...net.Conn) bool {
+	tunnel.mutex.Lock()
+	defer tunnel.mutex.Unlock()
+
+	for _, conn := range connections {
+		if tunnel.closed {
+			conn.Close()
+		} else {
+			tunnel.connections[conn] = true
+		}
+	}
+
+	return !tunnel.closed
+}
+
+func (tunnel *Tunnel) untrack(connections ...net.Conn) {
+	tunnel.mutex.Lock()
+	defer tunnel.mutex.Unlock()
+
+	for _, conn := range connections {
+		delete(tunnel.connections, conn)
+	}
+}
+
+// Close stops forwarding and closes the connection to the SSH server.
+func (tunnel *Tunnel) Close() error {
+	tunnel.mutex.Lock()
+	if tunnel.closed {
+		tunnel.mutex.Unlock()
+		return nil
+	}
+
+	tunnel.closed = true
+
+	for conn := range tunnel.connections {
+		conn.Close()
+	}
+
+	tunnel.mutex.Unlock()
+
+	tunnel.listener.Close()
+
+	return tunnel.client.Close()
+}
+
+// tunnelDriver is a driver connecting through an SSH tunnel, which is opened
+// on the first connection and closed along with the driver.
+type tunnelDriver struct {
+	Driver
+	provider string
+	settings models.SSHTunnel
+	tunnel   *Tunnel
+	// target is the url the tunnel was opened for
+	target string
+}
+
+// NewForConnection returns a new, not yet connected, driver for a
+// connection, connecting through its SSH tunnel if it has one and opening
+// read-only sessions if the connection is read-only.
+func NewForConnection(connection models.Connection) (Driver, error) {
+	driver, err := New(connection.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	SetReadOnly(driver, connection.ReadOnly)
+
+	if connection.SSH == nil {
+		return driver, nil
+	}
+
+	return &tunnelDriver{Driver: driver, provider: connection.Provider, settings: *connection.SSH}, nil
+}
+
+// open opens the tunnel, or reuses it when the url only differs by its
+// password, and returns the url to connect to through it
+func (driver *tunnelDriver) open(ctx context.Context, urlstr string) (string, error) {
+	parsed, err := url.Parse(urlstr)
+	if err != nil {
+		return "", err
+	}
+
+	if driver.tunnel != nil && parsed.Host != driver.target {
+		driver.tunnel.Close()
+		driver.tunnel = nil
+	}
+
+	if driver.tunnel == nil {
+		tunnel, _, err := OpenTunnel(ctx, driver.settings, driver.provider, urlstr)
+		if err != nil {
+			return "", err
+		}
+
+		driver.tunnel = tunnel
+		driver.target = parsed.Host
+	}
+
+	parsed.Host = driver.tunnel.Address()
+
+	return parsed.String(), nil
+}
+
+func (driver *tunnelDriver) Connect(ctx context.Context, urlstr string) error {
+	tunneled, err := driver.open(ctx, urlstr)
+	if err != nil {
+		return err
+	}
+
+	return driver.Driver.Connect(ctx, tunneled)
+}
+
+func (driver *tunnelDriver) TestConnection(ctx context.Context, urlstr string) error {
+	tunneled, err := driver.open(ctx, urlstr)
+	if err != nil {
+		return err
+	}
+
+	return driver.Driver.TestConnection(ctx, tunneled)
+}
+
+func (driver *tunnelDriver) Close() error {
+	err := driver.Driver.Close()
+
+	if driver.tunnel != nil {
+		err = errors.Join(err, driver.tunnel.Close())
+		driver.tunnel = nil
+	}
+
+	return err
+}
+
+// ForDatabase returns a driver for another database of the server, reached
+// through the same tunnel, which is still closed along with driver.
+func (driver *tunnelDriver) ForDatabase(database string) (Driver, error) {
+	databaseDriver, err := ForDatabase(driver.Driver, database)
+	if err != nil {
+		return nil, err
+	}
+
+	if databaseDriver == driver.Driver {
+		return driver, nil
+	}
+
+	return databaseDriver, nil
+}
@@ -0,0 +1,382 @@
+package drivers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// tunnelFixture is an SSH server forwarding to an echo server, along with the
+// files a tunnel to it is set up with
+type tunnelFixture struct {
+	// echo stands for the database
+	echo net.Listener
+	// settings authenticate with clientKey and trust the host key
+	settings  models.SSHTunnel
+	clientKey ed25519.PrivateKey
+	dir       string
+}
+
+func newTunnelFixture(t *testing.T) *tunnelFixture {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	echo := listen(t, "tcp", "127.0.0.1:0")
+	serve(echo, func(conn net.Conn) {
+		io.Copy(conn, conn)
+		conn.Close()
+	})
+
+	hostSigner := newSigner(t)
+
+	clientPublicKey, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorizedKey, err := ssh.NewPublicKey(clientPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == "bastion" && bytes.Equal(key.Marshal(), authorizedKey.Marshal()) {
+				return nil, nil
+			}
+
+			return nil, errors.New("unauthorized")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	server := listen(t, "tcp", "127.0.0.1:0")
+	serve(server, func(conn net.Conn) {
+		serveSSH(conn, config)
+	})
+
+	keyFile := filepath.Join(dir, "id_ed25519")
+
+	der, err := x509.MarshalPKCS8PrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	knownHosts := filepath.Join(dir, "known_hosts")
+	writeFile(t, knownHosts, []byte(knownhosts.Line([]string{knownhosts.Normalize(server.Addr().String())}, hostSigner.PublicKey())+"\n"))
+
+	return &tunnelFixture{
+		echo: echo,
+		settings: models.SSHTunnel{
+			Host:       server.Addr().String(),
+			User:       "bastion",
+			KeyFile:    keyFile,
+			KnownHosts: knownHosts,
+		},
+		clientKey: clientKey,
+		dir:       dir,
+	}
+}
+
+// databaseURL returns a url of the echo server
+func (fixture *tunnelFixture) databaseURL() string {
+	return "postgres://user:secret@" + fixture.echo.Addr().String() + "/db?sslmode=require"
+}
+
+func TestOpenTunnel(t *testing.T) {
+	fixture := newTunnelFixture(t)
+
+	tunnel, urlstr, err := OpenTunnel(context.Background(), fixture.settings, "postgres", fixture.databaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tunnel.Close()
+
+	parsed, err := url.Parse(urlstr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Host != tunnel.Address() {
+		t.Errorf("url reaches %s, want the tunnel at %s", parsed.Host, tunnel.Address())
+	}
+
+	if parsed.User.String() != "user:secret" || parsed.Path != "/db" || parsed.RawQuery != "sslmode=require" {
+		t.Errorf("url %s lost the credentials, the database or the options", urlstr)
+	}
+
+	assertEcho(t, tunnel.Address())
+}
+
+func TestOpenTunnelWithAgent(t *testing.T) {
+	fixture := newTunnelFixture(t)
+
+	keyring := agent.NewKeyring()
+
+	err := keyring.Add(agent.AddedKey{PrivateKey: fixture.clientKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	socket := listen(t, "unix", filepath.Join(fixture.dir, "agent.sock"))
+	serve(socket, func(conn net.Conn) {
+		agent.ServeAgent(keyring, conn)
+		conn.Close()
+	})
+
+	t.Setenv("SSH_AUTH_SOCK", socket.Addr().String())
+
+	settings := fixture.settings
+	settings.KeyFile = ""
+	settings.Agent = true
+
+	tunnel, _, err := OpenTunnel(context.Background(), settings, "postgres", fixture.databaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tunnel.Close()
+
+	assertEcho(t, tunnel.Address())
+}
+
+func TestOpenTunnelFails(t *testing.T) {
+	fixture := newTunnelFixture(t)
+
+	otherHostKeys := filepath.Join(fixture.dir, "other_hosts")
+	writeFile(t, otherHostKeys, []byte(knownhosts.Line([]string{knownhosts.Normalize(fixture.settings.Host)}, newSigner(t).PublicKey())+"\n"))
+
+	tests := []struct {
+		name     string
+		change   func(settings *models.SSHTunnel)
+		provider string
+		url      string
+	}{
+		{
+			name:   "host key mismatch",
+			change: func(settings *models.SSHTunnel) { settings.KnownHosts = otherHostKeys },
+		},
+		{
+			name:   "unknown host",
+			change: func(settings *models.SSHTunnel) { settings.KnownHosts = filepath.Join(fixture.dir, "missing") },
+		},
+		{
+			name:   "unauthorized user",
+			change: func(settings *models.SSHTunnel) { settings.User = "nobody" },
+		},
+		{
+			name:   "no authentication",
+			change: func(settings *models.SSHTunnel) { settings.KeyFile = "" },
+		},
+		{
+			name:     "no host in the url",
+			provider: "sqlite3",
+			url:      "sqlite:/tmp/lazysql.db",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := fixture.settings
+			if test.change != nil {
+				test.change(&settings)
+			}
+
+			provider, urlstr := test.provider, test.url
+			if provider == "" {
+				provider, urlstr = "postgres", fixture.databaseURL()
+			}
+
+			tunnel, _, err := OpenTunnel(context.Background(), settings, provider, urlstr)
+			if err == nil {
+				tunnel.Close()
+				t.Fatal("the tunnel is open, want an error")
+			}
+		})
+	}
+}
+
+func TestTunnelClose(t *testing.T) {
+	fixture := newTunnelFixture(t)
+
+	tunnel, _, err := OpenTunnel(context.Background(), fixture.settings, "postgres", fixture.databaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := assertEcho(t, tunnel.Address())
+
+	err = tunnel.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = conn.Read(make([]byte, 1))
+	if err == nil {
+		t.Error("a forwarded connection is still open after the tunnel is closed")
+	}
+
+	conn, err = net.Dial("tcp", tunnel.Address())
+	if err == nil {
+		conn.Close()
+		t.Error("the tunnel still accepts connections after it's closed")
+	}
+}
+
+// assertEcho checks that what is written to an address is read back, and
+// returns the connection
+func assertEcho(t *testing.T, address string) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = conn.Write([]byte("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply := make([]byte, 4)
+
+	_, err = io.ReadFull(conn, reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(reply) != "ping" {
+		t.Fatalf("read %q through the tunnel, want ping", reply)
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return conn
+}
+
+// serveSSH accepts the direct-tcpip channels of an SSH connection and
+// forwards them to their destination
+func serveSSH(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer serverConn.Close()
+
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		var destination struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+
+		if newChannel.ChannelType() != "direct-tcpip" || ssh.Unmarshal(newChannel.ExtraData(), &destination) != nil {
+			newChannel.Reject(ssh.UnknownChannelType, "only direct-tcpip is supported")
+			continue
+		}
+
+		target, err := net.Dial("tcp", net.JoinHostPort(destination.Host, strconv.Itoa(int(destination.Port))))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			target.Close()
+			continue
+		}
+
+		go ssh.DiscardRequests(channelRequests)
+
+		go func() {
+			io.Copy(channel, target)
+			channel.Close()
+		}()
+
+		go func() {
+			io.Copy(target, channel)
+			target.Close()
+		}()
+	}
+}
+
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+func listen(t *testing.T, network, address string) net.Listener {
+	t.Helper()
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	return listener
+}
+
+// serve handles each connection of a listener until it's closed
+func serve(listener net.Listener, handle func(conn net.Conn)) {
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go handle(conn)
+		}
+	}()
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	err := os.WriteFile(name, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,43 @@
+package drivers
+
+import (
+	"database/sql"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// cellValue returns the cell value of a scanned value, NULL being a cell of
+// its own type so it can be told apart from an empty string.
+func cellValue(value *sql.NullString) models.CellValue {
+	if !value.Valid {
+		return models.CellValue{Type: models.Null}
+	}
+
+	return models.CellValue{Type: models.String, Value: value.String}
+}
+
+// rowScanner reads the values of the current row of rows
+type rowScanner func(rows *sql.Rows) ([]models.CellValue, error)
+
+// textScanner returns a scanner reading the values of a row of the given
+// number of columns as text
+func textScanner(columns int) rowScanner {
+	return func(rows *sql.Rows) ([]models.CellValue, error) {
+		rowValues := make([]interface{}, columns)
+		for i := range rowValues {
+			rowValues[i] = new(sql.NullString)
+		}
+
+		err := rows.Scan(rowValues...)
+		if err != nil {
+			return nil, err
+		}
+
+		row := make([]models.CellValue, 0, columns)
+		for _, col := range rowValues {
+			row = append(row, cellValue(col.(*sql.NullString)))
+		}
+
+		return row, nil
+	}
+}
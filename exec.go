@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/jorgerojas26/lazysql/drivers"
+	"github.com/jorgerojas26/lazysql/helpers"
+)
+
+const execUsage = `Usage: lazysql exec --connection name [flags] [SQL]
+
+Runs the statements of SQL, or of the file given by -f, on a saved connection
+and prints the rows they return. The exit status is 1 when a statement fails,
+the statements after it aren't run.
+
+Flags:
+`
+
+// execFormats are the names of the formats exec prints rows in
+var execFormats = map[string]drivers.ExportFormat{
+	"table":    drivers.TableExport,
+	"csv":      drivers.CSVExport,
+	"tsv":      drivers.TSVExport,
+	"json":     drivers.JSONExport,
+	"ndjson":   drivers.NDJSONExport,
+	"markdown": drivers.MarkdownExport,
+}
+
+// execOptions are the arguments of the exec command
+type execOptions struct {
+	options
+	format string
+	file   string
+	script string
+}
+
+// parseExecOptions reads the arguments of the exec command, the flags can
+// come before or after the SQL
+func parseExecOptions(args []string, output io.Writer) (execOptions, error) {
+	opts := execOptions{}
+
+	flags := flag.NewFlagSet("lazysql exec", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&opts.config, "config", "", "read the config from `file` instead of ~/.config/lazysql/config.toml")
+	flags.StringVar(&opts.connection, "connection", "", "run on the saved connection called `name`")
+	flags.StringVar(&opts.database, "database", "", "run on `name` instead of the database of the connection")
+	flags.StringVar(&opts.format, "format", "table", "print the rows as `format`: table, csv, tsv, json, ndjson or markdown")
+	flags.StringVar(&opts.file, "f", "", "run the statements of `file` instead of the SQL argument, - reads them from the standard input")
+	flags.BoolVar(&opts.readOnly, "read-only", false, "don't run statements that write")
+	flags.Usage = func() {
+		fmt.Fprint(output, execUsage)
+		flags.PrintDefaults()
+	}
+
+	positional := []string{}
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return opts, err
+		}
+
+		if flags.NArg() == 0 {
+			break
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	switch {
+	case opts.connection == "":
+		return opts, errors.New("exec needs --connection")
+	case len(positional) > 1:
+		return opts, fmt.Errorf("expected the SQL as a single argument, got %d arguments", len(positional))
+	case len(positional) == 1 && opts.file != "":
+		return opts, errors.New("-f can't be used along with the SQL argument")
+	case len(positional) == 0 && opts.file == "":
+		return opts, errors.New("exec needs the SQL to run, as argument or with -f")
+	case len(positional) == 1:
+		opts.script = positional[0]
+	}
+
+	if _, ok := execFormats[opts.format]; !ok {
+		return opts, fmt.Errorf("unknown format %q, expected table, csv, tsv, json, ndjson or markdown", opts.format)
+	}
+
+	return opts, nil
+}
+
+// runExec runs the exec command and returns its exit status
+func runExec(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseExecOptions(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if opts.config != "" {
+		helpers.SetConfigFilePath(opts.config)
+	}
+
+	script := opts.script
+
+	switch opts.file {
+	case "":
+	case "-":
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "Can't read the standard input: %s\n", err)
+			return 1
+		}
+
+		script = string(content)
+	default:
+		content, err := os.ReadFile(opts.file)
+		if err != nil {
+			fmt.Fprintf(stderr, "Can't read the SQL: %s\n", err)
+			return 1
+		}
+
+		script = string(content)
+	}
+
+	connection, _, err := opts.openedConnection()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if opts.database != "" {
+		// The URL can't be parsed before its environment variables are expanded
+		urlstr, err := helpers.ExpandEnv(connection.URL)
+		if err == nil {
+			urlstr, err = drivers.WithDatabase(urlstr, opts.database)
+		}
+
+		if err != nil {
+			fmt.Fprintf(stderr, "Can't use the database %s: %s\n", opts.database, err)
+			return 1
+		}
+
+		connection.URL = urlstr
+	}
+
+	// Interrupting cancels the running statement
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	driver, err := drivers.NewForConnection(connection)
+	if err != nil {
+		fmt.Fprintf(stderr, "Can't connect to %s: %s\n", connection.Name, err)
+		return 1
+	}
+
+	// Closes the SSH tunnel of the connection too
+	defer driver.Close()
+
+	err = helpers.Authenticate(connection, func(urlstr string) error {
+		return driver.Connect(ctx, urlstr)
+	}, terminalPassword)
+	if err != nil {
+		fmt.Fprintf(stderr, "Can't connect to %s: %s\n", connection.Name, err)
+		return 1
+	}
+
+	dialect, err := drivers.DialectOf(connection.Provider)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	statements := dialect.SplitStatements(script)
+
+	for i, statement := range statements {
+		err := runExecStatement(ctx, driver, dialect, statement, connection.ReadOnly, execFormats[opts.format], i > 0, stdout, stderr)
+		if err != nil {
+			if len(statements) > 1 {
+				err = fmt.Errorf("statement %d: %w", i+1, err)
+			}
+
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// runExecStatement runs a statement and prints the rows it returns, or how
+// many rows it changed. The count goes to stderr unless the rows are printed
+// as a table, so that the output of the other formats can be parsed.
+func runExecStatement(ctx context.Context, driver drivers.Driver, dialect drivers.Dialect, statement drivers.ScriptStatement, readOnly bool, format drivers.ExportFormat, separate bool, stdout, stderr io.Writer) error {
+	if readOnly && !dialect.ReadsOnly(statement.Query) {
+		return errors.New("the connection is read-only, only statements that read can run")
+	}
+
+	status := stderr
+	if format == drivers.TableExport {
+		status = stdout
+
+		// A blank line separates the results of the statements
+		if separate {
+			fmt.Fprintln(stdout)
+		}
+	}
+
+	if !statement.ReturnsRows {
+		affected, err := driver.ExecuteDMLStatement(ctx, statement.Query)
+		if err != nil {
+			return err
+		}
+
+		if affected == 1 {
+			fmt.Fprintln(status, "1 row affected")
+		} else {
+			fmt.Fprintf(status, "%d rows affected\n", affected)
+		}
+
+		return nil
+	}
+
+	cursor, err := driver.ExecuteQuery(ctx, statement.Query)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	writer, err := dialect.NewResultWriter(format, stdout, cursor.Columns(), "")
+	if err != nil {
+		return err
+	}
+
+	_, err = drivers.ExportCursor(writer, cursor, nil)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
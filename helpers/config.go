@@ -1,6 +1,9 @@
 package helpers
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -11,10 +14,74 @@ import (
 
 type Config struct {
 	Connections []models.Connection `toml:"database"`
+	Application ApplicationConfig   `toml:"application,omitempty"`
+	History     HistoryConfig       `toml:"history,omitempty"`
+	// Keymap holds the keys of the commands, by group and command name. A
+	// command is bound to a key or a sequence of keys, e.g. "<C-r>" or "gg",
+	// or to a list of them. The leader key and the timeout of the sequences
+	// are set along with the groups.
+	Keymap      map[string]interface{} `toml:"keymap,omitempty"`
+	Theme       ThemeConfig            `toml:"theme,omitempty"`
+	Credentials CredentialsConfig      `toml:"credentials,omitempty"`
+}
+
+// CredentialsConfig holds the settings of the [credentials] section
+type CredentialsConfig struct {
+	// Keyring is where the passwords of the connections with keyring = true
+	// are kept: "system", the default, or "file"
+	Keyring string `toml:"keyring,omitempty"`
+}
+
+// ThemeConfig holds the settings of the [theme] section
+type ThemeConfig struct {
+	// Name is the built-in theme the interface is drawn with
+	Name string `toml:"name,omitempty"`
+	// Colors replace the colors of the theme, by name, e.g.
+	// pending_update = "#ffaf00"
+	Colors map[string]string `toml:"colors,omitempty"`
+}
+
+// ApplicationConfig holds the settings of the [application] section
+type ApplicationConfig struct {
+	// MaxQueryRows is the most rows read from the results of an editor query
+	MaxQueryRows int `toml:"max_query_rows,omitempty"`
+}
+
+const DefaultMaxQueryRows = 10000
+
+// HistoryConfig holds the settings of the [history] section
+type HistoryConfig struct {
+	// Exclude are regular expressions, the statements they match aren't recorded
+	Exclude []string `toml:"exclude,omitempty"`
+	// Redact are regular expressions, the text they match is replaced by
+	// RedactedText before a statement is recorded
+	Redact []string `toml:"redact,omitempty"`
+}
+
+func configDirectory() string {
+	return filepath.Join(os.Getenv("HOME"), ".config", "lazysql")
+}
+
+// configFile is the config file set by --config
+var configFile string
+
+// SetConfigFilePath reads and writes the config in another file than
+// ~/.config/lazysql/config.toml
+func SetConfigFilePath(path string) {
+	configFile = path
+}
+
+// ConfigFilePath returns the path of the config file
+func ConfigFilePath() string {
+	if configFile != "" {
+		return configFile
+	}
+
+	return filepath.Join(configDirectory(), "config.toml")
 }
 
 func LoadConfig() (config Config, err error) {
-	file, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".config", "lazysql", "config.toml"))
+	file, err := os.ReadFile(ConfigFilePath())
 	if err != nil {
 		return
 	}
@@ -35,11 +102,31 @@ func LoadConnections() (connections []models.Connection, err error) {
 	return
 }
 
+// LoadApplicationConfig returns the application settings, with defaults for
+// the ones that aren't set.
+func LoadApplicationConfig() ApplicationConfig {
+	config, _ := LoadConfig()
+	application := config.Application
+
+	if application.MaxQueryRows <= 0 {
+		application.MaxQueryRows = DefaultMaxQueryRows
+	}
+
+	return application
+}
+
 func SaveConnectionConfig(connections []models.Connection) (err error) {
-	config := Config{Connections: connections}
+	// The other sections are written back as they were, a file that can't be
+	// read is left untouched rather than written without them
+	config, err := LoadConfig()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("can't save the connections, %s can't be read: %w", ConfigFilePath(), err)
+	}
+
+	config.Connections = connections
 
-	directoriesPath := filepath.Join(os.Getenv("HOME"), ".config", "lazysql")
-	configFilePath := filepath.Join(directoriesPath, "config.toml")
+	configFilePath := ConfigFilePath()
+	directoriesPath := filepath.Dir(configFilePath)
 
 	err = os.MkdirAll(directoriesPath, 0755)
 
@@ -0,0 +1,76 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+func TestSaveConnectionConfig(t *testing.T) {
+	defer SetConfigFilePath("")
+
+	connections := []models.Connection{{Name: "local", Provider: "postgres", URL: "postgres://localhost/app"}}
+
+	t.Run("keeps the other sections", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		SetConfigFilePath(path)
+
+		err := os.WriteFile(path, []byte("[application]\nmax_query_rows = 50\n"), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = SaveConnectionConfig(connections)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if config.Application.MaxQueryRows != 50 || len(config.Connections) != 1 {
+			t.Errorf("config is %+v after saving", config)
+		}
+	})
+
+	t.Run("creates a missing file", func(t *testing.T) {
+		SetConfigFilePath(filepath.Join(t.TempDir(), "lazysql", "config.toml"))
+
+		err := SaveConnectionConfig(connections)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		saved, err := LoadConnections()
+		if err != nil || len(saved) != 1 || saved[0].Name != "local" {
+			t.Errorf("saved connections are %+v, %v", saved, err)
+		}
+	})
+
+	t.Run("leaves a broken file untouched", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		SetConfigFilePath(path)
+
+		broken := "[application]\nmax_query_rows = \n[theme]\nname = \"light\"\n"
+
+		err := os.WriteFile(path, []byte(broken), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = SaveConnectionConfig(connections)
+		if err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("saving returned %v, want an error about %s", err, path)
+		}
+
+		content, _ := os.ReadFile(path)
+		if string(content) != broken {
+			t.Errorf("the file was rewritten to %q", content)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package helpers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// PasswordPrompt asks the password of a connection, err tells why connecting
+// without one failed. ok is false when the user gives up.
+type PasswordPrompt func(connection string, err error) (password string, ok bool)
+
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandEnv replaces the ${NAME} references of a URL with the values of the
+// environment variables. The values are inserted as they are, the characters
+// with a meaning in URLs, like @ or /, have to be escaped in them.
+func ExpandEnv(urlstr string) (string, error) {
+	var err error
+
+	expanded := envReference.ReplaceAllStringFunc(urlstr, func(reference string) string {
+		name := envReference.FindStringSubmatch(reference)[1]
+
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("the environment variable %s isn't set", name)
+		}
+
+		return value
+	})
+
+	return expanded, err
+}
+
+// ConnectionURL returns the URL a connection connects with. The environment
+// variables are expanded and, when the URL has no password, it's given by the
+// password command or the keyring. prompt is true when the URL has a user but
+// still no password, so that it can be asked if connecting without fails.
+func ConnectionURL(connection models.Connection) (urlstr string, prompt bool, err error) {
+	urlstr, err = ExpandEnv(connection.URL)
+	if err != nil {
+		return "", false, err
+	}
+
+	if !missingPassword(urlstr) {
+		return urlstr, false, nil
+	}
+
+	password := ""
+
+	switch {
+	case connection.PasswordCmd != "":
+		password, err = runPasswordCmd(connection.PasswordCmd)
+		if err != nil {
+			return "", false, fmt.Errorf("password_cmd: %w", err)
+		}
+	case connection.Keyring:
+		keyring, err := OpenKeyring()
+		if err != nil {
+			return "", false, err
+		}
+
+		password, err = keyring.Get(connection.Name)
+		if errors.Is(err, ErrNoPassword) {
+			return urlstr, true, nil
+		} else if err != nil {
+			return "", false, fmt.Errorf("can't read the password from the keyring: %w", err)
+		}
+	default:
+		return urlstr, true, nil
+	}
+
+	urlstr, err = WithPassword(urlstr, password)
+
+	return urlstr, false, err
+}
+
+// Authenticate connects to a connection with its URL. When connecting fails
+// without a password, the password is asked until connecting works or the
+// user gives up, and it's kept in the keyring if the connection uses it.
+func Authenticate(connection models.Connection, connect func(urlstr string) error, ask PasswordPrompt) error {
+	urlstr, prompt, err := ConnectionURL(connection)
+	if err != nil {
+		return err
+	}
+
+	err = connect(urlstr)
+	if err == nil || !prompt || ask == nil {
+		return err
+	}
+
+	for {
+		password, ok := ask(connection.Name, err)
+		if !ok {
+			return err
+		}
+
+		withPassword, passwordErr := WithPassword(urlstr, password)
+		if passwordErr != nil {
+			return passwordErr
+		}
+
+		err = connect(withPassword)
+		if err != nil {
+			continue
+		}
+
+		if connection.Keyring {
+			// Failing to keep it only means that it's asked again next time
+			if keyring, err := OpenKeyring(); err == nil {
+				_ = keyring.Set(connection.Name, password)
+			}
+		}
+
+		return nil
+	}
+}
+
+// WithPassword returns a URL with the password of its user replaced
+func WithPassword(urlstr, password string) (string, error) {
+	parsed, err := url.Parse(urlstr)
+	if err != nil {
+		return "", err
+	}
+
+	parsed.User = url.UserPassword(parsed.User.Username(), password)
+
+	return parsed.String(), nil
+}
+
+// StripPassword returns a URL without the password of its user, and the
+// password
+func StripPassword(urlstr string) (stripped, password string, err error) {
+	parsed, err := url.Parse(urlstr)
+	if err != nil || parsed.User == nil {
+		return urlstr, "", err
+	}
+
+	password, _ = parsed.User.Password()
+	parsed.User = url.User(parsed.User.Username())
+
+	return parsed.String(), password, nil
+}
+
+// missingPassword tells whether a URL has a user without a password
+func missingPassword(urlstr string) bool {
+	parsed, err := url.Parse(urlstr)
+	if err != nil || parsed.User == nil || parsed.User.Username() == "" {
+		return false
+	}
+
+	_, ok := parsed.User.Password()
+
+	return !ok
+}
+
+// runPasswordCmd returns the first line printed by a password command, like
+// pass prints the password before the other fields of an entry
+func runPasswordCmd(command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%w: %s", err, message)
+		}
+
+		return "", err
+	}
+
+	password, _, _ := strings.Cut(string(output), "\n")
+
+	return strings.TrimSuffix(password, "\r"), nil
+}
@@ -0,0 +1,46 @@
+package helpers
+
+import "unicode"
+
+// FuzzyMatch tells whether every character of pattern appears in text, in
+// order and ignoring case. The score is higher when the characters are found
+// next to each other, at the start of words and early in the text.
+func FuzzyMatch(pattern, text string) (score int, ok bool) {
+	if pattern == "" {
+		return 0, true
+	}
+
+	patternRunes := []rune(pattern)
+	matched := 0
+	previousMatch := -2
+	previous := ' '
+
+	for index, char := range []rune(text) {
+		if matched == len(patternRunes) {
+			break
+		}
+
+		if unicode.ToLower(char) == unicode.ToLower(patternRunes[matched]) {
+			score++
+
+			if index == previousMatch+1 {
+				score += 4
+			}
+
+			if !unicode.IsLetter(previous) && !unicode.IsDigit(previous) {
+				score += 2
+			}
+
+			if index < len(patternRunes)*4 {
+				score++
+			}
+
+			previousMatch = index
+			matched++
+		}
+
+		previous = char
+	}
+
+	return score, matched == len(patternRunes)
+}
@@ -0,0 +1,136 @@
+package helpers
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+// RedactedText replaces the text matched by the redact patterns of the history
+const RedactedText = "<redacted>"
+
+// historyFilePath returns the JSONL file the history of a connection is kept
+// in. The characters a file name can't hold are replaced, so a hash of the
+// name tells apart the connections whose names only differ by them, e.g.
+// "my db" and "my_db".
+func historyFilePath(connection string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+
+		return '_'
+	}, connection)
+
+	hash := sha256.Sum256([]byte(connection))
+
+	return filepath.Join(configDirectory(), "history", fmt.Sprintf("%s-%x.jsonl", name, hash[:4]))
+}
+
+// AppendHistory records a statement in the history of its connection, unless
+// an exclude pattern matches it. Nothing is recorded when a pattern is invalid,
+// since the statements it should have excluded or redacted can't be told.
+func AppendHistory(entry models.HistoryEntry) error {
+	config, err := LoadConfig()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	exclude, err := compilePatterns(config.History.Exclude)
+	if err != nil {
+		return err
+	}
+
+	redact, err := compilePatterns(config.History.Redact)
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range exclude {
+		if pattern.MatchString(entry.Query) {
+			return nil
+		}
+	}
+
+	for _, pattern := range redact {
+		entry.Query = pattern.ReplaceAllLiteralString(entry.Query, RedactedText)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	path := historyFilePath(entry.Connection)
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	// The statements may hold data that isn't meant for other users
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+
+	return err
+}
+
+// LoadHistory returns the history of a connection, newest first. Lines that
+// can't be read are skipped.
+func LoadHistory(connection string) (entries []models.HistoryEntry, err error) {
+	file, err := os.Open(historyFilePath(connection))
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	} else if err != nil {
+		return entries, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	// A line holds a whole statement, that can be a long script
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var entry models.HistoryEntry
+
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries, scanner.Err()
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		compiled = append(compiled, expression)
+	}
+
+	return compiled, nil
+}
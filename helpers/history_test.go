@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jorgerojas26/lazysql/models"
+)
+
+func TestHistoryPerConnection(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	SetConfigFilePath(filepath.Join(home, "config.toml"))
+	defer SetConfigFilePath("")
+
+	for _, entry := range []models.HistoryEntry{
+		{Connection: "my db", Query: "SELECT 1"},
+		{Connection: "my_db", Query: "SELECT 2"},
+		{Connection: "my db", Query: "SELECT 3"},
+	} {
+		err := AppendHistory(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		connection string
+		want       []string
+	}{
+		{"my db", []string{"SELECT 3", "SELECT 1"}},
+		{"my_db", []string{"SELECT 2"}},
+		{"other", nil},
+	}
+
+	for _, test := range tests {
+		entries, err := LoadHistory(test.connection)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var queries []string
+		for _, entry := range entries {
+			queries = append(queries, entry.Query)
+		}
+
+		if !reflect.DeepEqual(queries, test.want) {
+			t.Errorf("history of %q is %q, want %q", test.connection, queries, test.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/zalando/go-keyring"
+)
+
+// Keyring keeps the passwords of the connections, by connection name
+type Keyring interface {
+	// Get returns ErrNoPassword when no password is kept for the connection
+	Get(connection string) (string, error)
+	Set(connection, password string) error
+	Delete(connection string) error
+}
+
+var ErrNoPassword = errors.New("no password is kept in the keyring")
+
+// keyringService names the passwords of lazysql in the system keyring
+const keyringService = "lazysql"
+
+// OpenKeyring returns the keyring set in the [credentials] section
+func OpenKeyring() (Keyring, error) {
+	config, err := LoadConfig()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	switch config.Credentials.Keyring {
+	case "", "system":
+		return systemKeyring{}, nil
+	case "file":
+		return fileKeyring{path: credentialsFilePath()}, nil
+	}
+
+	return nil, fmt.Errorf("credentials.keyring: unknown keyring %q, expected system or file", config.Credentials.Keyring)
+}
+
+// credentialsFilePath returns the file of the file keyring, next to the
+// config holding the connections
+func credentialsFilePath() string {
+	return filepath.Join(filepath.Dir(ConfigFilePath()), "credentials.toml")
+}
+
+// systemKeyring is the Secret Service on Linux, the Keychain on macOS and the
+// Credential Manager on Windows
+type systemKeyring struct{}
+
+func (systemKeyring) Get(connection string) (string, error) {
+	password, err := keyring.Get(keyringService, connection)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNoPassword
+	}
+
+	return password, err
+}
+
+func (systemKeyring) Set(connection, password string) error {
+	return keyring.Set(keyringService, connection, password)
+}
+
+func (systemKeyring) Delete(connection string) error {
+	err := keyring.Delete(keyringService, connection)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// fileKeyring keeps the passwords in a file only its owner can read, for the
+// machines without a system keyring, e.g. headless ones
+type fileKeyring struct {
+	path string
+}
+
+func (k fileKeyring) load() (map[string]string, error) {
+	passwords := map[string]string{}
+
+	content, err := os.ReadFile(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return passwords, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = toml.Unmarshal(content, &passwords)
+
+	return passwords, err
+}
+
+func (k fileKeyring) save(passwords map[string]string) error {
+	content, err := toml.Marshal(passwords)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(k.path), 0755)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(k.path, content, 0600)
+	if err != nil {
+		return err
+	}
+
+	// WriteFile keeps the permissions of an existing file
+	return os.Chmod(k.path, 0600)
+}
+
+func (k fileKeyring) Get(connection string) (string, error) {
+	passwords, err := k.load()
+	if err != nil {
+		return "", err
+	}
+
+	password, ok := passwords[connection]
+	if !ok {
+		return "", ErrNoPassword
+	}
+
+	return password, nil
+}
+
+func (k fileKeyring) Set(connection, password string) error {
+	passwords, err := k.load()
+	if err != nil {
+		return err
+	}
+
+	passwords[connection] = password
+
+	return k.save(passwords)
+}
+
+func (k fileKeyring) Delete(connection string) error {
+	passwords, err := k.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := passwords[connection]; !ok {
+		return nil
+	}
+
+	delete(passwords, connection)
+
+	return k.save(passwords)
+}
@@ -0,0 +1,124 @@
+package helpers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useFileKeyring makes OpenKeyring return the file keyring, in a temporary
+// directory, and returns the path of its file
+func useFileKeyring(t *testing.T) string {
+	t.Helper()
+
+	directory := t.TempDir()
+	SetConfigFilePath(filepath.Join(directory, "config.toml"))
+	t.Cleanup(func() { SetConfigFilePath("") })
+
+	err := os.WriteFile(ConfigFilePath(), []byte("[credentials]\nkeyring = \"file\"\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(directory, "credentials.toml")
+}
+
+func TestFileKeyring(t *testing.T) {
+	path := useFileKeyring(t)
+
+	keyring, err := OpenKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := keyring.Get("prod"); !errors.Is(err, ErrNoPassword) {
+		t.Errorf("Get(prod) error = %v before it's set, want ErrNoPassword", err)
+	}
+
+	for _, password := range []string{"first", "p@ss\"word\n"} {
+		err = keyring.Set("prod", password)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := keyring.Get("prod")
+		if err != nil || got != password {
+			t.Errorf("Get(prod) = %q, %v, want %q", got, err, password)
+		}
+	}
+
+	err = keyring.Set("staging", "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("%s has the permissions %v, want -rw-------", path, info.Mode().Perm())
+	}
+
+	err = keyring.Delete("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := keyring.Get("prod"); !errors.Is(err, ErrNoPassword) {
+		t.Errorf("Get(prod) error = %v after Delete, want ErrNoPassword", err)
+	}
+
+	if got, err := keyring.Get("staging"); err != nil || got != "other" {
+		t.Errorf("Get(staging) = %q, %v after deleting prod, want other", got, err)
+	}
+
+	if err := keyring.Delete("missing"); err != nil {
+		t.Errorf("Delete(missing) = %v, want nil", err)
+	}
+}
+
+func TestFileKeyringTightensPermissions(t *testing.T) {
+	path := useFileKeyring(t)
+
+	err := os.WriteFile(path, []byte{}, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyring, err := OpenKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = keyring.Set("prod", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("%s has the permissions %v, want -rw-------", path, info.Mode().Perm())
+	}
+}
+
+func TestOpenKeyringUnknown(t *testing.T) {
+	SetConfigFilePath(filepath.Join(t.TempDir(), "config.toml"))
+	defer SetConfigFilePath("")
+
+	err := os.WriteFile(ConfigFilePath(), []byte("[credentials]\nkeyring = \"vault\"\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = OpenKeyring()
+	if err == nil {
+		t.Error("OpenKeyring() didn't fail for an unknown keyring")
+	}
+}
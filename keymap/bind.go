@@ -10,8 +10,19 @@ import (
 type Bind struct {
 	Key Key
 	Cmd commands.Command
+	// Prefix are the keys typed before Key in a sequence, e.g. the first g of
+	// gg. A single key has none.
+	Prefix []Key
+}
+
+// Keys returns the whole sequence of keys of the bind
+func (b Bind) Keys() []Key {
+	keys := make([]Key, 0, len(b.Prefix)+1)
+	keys = append(keys, b.Prefix...)
+
+	return append(keys, b.Key)
 }
 
 func (b Bind) String() string {
-	return fmt.Sprintf("%s = %s", b.Key.String(), b.Cmd.String())
+	return fmt.Sprintf("%s = %s", SequenceString(b.Keys()), b.Cmd.String())
 }
@@ -1,21 +1,293 @@
 package keymap
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
 
 // Key is a structure that represents a key that can be bound
 // to an command
 type Key struct {
 	Code tcell.Key // Special character codes.
 	Char rune      // used when the key represents a single ascii char like "a" or "2".
+	// Mod are the modifiers held with the key. Only Alt is told apart for
+	// characters and control keys, e.g. <A-j>, Ctrl and Shift are for the
+	// other keys, e.g. <C-Up>.
+	Mod tcell.ModMask
+}
+
+// namedKeys are the keys written by name between angle brackets, e.g. <CR>.
+// The first name of a key is the one it's written back with.
+var namedKeys = []struct {
+	names []string
+	key   Key
+}{
+	{[]string{"CR", "Enter", "Return"}, Key{Code: tcell.KeyEnter}},
+	{[]string{"Esc", "Escape"}, Key{Code: tcell.KeyEscape}},
+	{[]string{"Tab"}, Key{Code: tcell.KeyTab}},
+	{[]string{"S-Tab", "Backtab"}, Key{Code: tcell.KeyBacktab}},
+	{[]string{"BS", "Backspace"}, Key{Code: tcell.KeyBackspace2}},
+	{[]string{"Del", "Delete"}, Key{Code: tcell.KeyDelete}},
+	{[]string{"Insert", "Ins"}, Key{Code: tcell.KeyInsert}},
+	{[]string{"Up"}, Key{Code: tcell.KeyUp}},
+	{[]string{"Down"}, Key{Code: tcell.KeyDown}},
+	{[]string{"Left"}, Key{Code: tcell.KeyLeft}},
+	{[]string{"Right"}, Key{Code: tcell.KeyRight}},
+	{[]string{"Home"}, Key{Code: tcell.KeyHome}},
+	{[]string{"End"}, Key{Code: tcell.KeyEnd}},
+	{[]string{"PageUp", "PgUp"}, Key{Code: tcell.KeyPgUp}},
+	{[]string{"PageDown", "PgDn"}, Key{Code: tcell.KeyPgDn}},
+	{[]string{"Space"}, Key{Char: ' '}},
+	{[]string{"lt"}, Key{Char: '<'}},
+	{[]string{"gt"}, Key{Char: '>'}},
+	{[]string{"Bar"}, Key{Char: '|'}},
+	{[]string{"Bslash"}, Key{Char: '\\'}},
+}
+
+// ctrlChars are the control keys typed with Ctrl and a character that isn't a
+// letter
+var ctrlChars = map[rune]tcell.Key{
+	' ':  tcell.KeyCtrlSpace,
+	'[':  tcell.KeyEscape,
+	'\\': tcell.KeyCtrlBackslash,
+	']':  tcell.KeyCtrlRightSq,
+	'^':  tcell.KeyCtrlCarat,
+	'_':  tcell.KeyCtrlUnderscore,
+}
+
+// ParseKey reads a key written the way Vim writes them: a single character,
+// e.g. "J", or a key between angle brackets, e.g. "<CR>", "<C-r>", "<A-j>",
+// "<S-Tab>", "<C-Up>" or "<F5>". Names aren't case sensitive but the
+// characters are.
+func ParseKey(notation string) (Key, error) {
+	if utf8.RuneCountInString(notation) == 1 {
+		char, _ := utf8.DecodeRuneInString(notation)
+		return Key{Char: char}, nil
+	}
+
+	if len(notation) < 3 || notation[0] != '<' || notation[len(notation)-1] != '>' {
+		return Key{}, fmt.Errorf("invalid key %q, expected a character or a key like <C-r>", notation)
+	}
+
+	name := notation[1 : len(notation)-1]
+
+	if key, ok := lookupKey(name); ok {
+		return key, nil
+	}
+
+	var mod tcell.ModMask
+
+modifiers:
+	for len(name) > 2 && name[1] == '-' {
+		switch name[0] {
+		case 'C', 'c':
+			mod |= tcell.ModCtrl
+		case 'S', 's':
+			mod |= tcell.ModShift
+		case 'A', 'a', 'M', 'm':
+			mod |= tcell.ModAlt
+		default:
+			break modifiers
+		}
+
+		name = name[2:]
+	}
+
+	key, ok := lookupKey(name)
+
+	if !ok {
+		char, size := utf8.DecodeRuneInString(name)
+
+		if size != len(name) {
+			return Key{}, fmt.Errorf("unknown key %q", notation)
+		}
+
+		key = Key{Char: char}
+	}
+
+	key, ok = withModifiers(key, mod)
+	if !ok {
+		return Key{}, fmt.Errorf("unsupported modifiers in key %q", notation)
+	}
+
+	return key, nil
+}
+
+// withModifiers returns key typed while holding mod. Ctrl and Shift change the
+// characters and the control keys, ok is false when they can't be typed.
+func withModifiers(key Key, mod tcell.ModMask) (Key, bool) {
+	ctrl, shift := mod&tcell.ModCtrl != 0, mod&tcell.ModShift != 0
+	key.Mod |= mod & tcell.ModAlt
+
+	switch {
+	case key.Char != 0:
+		letter := unicode.ToLower(key.Char)
+		isLetter := letter >= 'a' && letter <= 'z'
+
+		if ctrl && isLetter {
+			key = Key{Code: tcell.KeyCtrlA + tcell.Key(letter-'a'), Mod: key.Mod}
+		} else if code, ok := ctrlChars[key.Char]; ctrl && ok {
+			key = Key{Code: code, Mod: key.Mod}
+		} else if ctrl || (shift && !isLetter) {
+			return key, false
+		} else if shift {
+			key.Char = unicode.ToUpper(key.Char)
+		}
+	case key.Code == tcell.KeyTab && shift && !ctrl:
+		key.Code = tcell.KeyBacktab
+	case isControlKey(key.Code):
+		if ctrl || shift {
+			return key, false
+		}
+	default:
+		key.Mod |= mod & (tcell.ModCtrl | tcell.ModShift)
+	}
+
+	return key, true
 }
 
+// isControlKey tells the keys typed as an ASCII control character, which
+// terminals send the same with or without Ctrl and Shift
+func isControlKey(code tcell.Key) bool {
+	return code <= tcell.KeyUS || code == tcell.KeyDEL
+}
+
+// lookupKey finds a key by one of its names, or by its name in tcell, e.g.
+// "Ctrl-R" or "F5"
+func lookupKey(name string) (Key, bool) {
+	for _, named := range namedKeys {
+		for _, n := range named.names {
+			if strings.EqualFold(n, name) {
+				return named.key, true
+			}
+		}
+	}
+
+	for code, n := range tcell.KeyNames {
+		if strings.EqualFold(n, name) && code != tcell.KeyRune {
+			return Key{Code: code}, true
+		}
+	}
+
+	return Key{}, false
+}
+
+// KeyOf returns the key of an event, with the modifiers a Key tells apart
+func KeyOf(event *tcell.EventKey) Key {
+	mod := event.Modifiers() & (tcell.ModCtrl | tcell.ModShift | tcell.ModAlt)
+
+	if event.Key() == tcell.KeyRune {
+		return Key{Char: event.Rune(), Mod: mod & tcell.ModAlt}
+	}
+
+	if isControlKey(event.Key()) {
+		mod &= tcell.ModAlt
+	}
+
+	return Key{Code: event.Key(), Mod: mod}
+}
+
+// String returns the key written the way ParseKey reads it
 func (k Key) String() string {
-	if k.Char != 0 {
-		return string(k.Char)
+	var notation string
+
+	switch {
+	case k.Char == ' ':
+		notation = "Space"
+	case k.Char != 0:
+		if k.Mod == 0 {
+			return string(k.Char)
+		}
+
+		notation = string(k.Char)
+	default:
+		for _, named := range namedKeys {
+			if named.key.Char == 0 && named.key.Code == k.Code {
+				notation = named.names[0]
+				break
+			}
+		}
+
+		for char, code := range ctrlChars {
+			if notation == "" && code == k.Code && char == ' ' {
+				notation = "C-Space"
+			} else if notation == "" && code == k.Code {
+				notation = "C-" + string(char)
+			}
+		}
+
+		if notation == "" && k.Code >= tcell.KeyCtrlA && k.Code <= tcell.KeyCtrlZ {
+			notation = "C-" + string(rune('a'+k.Code-tcell.KeyCtrlA))
+		} else if notation == "" {
+			notation = tcell.KeyNames[k.Code]
+		}
+	}
+
+	if k.Mod&tcell.ModShift != 0 {
+		notation = "S-" + notation
+	}
+
+	if k.Mod&tcell.ModCtrl != 0 {
+		notation = "C-" + notation
 	}
 
-	if desc, ok := tcell.KeyNames[k.Code]; ok {
-		return "<" + desc + ">"
+	if k.Mod&tcell.ModAlt != 0 {
+		notation = "A-" + notation
 	}
-	return ""
+
+	return "<" + notation + ">"
+}
+
+// ParseSequence reads a sequence of keys written one after the other, e.g.
+// "gg", "<C-w>l" or "<leader>d". <leader> stands for the leader key.
+func ParseSequence(notation string, leader Key) ([]Key, error) {
+	keys := []Key{}
+
+	for notation != "" {
+		token, _ := utf8.DecodeRuneInString(notation)
+		length := utf8.RuneLen(token)
+
+		if end := strings.IndexByte(notation, '>'); token == '<' && end > 1 {
+			length = end + 1
+		}
+
+		if strings.EqualFold(notation[:length], "<leader>") {
+			keys = append(keys, leader)
+		} else {
+			key, err := ParseKey(notation[:length])
+			if err != nil {
+				return nil, err
+			}
+
+			keys = append(keys, key)
+		}
+
+		notation = notation[length:]
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("empty key")
+	}
+
+	return keys, nil
+}
+
+// SequenceString returns a sequence of keys written the way ParseSequence
+// reads it
+func SequenceString(keys []Key) string {
+	var notation strings.Builder
+
+	for _, key := range keys {
+		if key == (Key{Char: '<'}) && len(keys) > 1 {
+			notation.WriteString("<lt>")
+		} else {
+			notation.WriteString(key.String())
+		}
+	}
+
+	return notation.String()
 }
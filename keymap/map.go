@@ -11,17 +11,50 @@ type Map []Bind
 // Resolve translates a tcell.EventKey to a
 // command based on the bindings in the map.
 //
+// The event resolves the whole sequence it ended when one was read, see
+// SetSequence, and the key it holds otherwise.
+//
 // If no binding could be found. commands.Noop is returned.
 func (m Map) Resolve(event *tcell.EventKey) commands.Command {
+	command, _ := m.Match(sequenceOf(event))
+
+	return command
+}
+
+// Match returns the command bound to a sequence of keys, and whether the
+// sequence is the start of a longer one.
+func (m Map) Match(keys []Key) (command commands.Command, prefix bool) {
+	command = commands.Noop
+
 	for _, bind := range m {
-		if event.Key() == tcell.KeyRune {
-			if bind.Key.Char == event.Rune() {
-				return bind.Cmd
+		sequence := bind.Keys()
+
+		if len(sequence) < len(keys) || !equalKeys(sequence[:len(keys)], keys) {
+			continue
+		}
+
+		if len(sequence) == len(keys) {
+			if command == commands.Noop {
+				command = bind.Cmd
 			}
-		} else if event.Key() == bind.Key.Code {
-			return bind.Cmd
+		} else {
+			prefix = true
+		}
+	}
+
+	return command, prefix
+}
+
+func equalKeys(a, b []Key) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
 	}
 
-	return commands.Noop
+	return true
 }
@@ -0,0 +1,38 @@
+package keymap
+
+import "github.com/gdamore/tcell/v2"
+
+// read is the sequence the last key read ended, along with the count typed
+// before it
+var read struct {
+	event *tcell.EventKey
+	keys  []Key
+	count int
+}
+
+// SetSequence records that event ends a sequence of keys, typed after a
+// count. The maps resolve the event as the whole sequence. A count of 0 means
+// none was typed.
+func SetSequence(event *tcell.EventKey, keys []Key, count int) {
+	read.event = event
+	read.keys = keys
+	read.count = count
+}
+
+// Count returns the count typed before the sequence event ends, 1 when there
+// is none
+func Count(event *tcell.EventKey) int {
+	if read.event == event && read.count > 0 {
+		return read.count
+	}
+
+	return 1
+}
+
+func sequenceOf(event *tcell.EventKey) []Key {
+	if read.event == event {
+		return read.keys
+	}
+
+	return []Key{KeyOf(event)}
+}
@@ -0,0 +1,67 @@
+package keymap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseSequence(t *testing.T) {
+	leader := Key{Char: ' '}
+
+	tests := []struct {
+		notation string
+		want     []Key
+	}{
+		{"j", []Key{{Char: 'j'}}},
+		{"gg", []Key{{Char: 'g'}, {Char: 'g'}}},
+		{"<CR>", []Key{{Code: tcell.KeyEnter}}},
+		{"<C-w>l", []Key{{Code: tcell.KeyCtrlW}, {Char: 'l'}}},
+		{"<c-R>", []Key{{Code: tcell.KeyCtrlR}}},
+		{"<A-j>", []Key{{Char: 'j', Mod: tcell.ModAlt}}},
+		{"<S-j>", []Key{{Char: 'J'}}},
+		{"<S-Tab>", []Key{{Code: tcell.KeyBacktab}}},
+		{"<C-Up>", []Key{{Code: tcell.KeyUp, Mod: tcell.ModCtrl}}},
+		{"<F5>", []Key{{Code: tcell.KeyF5}}},
+		{"<leader>d", []Key{leader, {Char: 'd'}}},
+		{"<Leader><leader>", []Key{leader, leader}},
+		{"<", []Key{{Char: '<'}}},
+		{"<lt>gt", []Key{{Char: '<'}, {Char: 'g'}, {Char: 't'}}},
+		{"<<", []Key{{Char: '<'}, {Char: '<'}}},
+		{"é", []Key{{Char: 'é'}}},
+	}
+
+	for _, test := range tests {
+		keys, err := ParseSequence(test.notation, leader)
+		if err != nil {
+			t.Errorf("ParseSequence(%q) failed: %v", test.notation, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(keys, test.want) {
+			t.Errorf("ParseSequence(%q) is %v, want %v", test.notation, keys, test.want)
+		}
+	}
+}
+
+func TestParseSequenceErrors(t *testing.T) {
+	for _, notation := range []string{"", "<Nope>", "<C-1>", "<S-Up>x<Bogus>"} {
+		if keys, err := ParseSequence(notation, Key{Char: ' '}); err == nil {
+			t.Errorf("ParseSequence(%q) is %v, want an error", notation, keys)
+		}
+	}
+}
+
+func TestSequenceString(t *testing.T) {
+	for _, notation := range []string{"gg", "<C-w>l", "<", "<lt>a", "<CR>", "<A-j>"} {
+		keys, err := ParseSequence(notation, Key{Char: ' '})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := SequenceString(keys); got != notation {
+			t.Errorf("SequenceString(ParseSequence(%q)) is %q", notation, got)
+		}
+	}
+}
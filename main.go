@@ -1,13 +1,18 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
 
 	"github.com/jorgerojas26/lazysql/app"
 	"github.com/jorgerojas26/lazysql/components"
+	"github.com/jorgerojas26/lazysql/helpers"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/go-sql-driver/mysql"
 )
 
@@ -24,13 +29,69 @@ func main() {
 		case "version":
 			println("LazySQL version: ", version)
 			os.Exit(0)
+		case "exec":
+			os.Exit(runExec(os.Args[2:], os.Stdout, os.Stderr))
 		}
 	}
 
-	if err := app.App.
+	opts, err := parseOptions(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// The keys set in the config replace the default ones
+	config, err := opts.loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't read the config %s: %s\n", helpers.ConfigFilePath(), err)
+		os.Exit(1)
+	}
+
+	if err := app.Keymaps.Configure(config.Keymap); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid keymap in %s:\n%s\n", helpers.ConfigFilePath(), err)
+		os.Exit(1)
+	}
+
+	if err := app.SetTheme(config.Theme.Name, config.Theme.Colors); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid theme in %s:\n%s\n", helpers.ConfigFilePath(), err)
+		os.Exit(1)
+	}
+
+	connection, ok, err := opts.openedConnection()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	components.Init()
+	components.ReadOnly = opts.readOnly
+
+	app.App.
 		SetRoot(components.MainPages, true).
-		EnableMouse(true).
-		Run(); err != nil {
+		EnableMouse(true)
+
+	// The connection given as argument opens in place of the list
+	if ok {
+		if err := components.Open(connection, terminalPassword); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't connect to %s: %s\n", connection.Name, err)
+			os.Exit(1)
+		}
+	}
+
+	if app.Styles.NoColor {
+		screen, err := tcell.NewScreen()
+		if err != nil {
+			panic(err)
+		}
+
+		app.App.SetScreen(app.NoColorScreen(screen))
+	}
+
+	if err := app.App.Run(); err != nil {
 		panic(err)
 	}
+
+	components.CloseConnections()
 }
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/rivo/tview"
 )
@@ -9,7 +11,49 @@ type Connection struct {
 	Name     string
 	Provider string
 	DBName   string
-	URL      string
+	// URL may hold ${NAME} references to environment variables, and leave
+	// the password out when PasswordCmd or Keyring gives it
+	URL string
+	// PasswordCmd is a shell command printing the password on its first
+	// line, e.g. pass show db/prod
+	PasswordCmd string `toml:"password_cmd,omitempty"`
+	// Keyring is true when the password is kept in the keyring, under the
+	// name of the connection
+	Keyring bool `toml:"keyring,omitempty"`
+	// SSH is the tunnel the database is reached through, nil to connect
+	// directly
+	SSH *SSHTunnel `toml:"ssh,omitempty"`
+	// ReadOnly opens read-only sessions where the database supports it, and
+	// disables the changes to the rows and the statements of the SQL editor
+	// that write. --read-only sets it for the connections opened.
+	ReadOnly bool `toml:"read_only,omitempty"`
+}
+
+// SSHTunnel holds the settings of the SSH server a database is reached
+// through
+type SSHTunnel struct {
+	// Host is the SSH server, as host or host:port
+	Host string `toml:"host"`
+	// User defaults to the current user
+	User    string `toml:"user,omitempty"`
+	KeyFile string `toml:"key_file,omitempty"`
+	// Agent authenticates with the keys of the agent of SSH_AUTH_SOCK
+	Agent bool `toml:"agent,omitempty"`
+	// KnownHosts is the file the key of the server is checked against,
+	// ~/.ssh/known_hosts by default
+	KnownHosts string `toml:"known_hosts,omitempty"`
+}
+
+// HistoryEntry is a statement run from the SQL editor
+type HistoryEntry struct {
+	Connection string        `json:"connection"`
+	Database   string        `json:"database,omitempty"`
+	Query      string        `json:"query"`
+	Time       time.Time     `json:"time"`
+	Duration   time.Duration `json:"duration"`
+	// Rows is the number of rows read, or affected by a statement that doesn't return rows
+	Rows  int64  `json:"rows"`
+	Error string `json:"error,omitempty"`
 }
 
 type StateChange struct {
@@ -22,20 +66,72 @@ type ConnectionPages struct {
 	*tview.Pages
 }
 
+// PrimaryKeyInfo is one column/value pair of the set that identifies a row
+type PrimaryKeyInfo struct {
+	Name  string
+	Value string
+}
+
+// CellValueType tells whether a cell holds a literal value, NULL or the
+// default value of its column
+type CellValueType int8
+
+const (
+	String CellValueType = iota
+	Null
+	Default
+)
+
+type CellValue struct {
+	Type  CellValueType
+	Value string
+}
+
+// NewCellValues turns strings, e.g. the names of the columns heading the
+// records, into literal cell values
+func NewCellValues(values []string) []CellValue {
+	cells := make([]CellValue, len(values))
+
+	for i, value := range values {
+		cells[i] = CellValue{Type: String, Value: value}
+	}
+
+	return cells
+}
+
+// CellValueStrings returns the values of literal cells, e.g. the names of the
+// columns heading the records
+func CellValueStrings(cells []CellValue) []string {
+	values := make([]string, len(cells))
+
+	for i, cell := range cells {
+		values[i] = cell.Value
+	}
+
+	return values
+}
+
 type DbDmlChange struct {
-	Type                 string
-	Table                string
-	Column               string
-	Value                string
-	PrimaryKeyColumnName string
-	PrimaryKeyValue      string
-	Option               int
+	Type string
+	// Database is the database of the table, changes are saved through a
+	// connection to it
+	Database string
+	// Schema is the schema of the table, empty in the databases without
+	// schemas
+	Schema         string
+	Table          string
+	Column         string
+	Value          CellValue
+	PrimaryKeyInfo []PrimaryKeyInfo
+	Option         int
 }
 
 type DbInsert struct {
+	Database        string
+	Schema          string
 	Table           string
 	Columns         []string
-	Values          []string
+	Values          []CellValue
 	Option          int
 	PrimaryKeyValue uuid.UUID
 }